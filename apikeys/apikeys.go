@@ -0,0 +1,323 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrRevoked    = errors.New("API key revoked")
+	ErrExpired    = errors.New("API key expired")
+	ErrNotFound   = errors.New("API key not found")
+)
+
+// Key is the stored record of an API key. The secret itself is never stored, only its SHA-256 hash.
+type Key struct {
+	KeyID      string     `bson:"_id" json:"key_id"`
+	Hash       string     `bson:"hash" json:"-"`
+	Name       string     `bson:"name" json:"name"`
+	Owner      string     `bson:"owner" json:"owner"`
+	Scopes     []string   `bson:"scopes,omitempty" json:"scopes,omitempty"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RotatedTo  string     `bson:"rotated_to,omitempty" json:"rotated_to,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// StoreOptions configures a Store
+type StoreOptions struct {
+	Database   string
+	Collection string
+	// Prefix identifies the key issuer in the plaintext key, e.g. "cdk" gives "cdk_<id>_<secret>".
+	// It makes leaked keys easy to recognise in secret scanners. Defaults to "key".
+	Prefix string
+	// DefaultTTL applies to keys created without a TTL. 0 means keys do not expire.
+	DefaultTTL time.Duration
+	// RetainExpired keeps expired and revoked keys for this long before the TTL index removes them.
+	// Defaults to 30 days.
+	RetainExpired time.Duration
+}
+
+// CreateOptions describe a new key
+type CreateOptions struct {
+	Name   string
+	Owner  string
+	Scopes []string
+	// TTL overrides StoreOptions.DefaultTTL.
+	TTL time.Duration
+}
+
+// Store generates, stores and validates API keys in a MongoDB collection
+type Store struct {
+	client *mongoclient.Client
+	keys   *mongoclient.CollectionRepository
+	opts   StoreOptions
+	now    func() time.Time
+}
+
+// NewStore creates a Store backed by the given client
+func NewStore(client *mongoclient.Client, opts StoreOptions) *Store {
+	s := newStore(client, opts)
+	s.client = client
+	return s
+}
+
+// newStore creates a Store on any mongoclient.Store, e.g. a mongoclientmock.Store in tests.
+// Only EnsureIndexes needs the Client.
+func newStore(store mongoclient.Store, opts StoreOptions) *Store {
+	if opts.Prefix == "" {
+		opts.Prefix = "key"
+	}
+	if opts.RetainExpired <= 0 {
+		opts.RetainExpired = 30 * 24 * time.Hour
+	}
+	return &Store{
+		keys: mongoclient.NewRepository(store, mongoclient.RepositoryOptions{Database: opts.Database, Collection: opts.Collection}),
+		opts: opts,
+		now:  time.Now,
+	}
+}
+
+// EnsureIndexes creates the owner lookup index and the TTL index that purges expired keys,
+// in the namespace of the tenant in ctx when the client routes by tenant
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	ns, err := s.client.Resolve(ctx, mongoclient.Namespace{Database: s.opts.Database, Collection: s.opts.Collection})
+	if err != nil {
+		return err
+	}
+	err = s.client.EnsureIndexes(ctx, ns.Database, ns.Collection, []mongoclient.IndexSpec{
+		{Keys: bson.D{{Key: "owner", Value: 1}}},
+		{Keys: bson.D{{Key: "purge_at", Value: 1}}, ExpireAt: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create API key indexes: %w", err)
+	}
+	return nil
+}
+
+// Create generates a new key and returns its plaintext form.
+// The plaintext is only available here; show it to the user once and discard it.
+func (s *Store) Create(ctx context.Context, opts CreateOptions) (string, *Key, error) {
+	keyID, err := randomString(12)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := s.now().UTC()
+	key := &Key{
+		KeyID:     keyID,
+		Hash:      hash(secret),
+		Name:      opts.Name,
+		Owner:     opts.Owner,
+		Scopes:    opts.Scopes,
+		CreatedAt: now,
+	}
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = s.opts.DefaultTTL
+	}
+	if ttl > 0 {
+		exp := now.Add(ttl)
+		key.ExpiresAt = &exp
+	}
+
+	// purge_at drives the TTL index and is kept out of Key on purpose
+	raw, err := bson.Marshal(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode API key: %w", err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return "", nil, fmt.Errorf("failed to encode API key: %w", err)
+	}
+	if key.ExpiresAt != nil {
+		doc["purge_at"] = key.ExpiresAt.Add(s.opts.RetainExpired)
+	}
+	if _, err := s.keys.Insert(ctx, doc); err != nil {
+		return "", nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+	return s.opts.Prefix + "_" + keyID + "_" + secret, key, nil
+}
+
+// Lookup validates a plaintext key and returns its record.
+// It returns ErrInvalidKey, ErrRevoked or ErrExpired when the key must be rejected.
+func (s *Store) Lookup(ctx context.Context, plaintext string) (*Key, error) {
+	keyID, secret, ok := s.parse(plaintext)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	var key Key
+	err := s.keys.FindOne(ctx, bson.M{"_id": keyID}, mongoclient.QueryOptions{}, &key)
+	if errors.Is(err, mongoclient.ErrNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash(secret))) != 1 {
+		return nil, ErrInvalidKey
+	}
+	now := s.now()
+	if key.RevokedAt != nil && !key.RevokedAt.After(now) {
+		return nil, ErrRevoked
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return nil, ErrExpired
+	}
+
+	s.touch(ctx, keyID, now)
+	return &key, nil
+}
+
+// touch records last use, at most once a minute per key to keep writes off the hot path
+func (s *Store) touch(ctx context.Context, keyID string, now time.Time) {
+	filter := bson.M{
+		"_id": keyID,
+		"$or": bson.A{
+			bson.M{"last_used_at": bson.M{"$exists": false}},
+			bson.M{"last_used_at": bson.M{"$lt": now.Add(-time.Minute)}},
+		},
+	}
+	// Best effort: failing to record usage must not reject a valid key
+	_, _ = s.keys.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_used_at": now.UTC()}})
+}
+
+// Revoke immediately invalidates a key. It returns ErrNotFound if the key does not exist or is already revoked.
+func (s *Store) Revoke(ctx context.Context, keyID string) error {
+	now := s.now().UTC()
+	res, err := s.keys.UpdateOne(ctx,
+		bson.M{"_id": keyID, "$or": bson.A{
+			bson.M{"revoked_at": bson.M{"$exists": false}},
+			bson.M{"revoked_at": bson.M{"$gt": now}}, // rotated key still in its grace period
+		}},
+		bson.M{"$set": bson.M{"revoked_at": now, "purge_at": now.Add(s.opts.RetainExpired)}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Rotate issues a replacement for keyID with the same name, owner and scopes.
+// The old key keeps working for grace so clients can roll over, then is revoked.
+// It returns ErrRevoked, without a replacement, if the key is revoked, including by a
+// concurrent Revoke.
+func (s *Store) Rotate(ctx context.Context, keyID string, grace time.Duration) (string, *Key, error) {
+	var old Key
+	err := s.keys.FindOne(ctx, bson.M{"_id": keyID}, mongoclient.QueryOptions{}, &old)
+	if errors.Is(err, mongoclient.ErrNotFound) {
+		return "", nil, ErrNotFound
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if old.RevokedAt != nil {
+		return "", nil, ErrRevoked
+	}
+
+	var ttl time.Duration
+	if old.ExpiresAt != nil {
+		ttl = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+	plaintext, key, err := s.Create(ctx, CreateOptions{Name: old.Name, Owner: old.Owner, Scopes: old.Scopes, TTL: ttl})
+	if err != nil {
+		return "", nil, err
+	}
+
+	revokeAt := s.now().UTC().Add(grace)
+	// Revoke may have run since the lookup; its immediate revocation must not be replaced
+	res, err := s.keys.UpdateOne(ctx, bson.M{"_id": keyID, "revoked_at": bson.M{"$exists": false}}, bson.M{"$set": bson.M{
+		"revoked_at": revokeAt,
+		"rotated_to": key.KeyID,
+		"purge_at":   revokeAt.Add(s.opts.RetainExpired),
+	}})
+	if err == nil && res.MatchedCount == 0 {
+		err = ErrRevoked
+	}
+	if err != nil {
+		if _, delErr := s.keys.DeleteOne(ctx, bson.M{"_id": key.KeyID}); delErr != nil {
+			return "", nil, errors.Join(err, fmt.Errorf("failed to delete replacement API key: %w", delErr))
+		}
+		if errors.Is(err, ErrRevoked) {
+			return "", nil, err
+		}
+		return "", nil, fmt.Errorf("failed to schedule revocation of rotated API key: %w", err)
+	}
+	return plaintext, key, nil
+}
+
+// List returns the keys of an owner, newest first
+func (s *Store) List(ctx context.Context, owner string) ([]Key, error) {
+	var keys []Key
+	opts := mongoclient.QueryOptions{Sort: bson.D{{Key: "created_at", Value: -1}}}
+	if err := s.keys.Find(ctx, bson.M{"owner": owner}, opts, &keys); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// parse splits "<prefix>_<id>_<secret>"
+func (s *Store) parse(plaintext string) (keyID, secret string, ok bool) {
+	rest, found := strings.CutPrefix(plaintext, s.opts.Prefix+"_")
+	if !found {
+		return "", "", false
+	}
+	keyID, secret, found = strings.Cut(rest, "_")
+	if !found || keyID == "" || secret == "" {
+		return "", "", false
+	}
+	return keyID, secret, true
+}
+
+// hash is a plain SHA-256: keys carry 190 bits of entropy, so a slow password hash adds nothing
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range b {
+		v, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate API key: %w", err)
+		}
+		b[i] = alphabet[v.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,261 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"github.com/cdcloud-io/go-libs/mongoclient/mongoclientmock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// hookStore runs beforeUpdate ahead of each UpdateOne, to interleave another operation
+// at an exact point of a Store method
+type hookStore struct {
+	*mongoclientmock.Store
+	beforeUpdate func(update interface{})
+}
+
+func (h *hookStore) UpdateOne(ctx context.Context, params mongoclient.QueryParams, update interface{}) (*mongo.UpdateResult, error) {
+	if fn := h.beforeUpdate; fn != nil {
+		h.beforeUpdate = nil
+		fn(update)
+	}
+	return h.Store.UpdateOne(ctx, params, update)
+}
+
+func newTestStore(t *testing.T) (*Store, *hookStore, *mongoclientmock.Clock) {
+	t.Helper()
+	hooks := &hookStore{Store: mongoclientmock.New()}
+	s := newStore(hooks, StoreOptions{Database: "auth", Collection: "api_keys", Prefix: "cdk"})
+	c := mongoclientmock.NewClock(time.Now().Truncate(time.Millisecond))
+	s.now = c.Now
+	return s, hooks, c
+}
+
+func TestCreateAndLookup(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestStore(t)
+	plaintext, key, err := s.Create(ctx, CreateOptions{Name: "ci", Owner: "team-a", Scopes: []string{"orders:read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plaintext, "cdk_"+key.KeyID+"_") {
+		t.Fatalf("plaintext %q does not have the form cdk_<id>_<secret>", plaintext)
+	}
+
+	got, err := s.Lookup(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if got.Owner != "team-a" || !got.HasScope("orders:read") || got.HasScope("orders:write") {
+		t.Errorf("Lookup() = %+v", got)
+	}
+
+	wrong := []byte(plaintext)
+	wrong[len(wrong)-1] ^= 0x01
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{"wrong secret", string(wrong)},
+		{"unknown id", "cdk_000000000000_" + strings.Repeat("a", 32)},
+		{"other prefix", "key" + strings.TrimPrefix(plaintext, "cdk")},
+		{"no secret", "cdk_" + key.KeyID + "_"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Lookup(ctx, tt.plaintext); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Lookup(%q) error = %v, want ErrInvalidKey", tt.plaintext, err)
+			}
+		})
+	}
+}
+
+func TestLookupExpired(t *testing.T) {
+	ctx := context.Background()
+	s, _, c := newTestStore(t)
+	plaintext, _, err := s.Create(ctx, CreateOptions{Name: "ci", Owner: "team-a", TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Lookup(ctx, plaintext); err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	c.Advance(time.Hour)
+	if _, err := s.Lookup(ctx, plaintext); !errors.Is(err, ErrExpired) {
+		t.Errorf("Lookup() after the TTL error = %v, want ErrExpired", err)
+	}
+}
+
+func TestRotateGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	s, _, c := newTestStore(t)
+	oldPlain, old, err := s.Create(ctx, CreateOptions{Name: "ci", Owner: "team-a", Scopes: []string{"orders:read"}, TTL: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newPlain, replacement, err := s.Rotate(ctx, old.KeyID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replacement.Name != "ci" || replacement.Owner != "team-a" || !replacement.HasScope("orders:read") {
+		t.Errorf("replacement = %+v, want the name, owner and scopes of the old key", replacement)
+	}
+	if replacement.ExpiresAt == nil || !replacement.ExpiresAt.Equal(c.Now().Add(24*time.Hour)) {
+		t.Errorf("replacement expires at %v, want the TTL of the old key", replacement.ExpiresAt)
+	}
+
+	// During the grace period both keys work
+	c.Advance(59 * time.Minute)
+	for _, p := range []string{oldPlain, newPlain} {
+		if _, err := s.Lookup(ctx, p); err != nil {
+			t.Fatalf("Lookup() during the grace period error = %v", err)
+		}
+	}
+	if _, _, err := s.Rotate(ctx, old.KeyID, time.Hour); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Rotate() of a rotated key error = %v, want ErrRevoked", err)
+	}
+
+	// After it only the replacement does
+	c.Advance(time.Minute)
+	if _, err := s.Lookup(ctx, oldPlain); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Lookup(old) after the grace period error = %v, want ErrRevoked", err)
+	}
+	if _, err := s.Lookup(ctx, newPlain); err != nil {
+		t.Errorf("Lookup(new) after the grace period error = %v", err)
+	}
+}
+
+func TestRevokeDuringGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestStore(t)
+	oldPlain, old, err := s.Create(ctx, CreateOptions{Name: "ci", Owner: "team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Rotate(ctx, old.KeyID, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// A leaked key must not keep working for the rest of the grace period
+	if err := s.Revoke(ctx, old.KeyID); err != nil {
+		t.Fatalf("Revoke() during the grace period error = %v", err)
+	}
+	if _, err := s.Lookup(ctx, oldPlain); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Lookup() after Revoke error = %v, want ErrRevoked", err)
+	}
+	if err := s.Revoke(ctx, old.KeyID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Revoke() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRevokeRacingRotate(t *testing.T) {
+	ctx := context.Background()
+	s, hooks, _ := newTestStore(t)
+	oldPlain, old, err := s.Create(ctx, CreateOptions{Name: "ci", Owner: "team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Revoke lands after Rotate has read the key and created the replacement, but before
+	// it schedules the revocation with the grace period
+	hooks.beforeUpdate = func(update interface{}) {
+		if err := s.Revoke(ctx, old.KeyID); err != nil {
+			t.Errorf("Revoke() error = %v", err)
+		}
+	}
+	if _, _, err := s.Rotate(ctx, old.KeyID, time.Hour); !errors.Is(err, ErrRevoked) {
+		t.Fatalf("Rotate() error = %v, want ErrRevoked", err)
+	}
+
+	if _, err := s.Lookup(ctx, oldPlain); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Lookup() error = %v; the revocation was replaced by a grace period", err)
+	}
+	keys, err := s.List(ctx, "team-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].RotatedTo != "" {
+		t.Errorf("keys = %+v, want only the revoked key, with no replacement", keys)
+	}
+}
+
+func TestRevokeRacingRotateConcurrently(t *testing.T) {
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		s, _, _ := newTestStore(t)
+		oldPlain, old, err := s.Create(ctx, CreateOptions{Name: "ci", Owner: "team-a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		var rotateErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _, rotateErr = s.Rotate(ctx, old.KeyID, time.Hour)
+		}()
+		go func() {
+			defer wg.Done()
+			if err := s.Revoke(ctx, old.KeyID); err != nil {
+				t.Errorf("Revoke() error = %v", err)
+			}
+		}()
+		wg.Wait()
+
+		if rotateErr != nil && !errors.Is(rotateErr, ErrRevoked) {
+			t.Fatalf("Rotate() error = %v", rotateErr)
+		}
+		if _, err := s.Lookup(ctx, oldPlain); !errors.Is(err, ErrRevoked) {
+			t.Fatalf("Lookup() after Revoke error = %v, want ErrRevoked (Rotate error %v)", err, rotateErr)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	s, _, c := newTestStore(t)
+	for _, name := range []string{"first", "second"} {
+		if _, _, err := s.Create(ctx, CreateOptions{Name: name, Owner: "team-a"}); err != nil {
+			t.Fatal(err)
+		}
+		c.Advance(time.Second)
+	}
+	if _, _, err := s.Create(ctx, CreateOptions{Name: "other", Owner: "team-b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := s.List(ctx, "team-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].Name != "second" || keys[1].Name != "first" {
+		t.Errorf("List() = %+v, want second then first", keys)
+	}
+}
+
+// The secret is never stored, only its hash
+func TestSecretNotStored(t *testing.T) {
+	ctx := context.Background()
+	s, hooks, _ := newTestStore(t)
+	plaintext, _, err := s.Create(ctx, CreateOptions{Name: "ci", Owner: "team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := plaintext[strings.LastIndex(plaintext, "_")+1:]
+	for _, doc := range hooks.Documents("auth", "api_keys") {
+		raw, _ := bson.MarshalExtJSON(doc, false, false)
+		if strings.Contains(string(raw), secret) {
+			t.Errorf("stored document %s contains the secret", raw)
+		}
+	}
+}
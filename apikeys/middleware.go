@@ -0,0 +1,57 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// HeaderName is the header read by Middleware
+const HeaderName = "X-API-Key"
+
+type keyContextKey struct{}
+
+// FromContext returns the key authenticated by Middleware
+func FromContext(ctx context.Context) (*Key, bool) {
+	k, ok := ctx.Value(keyContextKey{}).(*Key)
+	return k, ok
+}
+
+// Middleware authenticates requests with a key from the X-API-Key header
+// (or "Authorization: ApiKey <key>") and rejects the rest with 401.
+// When scopes are given the key must hold all of them, otherwise 403.
+func (s *Store) Middleware(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plaintext := r.Header.Get(HeaderName)
+			if plaintext == "" {
+				if v, ok := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey "); ok {
+					plaintext = strings.TrimSpace(v)
+				}
+			}
+			if plaintext == "" {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			key, err := s.Lookup(r.Context(), plaintext)
+			switch {
+			case errors.Is(err, ErrInvalidKey), errors.Is(err, ErrRevoked), errors.Is(err, ErrExpired):
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			case err != nil:
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+
+			for _, scope := range scopes {
+				if !key.HasScope(scope) {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), keyContextKey{}, key)))
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cdcloud-io/go-libs/validate"
 	"gopkg.in/yaml.v3"
 )
 
@@ -40,11 +41,32 @@ func Load() Config {
 	// Replace placeholders with environment variables
 	ReplaceEnvVars(&config)
 
+	if err := validate.Struct(&config); err != nil {
+		fmt.Printf("🟥 STARTUP ERROR: Invalid configuration: %v. Exit 1\n\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("🟩 STARTUP INFO: Successfully validated the config data")
+
 	fmt.Printf("🟩 STARTUP INFO: configs loaded in: %v \n", time.Since(startTime))
 
 	return config
 }
 
+// Validate checks rules that span several fields; validate.Struct calls it after the tag rules
+func (c *Config) Validate() error {
+	var errs validate.Errors
+	if c.Telemetry.Enabled && c.Telemetry.OTLPEndpoint == "" {
+		errs = append(errs, validate.Errorf("telemetry.otlp_endpoint", "required", "telemetry.otlp_endpoint is required when telemetry is enabled")...)
+	}
+	if c.Metrics.Push.Exporter != "" && c.Metrics.Push.Endpoint == "" {
+		errs = append(errs, validate.Errorf("metrics.push.endpoint", "required", "metrics.push.endpoint is required when a push exporter is set")...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
 func ReplaceEnvVars(config *Config) {
 	v := reflect.ValueOf(config).Elem()
 	replaceEnvVars(v)
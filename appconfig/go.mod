@@ -1,5 +1,8 @@
 module github.com/cdcloud-io/go-libs/appconfig
 
-go 1.22.3
+go 1.22.4
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/cdcloud-io/go-libs/validate v0.1.0
+	gopkg.in/yaml.v3 v3.0.1
+)
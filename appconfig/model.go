@@ -1,8 +1,10 @@
 package appconfig
 
+import "time"
+
 type Config struct {
 	App struct {
-		Name      string `yaml:"name"`
+		Name      string `yaml:"name" validate:"required"`
 		Version   string `yaml:"version"`
 		CommitSha string `yaml:"commit_sha"`
 		BuildID   string `yaml:"build_id"`
@@ -12,8 +14,31 @@ type Config struct {
 	} `yaml:"app"`
 	Server struct {
 		Host           string `yaml:"host"`
-		Port           string `yaml:"port"`
+		Port           string `yaml:"port" validate:"required,numeric"`
 		HealthEndpoint string `yaml:"health_endpoint"`
 		InfoEndpoint   string `yaml:"info_endpoint"`
 	} `yaml:"server"`
+	Telemetry struct {
+		Enabled            bool              `yaml:"enabled"`
+		OTLPEndpoint       string            `yaml:"otlp_endpoint"`
+		OTLPProtocol       string            `yaml:"otlp_protocol" validate:"omitempty,oneof=grpc http http/protobuf"`
+		Insecure           bool              `yaml:"insecure"`
+		Sampler            string            `yaml:"sampler" validate:"omitempty,oneof=always_on always_off traceidratio parentbased_traceidratio"`
+		SamplerRatio       float64           `yaml:"sampler_ratio" validate:"gte=0,lte=1"`
+		ResourceAttributes map[string]string `yaml:"resource_attributes"`
+	} `yaml:"telemetry"`
+	Metrics struct {
+		Enabled bool   `yaml:"enabled"`
+		Path    string `yaml:"path"`
+		Runtime bool   `yaml:"runtime"`
+		Push    struct {
+			Exporter string        `yaml:"exporter" validate:"omitempty,oneof=otlp statsd"`
+			Endpoint string        `yaml:"endpoint"`
+			Protocol string        `yaml:"protocol" validate:"omitempty,oneof=grpc http http/protobuf"`
+			Insecure bool          `yaml:"insecure"`
+			Interval time.Duration `yaml:"interval"`
+			Prefix   string        `yaml:"prefix"`
+			Tagged   bool          `yaml:"tagged"`
+		} `yaml:"push"`
+	} `yaml:"metrics"`
 }
@@ -0,0 +1,200 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	ErrForbidden       = errors.New("permission denied")
+	ErrUnauthenticated = errors.New("no principal in context")
+)
+
+// Role grants permissions and inherits those of other roles.
+// Permissions have the form "resource:action"; "orders:*" grants every action
+// on orders and "*" grants everything.
+type Role struct {
+	Name        string   `yaml:"name" bson:"_id"`
+	Permissions []string `yaml:"permissions" bson:"permissions"`
+	Inherits    []string `yaml:"inherits,omitempty" bson:"inherits,omitempty"`
+}
+
+// Policy is a resolved set of roles
+type Policy struct {
+	roles map[string][]string // role -> effective permissions including inherited ones
+}
+
+// NewPolicy resolves role inheritance and returns an error on unknown parents or cycles
+func NewPolicy(roles []Role) (*Policy, error) {
+	byName := make(map[string]Role, len(roles))
+	for _, r := range roles {
+		if r.Name == "" {
+			return nil, fmt.Errorf("role without a name")
+		}
+		if _, dup := byName[r.Name]; dup {
+			return nil, fmt.Errorf("duplicate role %q", r.Name)
+		}
+		byName[r.Name] = r
+	}
+
+	p := &Policy{roles: make(map[string][]string, len(roles))}
+	for name := range byName {
+		perms, err := resolve(byName, name, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		p.roles[name] = perms
+	}
+	return p, nil
+}
+
+func resolve(roles map[string]Role, name string, visiting map[string]bool) ([]string, error) {
+	if visiting[name] {
+		return nil, fmt.Errorf("role inheritance cycle at %q", name)
+	}
+	r, ok := roles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown role %q", name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	perms := append([]string(nil), r.Permissions...)
+	for _, parent := range r.Inherits {
+		inherited, err := resolve(roles, parent, visiting)
+		if err != nil {
+			return nil, err
+		}
+		perms = append(perms, inherited...)
+	}
+	return perms, nil
+}
+
+// Allowed reports whether any of roles grants permission
+func (p *Policy) Allowed(roles []string, permission string) bool {
+	for _, role := range roles {
+		for _, granted := range p.roles[role] {
+			if matches(granted, permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Permissions returns the effective permissions of a role
+func (p *Policy) Permissions(role string) []string {
+	return append([]string(nil), p.roles[role]...)
+}
+
+func matches(granted, permission string) bool {
+	if granted == "*" || granted == permission {
+		return true
+	}
+	if resource, ok := strings.CutSuffix(granted, ":*"); ok {
+		return strings.HasPrefix(permission, resource+":")
+	}
+	return false
+}
+
+// Principal is the authenticated caller
+type Principal struct {
+	Subject string
+	Roles   []string
+}
+
+type principalKey struct{}
+
+// WithPrincipal stores the caller in ctx. Authentication middleware
+// (JWT, API keys, ...) calls it after mapping its credentials to roles.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the caller stored in ctx
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Authorizer checks permissions against a policy that can be swapped at runtime
+type Authorizer struct {
+	policy atomic.Pointer[Policy]
+}
+
+// New creates an Authorizer with the given policy
+func New(policy *Policy) *Authorizer {
+	a := &Authorizer{}
+	a.policy.Store(policy)
+	return a
+}
+
+// SetPolicy replaces the policy, e.g. after reloading it from Mongo
+func (a *Authorizer) SetPolicy(policy *Policy) {
+	a.policy.Store(policy)
+}
+
+// Require returns nil when the principal in ctx holds permission,
+// ErrUnauthenticated when there is no principal and ErrForbidden otherwise
+func (a *Authorizer) Require(ctx context.Context, permission string) error {
+	p, ok := PrincipalFrom(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if !a.policy.Load().Allowed(p.Roles, permission) {
+		return fmt.Errorf("%w: %s requires %q", ErrForbidden, p.Subject, permission)
+	}
+	return nil
+}
+
+// Can reports whether the principal in ctx holds permission
+func (a *Authorizer) Can(ctx context.Context, permission string) bool {
+	return a.Require(ctx, permission) == nil
+}
+
+// Middleware rejects requests whose principal lacks any of the permissions,
+// with 401 when there is no principal and 403 otherwise
+func (a *Authorizer) Middleware(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, perm := range permissions {
+				if err := a.Require(r.Context(), perm); err != nil {
+					status := http.StatusForbidden
+					if errors.Is(err, ErrUnauthenticated) {
+						status = http.StatusUnauthorized
+					}
+					http.Error(w, http.StatusText(status), status)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var defaultAuthorizer atomic.Pointer[Authorizer]
+
+// SetDefault installs the Authorizer used by the package-level Require and Can
+func SetDefault(a *Authorizer) {
+	defaultAuthorizer.Store(a)
+}
+
+// Require checks permission with the default Authorizer, so handlers can call
+// authz.Require(ctx, "orders:write") without passing an Authorizer around.
+// It returns ErrForbidden when no default has been set.
+func Require(ctx context.Context, permission string) error {
+	a := defaultAuthorizer.Load()
+	if a == nil {
+		return fmt.Errorf("%w: no default authorizer", ErrForbidden)
+	}
+	return a.Require(ctx, permission)
+}
+
+// Can reports whether the principal in ctx holds permission under the default Authorizer
+func Can(ctx context.Context, permission string) bool {
+	return Require(ctx, permission) == nil
+}
@@ -0,0 +1,44 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile reads a policy from a YAML file in the form
+//
+//	roles:
+//	  - name: viewer
+//	    permissions: ["orders:read"]
+//	  - name: editor
+//	    permissions: ["orders:write"]
+//	    inherits: [viewer]
+func LoadFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	var doc struct {
+		Roles []Role `yaml:"roles"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy file: %w", err)
+	}
+	return NewPolicy(doc.Roles)
+}
+
+// LoadMongo reads a policy from a collection with one document per role:
+// {_id: "editor", permissions: ["orders:write"], inherits: ["viewer"]}. With a TenantResolver
+// on the client, the roles are read from the collection of the tenant in ctx.
+func LoadMongo(ctx context.Context, client *mongoclient.Client, database, collection string) (*Policy, error) {
+	var roles []Role
+	if err := client.Repository(database, collection).Find(ctx, bson.M{}, mongoclient.QueryOptions{}, &roles); err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	return NewPolicy(roles)
+}
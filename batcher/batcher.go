@@ -0,0 +1,210 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// FlushFunc receives a full batch, e.g. to InsertMany into Mongo or send to a queue.
+// The slice is not reused by the batcher after the call returns.
+type FlushFunc[T any] func(ctx context.Context, batch []T) error
+
+// Options configures a Batcher. A batch is flushed as soon as any limit is reached.
+type Options[T any] struct {
+	// MaxItems flushes once the batch holds this many items. Defaults to 100.
+	MaxItems int
+	// MaxBytes flushes before the batch would exceed this size as measured by SizeOf.
+	// Zero disables the byte limit.
+	MaxBytes int
+	// SizeOf returns the size of an item in bytes. Required when MaxBytes is set.
+	SizeOf func(item T) int
+	// Interval flushes a non-empty batch after this long even if it is not full. Defaults to 1s.
+	Interval time.Duration
+	// BufferSize is the number of items Add can queue before it blocks. Defaults to MaxItems.
+	BufferSize int
+	// FlushTimeout bounds a single FlushFunc call. Defaults to 30s.
+	FlushTimeout time.Duration
+	// OnError is called when FlushFunc fails. The batch is dropped.
+	OnError func(err error, batch []T)
+}
+
+// ErrClosed is returned by Add and Flush after Close
+var ErrClosed = errors.New("batcher closed")
+
+// Batcher accumulates items and hands them to a FlushFunc in batches.
+// All flushes happen on a single background goroutine, one at a time.
+type Batcher[T any] struct {
+	flush FlushFunc[T]
+	opts  Options[T]
+
+	mu      sync.RWMutex
+	closed  bool
+	items   chan T
+	flushes chan chan error
+	done    chan struct{}
+}
+
+// New creates a Batcher and starts its background goroutine.
+// Call Close on shutdown to flush whatever is still buffered.
+func New[T any](flush FlushFunc[T], opts Options[T]) *Batcher[T] {
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = 100
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = opts.MaxItems
+	}
+	if opts.FlushTimeout <= 0 {
+		opts.FlushTimeout = 30 * time.Second
+	}
+	if opts.MaxBytes > 0 && opts.SizeOf == nil {
+		panic("batcher: SizeOf is required when MaxBytes is set")
+	}
+
+	b := &Batcher[T]{
+		flush:   flush,
+		opts:    opts,
+		items:   make(chan T, opts.BufferSize),
+		flushes: make(chan chan error),
+		done:    make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Add queues an item, blocking while the buffer is full or until ctx is done
+func (b *Batcher[T]) Add(ctx context.Context, item T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+	select {
+	case b.items <- item:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush forces the items added so far to be flushed and returns the FlushFunc error, if any
+func (b *Batcher[T]) Flush(ctx context.Context) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	reply := make(chan error, 1)
+	select {
+	case b.flushes <- reply:
+	case <-ctx.Done():
+		b.mu.RUnlock()
+		return ctx.Err()
+	}
+	b.mu.RUnlock()
+
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting items and drains the buffer with a final flush,
+// waiting until it completes or ctx is done.
+func (b *Batcher[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.items)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Batcher[T]) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.opts.Interval)
+	defer ticker.Stop()
+
+	var (
+		batch []T
+		bytes int
+	)
+	emit := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := b.deliver(batch)
+		batch, bytes = nil, 0
+		return err
+	}
+	add := func(item T) error {
+		var errs []error
+		if b.opts.MaxBytes > 0 {
+			size := b.opts.SizeOf(item)
+			if bytes+size > b.opts.MaxBytes {
+				errs = append(errs, emit())
+			}
+			bytes += size
+		}
+		batch = append(batch, item)
+		if len(batch) >= b.opts.MaxItems || (b.opts.MaxBytes > 0 && bytes >= b.opts.MaxBytes) {
+			errs = append(errs, emit())
+		}
+		return errors.Join(errs...)
+	}
+
+	for {
+		select {
+		case item, ok := <-b.items:
+			if !ok {
+				emit()
+				return
+			}
+			add(item)
+		case reply := <-b.flushes:
+			// Take everything already queued so Flush covers all prior Adds
+			var errs []error
+		drain:
+			for {
+				select {
+				case item, ok := <-b.items:
+					if !ok {
+						break drain
+					}
+					errs = append(errs, add(item))
+				default:
+					break drain
+				}
+			}
+			reply <- errors.Join(append(errs, emit())...)
+		case <-ticker.C:
+			emit()
+		}
+	}
+}
+
+func (b *Batcher[T]) deliver(batch []T) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.opts.FlushTimeout)
+	defer cancel()
+
+	err := b.flush(ctx, batch)
+	if err != nil && b.opts.OnError != nil {
+		b.opts.OnError(err, batch)
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/appconfig"
+	"github.com/cdcloud-io/go-libs/health"
+	"github.com/cdcloud-io/go-libs/lifecycle"
+)
+
+// Command is a subcommand of the service binary
+type Command struct {
+	// Summary is shown in the usage text.
+	Summary string
+	// Run receives the wired App, without the HTTP server started, and the remaining arguments.
+	Run func(ctx context.Context, app *App, args []string) error
+}
+
+// Options configures Main and Run
+type Options struct {
+	// Register wires the service: handlers on app.Mux, health checks on app.Health and
+	// components on app.Lifecycle. It runs for every command after the standard stack is up.
+	Register func(ctx context.Context, app *App) error
+	// Migrate, when set, is run by the "migrate" subcommand.
+	Migrate func(ctx context.Context, app *App) error
+	// Commands adds service-specific subcommands.
+	Commands map[string]Command
+	// ShutdownTimeout bounds graceful shutdown. Defaults to 30s.
+	ShutdownTimeout time.Duration
+	// Args are the command-line arguments without the program name. Defaults to os.Args[1:].
+	Args []string
+	// Output receives usage text. Defaults to os.Stderr.
+	Output io.Writer
+}
+
+// ErrUsage is returned for unknown subcommands and bad flags
+var ErrUsage = errors.New("invalid usage")
+
+// Main runs the service and exits the process with status 1 on failure.
+// With no arguments it serves; see Run for the available subcommands.
+func Main(opts Options) {
+	if err := Run(context.Background(), opts); err != nil {
+		if errors.Is(err, ErrUsage) {
+			os.Exit(2)
+		}
+		slog.Error("service failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// Run executes the subcommand named by the first argument:
+//
+//	serve            start the service (default)
+//	migrate          run Options.Migrate
+//	validate-config  load and validate the configuration, then exit
+//	version          print the build information from the configuration
+//
+// plus any Options.Commands.
+func Run(ctx context.Context, opts Options) error {
+	if opts.Args == nil {
+		opts.Args = os.Args[1:]
+	}
+	if opts.Output == nil {
+		opts.Output = os.Stderr
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = 30 * time.Second
+	}
+
+	commands := map[string]Command{
+		"serve": {Summary: "start the service (default)", Run: func(ctx context.Context, app *App, args []string) error {
+			return app.Lifecycle.Run(ctx)
+		}},
+		"validate-config": {Summary: "load and validate the configuration, then exit", Run: func(ctx context.Context, app *App, args []string) error {
+			fmt.Fprintln(opts.Output, "configuration is valid")
+			return nil
+		}},
+		"version": {Summary: "print the build information", Run: func(ctx context.Context, app *App, args []string) error {
+			a := app.Config.App
+			fmt.Fprintf(opts.Output, "%s %s (commit %s, build %s, %s)\n", a.Name, a.Version, a.CommitSha, a.BuildID, a.BuildDate)
+			return nil
+		}},
+	}
+	if opts.Migrate != nil {
+		commands["migrate"] = Command{Summary: "run database migrations", Run: func(ctx context.Context, app *App, args []string) error {
+			return opts.Migrate(ctx, app)
+		}}
+	}
+	for name, c := range opts.Commands {
+		commands[name] = c
+	}
+
+	fs := flag.NewFlagSet("service", flag.ContinueOnError)
+	fs.SetOutput(opts.Output)
+	fs.Usage = func() { usage(opts.Output, commands) }
+	if err := fs.Parse(opts.Args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrUsage, err)
+	}
+
+	name, args := "serve", fs.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		usage(opts.Output, commands)
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, name)
+	}
+
+	app, err := setup(ctx, opts, name == "serve")
+	if err != nil {
+		return err
+	}
+	if name != "serve" {
+		defer app.close()
+	}
+	return cmd.Run(ctx, app, args)
+}
+
+func usage(w io.Writer, commands map[string]Command) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Usage: " + os.Args[0] + " [command] [args]\n\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-16s %s\n", name, commands[name].Summary)
+	}
+	fmt.Fprint(w, b.String())
+}
+
+// App is the wired standard stack handed to Options.Register and to commands
+type App struct {
+	Config appconfig.Config
+	Logger *slog.Logger
+	Health *health.Registry
+	// Mux is served by the HTTP server on server.host:server.port; the health, info and
+	// metrics endpoints are already registered on it.
+	Mux       *http.ServeMux
+	Lifecycle *lifecycle.App
+
+	closers []func(ctx context.Context) error
+	timeout time.Duration
+}
+
+// OnClose registers fn to run when a non-serve command finishes. For serve, register
+// a lifecycle component with a Stop func instead.
+func (a *App) OnClose(fn func(ctx context.Context) error) {
+	a.closers = append(a.closers, fn)
+}
+
+func (a *App) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		if err := a.closers[i](ctx); err != nil {
+			a.Logger.Error("shutdown failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/appconfig"
+	"github.com/cdcloud-io/go-libs/health"
+	"github.com/cdcloud-io/go-libs/lifecycle"
+	"github.com/cdcloud-io/go-libs/metrics"
+	"github.com/cdcloud-io/go-libs/tracing"
+)
+
+// setup loads the configuration and wires logging, tracing, metrics, health and,
+// for serve, the HTTP server
+func setup(ctx context.Context, opts Options, serve bool) (*App, error) {
+	cfg := appconfig.Load()
+
+	logger := newLogger(cfg)
+	slog.SetDefault(logger)
+
+	app := &App{
+		Config: cfg,
+		Logger: logger,
+		Health: health.NewRegistry(health.RegistryOptions{}),
+		Mux:    http.NewServeMux(),
+		Lifecycle: lifecycle.New(lifecycle.Options{
+			ShutdownTimeout: opts.ShutdownTimeout,
+			Logf: func(format string, args ...interface{}) {
+				logger.Info(fmt.Sprintf(format, args...))
+			},
+		}),
+		timeout: opts.ShutdownTimeout,
+	}
+
+	tp, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	if err := metrics.Setup(cfg); err != nil {
+		return nil, fmt.Errorf("failed to set up metrics: %w", err)
+	}
+	pusher, err := metrics.StartPush(ctx, cfg)
+	if err != nil {
+		tp.Shutdown(ctx)
+		return nil, fmt.Errorf("failed to start metrics push: %w", err)
+	}
+
+	// Registered first so telemetry starts first and is flushed last
+	stopTelemetry := func(ctx context.Context) error {
+		return errors.Join(pusher.Shutdown(ctx), tp.Shutdown(ctx))
+	}
+	if serve {
+		app.Lifecycle.Register(lifecycle.Component{Name: "telemetry", Stop: stopTelemetry})
+	} else {
+		app.OnClose(stopTelemetry)
+	}
+
+	registerEndpoints(app)
+
+	if opts.Register != nil {
+		if err := opts.Register(ctx, app); err != nil {
+			if !serve {
+				app.close()
+			} else {
+				stopTelemetry(context.Background())
+			}
+			return nil, fmt.Errorf("failed to register service components: %w", err)
+		}
+	}
+
+	if serve {
+		// Registered last so the server stops taking traffic before anything else stops
+		app.Lifecycle.Register(lifecycle.Component{
+			Name: "health",
+			Run: func(ctx context.Context) error {
+				app.Health.Start(ctx)
+				<-ctx.Done()
+				return nil
+			},
+		})
+		app.Lifecycle.Register(httpComponent(app))
+	}
+	return app, nil
+}
+
+func newLogger(cfg appconfig.Config) *slog.Logger {
+	level := slog.LevelInfo
+	if cfg.App.Debug {
+		level = slog.LevelDebug
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})).With(
+		"service", cfg.App.Name,
+		"version", cfg.App.Version,
+		"env", cfg.App.Env,
+	)
+}
+
+// registerEndpoints adds the health, readiness, liveness, info and metrics endpoints
+func registerEndpoints(app *App) {
+	cfg := app.Config
+
+	healthPath := cfg.Server.HealthEndpoint
+	if healthPath == "" {
+		healthPath = "/health"
+	}
+	app.Mux.Handle(healthPath, app.Health.Handler())
+	app.Mux.Handle("/ready", app.Health.ReadinessHandler())
+	app.Mux.Handle("/live", health.LivenessHandler())
+
+	if cfg.Server.InfoEndpoint != "" {
+		info, _ := json.Marshal(map[string]string{
+			"name":       cfg.App.Name,
+			"version":    cfg.App.Version,
+			"commit_sha": cfg.App.CommitSha,
+			"build_id":   cfg.App.BuildID,
+			"build_date": cfg.App.BuildDate,
+			"env":        cfg.App.Env,
+		})
+		app.Mux.HandleFunc(cfg.Server.InfoEndpoint, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(info)
+		})
+	}
+
+	if cfg.Metrics.Enabled {
+		metricsPath := cfg.Metrics.Path
+		if metricsPath == "" {
+			metricsPath = "/metrics"
+		}
+		app.Mux.Handle(metricsPath, metrics.Handler())
+	}
+}
+
+// httpComponent serves app.Mux. Start binds the port so a port conflict fails startup;
+// Stop marks the service as draining and lets in-flight requests finish.
+func httpComponent(app *App) lifecycle.Component {
+	srv := &http.Server{
+		Addr:              net.JoinHostPort(app.Config.Server.Host, app.Config.Server.Port),
+		Handler:           app.Mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ErrorLog:          slog.NewLogLogger(app.Logger.Handler(), slog.LevelError),
+	}
+	var ln net.Listener
+
+	return lifecycle.Component{
+		Name: "http",
+		Start: func(ctx context.Context) error {
+			var err error
+			ln, err = net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %w", srv.Addr, err)
+			}
+			app.Logger.Info("http server listening", "addr", ln.Addr().String())
+			return nil
+		},
+		Run: func(ctx context.Context) error {
+			if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			app.Health.SetDraining(true)
+			return srv.Shutdown(ctx)
+		},
+	}
+}
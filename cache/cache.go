@@ -0,0 +1,290 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options configures a Cache
+type Options[K comparable, V any] struct {
+	// MaxEntries bounds the cache; the least recently used entry is evicted beyond it. 0 means unbounded.
+	MaxEntries int
+	// TTL is the default time an entry stays fresh. 0 means entries never expire.
+	TTL time.Duration
+	// StaleTTL lets GetOrLoad serve an expired entry for this long while it reloads the
+	// value in the background (stale-while-revalidate). 0 disables serving stale values.
+	StaleTTL time.Duration
+	// LoadTimeout bounds background refreshes, which run detached from the caller's context. Defaults to 30s.
+	LoadTimeout time.Duration
+	// OnEvict is called when an entry is evicted to make room or removed after expiry.
+	OnEvict func(key K, value V)
+}
+
+// Stats are cumulative cache counters
+type Stats struct {
+	Hits       uint64
+	Misses     uint64
+	StaleHits  uint64
+	Loads      uint64
+	LoadErrors uint64
+	Evictions  uint64
+	Entries    int
+}
+
+// HitRatio returns hits (fresh and stale) over all lookups
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.StaleHits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits+s.StaleHits) / float64(total)
+}
+
+// LoaderFunc loads the value for a key on a cache miss
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time // zero means no expiry
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Cache is a concurrency-safe in-process cache with per-entry TTL, LRU eviction,
+// stale-while-revalidate and deduplicated loading
+type Cache[K comparable, V any] struct {
+	opts Options[K, V]
+
+	mu       sync.Mutex
+	items    map[K]*list.Element
+	lru      *list.List // front is most recently used
+	inflight map[K]*call[V]
+
+	hits, misses, staleHits, loads, loadErrors, evictions atomic.Uint64
+}
+
+// New creates a cache
+func New[K comparable, V any](opts Options[K, V]) *Cache[K, V] {
+	if opts.LoadTimeout <= 0 {
+		opts.LoadTimeout = 30 * time.Second
+	}
+	return &Cache[K, V]{
+		opts:     opts,
+		items:    make(map[K]*list.Element),
+		lru:      list.New(),
+		inflight: make(map[K]*call[V]),
+	}
+}
+
+// Get returns a fresh value for key
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, fresh, _ := c.lookup(key, time.Now())
+	if fresh {
+		c.hits.Add(1)
+		return v, true
+	}
+	c.misses.Add(1)
+	var zero V
+	return zero, false
+}
+
+// Set stores value under key with the default TTL
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL stores value under key, expiring after ttl (0 means never)
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl)
+}
+
+// Delete removes key
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.lru.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// Purge removes every entry
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[K]*list.Element)
+	c.lru.Init()
+}
+
+// DeleteExpired removes entries past their TTL and stale window.
+// Expired entries are otherwise dropped lazily on access or by LRU eviction.
+func (c *Cache[K, V]) DeleteExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for el := c.lru.Back(); el != nil; {
+		prev := el.Prev()
+		e := el.Value.(*entry[K, V])
+		if c.dead(e, now) {
+			c.removeElement(el)
+		}
+		el = prev
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet removed
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns a snapshot of the counters
+func (c *Cache[K, V]) Stats() Stats {
+	return Stats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		StaleHits:  c.staleHits.Load(),
+		Loads:      c.loads.Load(),
+		LoadErrors: c.loadErrors.Load(),
+		Evictions:  c.evictions.Load(),
+		Entries:    c.Len(),
+	}
+}
+
+// GetOrLoad returns the cached value for key, calling load on a miss.
+// Concurrent callers for the same key share a single load. Within StaleTTL after expiry
+// the stale value is returned immediately and refreshed in the background.
+// Load errors are not cached.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load LoaderFunc[K, V]) (V, error) {
+	c.mu.Lock()
+	v, fresh, stale := c.lookup(key, time.Now())
+	switch {
+	case fresh:
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return v, nil
+	case stale:
+		c.staleHits.Add(1)
+		if _, loading := c.inflight[key]; !loading {
+			cl := c.startLoad(key)
+			c.mu.Unlock()
+			go func() {
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.opts.LoadTimeout)
+				defer cancel()
+				c.finishLoad(ctx, key, cl, load)
+			}()
+			return v, nil
+		}
+		c.mu.Unlock()
+		return v, nil
+	}
+
+	c.misses.Add(1)
+	if cl, loading := c.inflight[key]; loading {
+		c.mu.Unlock()
+		return c.wait(ctx, cl)
+	}
+	cl := c.startLoad(key)
+	c.mu.Unlock()
+
+	c.finishLoad(ctx, key, cl, load)
+	return cl.value, cl.err
+}
+
+func (c *Cache[K, V]) wait(ctx context.Context, cl *call[V]) (V, error) {
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// startLoad registers an in-flight load; c.mu must be held
+func (c *Cache[K, V]) startLoad(key K) *call[V] {
+	cl := &call[V]{done: make(chan struct{})}
+	c.inflight[key] = cl
+	return cl
+}
+
+func (c *Cache[K, V]) finishLoad(ctx context.Context, key K, cl *call[V], load LoaderFunc[K, V]) {
+	c.loads.Add(1)
+	cl.value, cl.err = load(ctx, key)
+	if cl.err != nil {
+		c.loadErrors.Add(1)
+	}
+
+	c.mu.Lock()
+	if cl.err == nil {
+		c.set(key, cl.value, c.opts.TTL)
+	}
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(cl.done)
+}
+
+// lookup finds key and reports whether it is fresh or servable stale; c.mu must be held
+func (c *Cache[K, V]) lookup(key K, now time.Time) (v V, fresh, stale bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return v, false, false
+	}
+	e := el.Value.(*entry[K, V])
+	if e.expiresAt.IsZero() || now.Before(e.expiresAt) {
+		c.lru.MoveToFront(el)
+		return e.value, true, false
+	}
+	if c.opts.StaleTTL > 0 && now.Before(e.expiresAt.Add(c.opts.StaleTTL)) {
+		return e.value, false, true
+	}
+	c.removeElement(el)
+	return v, false, false
+}
+
+// set stores an entry and evicts beyond MaxEntries; c.mu must be held
+func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value, e.expiresAt = value, expiresAt
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.lru.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+
+	for c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries {
+		c.removeElement(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+func (c *Cache[K, V]) dead(e *entry[K, V], now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt.Add(c.opts.StaleTTL))
+}
+
+func (c *Cache[K, V]) removeElement(el *list.Element) {
+	e := el.Value.(*entry[K, V])
+	c.lru.Remove(el)
+	delete(c.items, e.key)
+	if c.opts.OnEvict != nil {
+		c.opts.OnEvict(e.key, e.value)
+	}
+}
@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Store is a shared byte-oriented cache, such as Redis. rediscache.Store implements it.
+type Store interface {
+	// Get returns the value for key; found is false on a miss
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+	// Set stores value under key; a ttl of 0 means no expiry
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Invalidator broadcasts key invalidations between instances sharing a Store.
+// rediscache.Invalidator implements it with Redis pub/sub.
+type Invalidator interface {
+	// Publish announces that keys changed; origin identifies the sending instance
+	Publish(ctx context.Context, origin string, keys ...string) error
+	// Subscribe calls fn for every invalidation until ctx is done
+	Subscribe(ctx context.Context, fn func(origin string, keys []string)) error
+}
+
+// LayeredOptions configures a Layered cache
+type LayeredOptions struct {
+	// L2 is the shared cache. Required.
+	L2 Store
+	// Invalidator keeps L1 caches consistent across instances. Without it, L1 entries
+	// on other instances live until L1TTL expires.
+	Invalidator Invalidator
+	// TTL is the default L2 expiry. 0 means no expiry.
+	TTL time.Duration
+	// L1TTL bounds how long a value is served from memory. Defaults to 1m and is capped at TTL.
+	L1TTL time.Duration
+	// L1MaxEntries bounds the in-memory cache. Defaults to 10000.
+	L1MaxEntries int
+	// OnError is called when L2 or invalidation fails and the cache degrades to L1 or the loader.
+	// Defaults to log.Printf.
+	OnError func(error)
+}
+
+// Layered is a two-tier cache: an in-process L1 in front of a shared L2, with writes
+// and deletes invalidating the L1 of every other instance
+type Layered struct {
+	l1       *Cache[string, []byte]
+	opts     LayeredOptions
+	instance string
+}
+
+// NewLayered creates a layered cache. Call Start to receive invalidations from other instances.
+func NewLayered(opts LayeredOptions) (*Layered, error) {
+	if opts.L2 == nil {
+		return nil, fmt.Errorf("failed to create layered cache: L2 store is required")
+	}
+	if opts.L1TTL <= 0 {
+		opts.L1TTL = time.Minute
+	}
+	if opts.TTL > 0 && opts.L1TTL > opts.TTL {
+		opts.L1TTL = opts.TTL
+	}
+	if opts.L1MaxEntries <= 0 {
+		opts.L1MaxEntries = 10000
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(err error) { log.Printf("cache: %v", err) }
+	}
+
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate cache instance id: %w", err)
+	}
+
+	return &Layered{
+		l1:       New[string, []byte](Options[string, []byte]{MaxEntries: opts.L1MaxEntries, TTL: opts.L1TTL}),
+		opts:     opts,
+		instance: hex.EncodeToString(b[:]),
+	}, nil
+}
+
+// Start subscribes to invalidations and blocks until ctx is done
+func (l *Layered) Start(ctx context.Context) error {
+	if l.opts.Invalidator == nil {
+		<-ctx.Done()
+		return nil
+	}
+	err := l.opts.Invalidator.Subscribe(ctx, func(origin string, keys []string) {
+		if origin == l.instance {
+			return
+		}
+		for _, k := range keys {
+			l.l1.Delete(k)
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to subscribe to cache invalidations: %w", err)
+	}
+	return nil
+}
+
+// Get returns the value from L1, falling back to L2
+func (l *Layered) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if v, ok := l.l1.Get(key); ok {
+		return v, true, nil
+	}
+	v, found, err := l.opts.L2.Get(ctx, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get %q from L2 cache: %w", key, err)
+	}
+	if found {
+		l.l1.Set(key, v)
+	}
+	return v, found, nil
+}
+
+// Set stores value in both tiers and invalidates other instances; a ttl of 0 uses the default TTL
+func (l *Layered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl == 0 {
+		ttl = l.opts.TTL
+	}
+	if err := l.opts.L2.Set(ctx, key, value, ttl); err != nil {
+		l.l1.Delete(key)
+		return fmt.Errorf("failed to set %q in L2 cache: %w", key, err)
+	}
+	l.l1.SetWithTTL(key, value, l.l1TTL(ttl))
+	l.publish(ctx, key)
+	return nil
+}
+
+// Delete removes keys from both tiers and invalidates other instances
+func (l *Layered) Delete(ctx context.Context, keys ...string) error {
+	for _, k := range keys {
+		l.l1.Delete(k)
+	}
+	if err := l.opts.L2.Delete(ctx, keys...); err != nil {
+		return fmt.Errorf("failed to delete from L2 cache: %w", err)
+	}
+	l.publish(ctx, keys...)
+	return nil
+}
+
+// GetOrLoad returns the cached value or calls load and caches its result.
+// Concurrent loads of the same key on this instance are deduplicated. If L2 is
+// unavailable the error is reported to OnError and load is used directly.
+func (l *Layered) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	return l.l1.GetOrLoad(ctx, key, func(ctx context.Context, key string) ([]byte, error) {
+		v, found, err := l.opts.L2.Get(ctx, key)
+		if err != nil {
+			l.opts.OnError(fmt.Errorf("failed to get %q from L2 cache: %w", key, err))
+		} else if found {
+			return v, nil
+		}
+
+		v, err = load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.opts.L2.Set(ctx, key, v, l.opts.TTL); err != nil {
+			l.opts.OnError(fmt.Errorf("failed to set %q in L2 cache: %w", key, err))
+		}
+		return v, nil
+	})
+}
+
+// Stats returns the L1 counters
+func (l *Layered) Stats() Stats {
+	return l.l1.Stats()
+}
+
+func (l *Layered) l1TTL(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < l.opts.L1TTL {
+		return ttl
+	}
+	return l.opts.L1TTL
+}
+
+func (l *Layered) publish(ctx context.Context, keys ...string) {
+	if l.opts.Invalidator == nil {
+		return
+	}
+	if err := l.opts.Invalidator.Publish(ctx, l.instance, keys...); err != nil {
+		l.opts.OnError(fmt.Errorf("failed to publish cache invalidation: %w", err))
+	}
+}
+
+// GetJSON reads a JSON-encoded value from s
+func GetJSON[V any](ctx context.Context, s Store, key string) (V, bool, error) {
+	var v V
+	b, found, err := s.Get(ctx, key)
+	if err != nil || !found {
+		return v, found, err
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, false, fmt.Errorf("failed to decode cached %q: %w", key, err)
+	}
+	return v, true, nil
+}
+
+// SetJSON stores v JSON-encoded in s
+func SetJSON[V any](ctx context.Context, s Store, key string, v V, ttl time.Duration) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %q for cache: %w", key, err)
+	}
+	return s.Set(ctx, key, b, ttl)
+}
@@ -0,0 +1,120 @@
+package rediscache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultChannel is the pub/sub channel used for invalidations when none is set
+const DefaultChannel = "cache:invalidate"
+
+// Store is a cache.Store backed by Redis
+type Store struct {
+	rdb    redis.UniversalClient
+	prefix string
+}
+
+// NewStore creates a store; prefix namespaces every key, e.g. "orders:"
+func NewStore(rdb redis.UniversalClient, prefix string) *Store {
+	return &Store{rdb: rdb, prefix: prefix}
+}
+
+// Get returns the value for key
+func (s *Store) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := s.rdb.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get key %q: %w", key, err)
+	}
+	return b, true, nil
+}
+
+// Set stores value under key; a ttl of 0 means no expiry
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.rdb.Set(ctx, s.prefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set key %q: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes keys
+func (s *Store) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = s.prefix + k
+	}
+	if err := s.rdb.Del(ctx, prefixed...).Err(); err != nil {
+		return fmt.Errorf("failed to delete keys: %w", err)
+	}
+	return nil
+}
+
+// Invalidator is a cache.Invalidator using Redis pub/sub
+type Invalidator struct {
+	rdb     redis.UniversalClient
+	channel string
+}
+
+// NewInvalidator creates an invalidator on channel, or DefaultChannel if empty.
+// Instances sharing a cache must use the same channel.
+func NewInvalidator(rdb redis.UniversalClient, channel string) *Invalidator {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	return &Invalidator{rdb: rdb, channel: channel}
+}
+
+type message struct {
+	Origin string   `json:"o"`
+	Keys   []string `json:"k"`
+}
+
+// Publish announces that keys changed
+func (i *Invalidator) Publish(ctx context.Context, origin string, keys ...string) error {
+	b, err := json.Marshal(message{Origin: origin, Keys: keys})
+	if err != nil {
+		return fmt.Errorf("failed to encode invalidation: %w", err)
+	}
+	if err := i.rdb.Publish(ctx, i.channel, b).Err(); err != nil {
+		return fmt.Errorf("failed to publish invalidation: %w", err)
+	}
+	return nil
+}
+
+// Subscribe calls fn for every invalidation until ctx is done.
+// go-redis reconnects the subscription automatically after network errors.
+func (i *Invalidator) Subscribe(ctx context.Context, fn func(origin string, keys []string)) error {
+	sub := i.rdb.Subscribe(ctx, i.channel)
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", i.channel, err)
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			var m message
+			if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+				continue
+			}
+			fn(m.Origin, m.Keys)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeySize is the AES-256 key length in bytes
+const KeySize = 32
+
+var (
+	ErrInvalidKey         = errors.New("key must be 32 bytes")
+	ErrCiphertextTooShort = errors.New("ciphertext too short")
+	ErrDecrypt            = errors.New("failed to decrypt: message authentication failed")
+)
+
+// NewKey returns a random AES-256 key
+func NewKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+// DeriveKey derives a purpose-specific AES-256 key from master with HKDF-SHA256.
+// Use distinct info values (e.g. "users.email") so one master key never encrypts unrelated data directly.
+func DeriveKey(master, salt []byte, info string) ([]byte, error) {
+	if len(master) < KeySize {
+		return nil, fmt.Errorf("master key must be at least %d bytes", KeySize)
+	}
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, salt, []byte(info)), key); err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return key, nil
+}
+
+// Encrypt seals plaintext with AES-256-GCM. The random 96-bit nonce is prepended to the output.
+// aad is authenticated but not encrypted; bind ciphertexts to their context with it
+// (e.g. the document ID) so they can't be swapped between records.
+// Random nonces are safe for up to 2^32 messages per key; rotate keys (or use envelope encryption) beyond that.
+func Encrypt(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Decrypt opens a ciphertext produced by Encrypt with the same key and aad
+func Decrypt(key, ciphertext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, ErrCiphertextTooShort
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func mustKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := mustKey(t)
+	aad := []byte("users/42/email")
+
+	for _, plaintext := range [][]byte{nil, []byte("a"), bytes.Repeat([]byte("x"), 10000)} {
+		ct, err := Encrypt(key, plaintext, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pt, err := Decrypt(key, ct, aad)
+		if err != nil {
+			t.Fatalf("Decrypt() error = %v", err)
+		}
+		if !bytes.Equal(pt, plaintext) {
+			t.Errorf("Decrypt() = %q, want %q", pt, plaintext)
+		}
+	}
+
+	a, _ := Encrypt(key, []byte("same"), aad)
+	b, _ := Encrypt(key, []byte("same"), aad)
+	if bytes.Equal(a[:12], b[:12]) {
+		t.Error("two encryptions used the same nonce")
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	key := mustKey(t)
+	aad := []byte("users/42/email")
+	ct, err := Encrypt(key, []byte("alice@example.com"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flip := func(i int) []byte {
+		c := append([]byte(nil), ct...)
+		c[i] ^= 0x01
+		return c
+	}
+	tests := []struct {
+		name       string
+		key        []byte
+		ciphertext []byte
+		aad        []byte
+		err        error
+	}{
+		{"tampered nonce", key, flip(0), aad, ErrDecrypt},
+		{"tampered ciphertext", key, flip(12), aad, ErrDecrypt},
+		{"tampered tag", key, flip(len(ct) - 1), aad, ErrDecrypt},
+		{"wrong aad", key, ct, []byte("users/43/email"), ErrDecrypt},
+		{"missing aad", key, ct, nil, ErrDecrypt},
+		{"wrong key", mustKey(t), ct, aad, ErrDecrypt},
+		{"truncated", key, ct[:27], aad, ErrCiphertextTooShort},
+		{"short key", key[:16], ct, aad, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, err := Decrypt(tt.key, tt.ciphertext, tt.aad)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Decrypt() error = %v, want %v", err, tt.err)
+			}
+			if pt != nil {
+				t.Errorf("Decrypt() returned plaintext %q on error", pt)
+			}
+		})
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	master := mustKey(t)
+	email, err := DeriveKey(master, nil, "users.email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := DeriveKey(master, nil, "users.email")
+	phone, _ := DeriveKey(master, nil, "users.phone")
+	if !bytes.Equal(email, again) {
+		t.Error("DeriveKey is not deterministic")
+	}
+	if bytes.Equal(email, phone) {
+		t.Error("DeriveKey returned the same key for different info")
+	}
+	if _, err := DeriveKey(master[:16], nil, "users.email"); err == nil {
+		t.Error("DeriveKey accepted a short master key")
+	}
+}
@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+)
+
+// AzureKeyVault wraps DEKs with an RSA key in Azure Key Vault using RSA-OAEP-256
+type AzureKeyVault struct {
+	client  *azkeys.Client
+	keyName string
+	version string
+}
+
+// NewAzureKeyVault creates a KeyWrapper for the named key in the vault at vaultURL.
+// An empty version uses the latest key version for wrapping; unwrapping always uses
+// the version recorded in the envelope, so rotated keys keep decrypting old data.
+func NewAzureKeyVault(vaultURL, keyName, version string, cred azcore.TokenCredential) (*AzureKeyVault, error) {
+	client, err := azkeys.NewClient(vaultURL, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key vault client: %w", err)
+	}
+	return &AzureKeyVault{client: client, keyName: keyName, version: version}, nil
+}
+
+// WrapKey wraps dek with the vault key and returns the full key ID including its version
+func (a *AzureKeyVault) WrapKey(ctx context.Context, dek []byte) ([]byte, string, error) {
+	resp, err := a.client.WrapKey(ctx, a.keyName, a.version, azkeys.KeyOperationParameters{
+		Algorithm: to.Ptr(azkeys.EncryptionAlgorithmRSAOAEP256),
+		Value:     dek,
+	}, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to wrap key with key vault: %w", err)
+	}
+	if resp.KID == nil {
+		return nil, "", fmt.Errorf("key vault response is missing the key ID")
+	}
+	return resp.Result, string(*resp.KID), nil
+}
+
+// UnwrapKey unwraps dek with the key version identified by keyID
+func (a *AzureKeyVault) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	kid := azkeys.ID(keyID)
+	resp, err := a.client.UnwrapKey(ctx, kid.Name(), kid.Version(), azkeys.KeyOperationParameters{
+		Algorithm: to.Ptr(azkeys.EncryptionAlgorithmRSAOAEP256),
+		Value:     wrapped,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key with key vault: %w", err)
+	}
+	return resp.Result, nil
+}
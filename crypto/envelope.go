@@ -0,0 +1,189 @@
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// KeyWrapper wraps and unwraps data encryption keys (DEKs) with a key encryption key (KEK)
+// held by a KMS. The KEK never leaves the KMS.
+type KeyWrapper interface {
+	// WrapKey encrypts dek and returns the wrapped key and the ID of the KEK version used.
+	WrapKey(ctx context.Context, dek []byte) (wrapped []byte, keyID string, err error)
+	// UnwrapKey decrypts a DEK wrapped by the KEK identified by keyID.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Envelope is an encrypted value together with its wrapped DEK.
+// It marshals to BSON and JSON, so it can be stored directly in a Mongo document field.
+type Envelope struct {
+	KeyID      string `bson:"kid" json:"kid"`
+	WrappedKey []byte `bson:"wk" json:"wk"`
+	Ciphertext []byte `bson:"ct" json:"ct"`
+}
+
+// EnvelopeOptions configures an EnvelopeEncrypter
+type EnvelopeOptions struct {
+	// ReuseDEK encrypts up to this many values with one DEK before generating a new one,
+	// saving a KMS round trip per value. 0 or 1 generates a DEK per value.
+	ReuseDEK int
+	// CacheSize bounds the number of unwrapped DEKs kept in memory for decryption. Defaults to 1000.
+	CacheSize int
+}
+
+// EnvelopeEncrypter performs envelope encryption: each value is encrypted locally with
+// an AES-256-GCM DEK, and the DEK is wrapped by the KMS and stored alongside the ciphertext.
+type EnvelopeEncrypter struct {
+	kms  KeyWrapper
+	opts EnvelopeOptions
+
+	mu      sync.Mutex
+	current *dataKey
+	cache   map[string][]byte // wrapped key -> plaintext DEK
+}
+
+type dataKey struct {
+	plain   []byte
+	wrapped []byte
+	keyID   string
+	uses    int
+}
+
+// NewEnvelopeEncrypter creates an EnvelopeEncrypter using kms to wrap DEKs
+func NewEnvelopeEncrypter(kms KeyWrapper, opts EnvelopeOptions) *EnvelopeEncrypter {
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = 1000
+	}
+	return &EnvelopeEncrypter{kms: kms, opts: opts, cache: make(map[string][]byte)}
+}
+
+// Encrypt encrypts plaintext under a (possibly reused) DEK, authenticating aad
+func (e *EnvelopeEncrypter) Encrypt(ctx context.Context, plaintext, aad []byte) (*Envelope, error) {
+	dk, err := e.dataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := Encrypt(dk.plain, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{KeyID: dk.keyID, WrappedKey: dk.wrapped, Ciphertext: ct}, nil
+}
+
+// Decrypt unwraps the envelope's DEK (cached after first use) and decrypts the ciphertext
+func (e *EnvelopeEncrypter) Decrypt(ctx context.Context, env *Envelope, aad []byte) ([]byte, error) {
+	if env == nil {
+		return nil, fmt.Errorf("envelope is nil")
+	}
+	cacheKey := env.KeyID + "/" + string(env.WrappedKey)
+
+	e.mu.Lock()
+	dek, ok := e.cache[cacheKey]
+	e.mu.Unlock()
+
+	if !ok {
+		var err error
+		dek, err = e.kms.UnwrapKey(ctx, env.KeyID, env.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+		}
+		e.remember(cacheKey, dek)
+	}
+	return Decrypt(dek, env.Ciphertext, aad)
+}
+
+// EncryptString encrypts s and returns the envelope as a compact base64 string,
+// for fields that must stay strings
+func (e *EnvelopeEncrypter) EncryptString(ctx context.Context, s string, aad []byte) (string, error) {
+	env, err := e.Encrypt(ctx, []byte(s), aad)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(env)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode envelope: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecryptString reverses EncryptString
+func (e *EnvelopeEncrypter) DecryptString(ctx context.Context, s string, aad []byte) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode envelope: %w", err)
+	}
+	var env Envelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return "", fmt.Errorf("failed to decode envelope: %w", err)
+	}
+	pt, err := e.Decrypt(ctx, &env, aad)
+	if err != nil {
+		return "", err
+	}
+	return string(pt), nil
+}
+
+func (e *EnvelopeEncrypter) dataKey(ctx context.Context) (*dataKey, error) {
+	e.mu.Lock()
+	if dk := e.current; dk != nil && dk.uses < e.opts.ReuseDEK {
+		dk.uses++
+		e.mu.Unlock()
+		return dk, nil
+	}
+	e.mu.Unlock()
+
+	plain, err := NewKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, keyID, err := e.kms.WrapKey(ctx, plain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	dk := &dataKey{plain: plain, wrapped: wrapped, keyID: keyID, uses: 1}
+
+	e.mu.Lock()
+	if e.opts.ReuseDEK > 1 {
+		e.current = dk
+	}
+	e.mu.Unlock()
+	e.remember(keyID+"/"+string(wrapped), plain)
+	return dk, nil
+}
+
+func (e *EnvelopeEncrypter) remember(cacheKey string, dek []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.cache) >= e.opts.CacheSize {
+		// Drop an arbitrary entry; unwrapping again only costs a KMS call
+		for k := range e.cache {
+			delete(e.cache, k)
+			break
+		}
+	}
+	e.cache[cacheKey] = dek
+}
+
+// LocalKeyWrapper wraps DEKs with an in-process AES-256 key.
+// Intended for local development and tests, not production secrets.
+type LocalKeyWrapper struct {
+	KeyID string
+	KEK   []byte
+}
+
+// WrapKey encrypts dek with the local KEK
+func (l LocalKeyWrapper) WrapKey(ctx context.Context, dek []byte) ([]byte, string, error) {
+	wrapped, err := Encrypt(l.KEK, dek, []byte(l.KeyID))
+	return wrapped, l.KeyID, err
+}
+
+// UnwrapKey decrypts dek with the local KEK
+func (l LocalKeyWrapper) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if keyID != l.KeyID {
+		return nil, fmt.Errorf("unknown key %q", keyID)
+	}
+	return Decrypt(l.KEK, wrapped, []byte(keyID))
+}
@@ -0,0 +1,216 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// rotatingKMS is a KeyWrapper with KEK versions, like a KMS key that is rotated
+type rotatingKMS struct {
+	mu      sync.Mutex
+	keks    map[string][]byte
+	current string
+	wraps   int
+	unwraps int
+}
+
+func newRotatingKMS(t *testing.T) *rotatingKMS {
+	k := &rotatingKMS{keks: map[string][]byte{}}
+	k.rotate(t)
+	return k
+}
+
+// rotate adds a new KEK version and makes it current
+func (k *rotatingKMS) rotate(t *testing.T) string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	id := fmt.Sprintf("kek-v%d", len(k.keks)+1)
+	k.keks[id] = mustKey(t)
+	k.current = id
+	return id
+}
+
+// retire deletes a KEK version, so DEKs wrapped by it can no longer be unwrapped
+func (k *rotatingKMS) retire(id string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.keks, id)
+}
+
+func (k *rotatingKMS) WrapKey(ctx context.Context, dek []byte) ([]byte, string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.wraps++
+	wrapped, err := Encrypt(k.keks[k.current], dek, []byte(k.current))
+	return wrapped, k.current, err
+}
+
+func (k *rotatingKMS) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.unwraps++
+	kek, ok := k.keks[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", keyID)
+	}
+	return Decrypt(kek, wrapped, []byte(keyID))
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	kms := newRotatingKMS(t)
+	enc := NewEnvelopeEncrypter(kms, EnvelopeOptions{})
+	aad := []byte("users/42/ssn")
+
+	env, err := enc.Encrypt(ctx, []byte("078-05-1120"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.KeyID != "kek-v1" || len(env.WrappedKey) == 0 {
+		t.Fatalf("envelope = %+v, want a DEK wrapped by kek-v1", env)
+	}
+
+	// A fresh encrypter has no cached DEK, so it must unwrap through the KMS
+	pt, err := NewEnvelopeEncrypter(kms, EnvelopeOptions{}).Decrypt(ctx, env, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pt) != "078-05-1120" {
+		t.Errorf("Decrypt() = %q", pt)
+	}
+
+	s, err := enc.EncryptString(ctx, "078-05-1120", aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := enc.DecryptString(ctx, s, aad); err != nil || got != "078-05-1120" {
+		t.Errorf("DecryptString() = %q, %v", got, err)
+	}
+}
+
+func TestEnvelopeRejectsTampering(t *testing.T) {
+	ctx := context.Background()
+	kms := newRotatingKMS(t)
+	aad := []byte("users/42/ssn")
+	env, err := NewEnvelopeEncrypter(kms, EnvelopeOptions{}).Encrypt(ctx, []byte("078-05-1120"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamper := func(change func(*Envelope)) *Envelope {
+		e := Envelope{
+			KeyID:      env.KeyID,
+			WrappedKey: append([]byte(nil), env.WrappedKey...),
+			Ciphertext: append([]byte(nil), env.Ciphertext...),
+		}
+		change(&e)
+		return &e
+	}
+	tests := []struct {
+		name string
+		env  *Envelope
+		aad  []byte
+	}{
+		{"tampered ciphertext", tamper(func(e *Envelope) { e.Ciphertext[len(e.Ciphertext)-1] ^= 1 }), aad},
+		{"tampered nonce", tamper(func(e *Envelope) { e.Ciphertext[0] ^= 1 }), aad},
+		{"tampered wrapped key", tamper(func(e *Envelope) { e.WrappedKey[20] ^= 1 }), aad},
+		{"swapped key ID", tamper(func(e *Envelope) { e.KeyID = "kek-v2" }), aad},
+		{"wrong aad", env, []byte("users/43/ssn")},
+		{"nil envelope", nil, aad},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, err := NewEnvelopeEncrypter(kms, EnvelopeOptions{}).Decrypt(ctx, tt.env, tt.aad)
+			if err == nil {
+				t.Fatalf("Decrypt() = %q, want an error", pt)
+			}
+		})
+	}
+}
+
+func TestEnvelopeWrongKEK(t *testing.T) {
+	ctx := context.Background()
+	aad := []byte("users/42/ssn")
+	env, err := NewEnvelopeEncrypter(LocalKeyWrapper{KeyID: "local", KEK: mustKey(t)}, EnvelopeOptions{}).
+		Encrypt(ctx, []byte("078-05-1120"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same key ID, different key material
+	other := NewEnvelopeEncrypter(LocalKeyWrapper{KeyID: "local", KEK: mustKey(t)}, EnvelopeOptions{})
+	if _, err := other.Decrypt(ctx, env, aad); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Decrypt() with the wrong KEK error = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestEnvelopeKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	kms := newRotatingKMS(t)
+	enc := NewEnvelopeEncrypter(kms, EnvelopeOptions{})
+	aad := []byte("users/42/ssn")
+
+	before, err := enc.Encrypt(ctx, []byte("before"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2 := kms.rotate(t)
+	after, err := enc.Encrypt(ctx, []byte("after"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.KeyID != "kek-v1" || after.KeyID != v2 {
+		t.Fatalf("key IDs = %s, %s; want kek-v1, %s", before.KeyID, after.KeyID, v2)
+	}
+
+	// Values sealed under the previous KEK version stay readable until it is retired
+	reader := NewEnvelopeEncrypter(kms, EnvelopeOptions{})
+	for _, env := range []*Envelope{before, after} {
+		if _, err := reader.Decrypt(ctx, env, aad); err != nil {
+			t.Errorf("Decrypt(%s) error = %v", env.KeyID, err)
+		}
+	}
+	kms.retire("kek-v1")
+	if _, err := NewEnvelopeEncrypter(kms, EnvelopeOptions{}).Decrypt(ctx, before, aad); err == nil {
+		t.Error("Decrypt() succeeded with a retired KEK")
+	}
+	if _, err := NewEnvelopeEncrypter(kms, EnvelopeOptions{}).Decrypt(ctx, after, aad); err != nil {
+		t.Errorf("Decrypt(%s) error = %v", v2, err)
+	}
+}
+
+func TestEnvelopeReuseAndCache(t *testing.T) {
+	ctx := context.Background()
+	kms := newRotatingKMS(t)
+	enc := NewEnvelopeEncrypter(kms, EnvelopeOptions{ReuseDEK: 3})
+	aad := []byte("aad")
+
+	var envs []*Envelope
+	for i := 0; i < 4; i++ {
+		env, err := enc.Encrypt(ctx, []byte{byte(i)}, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		envs = append(envs, env)
+	}
+	if kms.wraps != 2 {
+		t.Errorf("%d DEKs wrapped for 4 values with ReuseDEK 3, want 2", kms.wraps)
+	}
+	if !bytes.Equal(envs[0].WrappedKey, envs[2].WrappedKey) || bytes.Equal(envs[2].WrappedKey, envs[3].WrappedKey) {
+		t.Error("DEK was not reused for exactly 3 values")
+	}
+
+	for i, env := range envs {
+		pt, err := enc.Decrypt(ctx, env, aad)
+		if err != nil || !bytes.Equal(pt, []byte{byte(i)}) {
+			t.Errorf("Decrypt(%d) = %v, %v", i, pt, err)
+		}
+	}
+	if kms.unwraps != 0 {
+		t.Errorf("%d KMS unwraps for DEKs this encrypter generated, want 0", kms.unwraps)
+	}
+}
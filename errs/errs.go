@@ -0,0 +1,228 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kind classifies an error independently of the transport it is reported over
+type Kind string
+
+const (
+	Unknown      Kind = ""
+	Invalid      Kind = "invalid"
+	NotFound     Kind = "not_found"
+	Conflict     Kind = "conflict"
+	Unauthorized Kind = "unauthorized"
+	Forbidden    Kind = "forbidden"
+	Unavailable  Kind = "unavailable"
+	Timeout      Kind = "timeout"
+	Canceled     Kind = "canceled"
+	Internal     Kind = "internal"
+)
+
+// Error carries a Kind, the operation that failed and optional metadata alongside the cause
+type Error struct {
+	Kind Kind
+	// Op names the failing operation, e.g. "mongoclient.InsertOne".
+	Op string
+	// Message is safe to show to clients. The cause is not.
+	Message string
+	// Meta holds structured context such as IDs or field names.
+	Meta map[string]interface{}
+	Err  error
+}
+
+// Error formats as "<message>: <cause>", falling back to the op when there is no message
+func (e *Error) Error() string {
+	var b strings.Builder
+	switch {
+	case e.Message != "":
+		b.WriteString(e.Message)
+	case e.Op != "":
+		b.WriteString(e.Op)
+	default:
+		b.WriteString(string(e.Kind))
+	}
+	if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches another *Error with the same Kind and no cause, so sentinels
+// like errs.New(errs.NotFound, "") work with errors.Is
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Err == nil && t.Kind == e.Kind && (t.Message == "" || t.Message == e.Message)
+}
+
+// New creates an error of the given kind
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Newf creates an error of the given kind with a formatted message
+func Newf(kind Kind, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap annotates err with a kind, operation and message. It returns nil if err is nil.
+// An Unknown kind inherits the kind of err.
+func Wrap(err error, kind Kind, op, message string) error {
+	if err == nil {
+		return nil
+	}
+	if kind == Unknown {
+		kind = KindOf(err)
+	}
+	return &Error{Kind: kind, Op: op, Message: message, Err: err}
+}
+
+// With returns a copy of e with key=value added to its metadata
+func (e *Error) With(key string, value interface{}) *Error {
+	c := *e
+	c.Meta = make(map[string]interface{}, len(e.Meta)+1)
+	for k, v := range e.Meta {
+		c.Meta[k] = v
+	}
+	c.Meta[key] = value
+	return &c
+}
+
+// KindOf returns the kind of the outermost *Error in err's chain.
+// Context cancellation and deadlines are recognised without wrapping.
+func KindOf(err error) Kind {
+	if err == nil {
+		return Unknown
+	}
+	var e *Error
+	for target := err; errors.As(target, &e); target = e.Err {
+		if e.Kind != Unknown {
+			return e.Kind
+		}
+		if e.Err == nil {
+			break
+		}
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	}
+	return Unknown
+}
+
+// Is reports whether err is of the given kind
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+// Op returns the operation of the outermost *Error that has one
+func Op(err error) string {
+	var e *Error
+	for target := err; errors.As(target, &e); target = e.Err {
+		if e.Op != "" {
+			return e.Op
+		}
+		if e.Err == nil {
+			break
+		}
+	}
+	return ""
+}
+
+// Meta merges the metadata of every *Error in err's chain, outer values winning
+func Meta(err error) map[string]interface{} {
+	out := map[string]interface{}{}
+	var chain []*Error
+	var e *Error
+	for target := err; errors.As(target, &e); target = e.Err {
+		chain = append(chain, e)
+		if e.Err == nil {
+			break
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for k, v := range chain[i].Meta {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Message returns the client-safe message of err, or a generic text for its kind
+// so internal details never leak to callers
+func Message(err error) string {
+	var e *Error
+	for target := err; errors.As(target, &e); target = e.Err {
+		if e.Message != "" && e.Kind != Internal && e.Kind != Unknown {
+			return e.Message
+		}
+		if e.Err == nil {
+			break
+		}
+	}
+	return http.StatusText(HTTPStatus(err))
+}
+
+// HTTPStatus maps err's kind to an HTTP status code
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	switch KindOf(err) {
+	case Invalid:
+		return http.StatusBadRequest
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict:
+		return http.StatusConflict
+	case Unauthorized:
+		return http.StatusUnauthorized
+	case Forbidden:
+		return http.StatusForbidden
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case Timeout:
+		return http.StatusGatewayTimeout
+	case Canceled:
+		return 499 // client closed request
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FromHTTPStatus maps an HTTP status code from a downstream service to a kind
+func FromHTTPStatus(status int) Kind {
+	switch {
+	case status == http.StatusBadRequest, status == http.StatusUnprocessableEntity:
+		return Invalid
+	case status == http.StatusNotFound:
+		return NotFound
+	case status == http.StatusConflict, status == http.StatusPreconditionFailed:
+		return Conflict
+	case status == http.StatusUnauthorized:
+		return Unauthorized
+	case status == http.StatusForbidden:
+		return Forbidden
+	case status == http.StatusTooManyRequests, status == http.StatusServiceUnavailable, status == http.StatusBadGateway:
+		return Unavailable
+	case status == http.StatusGatewayTimeout, status == http.StatusRequestTimeout:
+		return Timeout
+	case status >= 500:
+		return Internal
+	default:
+		return Unknown
+	}
+}
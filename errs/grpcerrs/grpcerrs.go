@@ -0,0 +1,105 @@
+// Package grpcerrs maps errs kinds to and from gRPC status codes.
+// It lives in its own package so services importing only errs do not link gRPC.
+package grpcerrs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code maps err's kind to a gRPC code
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	switch errs.KindOf(err) {
+	case errs.Invalid:
+		return codes.InvalidArgument
+	case errs.NotFound:
+		return codes.NotFound
+	case errs.Conflict:
+		return codes.AlreadyExists
+	case errs.Unauthorized:
+		return codes.Unauthenticated
+	case errs.Forbidden:
+		return codes.PermissionDenied
+	case errs.Unavailable:
+		return codes.Unavailable
+	case errs.Timeout:
+		return codes.DeadlineExceeded
+	case errs.Canceled:
+		return codes.Canceled
+	default:
+		return codes.Internal
+	}
+}
+
+// Kind maps a gRPC code to an errs kind
+func Kind(code codes.Code) errs.Kind {
+	switch code {
+	case codes.OK:
+		return errs.Unknown
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return errs.Invalid
+	case codes.NotFound:
+		return errs.NotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return errs.Conflict
+	case codes.Unauthenticated:
+		return errs.Unauthorized
+	case codes.PermissionDenied:
+		return errs.Forbidden
+	case codes.Unavailable, codes.ResourceExhausted:
+		return errs.Unavailable
+	case codes.DeadlineExceeded:
+		return errs.Timeout
+	case codes.Canceled:
+		return errs.Canceled
+	default:
+		return errs.Internal
+	}
+}
+
+// Status converts err into a gRPC status carrying only the client-safe message
+func Status(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	if s, ok := status.FromError(err); ok && !isErrsError(err) {
+		return s
+	}
+	return status.New(Code(err), errs.Message(err))
+}
+
+// FromGRPC converts an error returned by a gRPC client call into an *errs.Error
+func FromGRPC(err error, op string) error {
+	if err == nil {
+		return nil
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return errs.Wrap(err, errs.Unknown, op, "")
+	}
+	return &errs.Error{Kind: Kind(s.Code()), Op: op, Message: s.Message(), Err: err}
+}
+
+// UnaryServerInterceptor converts errors returned by handlers into gRPC statuses
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, Status(err).Err()
+		}
+		return resp, nil
+	}
+}
+
+func isErrsError(err error) bool {
+	var e *errs.Error
+	return errors.As(err, &e)
+}
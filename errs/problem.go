@@ -0,0 +1,186 @@
+package errs
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Extensions are serialized as top-level members next to the standard ones.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON flattens Extensions into the object; standard members win on conflict
+func (p Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+// ProblemOptions configures how errors are rendered as problems
+type ProblemOptions struct {
+	// TypeBaseURI prefixes the kind to form the type URI, e.g. "https://errors.example.com/"
+	// gives "https://errors.example.com/not_found". Empty uses "about:blank" as RFC 7807 suggests.
+	TypeBaseURI string
+	// CorrelationID returns the ID added as the "correlation_id" extension. Defaults to the
+	// X-Correlation-ID or X-Request-ID request header.
+	CorrelationID func(r *http.Request) string
+	// IncludeMeta adds the error's Meta as extensions. Only enable it when Meta is client-safe.
+	IncludeMeta bool
+}
+
+// ToProblem converts err to a problem for the request r. Detail carries the client-safe
+// Message of err, never its cause.
+func ToProblem(err error, r *http.Request, opts ProblemOptions) Problem {
+	status := HTTPStatus(err)
+	p := Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     Message(err),
+		Extensions: map[string]interface{}{},
+	}
+	if p.Title == "" {
+		p.Title = "Client Closed Request" // 499 has no standard text
+	}
+	if p.Detail == p.Title {
+		p.Detail = ""
+	}
+
+	kind := KindOf(err)
+	if kind == Unknown {
+		kind = Internal
+	}
+	if opts.TypeBaseURI != "" {
+		p.Type = opts.TypeBaseURI + string(kind)
+	}
+	p.Extensions["kind"] = kind
+
+	if r != nil {
+		p.Instance = r.URL.Path
+		if id := correlationID(r, opts); id != "" {
+			p.Extensions["correlation_id"] = id
+		}
+	}
+	if opts.IncludeMeta {
+		for k, v := range Meta(err) {
+			if _, taken := p.Extensions[k]; !taken {
+				p.Extensions[k] = v
+			}
+		}
+	}
+	return p
+}
+
+func correlationID(r *http.Request, opts ProblemOptions) string {
+	if opts.CorrelationID != nil {
+		return opts.CorrelationID(r)
+	}
+	if id := r.Header.Get("X-Correlation-ID"); id != "" {
+		return id
+	}
+	return r.Header.Get("X-Request-ID")
+}
+
+// WriteProblem renders err as application/problem+json, or as plain text when the
+// client's Accept header prefers text over JSON
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error, opts ProblemOptions) {
+	p := ToProblem(err, r, opts)
+
+	if r != nil && !acceptsJSON(r.Header.Get("Accept")) {
+		msg := p.Title
+		if p.Detail != "" {
+			msg += ": " + p.Detail
+		}
+		http.Error(w, msg, p.Status)
+		return
+	}
+
+	body, mErr := json.Marshal(p)
+	if mErr != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
+
+// HandlerFunc is an HTTP handler that returns an error
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ProblemHandler adapts fn to an http.Handler that renders returned errors with WriteProblem.
+// fn must not have written a response when it returns an error.
+func ProblemHandler(fn HandlerFunc, opts ProblemOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(w, r); err != nil {
+			WriteProblem(w, r, err, opts)
+		}
+	})
+}
+
+// acceptsJSON reports whether the Accept header prefers a JSON type at least as much as
+// any text type. An empty header, */* and application/* all count as JSON.
+func acceptsJSON(accept string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+
+	type mediaRange struct {
+		typ string
+		q   float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mr := mediaRange{typ: strings.ToLower(strings.TrimSpace(fields[0])), q: 1}
+		for _, param := range fields[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					mr.q = q
+				}
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, mr := range ranges {
+		if mr.q <= 0 {
+			continue
+		}
+		switch {
+		case mr.typ == ProblemContentType, mr.typ == "application/json",
+			strings.HasSuffix(mr.typ, "+json"), mr.typ == "application/*", mr.typ == "*/*":
+			return true
+		case strings.HasPrefix(mr.typ, "text/"):
+			return false
+		}
+	}
+	return true
+}
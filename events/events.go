@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Severity classifies how important an event is
+type Severity string
+
+const (
+	SeverityDebug    Severity = "debug"
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityError    Severity = "error"
+	SeverityCritical Severity = "critical"
+)
+
+// Event is a business or operational event flowing through a Pipeline
+type Event struct {
+	ID            string      `json:"id" bson:"_id"`
+	Type          string      `json:"type" bson:"type"`
+	Severity      Severity    `json:"severity" bson:"severity"`
+	Source        string      `json:"source,omitempty" bson:"source,omitempty"`
+	CorrelationID string      `json:"correlation_id,omitempty" bson:"correlation_id,omitempty"`
+	Time          time.Time   `json:"time" bson:"time"`
+	Payload       interface{} `json:"payload,omitempty" bson:"payload,omitempty"`
+}
+
+// New creates an event of the given type carrying a typed payload.
+// ID and Time are filled in; Severity defaults to info.
+func New[T any](eventType string, payload T) Event {
+	return Event{
+		ID:       newID(),
+		Type:     eventType,
+		Severity: SeverityInfo,
+		Time:     time.Now().UTC(),
+		Payload:  payload,
+	}
+}
+
+// WithSeverity returns a copy of the event with the given severity
+func (e Event) WithSeverity(s Severity) Event {
+	e.Severity = s
+	return e
+}
+
+// PayloadAs returns the event payload as T, reporting false if it holds a different type
+func PayloadAs[T any](e Event) (T, bool) {
+	v, ok := e.Payload.(T)
+	return v, ok
+}
+
+type correlationKey struct{}
+
+// WithCorrelationID stores a correlation ID in ctx.
+// Events emitted with that context inherit it unless they already have one.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID stored in ctx, if any
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,234 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink delivers a batch of events to a destination (log, Mongo collection, queue, ...)
+type Sink interface {
+	Write(ctx context.Context, batch []Event) error
+}
+
+// SinkFunc adapts a plain function to the Sink interface.
+// Use it to forward events to a queue producer or any other destination.
+type SinkFunc func(ctx context.Context, batch []Event) error
+
+// Write calls f(ctx, batch)
+func (f SinkFunc) Write(ctx context.Context, batch []Event) error {
+	return f(ctx, batch)
+}
+
+var (
+	// ErrBufferFull is returned by Emit when the buffer is full and the pipeline drops instead of blocking
+	ErrBufferFull = errors.New("event buffer full, event dropped")
+	// ErrClosed is returned by Emit after Close has been called
+	ErrClosed = errors.New("event pipeline closed")
+)
+
+// PipelineOptions configures a Pipeline
+type PipelineOptions struct {
+	// Source is stamped on events that do not set one, typically the application name.
+	Source string
+	// BufferSize is the number of events held in memory before backpressure applies. Defaults to 1024.
+	BufferSize int
+	// BatchSize is the maximum number of events handed to the sink at once. Defaults to 100.
+	BatchSize int
+	// FlushInterval bounds how long an event waits for its batch to fill. Defaults to 1s.
+	FlushInterval time.Duration
+	// WriteTimeout bounds a single sink write. Defaults to 10s.
+	WriteTimeout time.Duration
+	// Block makes Emit wait for buffer space (until its context is done) instead of dropping.
+	Block bool
+	// OnError is called when the sink fails to write a batch. The batch is discarded.
+	OnError func(err error, batch []Event)
+	// OnDrop is called for every event Emit does not queue, with the reason: ErrBufferFull,
+	// ErrClosed or the error of the context. Use it to count drops in a metrics counter,
+	// e.g. by event type. It runs on the goroutine calling Emit and must not block.
+	OnDrop func(e Event, err error)
+}
+
+// Stats is a snapshot of pipeline counters
+type Stats struct {
+	Emitted    uint64
+	Dropped    uint64
+	Delivered  uint64
+	Failed     uint64
+	QueueDepth int
+}
+
+// Pipeline buffers emitted events and delivers them to a Sink in batches
+// from a single background goroutine.
+type Pipeline struct {
+	sink Sink
+	opts PipelineOptions
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan Event
+	done   chan struct{}
+
+	emitted   atomic.Uint64
+	dropped   atomic.Uint64
+	delivered atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// NewPipeline creates a Pipeline writing to sink and starts its delivery goroutine.
+// Call Close on shutdown to drain buffered events.
+func NewPipeline(sink Sink, opts PipelineOptions) *Pipeline {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1024
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 10 * time.Second
+	}
+
+	p := &Pipeline{
+		sink:  sink,
+		opts:  opts,
+		queue: make(chan Event, opts.BufferSize),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Emit queues an event for delivery.
+// Missing ID, Time, Severity and Source are filled in, and the correlation ID is taken from ctx.
+// When the buffer is full it either drops the event (ErrBufferFull) or, with Block set,
+// waits until there is room or ctx is done.
+func (p *Pipeline) Emit(ctx context.Context, e Event) error {
+	if e.ID == "" {
+		e.ID = newID()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.Severity == "" {
+		e.Severity = SeverityInfo
+	}
+	if e.Source == "" {
+		e.Source = p.opts.Source
+	}
+	if e.CorrelationID == "" {
+		e.CorrelationID = CorrelationID(ctx)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return p.drop(e, ErrClosed)
+	}
+
+	if p.opts.Block {
+		select {
+		case p.queue <- e:
+			p.emitted.Add(1)
+			return nil
+		case <-ctx.Done():
+			return p.drop(e, ctx.Err())
+		}
+	}
+
+	select {
+	case p.queue <- e:
+		p.emitted.Add(1)
+		return nil
+	default:
+		return p.drop(e, ErrBufferFull)
+	}
+}
+
+// drop counts an event that was not queued, reports it to OnDrop and returns err
+func (p *Pipeline) drop(e Event, err error) error {
+	p.dropped.Add(1)
+	if p.opts.OnDrop != nil {
+		p.opts.OnDrop(e, err)
+	}
+	return err
+}
+
+// Close stops accepting events and waits until buffered events are delivered or ctx is done
+func (p *Pipeline) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the current pipeline counters
+func (p *Pipeline) Stats() Stats {
+	return Stats{
+		Emitted:    p.emitted.Load(),
+		Dropped:    p.dropped.Load(),
+		Delivered:  p.delivered.Load(),
+		Failed:     p.failed.Load(),
+		QueueDepth: len(p.queue),
+	}
+}
+
+func (p *Pipeline) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, p.opts.BatchSize)
+	for {
+		select {
+		case e, ok := <-p.queue:
+			if !ok {
+				p.flush(batch)
+				return
+			}
+			batch = append(batch, e)
+			if len(batch) >= p.opts.BatchSize {
+				p.flush(batch)
+				batch = make([]Event, 0, p.opts.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				p.flush(batch)
+				batch = make([]Event, 0, p.opts.BatchSize)
+			}
+		}
+	}
+}
+
+func (p *Pipeline) flush(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.WriteTimeout)
+	defer cancel()
+
+	if err := p.sink.Write(ctx, batch); err != nil {
+		p.failed.Add(uint64(len(batch)))
+		if p.opts.OnError != nil {
+			p.opts.OnError(err, batch)
+		}
+		return
+	}
+	p.delivered.Add(uint64(len(batch)))
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/cdcloud-io/go-libs/mongoclient/mongoclientmock"
+)
+
+// blockingSink holds every Write until release is closed
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (s *blockingSink) Write(ctx context.Context, batch []Event) error {
+	<-s.release
+	return nil
+}
+
+func TestPipelineOnDrop(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+
+	var mu sync.Mutex
+	drops := map[error]int{}
+	p := NewPipeline(sink, PipelineOptions{
+		BufferSize: 1,
+		BatchSize:  1,
+		OnDrop: func(e Event, err error) {
+			mu.Lock()
+			drops[err]++
+			mu.Unlock()
+		},
+	})
+
+	// The first event may be taken by the delivery goroutine, so keep emitting until the
+	// buffer is full
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		if err := p.Emit(ctx, New("test", i)); errors.Is(err, ErrBufferFull) {
+			break
+		}
+	}
+	close(sink.release)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Emit(ctx, New("test", 0)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Emit after Close = %v, want ErrClosed", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if drops[ErrBufferFull] != 1 || drops[ErrClosed] != 1 {
+		t.Fatalf("drops = %v, want one ErrBufferFull and one ErrClosed", drops)
+	}
+	if got := p.Stats().Dropped; got != 2 {
+		t.Fatalf("Stats().Dropped = %d, want 2", got)
+	}
+}
+
+func TestMongoSink(t *testing.T) {
+	store := mongoclientmock.New()
+	sink := newMongoSink(store, "app", "events")
+
+	batch := []Event{New("user.created", "a"), New("user.deleted", "b")}
+	if err := sink.Write(context.Background(), batch); err != nil {
+		t.Fatal(err)
+	}
+	if docs := store.Documents("app", "events"); len(docs) != 2 {
+		t.Fatalf("stored %d documents, want 2", len(docs))
+	}
+}
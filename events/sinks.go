@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+)
+
+// LogSink writes each event as a JSON line to a standard library logger
+type LogSink struct {
+	// Logger defaults to log.Default().
+	Logger *log.Logger
+}
+
+// Write logs every event in the batch
+func (s *LogSink) Write(ctx context.Context, batch []Event) error {
+	logger := s.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	for _, e := range batch {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event %s: %w", e.ID, err)
+		}
+		logger.Println(string(line))
+	}
+	return nil
+}
+
+// MongoSink inserts events into a MongoDB collection, one document per event. Inserts go
+// through the mongoclient.Client, so its tenant routing, read-only mode and write
+// throttle apply.
+type MongoSink struct {
+	store  mongoclient.Store
+	params mongoclient.QueryParams
+}
+
+// NewMongoSink creates a sink writing to the given database and collection
+func NewMongoSink(client *mongoclient.Client, database, collection string) *MongoSink {
+	return newMongoSink(client, database, collection)
+}
+
+// newMongoSink creates a sink on any mongoclient.Store, e.g. a mongoclientmock.Store in tests
+func newMongoSink(store mongoclient.Store, database, collection string) *MongoSink {
+	return &MongoSink{
+		store:  store,
+		params: mongoclient.QueryParams{Database: database, Collection: collection},
+	}
+}
+
+// Write inserts the batch with an unordered insert so one bad document does not block the rest
+func (s *MongoSink) Write(ctx context.Context, batch []Event) error {
+	docs := make([]interface{}, len(batch))
+	for i, e := range batch {
+		docs[i] = e
+	}
+
+	if _, err := s.store.InsertMany(ctx, s.params, docs, mongoclient.BulkOptions{Unordered: true}); err != nil {
+		return fmt.Errorf("failed to insert events: %w", err)
+	}
+	return nil
+}
+
+// MultiSink fans every batch out to several sinks and joins their errors
+type MultiSink []Sink
+
+// Write delivers the batch to each sink in turn
+func (m MultiSink) Write(ctx context.Context, batch []Event) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Write(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+package eventstore
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Event is a persisted domain event of an aggregate
+type Event struct {
+	ID            string `bson:"_id"`
+	AggregateID   string `bson:"aggregate_id"`
+	AggregateType string `bson:"aggregate_type"`
+	// Version is the position of the event in its aggregate's stream, starting at 1.
+	Version  int64             `bson:"version"`
+	Type     string            `bson:"type"`
+	Data     bson.Raw          `bson:"data"`
+	Metadata map[string]string `bson:"metadata,omitempty"`
+	Time     time.Time         `bson:"time"`
+	// ResumeToken is set on events delivered by Subscribe; persist it to resume from there.
+	ResumeToken bson.Raw `bson:"-"`
+}
+
+// Decode unmarshals the event data into v
+func (e Event) Decode(v interface{}) error {
+	if err := bson.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("failed to decode %s event data: %w", e.Type, err)
+	}
+	return nil
+}
+
+// DataAs decodes the event data as T
+func DataAs[T any](e Event) (T, error) {
+	var v T
+	err := e.Decode(&v)
+	return v, err
+}
+
+// EventData is a new event to append
+type EventData struct {
+	Type     string
+	Data     interface{}
+	Metadata map[string]string
+}
+
+// NewEvent creates an event of the given type carrying a typed payload.
+// The payload must marshal to a BSON document, so use a struct or a map.
+func NewEvent[T any](eventType string, data T) EventData {
+	return EventData{Type: eventType, Data: data}
+}
+
+// WithMetadata returns a copy of the event with key set in its metadata
+func (d EventData) WithMetadata(key, value string) EventData {
+	md := make(map[string]string, len(d.Metadata)+1)
+	for k, v := range d.Metadata {
+		md[k] = v
+	}
+	md[key] = value
+	d.Metadata = md
+	return d
+}
+
+// Aggregate is state rebuilt from its events
+type Aggregate interface {
+	Apply(e Event) error
+}
+
+// Root tracks the version and uncommitted events of an aggregate; embed it in aggregates.
+//
+//	type Order struct {
+//		eventstore.Root `bson:"-"`
+//		Status string `bson:"status"`
+//	}
+//
+//	func (o *Order) Pay() error {
+//		return o.Raise(o, eventstore.NewEvent("order.paid", OrderPaid{At: time.Now()}))
+//	}
+type Root struct {
+	version int64
+	changes []EventData
+}
+
+// Version returns the stream version the aggregate was loaded at, or last saved at
+func (r *Root) Version() int64 {
+	return r.version
+}
+
+// Changes returns the events raised since the aggregate was loaded or saved
+func (r *Root) Changes() []EventData {
+	return r.changes
+}
+
+// Raise applies d to agg and records it as an uncommitted change
+func (r *Root) Raise(agg Aggregate, d EventData) error {
+	data, err := bson.Marshal(d.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event data: %w", d.Type, err)
+	}
+	e := Event{
+		Version:  r.version + int64(len(r.changes)) + 1,
+		Type:     d.Type,
+		Data:     data,
+		Metadata: d.Metadata,
+		Time:     time.Now().UTC(),
+	}
+	if err := agg.Apply(e); err != nil {
+		return err
+	}
+	r.changes = append(r.changes, d)
+	return nil
+}
+
+func (r *Root) root() *Root {
+	return r
+}
+
+// rooted is implemented by aggregates embedding Root
+type rooted interface {
+	root() *Root
+}
@@ -0,0 +1,92 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Snapshot is the stored state of an aggregate at a version
+type Snapshot struct {
+	AggregateID   string    `bson:"_id"`
+	AggregateType string    `bson:"aggregate_type"`
+	Version       int64     `bson:"version"`
+	State         bson.Raw  `bson:"state"`
+	Time          time.Time `bson:"time"`
+}
+
+// SaveSnapshot stores state as the snapshot of an aggregate at version. An existing
+// snapshot is only replaced by a newer one. state must marshal to a BSON document.
+func (s *Store) SaveSnapshot(ctx context.Context, aggregateType, aggregateID string, version int64, state interface{}) error {
+	raw, err := bson.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	_, err = s.snapshots.Upsert(ctx,
+		bson.M{"_id": aggregateID, "version": bson.M{"$lt": version}},
+		bson.M{"$set": bson.M{
+			"aggregate_type": aggregateType,
+			"version":        version,
+			"state":          bson.Raw(raw),
+			"time":           s.now().UTC(),
+		}},
+	)
+	if err != nil && !errors.Is(err, mongoclient.ErrDuplicateKey) {
+		// A duplicate key means a newer snapshot already exists
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot returns the latest snapshot of an aggregate, or nil if there is none
+func (s *Store) LoadSnapshot(ctx context.Context, aggregateID string) (*Snapshot, error) {
+	var snap Snapshot
+	err := s.snapshots.FindOne(ctx, bson.M{"_id": aggregateID}, mongoclient.QueryOptions{}, &snap)
+	if errors.Is(err, mongoclient.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// Load rebuilds agg from its latest snapshot, decoded into agg, and the events after it.
+// It returns the aggregate's version, and ErrNotFound if there is neither a snapshot nor
+// any events. Aggregates embedding Root are set to that version.
+func (s *Store) Load(ctx context.Context, aggregateID string, agg Aggregate) (int64, error) {
+	snap, err := s.LoadSnapshot(ctx, aggregateID)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	if snap != nil {
+		if err := bson.Unmarshal(snap.State, agg); err != nil {
+			return 0, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+		version = snap.Version
+	}
+
+	err = s.read(ctx, aggregateID, version, func(e Event) error {
+		if err := agg.Apply(e); err != nil {
+			return fmt.Errorf("failed to apply %s event %d: %w", e.Type, e.Version, err)
+		}
+		version = e.Version
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if version == 0 {
+		return 0, ErrNotFound.With("aggregate_id", aggregateID)
+	}
+	if r, ok := agg.(rooted); ok {
+		r.root().version = version
+		r.root().changes = nil
+	}
+	return version, nil
+}
@@ -0,0 +1,206 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"github.com/cdcloud-io/go-libs/events"
+	"github.com/cdcloud-io/go-libs/id"
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrConcurrency is returned when the aggregate changed since it was loaded
+	ErrConcurrency = errs.New(errs.Conflict, "aggregate was modified concurrently")
+	// ErrNotFound is returned when an aggregate has no events
+	ErrNotFound = errs.New(errs.NotFound, "aggregate not found")
+)
+
+// Options configures a Store
+type Options struct {
+	Database string
+	// Collection holds the events. Defaults to "events".
+	Collection string
+	// SnapshotCollection holds aggregate snapshots. Defaults to "snapshots".
+	SnapshotCollection string
+}
+
+// Store is an append-only event store on MongoDB, one document per event.
+// Appending several events at once and Subscribe need a replica set. Every operation goes
+// through the mongoclient.Client, so a client with a TenantResolver keeps one store per
+// tenant and needs the tenant in the context of each call.
+type Store struct {
+	client    *mongoclient.Client
+	store     mongoclient.Store
+	events    *mongoclient.CollectionRepository
+	snapshots *mongoclient.CollectionRepository
+	opts      Options
+	// transact runs fn atomically; see New
+	transact func(ctx context.Context, fn func(ctx context.Context) error) error
+	now      func() time.Time
+}
+
+// New creates a Store backed by the given client
+func New(client *mongoclient.Client, opts Options) *Store {
+	s := newStore(client, opts)
+	s.client = client
+	s.transact = func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return client.RunTransaction(ctx, func(sc mongo.SessionContext) error { return fn(sc) })
+	}
+	return s
+}
+
+// newStore creates a Store on any mongoclient.Store, e.g. a mongoclientmock.Store in tests.
+// Only EnsureIndexes and transactions need the Client; without one, a multi-event Append
+// is an ordered insert, which writes nothing when its first event conflicts.
+func newStore(store mongoclient.Store, opts Options) *Store {
+	if opts.Collection == "" {
+		opts.Collection = "events"
+	}
+	if opts.SnapshotCollection == "" {
+		opts.SnapshotCollection = "snapshots"
+	}
+	return &Store{
+		store:     store,
+		events:    mongoclient.NewRepository(store, mongoclient.RepositoryOptions{Database: opts.Database, Collection: opts.Collection}),
+		snapshots: mongoclient.NewRepository(store, mongoclient.RepositoryOptions{Database: opts.Database, Collection: opts.SnapshotCollection}),
+		opts:      opts,
+		transact:  func(ctx context.Context, fn func(ctx context.Context) error) error { return fn(ctx) },
+		now:       time.Now,
+	}
+}
+
+// EnsureIndexes creates the unique stream index that enforces optimistic concurrency, in
+// the namespace of the tenant in ctx when the client routes by tenant
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	ns, err := s.client.Resolve(ctx, mongoclient.Namespace{Database: s.opts.Database, Collection: s.opts.Collection})
+	if err != nil {
+		return err
+	}
+	err = s.client.EnsureIndexes(ctx, ns.Database, ns.Collection, []mongoclient.IndexSpec{
+		{Keys: bson.D{{Key: "aggregate_id", Value: 1}, {Key: "version", Value: 1}}, Unique: true},
+		{Keys: bson.D{{Key: "aggregate_type", Value: 1}, {Key: "time", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create event store indexes: %w", err)
+	}
+	return nil
+}
+
+// Append adds changes to the stream of an aggregate. expectedVersion is the version the
+// aggregate was loaded at, 0 for a new aggregate; if another writer appended in the meantime
+// nothing is written and ErrConcurrency is returned. The correlation ID from ctx (see
+// events.WithCorrelationID) is added to the metadata as "correlation_id".
+func (s *Store) Append(ctx context.Context, aggregateType, aggregateID string, expectedVersion int64, changes ...EventData) ([]Event, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	now := s.now().UTC()
+	correlationID := events.CorrelationID(ctx)
+	stored := make([]Event, len(changes))
+	docs := make([]interface{}, len(changes))
+	for i, d := range changes {
+		data, err := bson.Marshal(d.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s event data: %w", d.Type, err)
+		}
+		if correlationID != "" && d.Metadata["correlation_id"] == "" {
+			d = d.WithMetadata("correlation_id", correlationID)
+		}
+		stored[i] = Event{
+			ID:            id.NewULID().String(),
+			AggregateID:   aggregateID,
+			AggregateType: aggregateType,
+			Version:       expectedVersion + int64(i) + 1,
+			Type:          d.Type,
+			Data:          data,
+			Metadata:      d.Metadata,
+			Time:          now,
+		}
+		docs[i] = stored[i]
+	}
+
+	var err error
+	if len(docs) == 1 {
+		_, err = s.events.Insert(ctx, docs[0])
+	} else {
+		err = s.transact(ctx, func(ctx context.Context) error {
+			_, err := s.events.InsertMany(ctx, docs)
+			return err
+		})
+	}
+	if err != nil {
+		if errors.Is(err, mongoclient.ErrDuplicateKey) {
+			return nil, ErrConcurrency.With("aggregate_id", aggregateID).With("expected_version", expectedVersion)
+		}
+		return nil, fmt.Errorf("failed to append events: %w", err)
+	}
+	return stored, nil
+}
+
+// Save appends the uncommitted changes of an aggregate embedding Root and marks them committed
+func (s *Store) Save(ctx context.Context, aggregateType, aggregateID string, agg Aggregate) ([]Event, error) {
+	r, ok := agg.(rooted)
+	if !ok {
+		return nil, fmt.Errorf("eventstore: %T does not embed eventstore.Root", agg)
+	}
+	root := r.root()
+	stored, err := s.Append(ctx, aggregateType, aggregateID, root.version, root.changes...)
+	if err != nil {
+		return nil, err
+	}
+	root.version += int64(len(root.changes))
+	root.changes = nil
+	return stored, nil
+}
+
+// Read returns the events of an aggregate after afterVersion, in order
+func (s *Store) Read(ctx context.Context, aggregateID string, afterVersion int64) ([]Event, error) {
+	var result []Event
+	err := s.read(ctx, aggregateID, afterVersion, func(e Event) error {
+		result = append(result, e)
+		return nil
+	})
+	return result, err
+}
+
+func (s *Store) read(ctx context.Context, aggregateID string, afterVersion int64, fn func(e Event) error) error {
+	params := s.events.Params(bson.M{"aggregate_id": aggregateID, "version": bson.M{"$gt": afterVersion}})
+	params.Options.Sort = bson.D{{Key: "version", Value: 1}}
+	var fnErr error
+	err := s.store.QueryStream(ctx, params, func(doc bson.Raw) error {
+		var e Event
+		if err := bson.Unmarshal(doc, &e); err != nil {
+			return fmt.Errorf("failed to decode event: %w", err)
+		}
+		fnErr = fn(e)
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+	return err
+}
+
+// Version returns the current version of an aggregate, 0 if it has no events
+func (s *Store) Version(ctx context.Context, aggregateID string) (int64, error) {
+	var last Event
+	err := s.events.FindOne(ctx,
+		bson.M{"aggregate_id": aggregateID},
+		mongoclient.QueryOptions{Sort: bson.D{{Key: "version", Value: -1}}, Projection: bson.M{"version": 1}},
+		&last,
+	)
+	if errors.Is(err, mongoclient.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get aggregate version: %w", err)
+	}
+	return last.Version, nil
+}
@@ -0,0 +1,222 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"github.com/cdcloud-io/go-libs/events"
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"github.com/cdcloud-io/go-libs/mongoclient/mongoclientmock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// streamStore adds the unique (aggregate_id, version) index of EnsureIndexes to the mock.
+// InsertMany checks every event before writing any, like the transaction of New.
+type streamStore struct {
+	*mongoclientmock.Store
+}
+
+func (s *streamStore) InsertOne(ctx context.Context, params mongoclient.QueryParams, document interface{}) (*mongo.InsertOneResult, error) {
+	if err := s.checkStream(ctx, params, document); err != nil {
+		return nil, err
+	}
+	return s.Store.InsertOne(ctx, params, document)
+}
+
+func (s *streamStore) InsertMany(ctx context.Context, params mongoclient.QueryParams, documents []interface{}, opts mongoclient.BulkOptions) ([]interface{}, error) {
+	for _, d := range documents {
+		if err := s.checkStream(ctx, params, d); err != nil {
+			return nil, err
+		}
+	}
+	return s.Store.InsertMany(ctx, params, documents, opts)
+}
+
+func (s *streamStore) checkStream(ctx context.Context, params mongoclient.QueryParams, document interface{}) error {
+	e := document.(Event)
+	params.Filter = bson.M{"aggregate_id": e.AggregateID, "version": e.Version}
+	found, err := s.QueryOneFound(ctx, params, &Event{})
+	if err != nil {
+		return err
+	}
+	if found {
+		dup := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "E11000 duplicate key"}}}
+		return mongoclient.WrapError(dup, "streamStore.Insert", "failed to insert event")
+	}
+	return nil
+}
+
+type OrderPlaced struct {
+	Total float64 `bson:"total"`
+}
+
+type Order struct {
+	Root   `bson:"-"`
+	Total  float64 `bson:"total"`
+	Paid   bool    `bson:"paid"`
+	Events int     `bson:"events"`
+}
+
+func (o *Order) Apply(e Event) error {
+	switch e.Type {
+	case "order.placed":
+		placed, err := DataAs[OrderPlaced](e)
+		if err != nil {
+			return err
+		}
+		o.Total = placed.Total
+	case "order.paid":
+		o.Paid = true
+	}
+	o.Events++
+	return nil
+}
+
+func newTestStore() *Store {
+	return newStore(&streamStore{Store: mongoclientmock.New()}, Options{Database: "orders"})
+}
+
+func TestAppend(t *testing.T) {
+	ctx := events.WithCorrelationID(context.Background(), "req-1")
+	s := newTestStore()
+
+	stored, err := s.Append(ctx, "order", "o1", 0,
+		NewEvent("order.placed", OrderPlaced{Total: 10}),
+		NewEvent("order.paid", bson.M{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 || stored[0].Version != 1 || stored[1].Version != 2 {
+		t.Fatalf("Append() = %+v, want versions 1 and 2", stored)
+	}
+
+	read, err := s.Read(ctx, "o1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 || read[0].Type != "order.paid" || read[0].Metadata["correlation_id"] != "req-1" {
+		t.Fatalf("Read() after version 1 = %+v", read)
+	}
+	if v, err := s.Version(ctx, "o1"); err != nil || v != 2 {
+		t.Fatalf("Version() = %d, %v, want 2", v, err)
+	}
+	if v, err := s.Version(ctx, "unknown"); err != nil || v != 0 {
+		t.Fatalf("Version() of an unknown aggregate = %d, %v, want 0", v, err)
+	}
+}
+
+func TestAppendConcurrency(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	if _, err := s.Append(ctx, "order", "o1", 0, NewEvent("order.placed", OrderPlaced{Total: 10})); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		changes []EventData
+	}{
+		{"single event", []EventData{NewEvent("order.paid", bson.M{})}},
+		{"several events", []EventData{NewEvent("order.paid", bson.M{}), NewEvent("order.shipped", bson.M{})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Another writer appended version 1 since this one loaded the new aggregate
+			_, err := s.Append(ctx, "order", "o1", 0, tt.changes...)
+			if !errors.Is(err, ErrConcurrency) || !errs.Is(err, errs.Conflict) {
+				t.Fatalf("Append() = %v, want ErrConcurrency", err)
+			}
+			if v, _ := s.Version(ctx, "o1"); v != 1 {
+				t.Fatalf("version after the conflict = %d, want 1", v)
+			}
+		})
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	if _, err := s.Load(ctx, "o1", &Order{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load() of an unknown aggregate = %v, want ErrNotFound", err)
+	}
+
+	order := &Order{}
+	if err := order.Raise(order, NewEvent("order.placed", OrderPlaced{Total: 10})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Save(ctx, "order", "o1", order); err != nil {
+		t.Fatal(err)
+	}
+	if order.Version() != 1 || len(order.Changes()) != 0 {
+		t.Fatalf("after Save version = %d with %d changes, want 1 and none", order.Version(), len(order.Changes()))
+	}
+
+	// Two copies loaded at the same version race to pay the order
+	first, second := &Order{}, &Order{}
+	for _, o := range []*Order{first, second} {
+		if v, err := s.Load(ctx, "o1", o); err != nil || v != 1 || o.Total != 10 {
+			t.Fatalf("Load() = %d, %v with %+v", v, err, o)
+		}
+		if err := o.Raise(o, NewEvent("order.paid", bson.M{})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Save(ctx, "order", "o1", first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Save(ctx, "order", "o1", second); !errors.Is(err, ErrConcurrency) {
+		t.Fatalf("Save() of the stale copy = %v, want ErrConcurrency", err)
+	}
+	if len(second.Changes()) != 1 {
+		t.Fatal("a failed Save must keep the changes")
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	if snap, err := s.LoadSnapshot(ctx, "o1"); err != nil || snap != nil {
+		t.Fatalf("LoadSnapshot() without a snapshot = %v, %v", snap, err)
+	}
+
+	_, err := s.Append(ctx, "order", "o1", 0,
+		NewEvent("order.placed", OrderPlaced{Total: 10}),
+		NewEvent("order.updated", bson.M{}),
+		NewEvent("order.paid", bson.M{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only a newer snapshot replaces the stored one
+	saves := []struct {
+		version int64
+		total   float64
+	}{{2, 20}, {1, 99}, {2, 99}}
+	for _, save := range saves {
+		if err := s.SaveSnapshot(ctx, "order", "o1", save.version, Order{Total: save.total, Events: 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snap, err := s.LoadSnapshot(ctx, "o1")
+	if err != nil || snap == nil || snap.Version != 2 || snap.AggregateType != "order" {
+		t.Fatalf("LoadSnapshot() = %+v, %v, want the snapshot at version 2", snap, err)
+	}
+
+	// Load starts from the snapshot and applies only the events after it
+	order := &Order{}
+	version, err := s.Load(ctx, "o1", order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 || order.Version() != 3 || order.Total != 20 || !order.Paid || order.Events != 3 {
+		t.Fatalf("Load() = %d with %+v, want version 3 from the snapshot plus one event", version, order)
+	}
+}
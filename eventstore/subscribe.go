@@ -0,0 +1,68 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SubscribeOptions configures Subscribe
+type SubscribeOptions struct {
+	// AggregateTypes limits delivery to these aggregate types. Empty means all.
+	AggregateTypes []string
+	// EventTypes limits delivery to these event types. Empty means all.
+	EventTypes []string
+	// ResumeToken continues after the event that carried it, e.g. one persisted by the handler.
+	ResumeToken bson.Raw
+	// StartAt delivers events appended from this cluster time on when there is no ResumeToken.
+	// Zero starts with events appended after Subscribe is called.
+	StartAt primitive.Timestamp
+}
+
+// Subscribe delivers appended events to handler, in append order, through a MongoDB change
+// stream. It blocks until ctx is done, returning nil, or until handler returns an error,
+// which is returned. Each event carries its ResumeToken; persist it once the event is
+// handled and pass it back on restart for at-least-once delivery.
+func (s *Store) Subscribe(ctx context.Context, opts SubscribeOptions, handler func(ctx context.Context, e Event) error) error {
+	match := bson.M{"operationType": "insert"}
+	if len(opts.AggregateTypes) > 0 {
+		match["fullDocument.aggregate_type"] = bson.M{"$in": opts.AggregateTypes}
+	}
+	if len(opts.EventTypes) > 0 {
+		match["fullDocument.type"] = bson.M{"$in": opts.EventTypes}
+	}
+	params := s.events.Params(match)
+	params.WatchOptions = mongoclient.WatchOptions{ResumeAfter: opts.ResumeToken, StartAt: opts.StartAt}
+
+	// Cancelling stops the stream when the handler fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	changes, err := s.store.Watch(ctx, params, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to watch events: %w", err)
+	}
+
+	for change := range changes {
+		if change.Err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("event change stream failed: %w", change.Err)
+		}
+		var e Event
+		if err := change.Decode(&e); err != nil {
+			return fmt.Errorf("failed to decode event change: %w", err)
+		}
+		e.ResumeToken = change.ResumeToken
+		if err := handler(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
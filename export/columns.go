@@ -0,0 +1,69 @@
+package export
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Column maps a document field to an output column
+type Column struct {
+	// Header is the column title in the first row.
+	Header string
+	// Field is the dotted path of the document field, e.g. "customer.name".
+	Field string
+	// Format, when set, converts the field value before it is written. The value is nil
+	// when the field is missing.
+	Format func(v interface{}) interface{}
+}
+
+// ColumnsFor derives columns from the exported fields of the struct type T.
+// The header comes from the export tag and the field path from the bson tag, or from the
+// export tag's field option for nested values:
+//
+//	type Order struct {
+//		ID       string   `bson:"_id" export:"Order ID"`
+//		Customer Customer `bson:"customer" export:"Customer,field=customer.name"`
+//		Total    float64  `bson:"total"`
+//		Notes    string   `bson:"notes" export:"-"`
+//	}
+//
+// Fields without an export header use the field name; `export:"-"` skips the field.
+// Fields without a bson tag use the lowercased field name, as the mongo driver does.
+func ColumnsFor[T any]() []Column {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var cols []Column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		header, tagOpts, _ := strings.Cut(f.Tag.Get("export"), ",")
+		if header == "-" {
+			continue
+		}
+		if header == "" {
+			header = f.Name
+		}
+		field, _, _ := strings.Cut(f.Tag.Get("bson"), ",")
+		if field == "-" {
+			continue
+		}
+		if field == "" {
+			field = strings.ToLower(f.Name)
+		}
+		for _, opt := range strings.Split(tagOpts, ",") {
+			if path, ok := strings.CutPrefix(opt, "field="); ok && path != "" {
+				field = path
+			}
+		}
+		cols = append(cols, Column{Header: header, Field: field})
+	}
+	return cols
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type csvWriter struct {
+	w             *csv.Writer
+	record        []string
+	allowFormulas bool
+}
+
+func newCSVWriter(w io.Writer, opts Options) *csvWriter {
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	return &csvWriter{w: cw, allowFormulas: opts.AllowFormulas}
+}
+
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	c.record = c.record[:0]
+	for _, v := range values {
+		s := formatText(v)
+		if _, isText := v.(string); isText && !c.allowFormulas && isFormula(s) {
+			s = "'" + s
+		}
+		c.record = append(c.record, s)
+	}
+	if err := c.w.Write(c.record); err != nil {
+		return fmt.Errorf("failed to write csv row: %w", err)
+	}
+	return nil
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return fmt.Errorf("failed to flush csv: %w", err)
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
+
+// isFormula reports whether a spreadsheet would evaluate s as a formula
+func isFormula(s string) bool {
+	if s == "" {
+		return false
+	}
+	switch s[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return true
+	}
+	return false
+}
+
+// formatText renders a cell value as text
+func formatText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,267 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Format is the output file format
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// ContentType returns the media type of the format
+func (f Format) ContentType() string {
+	if f == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Options configures an export
+type Options struct {
+	// Format defaults to CSV.
+	Format Format
+	// Columns to write, in order. Required; see ColumnsFor.
+	Columns []Column
+	// MaxRows stops the export after this many data rows; 0 means no limit.
+	// XLSX exports are always capped at the worksheet size.
+	MaxRows int
+	// FlushEvery flushes the output after this many rows so HTTP clients receive data
+	// while the query runs. Defaults to 1000.
+	FlushEvery int
+	// Comma is the CSV field delimiter. Defaults to ','.
+	Comma rune
+	// AllowFormulas writes CSV text starting with =, +, - or @ as is. By default such text
+	// is prefixed with a quote so spreadsheets do not evaluate it.
+	AllowFormulas bool
+	// SheetName names the XLSX worksheet. Defaults to "Sheet1".
+	SheetName string
+	// BatchSize sets the cursor batch size for Query and Aggregate. Defaults to the driver's.
+	BatchSize int32
+}
+
+// Result describes a finished export
+type Result struct {
+	Rows int
+	// Truncated is set when the export stopped at MaxRows with documents left.
+	Truncated bool
+}
+
+type rowWriter interface {
+	WriteRow(values []interface{}) error
+	Flush() error
+	Close() error
+}
+
+// Stream writes the documents of cursor to w, one row per document. It closes the cursor.
+// Documents are decoded one at a time, so memory use does not grow with the result size.
+func Stream(ctx context.Context, cursor *mongo.Cursor, w io.Writer, opts Options) (Result, error) {
+	defer cursor.Close(context.WithoutCancel(ctx))
+	return write(w, opts, func(fn func(doc bson.Raw) error) error {
+		for cursor.Next(ctx) {
+			if err := fn(cursor.Current); err != nil {
+				return err
+			}
+		}
+		return cursor.Err()
+	})
+}
+
+// errTruncated stops reading documents once MaxRows are written
+var errTruncated = errors.New("export truncated")
+
+// write writes the documents that each hands to its callback to w, one row per document
+func write(w io.Writer, opts Options, each func(fn func(doc bson.Raw) error) error) (Result, error) {
+	if len(opts.Columns) == 0 {
+		return Result{}, errors.New("export: no columns configured")
+	}
+	if opts.Format == "" {
+		opts.Format = CSV
+	}
+	if opts.FlushEvery <= 0 {
+		opts.FlushEvery = 1000
+	}
+	if opts.Format == XLSX && (opts.MaxRows <= 0 || opts.MaxRows > MaxXLSXRows-1) {
+		opts.MaxRows = MaxXLSXRows - 1
+	}
+
+	var rw rowWriter
+	switch opts.Format {
+	case CSV:
+		rw = newCSVWriter(w, opts)
+	case XLSX:
+		xw, err := newXLSXWriter(w, opts)
+		if err != nil {
+			return Result{}, err
+		}
+		rw = xw
+	default:
+		return Result{}, fmt.Errorf("export: unsupported format %q", opts.Format)
+	}
+	flusher, _ := w.(http.Flusher)
+
+	values := make([]interface{}, len(opts.Columns))
+	for i, col := range opts.Columns {
+		values[i] = col.Header
+	}
+	if err := rw.WriteRow(values); err != nil {
+		return Result{}, err
+	}
+
+	paths := make([][]string, len(opts.Columns))
+	for i, col := range opts.Columns {
+		paths[i] = strings.Split(col.Field, ".")
+	}
+
+	var res Result
+	var writeErr error
+	err := each(func(doc bson.Raw) error {
+		if opts.MaxRows > 0 && res.Rows >= opts.MaxRows {
+			res.Truncated = true
+			return errTruncated
+		}
+		for i, col := range opts.Columns {
+			v := lookup(doc, paths[i])
+			if col.Format != nil {
+				v = col.Format(v)
+			}
+			values[i] = v
+		}
+		if writeErr = rw.WriteRow(values); writeErr != nil {
+			return writeErr
+		}
+		res.Rows++
+
+		if res.Rows%opts.FlushEvery == 0 {
+			if writeErr = rw.Flush(); writeErr != nil {
+				return writeErr
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errTruncated):
+	case err != nil && err == writeErr:
+		return res, err
+	case err != nil:
+		return res, fmt.Errorf("failed to read export documents: %w", err)
+	}
+	if err := rw.Close(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// Query exports the documents matching params.Filter through the client, so tenant
+// routing and soft delete apply as for Client.QueryStream, and params.IncludeDeleted
+// exports deleted documents too. params.Options sort, project and limit the documents.
+func Query(ctx context.Context, c *mongoclient.Client, params mongoclient.QueryParams, w io.Writer, opts Options) (Result, error) {
+	if opts.BatchSize > 0 {
+		params.Options.BatchSize = opts.BatchSize
+	}
+	if opts.MaxRows > 0 && (params.Options.Limit <= 0 || params.Options.Limit > int64(opts.MaxRows)) {
+		// One extra document tells whether the export was truncated
+		params.Options.Limit = int64(opts.MaxRows) + 1
+	}
+	return write(w, opts, func(fn func(doc bson.Raw) error) error {
+		return c.QueryStream(ctx, params, fn)
+	})
+}
+
+// Aggregate exports the results of pipeline run on the collection of params through the
+// client, so tenant routing and soft delete apply as for Client.Aggregate. params.Filter,
+// when set, becomes a leading $match stage.
+func Aggregate(ctx context.Context, c *mongoclient.Client, params mongoclient.QueryParams, pipeline mongo.Pipeline, w io.Writer, opts Options) (Result, error) {
+	if opts.BatchSize > 0 {
+		params.Options.BatchSize = opts.BatchSize
+	}
+	return write(w, opts, func(fn func(doc bson.Raw) error) error {
+		return c.AggregateStream(ctx, params, pipeline, fn)
+	})
+}
+
+// SetHeaders sets the content type and an attachment disposition for filename, adding
+// the format's extension when it is missing. Call it before Stream writes to w.
+func SetHeaders(w http.ResponseWriter, filename string, format Format) {
+	if format == "" {
+		format = CSV
+	}
+	if !strings.HasSuffix(strings.ToLower(filename), "."+string(format)) {
+		filename += "." + string(format)
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+}
+
+// lookup returns the value at path in doc as a plain Go value, or nil when it is missing
+func lookup(doc bson.Raw, path []string) interface{} {
+	rv, err := doc.LookupErr(path...)
+	if err != nil {
+		return nil
+	}
+	switch rv.Type {
+	case bson.TypeString:
+		return rv.StringValue()
+	case bson.TypeInt32:
+		return rv.Int32()
+	case bson.TypeInt64:
+		return rv.Int64()
+	case bson.TypeDouble:
+		return rv.Double()
+	case bson.TypeBoolean:
+		return rv.Boolean()
+	case bson.TypeDateTime:
+		return rv.Time()
+	case bson.TypeTimestamp:
+		t, _ := rv.Timestamp()
+		return time.Unix(int64(t), 0)
+	case bson.TypeObjectID:
+		return rv.ObjectID().Hex()
+	case bson.TypeDecimal128:
+		return rv.Decimal128().String()
+	case bson.TypeNull, bson.TypeUndefined:
+		return nil
+	case bson.TypeEmbeddedDocument, bson.TypeArray:
+		// Nested values are written as relaxed extended JSON
+		b, err := bson.MarshalExtJSON(rawWrapper{rv}, false, false)
+		if err != nil {
+			return rv.String()
+		}
+		s := string(b)
+		s = strings.TrimPrefix(s, `{"v":`)
+		return strings.TrimSuffix(s, "}")
+	default:
+		var v interface{}
+		if err := rv.Unmarshal(&v); err != nil {
+			return rv.String()
+		}
+		if b, ok := v.(primitive.Binary); ok {
+			return fmt.Sprintf("%x", b.Data)
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+// rawWrapper lets MarshalExtJSON render a single value
+type rawWrapper struct {
+	V bson.RawValue `bson:"v"`
+}
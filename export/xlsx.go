@@ -0,0 +1,177 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaxXLSXRows is the number of rows an Excel worksheet holds, including the header
+const MaxXLSXRows = 1048576
+
+// The static parts of a single-sheet workbook. Strings are written inline, so no shared
+// strings table is needed and the sheet can be streamed row by row.
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+	// Style 1 is bold for the header row
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+	bold  bool
+}
+
+func newXLSXWriter(w io.Writer, opts Options) (*xlsxWriter, error) {
+	sheetName := opts.SheetName
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	}
+
+	zw := zip.NewWriter(w)
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` +
+		escapeXML(sheetName) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, p := range parts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create xlsx part %s: %w", p.name, err)
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return nil, fmt.Errorf("failed to write xlsx part %s: %w", p.name, err)
+		}
+	}
+
+	// The sheet is the last entry, so it can be streamed until Close
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create xlsx sheet: %w", err)
+	}
+	sheet := bufio.NewWriter(f)
+	sheet.WriteString(xlsxSheetStart)
+	return &xlsxWriter{zw: zw, sheet: sheet, bold: true}, nil
+}
+
+func (x *xlsxWriter) WriteRow(values []interface{}) error {
+	x.rows++
+	row := strconv.Itoa(x.rows)
+	style := ""
+	if x.bold {
+		// Only the header row is written bold
+		style = ` s="1"`
+		x.bold = false
+	}
+
+	b := x.sheet
+	b.WriteString(`<row r="` + row + `">`)
+	for i, v := range values {
+		ref := columnName(i) + row
+		if n, ok := number(v); ok {
+			b.WriteString(`<c r="` + ref + `"` + style + `><v>` + n + `</v></c>`)
+			continue
+		}
+		if bv, ok := v.(bool); ok {
+			val := "0"
+			if bv {
+				val = "1"
+			}
+			b.WriteString(`<c r="` + ref + `"` + style + ` t="b"><v>` + val + `</v></c>`)
+			continue
+		}
+		s := formatText(v)
+		if s == "" {
+			continue
+		}
+		b.WriteString(`<c r="` + ref + `"` + style + ` t="inlineStr"><is><t xml:space="preserve">`)
+		b.WriteString(escapeXML(s))
+		b.WriteString(`</t></is></c>`)
+	}
+	if _, err := b.WriteString(`</row>`); err != nil {
+		return fmt.Errorf("failed to write xlsx row: %w", err)
+	}
+	return nil
+}
+
+func (x *xlsxWriter) Flush() error {
+	if err := x.sheet.Flush(); err != nil {
+		return fmt.Errorf("failed to flush xlsx: %w", err)
+	}
+	if err := x.zw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush xlsx: %w", err)
+	}
+	return nil
+}
+
+func (x *xlsxWriter) Close() error {
+	x.sheet.WriteString(xlsxSheetEnd)
+	if err := x.sheet.Flush(); err != nil {
+		return fmt.Errorf("failed to write xlsx sheet: %w", err)
+	}
+	if err := x.zw.Close(); err != nil {
+		return fmt.Errorf("failed to close xlsx: %w", err)
+	}
+	return nil
+}
+
+// number returns the cell text of numeric values. Times are written as text,
+// since a date cell would need a number format per column.
+func number(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case int32, int64, int:
+		return formatText(v), true
+	case float32:
+		return number(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	}
+	return "", false
+}
+
+// columnName converts a zero-based index to a column name: 0 is A, 26 is AA
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// escapeXML escapes s and drops characters that XML 1.0 does not allow
+func escapeXML(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || (r >= 0x20 && r <= 0xD7FF) ||
+			(r >= 0xE000 && r <= 0xFFFD) || (r >= 0x10000 && r <= 0x10FFFF) {
+			return r
+		}
+		return -1
+	}, s)
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,252 @@
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Operator compares an evaluation context attribute against rule values
+type Operator string
+
+const (
+	OpEquals    Operator = "eq"
+	OpNotEquals Operator = "neq"
+	OpIn        Operator = "in"
+	OpNotIn     Operator = "not_in"
+	OpContains  Operator = "contains"
+	OpHasPrefix Operator = "prefix"
+	OpHasSuffix Operator = "suffix"
+)
+
+// Rule targets a segment of callers by attribute. The first matching rule decides the flag.
+type Rule struct {
+	Attribute string   `yaml:"attribute" bson:"attribute" json:"attribute"`
+	Operator  Operator `yaml:"operator" bson:"operator" json:"operator"`
+	Values    []string `yaml:"values" bson:"values" json:"values"`
+	// Enabled is the result for callers matching the rule.
+	Enabled bool `yaml:"enabled" bson:"enabled" json:"enabled"`
+	// Rollout optionally limits the rule to a percentage (0-100) of matching callers.
+	Rollout *float64 `yaml:"rollout,omitempty" bson:"rollout,omitempty" json:"rollout,omitempty"`
+}
+
+// Flag is a boolean feature flag
+type Flag struct {
+	Key string `yaml:"key" bson:"_id" json:"key"`
+	// Enabled is the kill switch: a disabled flag is off for everyone.
+	Enabled bool   `yaml:"enabled" bson:"enabled" json:"enabled"`
+	Rules   []Rule `yaml:"rules,omitempty" bson:"rules,omitempty" json:"rules,omitempty"`
+	// Rollout is the percentage (0-100) of callers not matched by a rule that get the flag.
+	// Nil means 100. Callers are bucketed by EvalContext.TargetingKey, so results are sticky.
+	Rollout *float64 `yaml:"rollout,omitempty" bson:"rollout,omitempty" json:"rollout,omitempty"`
+}
+
+// EvalContext describes the caller a flag is evaluated for
+type EvalContext struct {
+	// TargetingKey identifies the caller for percentage rollouts, e.g. a user or tenant ID.
+	TargetingKey string
+	Attributes   map[string]string
+}
+
+// Evaluate returns whether the flag is on for ec
+func (f Flag) Evaluate(ec EvalContext) bool {
+	if !f.Enabled {
+		return false
+	}
+	for _, r := range f.Rules {
+		if r.matches(ec) {
+			return r.Enabled && inRollout(f.Key, ec.TargetingKey, r.Rollout)
+		}
+	}
+	return inRollout(f.Key, ec.TargetingKey, f.Rollout)
+}
+
+func (r Rule) matches(ec EvalContext) bool {
+	v, ok := ec.Attributes[r.Attribute]
+	if r.Attribute == "targetingKey" {
+		v, ok = ec.TargetingKey, ec.TargetingKey != ""
+	}
+
+	switch r.Operator {
+	case OpEquals, OpIn:
+		return ok && contains(r.Values, v)
+	case OpNotEquals, OpNotIn:
+		return !ok || !contains(r.Values, v)
+	case OpContains:
+		return ok && anyOf(r.Values, func(s string) bool { return strings.Contains(v, s) })
+	case OpHasPrefix:
+		return ok && anyOf(r.Values, func(s string) bool { return strings.HasPrefix(v, s) })
+	case OpHasSuffix:
+		return ok && anyOf(r.Values, func(s string) bool { return strings.HasSuffix(v, s) })
+	default:
+		return false
+	}
+}
+
+// inRollout hashes flag key and targeting key into one of 10000 buckets,
+// so each caller consistently lands on the same side of the percentage
+func inRollout(flagKey, targetingKey string, rollout *float64) bool {
+	if rollout == nil || *rollout >= 100 {
+		return true
+	}
+	if *rollout <= 0 || targetingKey == "" {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(flagKey))
+	h.Write([]byte{0})
+	h.Write([]byte(targetingKey))
+	return float64(h.Sum32()%10000) < *rollout*100
+}
+
+// Evaluator answers flag queries. *Client and *OFREPProvider implement it.
+type Evaluator interface {
+	Bool(ctx context.Context, key string, ec EvalContext, defaultValue bool) bool
+}
+
+// Source loads flag definitions. Static, environment and Mongo sources are provided.
+type Source interface {
+	Load(ctx context.Context) ([]Flag, error)
+}
+
+// Options configures a Client
+type Options struct {
+	// RefreshInterval reloads flags from the source in Start. Defaults to 30s.
+	RefreshInterval time.Duration
+	// OnError is called when a refresh fails; the previous flags stay in use. Defaults to log.Printf.
+	OnError func(error)
+}
+
+// Client evaluates flags loaded from a Source and keeps them fresh
+type Client struct {
+	source Source
+	opts   Options
+	flags  atomic.Pointer[map[string]Flag]
+
+	mu        sync.Mutex
+	listeners []func()
+}
+
+// New creates a client and performs the initial load
+func New(ctx context.Context, source Source, opts Options) (*Client, error) {
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = 30 * time.Second
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(err error) { log.Printf("featureflags: %v", err) }
+	}
+	c := &Client{source: source, opts: opts}
+	if err := c.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Refresh reloads flags from the source now
+func (c *Client) Refresh(ctx context.Context) error {
+	flags, err := c.source.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load feature flags: %w", err)
+	}
+	m := make(map[string]Flag, len(flags))
+	for _, f := range flags {
+		m[f.Key] = f
+	}
+	c.flags.Store(&m)
+
+	c.mu.Lock()
+	listeners := append([]func(){}, c.listeners...)
+	c.mu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+	return nil
+}
+
+// Start refreshes flags every RefreshInterval until ctx is done
+func (c *Client) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil {
+				c.opts.OnError(err)
+			}
+		}
+	}
+}
+
+// OnChange registers fn to run after every successful refresh
+func (c *Client) OnChange(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// Bool evaluates the flag for ec, returning defaultValue for unknown flags
+func (c *Client) Bool(ctx context.Context, key string, ec EvalContext, defaultValue bool) bool {
+	f, ok := (*c.flags.Load())[key]
+	if !ok {
+		return defaultValue
+	}
+	return f.Evaluate(ec)
+}
+
+// Enabled is Bool with an empty context and a false default, for global switches
+func (c *Client) Enabled(key string) bool {
+	return c.Bool(context.Background(), key, EvalContext{}, false)
+}
+
+// Flags returns the currently loaded flags
+func (c *Client) Flags() []Flag {
+	m := *c.flags.Load()
+	out := make([]Flag, 0, len(m))
+	for _, f := range m {
+		out = append(out, f)
+	}
+	return out
+}
+
+type evalContextKey struct{}
+
+// WithEvalContext stores the caller's evaluation context in ctx, typically from HTTP middleware
+func WithEvalContext(ctx context.Context, ec EvalContext) context.Context {
+	return context.WithValue(ctx, evalContextKey{}, ec)
+}
+
+// EvalContextFrom returns the evaluation context stored in ctx
+func EvalContextFrom(ctx context.Context) EvalContext {
+	ec, _ := ctx.Value(evalContextKey{}).(EvalContext)
+	return ec
+}
+
+// IsEnabled evaluates key with the evaluation context stored in ctx
+func IsEnabled(ctx context.Context, e Evaluator, key string) bool {
+	return e.Bool(ctx, key, EvalContextFrom(ctx), false)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func anyOf(list []string, fn func(string) bool) bool {
+	for _, v := range list {
+		if fn(v) {
+			return true
+		}
+	}
+	return false
+}
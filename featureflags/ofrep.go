@@ -0,0 +1,99 @@
+package featureflags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OFREPProvider evaluates flags remotely against a service implementing the
+// OpenFeature Remote Evaluation Protocol (POST /ofrep/v1/evaluate/flags/{key}),
+// such as flagd, GO Feature Flag or a vendor's OFREP endpoint.
+type OFREPProvider struct {
+	baseURL string
+	opts    OFREPOptions
+}
+
+// OFREPOptions configures an OFREPProvider
+type OFREPOptions struct {
+	// Headers are added to every request, e.g. an Authorization header.
+	Headers map[string]string
+	// HTTPClient defaults to a client with a 2s timeout; flag checks sit on request paths.
+	HTTPClient *http.Client
+	// OnError is called when an evaluation fails and the default is returned. Defaults to log.Printf.
+	OnError func(key string, err error)
+}
+
+// NewOFREPProvider creates a provider for the service at baseURL
+func NewOFREPProvider(baseURL string, opts OFREPOptions) *OFREPProvider {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 2 * time.Second}
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(key string, err error) { log.Printf("featureflags: evaluating %q: %v", key, err) }
+	}
+	return &OFREPProvider{baseURL: strings.TrimSuffix(baseURL, "/"), opts: opts}
+}
+
+// Bool evaluates the flag remotely, returning defaultValue on any error
+func (p *OFREPProvider) Bool(ctx context.Context, key string, ec EvalContext, defaultValue bool) bool {
+	v, err := p.evaluate(ctx, key, ec)
+	if err != nil {
+		p.opts.OnError(key, err)
+		return defaultValue
+	}
+	b, ok := v.(bool)
+	if !ok {
+		p.opts.OnError(key, fmt.Errorf("flag value is %T, not bool", v))
+		return defaultValue
+	}
+	return b
+}
+
+func (p *OFREPProvider) evaluate(ctx context.Context, key string, ec EvalContext) (interface{}, error) {
+	evalCtx := make(map[string]interface{}, len(ec.Attributes)+1)
+	for k, v := range ec.Attributes {
+		evalCtx[k] = v
+	}
+	if ec.TargetingKey != "" {
+		evalCtx["targetingKey"] = ec.TargetingKey
+	}
+	body, err := json.Marshal(map[string]interface{}{"context": evalCtx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode evaluation context: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/ofrep/v1/evaluate/flags/"+url.PathEscape(key), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create evaluation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range p.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate flag: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Value        interface{} `json:"value"`
+		ErrorCode    string      `json:"errorCode"`
+		ErrorDetails string      `json:"errorDetails"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode evaluation response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("evaluation failed with status %d: %s %s", resp.StatusCode, result.ErrorCode, result.ErrorDetails)
+	}
+	return result.Value, nil
+}
@@ -0,0 +1,115 @@
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// StaticSource serves a fixed list of flags, e.g. unmarshaled from the service config
+type StaticSource []Flag
+
+// Load returns the flags
+func (s StaticSource) Load(ctx context.Context) ([]Flag, error) {
+	return s, nil
+}
+
+// EnvSource reads flags from environment variables with the given prefix:
+//
+//	FF_NEW_CHECKOUT=true     -> flag "new-checkout" on
+//	FF_NEW_CHECKOUT=false    -> off
+//	FF_NEW_CHECKOUT=25%      -> on for 25% of targeting keys
+//
+// Flag keys are the lowercased remainder with '_' replaced by '-'.
+type EnvSource struct {
+	// Prefix defaults to "FF_".
+	Prefix string
+}
+
+// Load parses the matching environment variables
+func (s EnvSource) Load(ctx context.Context) ([]Flag, error) {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = "FF_"
+	}
+
+	var flags []Flag
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		key := strings.ReplaceAll(strings.ToLower(rest), "_", "-")
+
+		f := Flag{Key: key}
+		if pct, ok := strings.CutSuffix(strings.TrimSpace(value), "%"); ok {
+			p, err := strconv.ParseFloat(pct, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rollout for %s: %q", name, value)
+			}
+			f.Enabled = p > 0
+			f.Rollout = &p
+		} else {
+			b, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid boolean for %s: %q", name, value)
+			}
+			f.Enabled = b
+		}
+		flags = append(flags, f)
+	}
+	return flags, nil
+}
+
+// MongoSource reads flags from a collection with one document per flag, keyed by _id
+type MongoSource struct {
+	flags *mongoclient.CollectionRepository
+}
+
+// NewMongoSource creates a source reading from the given database and collection. With a
+// TenantResolver on the client, Load reads the collection of the tenant in its context.
+func NewMongoSource(client *mongoclient.Client, database, collection string) *MongoSource {
+	return &MongoSource{flags: client.Repository(database, collection)}
+}
+
+// Load reads every flag document
+func (s *MongoSource) Load(ctx context.Context) ([]Flag, error) {
+	var flags []Flag
+	if err := s.flags.Find(ctx, bson.M{}, mongoclient.QueryOptions{}, &flags); err != nil {
+		return nil, fmt.Errorf("failed to query feature flags: %w", err)
+	}
+	return flags, nil
+}
+
+// MultiSource merges several sources; flags from later sources override earlier ones,
+// e.g. MultiSource{mongo, EnvSource{}} lets an environment variable override a stored flag
+type MultiSource []Source
+
+// Load loads every source in order
+func (m MultiSource) Load(ctx context.Context) ([]Flag, error) {
+	byKey := map[string]Flag{}
+	var order []string
+	for _, s := range m {
+		flags, err := s.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range flags {
+			if _, seen := byKey[f.Key]; !seen {
+				order = append(order, f.Key)
+			}
+			byKey[f.Key] = f
+		}
+	}
+	out := make([]Flag, len(order))
+	for i, k := range order {
+		out[i] = byKey[k]
+	}
+	return out, nil
+}
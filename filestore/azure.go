@@ -0,0 +1,229 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+)
+
+// AzureConfig configures the Azure Blob Storage backend
+type AzureConfig struct {
+	// AccountURL is the blob endpoint, e.g. "https://myaccount.blob.core.windows.net"
+	AccountURL string `yaml:"account_url"`
+	Container  string `yaml:"container"`
+	// AccountName and AccountKey authenticate with a shared key. When AccountKey is empty,
+	// DefaultAzureCredential (managed identity, workload identity, az login) is used and
+	// signed URLs are user delegation SAS.
+	AccountName string `yaml:"account_name"`
+	AccountKey  string `yaml:"account_key"`
+}
+
+// AzureStore stores objects as block blobs in one container
+type AzureStore struct {
+	client    *azblob.Client
+	container *container.Client
+	cfg       AzureConfig
+}
+
+// NewAzure creates an Azure Blob Storage store
+func NewAzure(ctx context.Context, cfg AzureConfig) (*AzureStore, error) {
+	if cfg.AccountURL == "" || cfg.Container == "" {
+		return nil, fmt.Errorf("failed to create azure file store: account_url and container are required")
+	}
+
+	var client *azblob.Client
+	if cfg.AccountKey != "" {
+		cred, err := azblob.NewSharedKeyCredential(cfg.AccountName, cfg.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create azure shared key credential: %w", err)
+		}
+		client, err = azblob.NewClientWithSharedKeyCredential(cfg.AccountURL, cred, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create azure blob client: %w", err)
+		}
+	} else {
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create azure credential: %w", err)
+		}
+		client, err = azblob.NewClient(cfg.AccountURL, cred, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create azure blob client: %w", err)
+		}
+	}
+
+	return &AzureStore{
+		client:    client,
+		container: client.ServiceClient().NewContainerClient(cfg.Container),
+		cfg:       cfg,
+	}, nil
+}
+
+// Put uploads the object as a block blob
+func (s *AzureStore) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error {
+	if key == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	upload := &azblob.UploadStreamOptions{Metadata: toPtrMap(opts.Metadata)}
+	if opts.ContentType != "" {
+		upload.HTTPHeaders = &blob.HTTPHeaders{BlobContentType: &opts.ContentType}
+	}
+	if _, err := s.client.UploadStream(ctx, s.cfg.Container, key, r, upload); err != nil {
+		return fmt.Errorf("failed to upload %q: %w", key, err)
+	}
+	return nil
+}
+
+// Get downloads the object as a stream
+func (s *AzureStore) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	resp, err := s.client.DownloadStream(ctx, s.cfg.Container, key, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, Object{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, Object{}, fmt.Errorf("failed to download %q: %w", key, err)
+	}
+	obj := Object{Key: key, Metadata: fromPtrMap(resp.Metadata)}
+	if resp.ContentLength != nil {
+		obj.Size = *resp.ContentLength
+	}
+	if resp.ContentType != nil {
+		obj.ContentType = *resp.ContentType
+	}
+	if resp.LastModified != nil {
+		obj.LastModified = *resp.LastModified
+	}
+	return resp.Body, obj, nil
+}
+
+// Delete removes the blob and its snapshots
+func (s *AzureStore) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteBlob(ctx, s.cfg.Container, key, &azblob.DeleteBlobOptions{
+		DeleteSnapshots: to(azblob.DeleteSnapshotsOptionTypeInclude),
+	})
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("failed to delete %q: %w", key, err)
+	}
+	return nil
+}
+
+// List pages through blobs starting with prefix
+func (s *AzureStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	pager := s.client.NewListBlobsFlatPager(s.cfg.Container, &azblob.ListBlobsFlatOptions{
+		Prefix:  &prefix,
+		Include: azblob.ListBlobsInclude{Metadata: true},
+	})
+
+	var objects []Object
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %q: %w", prefix, err)
+		}
+		if page.Segment == nil {
+			continue
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			obj := Object{Key: *item.Name, Metadata: fromPtrMap(item.Metadata)}
+			if p := item.Properties; p != nil {
+				if p.ContentLength != nil {
+					obj.Size = *p.ContentLength
+				}
+				if p.ContentType != nil {
+					obj.ContentType = *p.ContentType
+				}
+				if p.LastModified != nil {
+					obj.LastModified = *p.LastModified
+				}
+			}
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+// SignedURL returns a blob SAS URL: a service SAS with a shared key,
+// otherwise a user delegation SAS signed with a key fetched via the identity
+func (s *AzureStore) SignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return "", err
+	}
+
+	perms := sas.BlobPermissions{Read: true}
+	if opts.Method == http.MethodPut {
+		perms = sas.BlobPermissions{Create: true, Write: true}
+	}
+	start := time.Now().UTC().Add(-5 * time.Minute) // tolerate clock skew
+	expiry := time.Now().UTC().Add(opts.Expiry)
+
+	blobClient := s.container.NewBlobClient(key)
+	if s.cfg.AccountKey != "" {
+		u, err := blobClient.GetSASURL(perms, expiry, &blob.GetSASURLOptions{StartTime: &start})
+		if err != nil {
+			return "", fmt.Errorf("failed to sign URL for %q: %w", key, err)
+		}
+		return u, nil
+	}
+
+	udc, err := s.client.ServiceClient().GetUserDelegationCredential(ctx, service.KeyInfo{
+		Start:  to(start.Format(sas.TimeFormat)),
+		Expiry: to(expiry.Format(sas.TimeFormat)),
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user delegation key: %w", err)
+	}
+	qp, err := sas.BlobSignatureValues{
+		Protocol:      sas.ProtocolHTTPS,
+		StartTime:     start,
+		ExpiryTime:    expiry,
+		Permissions:   perms.String(),
+		ContainerName: s.cfg.Container,
+		BlobName:      key,
+	}.SignWithUserDelegation(udc)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign URL for %q: %w", key, err)
+	}
+	return blobClient.URL() + "?" + qp.Encode(), nil
+}
+
+func to[T any](v T) *T {
+	return &v
+}
+
+func toPtrMap(m map[string]string) map[string]*string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]*string, len(m))
+	for k, v := range m {
+		out[k] = to(v)
+	}
+	return out
+}
+
+func fromPtrMap(m map[string]*string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if v != nil {
+			out[k] = *v
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the object does not exist
+	ErrNotFound = errors.New("object not found")
+	// ErrNotSupported is returned when a backend cannot perform an operation with its configuration
+	ErrNotSupported = errors.New("operation not supported by backend")
+	// ErrInvalidKey is returned for empty keys or keys escaping the store root
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object describes a stored object
+type Object struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+	Metadata     map[string]string
+}
+
+// PutOptions are optional settings for Put
+type PutOptions struct {
+	ContentType string
+	Metadata    map[string]string
+	// Size is the content length if known; backends that need it buffer the body otherwise.
+	Size int64
+}
+
+// SignedURLOptions configures SignedURL
+type SignedURLOptions struct {
+	// Method is http.MethodGet (download, the default) or http.MethodPut (direct upload).
+	Method string
+	// Expiry defaults to 15m.
+	Expiry time.Duration
+	// ContentType must be sent by the client when uploading to a PUT URL on S3.
+	ContentType string
+}
+
+// Store is a blob/object store. Keys use '/' separators regardless of backend.
+type Store interface {
+	// Put writes the object, replacing any existing one
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error
+	// Get opens the object for reading; the caller must close the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns the objects whose keys start with prefix
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// SignedURL returns a time-limited URL granting access to the object without credentials
+	SignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error)
+}
+
+// Backend names for Config.Backend
+const (
+	BackendLocal = "local"
+	BackendAzure = "azure"
+	BackendS3    = "s3"
+)
+
+// Config selects and configures a backend; it is meant to be embedded in service config
+type Config struct {
+	Backend string      `yaml:"backend"`
+	Local   LocalConfig `yaml:"local"`
+	Azure   AzureConfig `yaml:"azure"`
+	S3      S3Config    `yaml:"s3"`
+}
+
+// New creates the store selected by cfg.Backend
+func New(ctx context.Context, cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case BackendLocal, "":
+		return NewLocal(cfg.Local)
+	case BackendAzure:
+		return NewAzure(ctx, cfg.Azure)
+	case BackendS3:
+		return NewS3(ctx, cfg.S3)
+	default:
+		return nil, fmt.Errorf("failed to create file store: unknown backend %q", cfg.Backend)
+	}
+}
+
+func (o SignedURLOptions) withDefaults() (SignedURLOptions, error) {
+	if o.Method == "" {
+		o.Method = http.MethodGet
+	}
+	if o.Method != http.MethodGet && o.Method != http.MethodPut {
+		return o, fmt.Errorf("%w: signed %s URLs", ErrNotSupported, o.Method)
+	}
+	if o.Expiry <= 0 {
+		o.Expiry = 15 * time.Minute
+	}
+	return o, nil
+}
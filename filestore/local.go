@@ -0,0 +1,263 @@
+package filestore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metaDir holds content type and metadata sidecars, mirroring the object tree
+const metaDir = ".meta"
+
+// LocalConfig configures the local-disk backend
+type LocalConfig struct {
+	// Root is the directory objects are stored under. Created if missing.
+	Root string `yaml:"root"`
+	// BaseURL is where Handler is mounted, e.g. "https://api.example.com/files". Required for SignedURL.
+	BaseURL string `yaml:"base_url"`
+	// SigningKey signs URLs served by Handler. Required for SignedURL.
+	SigningKey string `yaml:"signing_key"`
+}
+
+// LocalStore stores objects as files, for development and single-node deployments
+type LocalStore struct {
+	cfg LocalConfig
+}
+
+type localMeta struct {
+	ContentType string            `json:"content_type,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// NewLocal creates a local-disk store
+func NewLocal(cfg LocalConfig) (*LocalStore, error) {
+	if cfg.Root == "" {
+		return nil, fmt.Errorf("failed to create local file store: root is required")
+	}
+	if err := os.MkdirAll(cfg.Root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create local file store root: %w", err)
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	return &LocalStore{cfg: cfg}, nil
+}
+
+// Put writes the object atomically
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %q: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %q: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %q: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %q: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("failed to store %q: %w", key, err)
+	}
+	return s.writeMeta(key, localMeta{ContentType: opts.ContentType, Metadata: opts.Metadata})
+}
+
+// Get opens the object
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Object{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, Object{}, fmt.Errorf("failed to open %q: %w", key, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Object{}, fmt.Errorf("failed to stat %q: %w", key, err)
+	}
+	return f, s.object(key, info), nil
+}
+
+// Delete removes the object and its metadata
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete %q: %w", key, err)
+	}
+	os.Remove(s.metaPath(key))
+	return nil
+}
+
+// List walks the tree for keys starting with prefix
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(s.cfg.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(s.cfg.Root, p)
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			if key == metaDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".upload-") || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, s.object(key, info))
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %q: %w", prefix, err)
+	}
+	return objects, nil
+}
+
+// SignedURL returns a URL served by Handler, signed with the configured key
+func (s *LocalStore) SignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	if s.cfg.BaseURL == "" || s.cfg.SigningKey == "" {
+		return "", fmt.Errorf("%w: local signed URLs need base_url and signing_key", ErrNotSupported)
+	}
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(opts.Expiry).Unix(), 10)
+	q := url.Values{}
+	q.Set("method", opts.Method)
+	q.Set("expires", expires)
+	q.Set("sig", s.sign(opts.Method, key, expires))
+	return s.cfg.BaseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + q.Encode(), nil
+}
+
+// Handler serves signed GET and PUT URLs produced by SignedURL. Mount it at BaseURL's path
+// with http.StripPrefix, e.g. mux.Handle("/files/", http.StripPrefix("/files/", store.Handler())).
+func (s *LocalStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		q := r.URL.Query()
+		expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+		if err != nil || time.Now().Unix() > expires || q.Get("method") != r.Method ||
+			!hmac.Equal([]byte(q.Get("sig")), []byte(s.sign(r.Method, key, q.Get("expires")))) {
+			http.Error(w, "invalid or expired signature", http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			rc, obj, err := s.Get(r.Context(), key)
+			if errors.Is(err, ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			if err != nil {
+				http.Error(w, "failed to read object", http.StatusInternalServerError)
+				return
+			}
+			defer rc.Close()
+			if obj.ContentType != "" {
+				w.Header().Set("Content-Type", obj.ContentType)
+			}
+			http.ServeContent(w, r, path.Base(key), obj.LastModified, rc.(io.ReadSeeker))
+		case http.MethodPut:
+			if err := s.Put(r.Context(), key, r.Body, PutOptions{ContentType: r.Header.Get("Content-Type")}); err != nil {
+				http.Error(w, "failed to store object", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (s *LocalStore) sign(method, key, expires string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.SigningKey))
+	mac.Write([]byte(method + "\n" + key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path maps key to a file under Root, rejecting keys that escape it
+func (s *LocalStore) path(key string) (string, error) {
+	if key == "" || !fs.ValidPath(key) || key == metaDir || strings.HasPrefix(key, metaDir+"/") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(s.cfg.Root, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalStore) metaPath(key string) string {
+	return filepath.Join(s.cfg.Root, metaDir, filepath.FromSlash(key)+".json")
+}
+
+func (s *LocalStore) writeMeta(key string, m localMeta) error {
+	p := s.metaPath(key)
+	if m.ContentType == "" && len(m.Metadata) == 0 {
+		os.Remove(p)
+		return nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata for %q: %w", key, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("failed to create metadata directory for %q: %w", key, err)
+	}
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		return fmt.Errorf("failed to write metadata for %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s *LocalStore) object(key string, info fs.FileInfo) Object {
+	obj := Object{Key: key, Size: info.Size(), LastModified: info.ModTime()}
+	var m localMeta
+	if b, err := os.ReadFile(s.metaPath(key)); err == nil {
+		_ = json.Unmarshal(b, &m)
+	}
+	obj.ContentType, obj.Metadata = m.ContentType, m.Metadata
+	if obj.ContentType == "" {
+		obj.ContentType = mime.TypeByExtension(path.Ext(key))
+	}
+	return obj
+}
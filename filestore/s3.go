@@ -0,0 +1,183 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Config configures the S3 backend. Credentials come from the default AWS chain
+// (environment, shared config, IRSA, instance role).
+type S3Config struct {
+	Bucket string `yaml:"bucket"`
+	Region string `yaml:"region"`
+	// Endpoint overrides the S3 endpoint for S3-compatible stores such as MinIO
+	Endpoint string `yaml:"endpoint"`
+	// UsePathStyle addresses the bucket in the path instead of the host; most S3-compatible stores need it
+	UsePathStyle bool `yaml:"use_path_style"`
+}
+
+// S3Store stores objects in one S3 bucket
+type S3Store struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+}
+
+// NewS3 creates an S3 store
+func NewS3(ctx context.Context, cfg S3Config) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("failed to create s3 file store: bucket is required")
+	}
+
+	var loadOpts []func(*config.LoadOptions) error
+	if cfg.Region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(cfg.Region))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+		o.UsePathStyle = cfg.UsePathStyle
+	})
+
+	return &S3Store{
+		client:  client,
+		presign: s3.NewPresignClient(client),
+		bucket:  cfg.Bucket,
+	}, nil
+}
+
+// Put uploads the object. S3 needs the content length, so the body is buffered when opts.Size is unset.
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) error {
+	if key == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	size := opts.Size
+	if size <= 0 {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read %q: %w", key, err)
+		}
+		r, size = bytes.NewReader(b), int64(len(b))
+	}
+
+	in := &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          r,
+		ContentLength: aws.Int64(size),
+		Metadata:      opts.Metadata,
+	}
+	if opts.ContentType != "" {
+		in.ContentType = aws.String(opts.ContentType)
+	}
+	if _, err := s.client.PutObject(ctx, in); err != nil {
+		return fmt.Errorf("failed to upload %q: %w", key, err)
+	}
+	return nil
+}
+
+// Get downloads the object as a stream
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	var noKey *types.NoSuchKey
+	if errors.As(err, &noKey) {
+		return nil, Object{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, Object{}, fmt.Errorf("failed to download %q: %w", key, err)
+	}
+
+	obj := Object{
+		Key:         key,
+		Size:        aws.ToInt64(out.ContentLength),
+		ContentType: aws.ToString(out.ContentType),
+		Metadata:    out.Metadata,
+	}
+	if out.LastModified != nil {
+		obj.LastModified = *out.LastModified
+	}
+	return out.Body, obj, nil
+}
+
+// Delete removes the object
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %q: %w", key, err)
+	}
+	return nil
+}
+
+// List pages through objects starting with prefix. Content type and metadata are not
+// returned by the S3 listing and are left empty.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	pager := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	var objects []Object
+	for pager.HasMorePages() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %q: %w", prefix, err)
+		}
+		for _, item := range page.Contents {
+			obj := Object{Key: aws.ToString(item.Key), Size: aws.ToInt64(item.Size)}
+			if item.LastModified != nil {
+				obj.LastModified = *item.LastModified
+			}
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+// SignedURL returns a presigned GET or PUT URL
+func (s *S3Store) SignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return "", err
+	}
+
+	var req *v4.PresignedHTTPRequest
+	if opts.Method == http.MethodPut {
+		in := &s3.PutObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)}
+		if opts.ContentType != "" {
+			in.ContentType = aws.String(opts.ContentType)
+		}
+		req, err = s.presign.PresignPutObject(ctx, in, s3.WithPresignExpires(opts.Expiry))
+	} else {
+		req, err = s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		}, s3.WithPresignExpires(opts.Expiry))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign URL for %q: %w", key, err)
+	}
+	return req.URL, nil
+}
@@ -0,0 +1,271 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a single check or of the whole registry
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+	StatusUnknown  Status = "unknown"
+)
+
+// CheckFunc reports the health of one dependency. Returning nil means healthy.
+// Implementations must honor ctx, which carries the per-check timeout.
+type CheckFunc func(ctx context.Context) error
+
+// Check describes a registered dependency check.
+// A failing Critical check takes the whole service down (and fails readiness),
+// while a failing non-critical check only degrades it.
+type Check struct {
+	Name     string
+	Func     CheckFunc
+	Timeout  time.Duration
+	Critical bool
+}
+
+// Result is the cached outcome of the last evaluation of a check
+type Result struct {
+	Status    Status        `json:"status"`
+	Critical  bool          `json:"critical"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration_ns"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// Report aggregates the results of every registered check
+type Report struct {
+	Status    Status            `json:"status"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// RegistryOptions configures a Registry
+type RegistryOptions struct {
+	// Interval between background evaluations started with Start.
+	Interval time.Duration
+	// DefaultTimeout is used for checks registered without a Timeout.
+	DefaultTimeout time.Duration
+}
+
+// Registry holds the registered checks and the cached result of their last evaluation.
+// Checks run in the background (see Start) so HTTP probes never block on slow dependencies.
+type Registry struct {
+	opts RegistryOptions
+
+	mu       sync.RWMutex
+	checks   []Check
+	report   Report
+	draining bool
+}
+
+// ErrDuplicateCheck is returned when registering a check name twice
+var ErrDuplicateCheck = errors.New("health check already registered")
+
+// NewRegistry creates an empty Registry.
+// Interval defaults to 15s and DefaultTimeout to 5s.
+func NewRegistry(opts RegistryOptions) *Registry {
+	if opts.Interval <= 0 {
+		opts.Interval = 15 * time.Second
+	}
+	if opts.DefaultTimeout <= 0 {
+		opts.DefaultTimeout = 5 * time.Second
+	}
+	return &Registry{
+		opts:   opts,
+		report: Report{Status: StatusUnknown, Checks: map[string]Result{}},
+	}
+}
+
+// Register adds a check to the registry.
+// Its result is unknown until the next evaluation.
+func (r *Registry) Register(check Check) error {
+	if check.Name == "" || check.Func == nil {
+		return fmt.Errorf("health check requires a name and a func")
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = r.opts.DefaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.checks {
+		if c.Name == check.Name {
+			return fmt.Errorf("%w: %s", ErrDuplicateCheck, check.Name)
+		}
+	}
+	r.checks = append(r.checks, check)
+	return nil
+}
+
+// Start evaluates all checks immediately and then on every interval until ctx is cancelled.
+// It returns right away; evaluation happens in a background goroutine.
+func (r *Registry) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.opts.Interval)
+		defer ticker.Stop()
+
+		r.Run(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Run evaluates every check concurrently, caches the results and returns the new report.
+// Workers that do not serve HTTP can call it directly.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make([]Check, len(r.checks))
+	copy(checks, r.checks)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusUp,
+		Checks:    make(map[string]Result, len(checks)),
+		CheckedAt: time.Now(),
+	}
+	for i, c := range checks {
+		res := results[i]
+		report.Checks[c.Name] = res
+		if res.Status != StatusDown {
+			continue
+		}
+		if c.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+
+	r.mu.Lock()
+	r.report = report
+	r.mu.Unlock()
+
+	return report
+}
+
+// Report returns the cached result of the last evaluation
+func (r *Registry) Report() Report {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.report
+}
+
+// Ready reports whether the service should receive traffic: checks have been evaluated
+// at least once, no critical check is failing and the registry is not draining.
+func (r *Registry) Ready() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.draining {
+		return false
+	}
+	return r.report.Status == StatusUp || r.report.Status == StatusDegraded
+}
+
+// SetDraining marks the service as not ready regardless of check results.
+// Call it at the start of a graceful shutdown so load balancers stop routing traffic.
+func (r *Registry) SetDraining(draining bool) {
+	r.mu.Lock()
+	r.draining = draining
+	r.mu.Unlock()
+}
+
+// Handler renders the cached report as JSON.
+// It responds 200 unless a critical check is failing, in which case it responds 503.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Report()
+		code := http.StatusOK
+		if report.Status == StatusDown {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, report)
+	})
+}
+
+// ReadinessHandler responds 200 when Ready reports true and 503 otherwise
+func (r *Registry) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !r.Ready() {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": string(StatusDown)})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": string(StatusUp)})
+	})
+}
+
+// LivenessHandler always responds 200; it only proves the process is serving requests
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": string(StatusUp)})
+	})
+}
+
+func runCheck(ctx context.Context, c Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	res := Result{Status: StatusUp, Critical: c.Critical, CheckedAt: start}
+
+	// Run the check in its own goroutine so a check that ignores ctx
+	// still reports a timeout instead of blocking the whole evaluation,
+	// and a panicking check does not take the registry down with it.
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.Func(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res.Duration = time.Since(start)
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,5 @@
+module github.com/cdcloud-io/go-libs/id
+
+go 1.22.4
+
+require go.mongodb.org/mongo-driver v1.16.1
@@ -0,0 +1,34 @@
+package id
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// PathUUID parses the named path wildcard of a Go 1.22 ServeMux pattern, e.g. "GET /orders/{id}"
+func PathUUID(r *http.Request, name string) (UUID, error) {
+	v := r.PathValue(name)
+	if v == "" {
+		return Nil, fmt.Errorf("%w: missing path parameter %q", ErrInvalid, name)
+	}
+	return ParseUUID(v)
+}
+
+// PathULID parses the named path wildcard as a ULID
+func PathULID(r *http.Request, name string) (ULID, error) {
+	v := r.PathValue(name)
+	if v == "" {
+		return ULID{}, fmt.Errorf("%w: missing path parameter %q", ErrInvalid, name)
+	}
+	return ParseULID(v)
+}
+
+// QueryUUID parses the named query parameter. ok is false when the parameter is absent.
+func QueryUUID(r *http.Request, name string) (u UUID, ok bool, err error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return Nil, false, nil
+	}
+	u, err = ParseUUID(v)
+	return u, err == nil, err
+}
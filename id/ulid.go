@@ -0,0 +1,199 @@
+package id
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// ULID is a 128-bit lexicographically sortable identifier: a 48-bit millisecond timestamp
+// and 80 random bits, encoded as 26 Crockford base32 characters.
+// ULIDs are stored in Mongo as strings so they stay human-readable and sort correctly.
+type ULID [16]byte
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var crockfordDec [256]byte
+
+func init() {
+	for i := range crockfordDec {
+		crockfordDec[i] = 0xff
+	}
+	for i := 0; i < len(crockford); i++ {
+		crockfordDec[crockford[i]] = byte(i)
+		crockfordDec[crockford[i]|0x20] = byte(i) // lowercase
+	}
+	// Crockford aliases
+	for _, c := range "oO" {
+		crockfordDec[c] = 0
+	}
+	for _, c := range "iIlL" {
+		crockfordDec[c] = 1
+	}
+}
+
+var (
+	ulidMu   sync.Mutex
+	ulidLast ULID
+)
+
+// NewULID returns a new ULID. ULIDs generated by this process within the same
+// millisecond increment the random part, so they are strictly increasing.
+func NewULID() ULID {
+	ms := uint64(time.Now().UnixMilli())
+
+	ulidMu.Lock()
+	defer ulidMu.Unlock()
+
+	var u ULID
+	if last := ulidLast.timestamp(); ms <= last {
+		u = ulidLast
+		// Increment the 80-bit random part; on the (astronomically unlikely) overflow move to the next millisecond
+		for i := 15; i >= 6; i-- {
+			u[i]++
+			if u[i] != 0 {
+				break
+			}
+			if i == 6 {
+				u.setTimestamp(last + 1)
+			}
+		}
+	} else {
+		u.setTimestamp(ms)
+		if _, err := rand.Read(u[6:]); err != nil {
+			panic(fmt.Sprintf("id: failed to read random bytes: %v", err))
+		}
+	}
+	ulidLast = u
+	return u
+}
+
+// ParseULID parses the 26-character base32 form, case-insensitively
+func ParseULID(s string) (ULID, error) {
+	var u ULID
+	if len(s) != 26 {
+		return u, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	var v [26]byte
+	for i := 0; i < 26; i++ {
+		v[i] = crockfordDec[s[i]]
+		if v[i] == 0xff {
+			return u, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+	}
+	// The first character carries only 3 bits
+	if v[0] > 7 {
+		return u, fmt.Errorf("%w: %q overflows 128 bits", ErrInvalid, s)
+	}
+
+	// Decode 130 bits (26 x 5) into 128, dropping the 2 leading zero bits
+	var acc uint64
+	bits := 0
+	out := 0
+	for i := 0; i < 26; i++ {
+		acc = acc<<5 | uint64(v[i])
+		bits += 5
+		if i == 0 {
+			bits -= 2 // discard the two padding bits of the first character
+			acc &= 0x7
+		}
+		for bits >= 8 {
+			bits -= 8
+			u[out] = byte(acc >> uint(bits))
+			out++
+		}
+	}
+	return u, nil
+}
+
+// MustParseULID is ParseULID that panics on error, for constants and tests
+func MustParseULID(s string) ULID {
+	u, err := ParseULID(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// String returns the 26-character uppercase base32 form
+func (u ULID) String() string {
+	var buf [26]byte
+	// 128 bits plus 2 leading zero bits make 26 groups of 5
+	var acc uint64
+	bits := 2
+	pos := 0
+	for _, b := range u {
+		acc = acc<<8 | uint64(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			buf[pos] = crockford[(acc>>uint(bits))&0x1f]
+			pos++
+		}
+	}
+	return string(buf[:])
+}
+
+// Time returns the creation time encoded in the ULID
+func (u ULID) Time() time.Time {
+	return time.UnixMilli(int64(u.timestamp()))
+}
+
+// IsZero reports whether u is the zero ULID
+func (u ULID) IsZero() bool {
+	return u == ULID{}
+}
+
+func (u ULID) timestamp() uint64 {
+	return uint64(u[0])<<40 | uint64(u[1])<<32 | uint64(u[2])<<24 | uint64(u[3])<<16 | uint64(u[4])<<8 | uint64(u[5])
+}
+
+func (u *ULID) setTimestamp(ms uint64) {
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (u ULID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *ULID) UnmarshalText(b []byte) error {
+	parsed, err := ParseULID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// MarshalBSONValue stores the ULID as its string form
+func (u ULID) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bsontype.String, bsoncore.AppendString(nil, u.String()), nil
+}
+
+// UnmarshalBSONValue reads the string form
+func (u *ULID) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.String:
+		s, _, ok := bsoncore.ReadString(data)
+		if !ok {
+			return fmt.Errorf("%w: malformed BSON string", ErrInvalid)
+		}
+		return u.UnmarshalText([]byte(s))
+	case bsontype.Null, bsontype.Undefined:
+		*u = ULID{}
+		return nil
+	default:
+		return fmt.Errorf("%w: cannot decode BSON %s into ULID", ErrInvalid, t)
+	}
+}
@@ -0,0 +1,188 @@
+package id
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// ErrInvalid is returned when parsing a malformed ID
+var ErrInvalid = errors.New("invalid id")
+
+// UUID is an RFC 9562 UUID. New values are version 7: a 48-bit millisecond timestamp
+// followed by random bits, so they sort by creation time and index well as Mongo _id values.
+type UUID [16]byte
+
+// Nil is the zero UUID
+var Nil UUID
+
+// bsonSubtypeUUID is the standard BSON binary subtype for UUIDs
+const bsonSubtypeUUID = 0x04
+
+var (
+	v7mu   sync.Mutex
+	v7last int64
+	v7seq  uint16
+)
+
+// NewUUID returns a new UUIDv7.
+// UUIDs generated by this process within the same millisecond are strictly increasing.
+func NewUUID() UUID {
+	var u UUID
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(fmt.Sprintf("id: failed to read random bytes: %v", err))
+	}
+
+	ms := time.Now().UnixMilli()
+	v7mu.Lock()
+	if ms <= v7last {
+		// Same (or earlier, if the clock stepped back) millisecond: keep the timestamp and bump the counter
+		ms = v7last
+		v7seq++
+		if v7seq > 0x0fff {
+			// Counter exhausted: borrow the next millisecond rather than wrap
+			v7last++
+			ms = v7last
+			v7seq = 0
+		}
+	} else {
+		v7last = ms
+		v7seq = binary.BigEndian.Uint16(u[6:8]) & 0x07ff // leave room to count up within the millisecond
+	}
+	seq := v7seq
+	v7mu.Unlock()
+
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+	// 12-bit rand_a holds the sequence so ordering within a millisecond is preserved
+	u[6] = 0x70 | byte(seq>>8)&0x0f
+	u[7] = byte(seq)
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+	return u
+}
+
+// ParseUUID parses the canonical 36-character form, with or without braces or a urn:uuid: prefix
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	switch len(s) {
+	case 36:
+	case 38:
+		if s[0] != '{' || s[37] != '}' {
+			return u, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+		s = s[1:37]
+	case 45:
+		if s[:9] != "urn:uuid:" {
+			return u, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+		s = s[9:]
+	default:
+		return u, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	hexStr := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	if _, err := hex.Decode(u[:], []byte(hexStr)); err != nil {
+		return u, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	return u, nil
+}
+
+// MustParseUUID is ParseUUID that panics on error, for constants and tests
+func MustParseUUID(s string) UUID {
+	u, err := ParseUUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// String returns the canonical lowercase form
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// Version returns the UUID version number
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// IsZero reports whether u is Nil
+func (u UUID) IsZero() bool {
+	return u == Nil
+}
+
+// Time returns the creation time of a UUIDv7, or the zero time for other versions
+func (u UUID) Time() time.Time {
+	if u.Version() != 7 {
+		return time.Time{}
+	}
+	ms := int64(u[0])<<40 | int64(u[1])<<32 | int64(u[2])<<24 | int64(u[3])<<16 | int64(u[4])<<8 | int64(u[5])
+	return time.UnixMilli(ms)
+}
+
+// MarshalText implements encoding.TextMarshaler, so UUIDs encode as strings in JSON
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *UUID) UnmarshalText(b []byte) error {
+	parsed, err := ParseUUID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// MarshalBSONValue stores the UUID as BSON binary subtype 4
+func (u UUID) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bsontype.Binary, bsoncore.AppendBinary(nil, bsonSubtypeUUID, u[:]), nil
+}
+
+// UnmarshalBSONValue reads binary subtype 4 (or legacy 3) and string representations
+func (u *UUID) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.Binary:
+		subtype, b, _, ok := bsoncore.ReadBinary(data)
+		if !ok || (subtype != bsonSubtypeUUID && subtype != 0x03) || len(b) != 16 {
+			return fmt.Errorf("%w: unexpected BSON binary for UUID", ErrInvalid)
+		}
+		copy(u[:], b)
+		return nil
+	case bsontype.String:
+		s, _, ok := bsoncore.ReadString(data)
+		if !ok {
+			return fmt.Errorf("%w: malformed BSON string", ErrInvalid)
+		}
+		return u.UnmarshalText([]byte(s))
+	case bsontype.Null, bsontype.Undefined:
+		*u = Nil
+		return nil
+	default:
+		return fmt.Errorf("%w: cannot decode BSON %s into UUID", ErrInvalid, t)
+	}
+}
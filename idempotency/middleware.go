@@ -0,0 +1,192 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Header is the request header carrying the client's idempotency key
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set to "true" on responses replayed from the store
+const ReplayedHeader = "Idempotent-Replayed"
+
+// Options configures Middleware
+type Options struct {
+	Store Store
+	// Methods that honor the header. Defaults to POST and PATCH.
+	Methods []string
+	// Required rejects requests with these methods that carry no key with 400.
+	Required bool
+	// Scope namespaces keys, e.g. by tenant or authenticated client, so two callers
+	// cannot collide on or read each other's responses. Defaults to no scope.
+	Scope func(r *http.Request) string
+	// TTL is how long responses are kept for replay. Defaults to 24h.
+	TTL time.Duration
+	// LockTTL bounds how long a crashed request blocks its key. Defaults to 1m.
+	LockTTL time.Duration
+	// MaxBodyBytes limits the request body read for fingerprinting. Defaults to 1MiB.
+	MaxBodyBytes int64
+	// MaxKeyLength defaults to 255.
+	MaxKeyLength int
+}
+
+// Middleware makes handlers safely retryable. The first request with a key is processed and
+// its response stored; retries with the same key and body get the stored response, concurrent
+// duplicates get 409 and reuse of a key for a different request gets 422. Server errors (5xx)
+// are not stored, so the client can retry them.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	if len(opts.Methods) == 0 {
+		opts.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = time.Minute
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 1 << 20
+	}
+	if opts.MaxKeyLength <= 0 {
+		opts.MaxKeyLength = 255
+	}
+	methods := make(map[string]bool, len(opts.Methods))
+	for _, m := range opts.Methods {
+		methods[m] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !methods[r.Method] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			clientKey := r.Header.Get(Header)
+			if clientKey == "" {
+				if opts.Required {
+					http.Error(w, "missing "+Header+" header", http.StatusBadRequest)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(clientKey) > opts.MaxKeyLength {
+				http.Error(w, Header+" header too long", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, opts.MaxBodyBytes+1))
+			if err != nil {
+				http.Error(w, "failed to read request body", http.StatusBadRequest)
+				return
+			}
+			if int64(len(body)) > opts.MaxBodyBytes {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := ""
+			if opts.Scope != nil {
+				scope = opts.Scope(r)
+			}
+			key := scope + "|" + clientKey
+
+			rec, started, err := opts.Store.Begin(r.Context(), key, fingerprint(r, body), opts.LockTTL, opts.TTL)
+			switch {
+			case errors.Is(err, ErrInProgress):
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			case errors.Is(err, ErrFingerprintMismatch):
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			case err != nil:
+				log.Printf("idempotency: %v", err)
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			case !started:
+				replay(w, rec.Response)
+				return
+			}
+
+			rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				// Release on panic or server error so the client can retry
+				if !completed {
+					if err := opts.Store.Release(context.WithoutCancel(r.Context()), key, rec.Owner); err != nil {
+						log.Printf("idempotency: %v", err)
+					}
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			if rw.status >= 500 {
+				return
+			}
+			resp := Response{StatusCode: rw.status, Header: rw.Header().Clone(), Body: rw.body.Bytes()}
+			if err := opts.Store.Complete(context.WithoutCancel(r.Context()), key, rec.Owner, resp); err != nil {
+				log.Printf("idempotency: %v", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// fingerprint identifies the request a key was first used for
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, resp *Response) {
+	if resp == nil {
+		http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+		return
+	}
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(resp.Body)
+}
+
+// recorder passes the response through while keeping a copy for the store
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recorder) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
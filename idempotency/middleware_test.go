@@ -0,0 +1,117 @@
+package idempotency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	s, _ := newTestStore(t)
+	var calls atomic.Int32
+	status := http.StatusCreated
+	handler := Middleware(Options{Store: s})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Location", "/orders/7")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"call":%d}`, n)
+	}))
+
+	do := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(Header, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := do("key-1", `{"item":"a"}`)
+	if first.Code != http.StatusCreated || first.Header().Get(ReplayedHeader) != "" {
+		t.Fatalf("first response = %d %v", first.Code, first.Header())
+	}
+
+	retry := do("key-1", `{"item":"a"}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() ||
+		retry.Header().Get("Location") != "/orders/7" || retry.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("retry = %d %v %s; want the stored response", retry.Code, retry.Header(), retry.Body)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+
+	if rec := do("key-1", `{"item":"b"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with another body = %d, want 422", rec.Code)
+	}
+	if rec := do("", `{"item":"a"}`); rec.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Errorf("request without a key = %d; want it passed through", rec.Code)
+	}
+
+	// Server errors are not stored, so a retry runs the handler again
+	status = http.StatusInternalServerError
+	if rec := do("key-2", `{}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("failing request = %d", rec.Code)
+	}
+	status = http.StatusCreated
+	if rec := do("key-2", `{}`); rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("retry after a server error = %d %v; want a fresh run", rec.Code, rec.Header())
+	}
+}
+
+func TestMiddlewareInFlightDuplicate(t *testing.T) {
+	s, _ := newTestStore(t)
+	entered, release := make(chan struct{}), make(chan struct{})
+	handler := Middleware(Options{Store: s})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan int)
+	go func() {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+		req.Header.Set(Header, "key-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		done <- rec.Code
+	}()
+	<-entered
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+	req.Header.Set(Header, "key-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("duplicate while the first is in flight = %d %v, want 409 with Retry-After", rec.Code, rec.Header())
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusCreated {
+		t.Errorf("first request = %d", code)
+	}
+}
+
+func TestMiddlewareScope(t *testing.T) {
+	s, _ := newTestStore(t)
+	handler := Middleware(Options{
+		Store: s,
+		Scope: func(r *http.Request) string { return r.Header.Get("X-Tenant") },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Tenant")))
+	}))
+
+	for _, tenant := range []string{"a", "b"} {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+		req.Header.Set(Header, "key-1")
+		req.Header.Set("X-Tenant", tenant)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Body.String() != tenant || rec.Header().Get(ReplayedHeader) != "" {
+			t.Errorf("tenant %s got %q replayed=%q; keys must not collide across scopes", tenant, rec.Body, rec.Header().Get(ReplayedHeader))
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	// ErrInProgress is returned when another request with the same key is still being processed
+	ErrInProgress = errors.New("request with this idempotency key is in progress")
+	// ErrFingerprintMismatch is returned when a key is reused with a different request
+	ErrFingerprintMismatch = errors.New("idempotency key reused with a different request")
+	// ErrLockLost is returned by Complete when the lock on the key lapsed and another request
+	// took it over
+	ErrLockLost = errors.New("idempotency key was taken over by another request")
+)
+
+// Status is the state of a stored request
+type Status string
+
+const (
+	StatusProcessing Status = "processing"
+	StatusCompleted  Status = "completed"
+)
+
+// Response is a cached HTTP response
+type Response struct {
+	StatusCode int         `bson:"status_code"`
+	Header     http.Header `bson:"header"`
+	Body       []byte      `bson:"body"`
+}
+
+// Record is the stored state of one idempotency key
+type Record struct {
+	Key         string `bson:"_id"`
+	Fingerprint string `bson:"fingerprint"`
+	Status      Status `bson:"status"`
+	// Owner identifies the request processing the key; Complete and Release must present it.
+	Owner       string    `bson:"owner"`
+	Response    *Response `bson:"response,omitempty"`
+	LockedUntil time.Time `bson:"locked_until"`
+	CreatedAt   time.Time `bson:"created_at"`
+	// ExpiresAt drives the TTL index that purges the record.
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// Store keeps request fingerprints and cached responses
+type Store interface {
+	// Begin claims key for a request with fingerprint. It returns started=true when the caller
+	// should process the request, with a fresh Owner, or the completed record to replay. It
+	// returns ErrInProgress while another caller holds the key and ErrFingerprintMismatch if
+	// the request differs.
+	Begin(ctx context.Context, key, fingerprint string, lockTTL, ttl time.Duration) (rec *Record, started bool, err error)
+	// Complete stores the response for key if owner still holds it, and returns ErrLockLost
+	// otherwise
+	Complete(ctx context.Context, key, owner string, resp Response) error
+	// Release forgets key so the request can be retried, e.g. after a server error. It does
+	// nothing when owner no longer holds the key.
+	Release(ctx context.Context, key, owner string) error
+}
+
+// MongoStoreOptions configures a MongoStore
+type MongoStoreOptions struct {
+	Database string
+	// Collection defaults to "idempotency_keys".
+	Collection string
+}
+
+// MongoStore is a Store backed by a MongoDB collection
+type MongoStore struct {
+	client  *mongoclient.Client
+	records *mongoclient.CollectionRepository
+	opts    MongoStoreOptions
+	now     func() time.Time
+}
+
+var _ Store = (*MongoStore)(nil)
+
+// NewMongoStore creates a MongoStore backed by the given client
+func NewMongoStore(client *mongoclient.Client, opts MongoStoreOptions) *MongoStore {
+	s := newMongoStore(client, opts)
+	s.client = client
+	return s
+}
+
+// newMongoStore creates a MongoStore on any mongoclient.Store, e.g. a mongoclientmock.Store
+// in tests. Only EnsureIndexes needs the Client.
+func newMongoStore(store mongoclient.Store, opts MongoStoreOptions) *MongoStore {
+	if opts.Collection == "" {
+		opts.Collection = "idempotency_keys"
+	}
+	return &MongoStore{
+		records: mongoclient.NewRepository(store, mongoclient.RepositoryOptions{Database: opts.Database, Collection: opts.Collection}),
+		opts:    opts,
+		now:     time.Now,
+	}
+}
+
+// EnsureIndexes creates the TTL index that purges expired records, in the namespace of the
+// tenant in ctx when the client routes by tenant
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	ns, err := s.client.Resolve(ctx, mongoclient.Namespace{Database: s.opts.Database, Collection: s.opts.Collection})
+	if err != nil {
+		return err
+	}
+	err = s.client.EnsureIndexes(ctx, ns.Database, ns.Collection, []mongoclient.IndexSpec{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, ExpireAt: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create idempotency indexes: %w", err)
+	}
+	return nil
+}
+
+// Begin claims key, relying on the unique _id to settle concurrent duplicates
+func (s *MongoStore) Begin(ctx context.Context, key, fingerprint string, lockTTL, ttl time.Duration) (*Record, bool, error) {
+	now := s.now().UTC()
+	owner := newOwner()
+	rec := Record{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      StatusProcessing,
+		Owner:       owner,
+		LockedUntil: now.Add(lockTTL),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	_, err := s.records.Insert(ctx, rec)
+	if err == nil {
+		return &rec, true, nil
+	}
+	if !errors.Is(err, mongoclient.ErrDuplicateKey) {
+		return nil, false, fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+
+	var existing Record
+	if err := s.records.FindOne(ctx, bson.M{"_id": key}, mongoclient.QueryOptions{}, &existing); err != nil {
+		if errors.Is(err, mongoclient.ErrNotFound) {
+			// Purged or released between the insert and the lookup; the client can retry
+			return nil, false, ErrInProgress
+		}
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, false, ErrFingerprintMismatch
+	}
+	if existing.Status == StatusCompleted {
+		return &existing, false, nil
+	}
+	if existing.LockedUntil.After(now) {
+		return nil, false, ErrInProgress
+	}
+
+	// The previous holder died mid-request; take the lock over if nobody else did
+	result, err := s.records.UpdateOne(ctx,
+		bson.M{"_id": key, "status": StatusProcessing, "locked_until": existing.LockedUntil},
+		bson.M{"$set": bson.M{"locked_until": now.Add(lockTTL), "owner": owner}},
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to take over idempotency key: %w", err)
+	}
+	if result.ModifiedCount == 0 {
+		return nil, false, ErrInProgress
+	}
+	existing.LockedUntil = now.Add(lockTTL)
+	existing.Owner = owner
+	return &existing, true, nil
+}
+
+// Complete stores the response and marks the key completed, unless another request took
+// the key over after owner's lock lapsed
+func (s *MongoStore) Complete(ctx context.Context, key, owner string, resp Response) error {
+	result, err := s.records.UpdateOne(ctx,
+		bson.M{"_id": key, "owner": owner, "status": StatusProcessing},
+		bson.M{"$set": bson.M{"status": StatusCompleted, "response": resp}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release deletes the record of key if owner still holds it
+func (s *MongoStore) Release(ctx context.Context, key, owner string) error {
+	if _, err := s.records.DeleteOne(ctx, bson.M{"_id": key, "owner": owner}); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// newOwner returns a random owner token
+func newOwner() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
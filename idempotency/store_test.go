@@ -0,0 +1,146 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient/mongoclientmock"
+)
+
+func newTestStore(t *testing.T) (*MongoStore, *mongoclientmock.Clock) {
+	t.Helper()
+	s := newMongoStore(mongoclientmock.New(), MongoStoreOptions{Database: "api"})
+	c := mongoclientmock.NewClock(time.Now().Truncate(time.Millisecond))
+	s.now = c.Now
+	return s, c
+}
+
+func TestBeginConcurrentDuplicates(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t)
+
+	const n = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	started, inProgress := 0, 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := s.Begin(ctx, "key-1", "fp", time.Minute, time.Hour)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil && ok:
+				started++
+			case errors.Is(err, ErrInProgress):
+				inProgress++
+			default:
+				t.Errorf("Begin() = %v, %v", ok, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if started != 1 || inProgress != n-1 {
+		t.Errorf("%d started and %d in progress, want 1 and %d", started, inProgress, n-1)
+	}
+}
+
+func TestBeginFingerprintMismatch(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t)
+	rec, _, err := s.Begin(ctx, "key-1", "fp-a", time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Begin(ctx, "key-1", "fp-b", time.Minute, time.Hour); !errors.Is(err, ErrFingerprintMismatch) {
+		t.Errorf("Begin() while processing error = %v, want ErrFingerprintMismatch", err)
+	}
+	if err := s.Complete(ctx, "key-1", rec.Owner, Response{StatusCode: 201}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Begin(ctx, "key-1", "fp-b", time.Minute, time.Hour); !errors.Is(err, ErrFingerprintMismatch) {
+		t.Errorf("Begin() after completion error = %v, want ErrFingerprintMismatch", err)
+	}
+}
+
+func TestCompleteAndReplay(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t)
+	rec, started, err := s.Begin(ctx, "key-1", "fp", time.Minute, time.Hour)
+	if err != nil || !started {
+		t.Fatalf("Begin() = %v, %v", started, err)
+	}
+	resp := Response{StatusCode: 201, Header: map[string][]string{"Location": {"/orders/7"}}, Body: []byte(`{"id":7}`)}
+	if err := s.Complete(ctx, "key-1", rec.Owner, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, started, err := s.Begin(ctx, "key-1", "fp", time.Minute, time.Hour)
+	if err != nil || started {
+		t.Fatalf("Begin() after completion = %v, %v; want the stored response", started, err)
+	}
+	if replayed.Status != StatusCompleted || replayed.Response == nil ||
+		replayed.Response.StatusCode != 201 || string(replayed.Response.Body) != `{"id":7}` ||
+		replayed.Response.Header.Get("Location") != "/orders/7" {
+		t.Errorf("replayed record = %+v", replayed)
+	}
+	if err := s.Complete(ctx, "key-1", rec.Owner, resp); !errors.Is(err, ErrLockLost) {
+		t.Errorf("second Complete() error = %v, want ErrLockLost", err)
+	}
+}
+
+func TestStaleOwnerCannotComplete(t *testing.T) {
+	ctx := context.Background()
+	s, c := newTestStore(t)
+	stale, _, err := s.Begin(ctx, "key-1", "fp", time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first request stalls past its lock; a retry takes the key over
+	c.Advance(30 * time.Second)
+	if _, _, err := s.Begin(ctx, "key-1", "fp", time.Minute, time.Hour); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("Begin() within the lock error = %v, want ErrInProgress", err)
+	}
+	c.Advance(time.Minute)
+	current, started, err := s.Begin(ctx, "key-1", "fp", time.Minute, time.Hour)
+	if err != nil || !started {
+		t.Fatalf("Begin() after the lock lapsed = %v, %v; want a takeover", started, err)
+	}
+	if current.Owner == stale.Owner {
+		t.Fatal("takeover kept the stale owner")
+	}
+
+	if err := s.Complete(ctx, "key-1", stale.Owner, Response{StatusCode: 200, Body: []byte("stale")}); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Complete() by the stale owner error = %v, want ErrLockLost", err)
+	}
+	if err := s.Release(ctx, "key-1", stale.Owner); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete(ctx, "key-1", current.Owner, Response{StatusCode: 200, Body: []byte("current")}); err != nil {
+		t.Fatalf("Complete() by the current owner error = %v; the stale Release must not remove the key", err)
+	}
+	rec, _, err := s.Begin(ctx, "key-1", "fp", time.Minute, time.Hour)
+	if err != nil || string(rec.Response.Body) != "current" {
+		t.Errorf("replayed %v, %v; want the current owner's response", rec, err)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t)
+	rec, _, err := s.Begin(ctx, "key-1", "fp", time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Release(ctx, "key-1", rec.Owner); err != nil {
+		t.Fatal(err)
+	}
+	if _, started, err := s.Begin(ctx, "key-1", "fp", time.Minute, time.Hour); err != nil || !started {
+		t.Errorf("Begin() after Release = %v, %v; want a fresh start", started, err)
+	}
+}
@@ -0,0 +1,298 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Status is the state of a job in the queue
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusDead    Status = "dead"
+)
+
+// Job is a unit of work stored in the queue collection
+type Job struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Queue       string             `bson:"queue"`
+	Type        string             `bson:"type"`
+	Payload     bson.Raw           `bson:"payload,omitempty"`
+	Status      Status             `bson:"status"`
+	Attempts    int                `bson:"attempts"`
+	MaxAttempts int                `bson:"max_attempts"`
+	RunAt       time.Time          `bson:"run_at"`
+	LockedUntil time.Time          `bson:"locked_until,omitempty"`
+	LockedBy    string             `bson:"locked_by,omitempty"`
+	LastError   string             `bson:"last_error,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at"`
+}
+
+// Decode unmarshals the job payload into v
+func (j *Job) Decode(v interface{}) error {
+	if err := bson.Unmarshal(j.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode payload of job %s: %w", j.ID.Hex(), err)
+	}
+	return nil
+}
+
+// QueueOptions configures a Queue
+type QueueOptions struct {
+	Database   string
+	Collection string
+	// Name separates independent queues sharing a collection. Defaults to "default".
+	Name string
+	// MaxAttempts is used for jobs enqueued without their own limit. Defaults to 5.
+	MaxAttempts int
+	// LockTimeout is how long a claimed job stays invisible to other workers.
+	// A job whose worker dies is picked up again once it expires. Defaults to 5m.
+	LockTimeout time.Duration
+	// Backoff returns the delay before retrying after the given failed attempt.
+	// Defaults to exponential backoff starting at 10s, capped at 1h.
+	Backoff func(attempt int) time.Duration
+}
+
+// EnqueueOptions are per-job settings for Enqueue
+type EnqueueOptions struct {
+	// RunAt delays the job until the given time. Defaults to now.
+	RunAt time.Time
+	// MaxAttempts overrides QueueOptions.MaxAttempts for this job.
+	MaxAttempts int
+}
+
+// ErrNoJob is returned by Claim when no job is ready to run
+var ErrNoJob = errors.New("no job ready")
+
+// Queue is a durable job queue stored in a MongoDB collection.
+// Jobs are claimed atomically with findAndModify, so any number of workers
+// across replicas can consume the same queue. Every operation goes through the
+// mongoclient.Client, so a client with a TenantResolver keeps one queue per tenant
+// and needs the tenant in the context of Enqueue and Run.
+type Queue struct {
+	client *mongoclient.Client
+	jobs   *mongoclient.CollectionRepository
+	opts   QueueOptions
+	now    func() time.Time
+}
+
+// NewQueue creates a queue backed by the given client
+func NewQueue(client *mongoclient.Client, opts QueueOptions) *Queue {
+	q := newQueue(client, opts)
+	q.client = client
+	return q
+}
+
+// newQueue creates a Queue on any mongoclient.Store, e.g. a mongoclientmock.Store in tests.
+// Only EnsureIndexes needs the Client.
+func newQueue(store mongoclient.Store, opts QueueOptions) *Queue {
+	if opts.Name == "" {
+		opts.Name = "default"
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = 5 * time.Minute
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = ExponentialBackoff(10*time.Second, time.Hour)
+	}
+	return &Queue{
+		jobs: mongoclient.NewRepository(store, mongoclient.RepositoryOptions{Database: opts.Database, Collection: opts.Collection}),
+		opts: opts,
+		now:  time.Now,
+	}
+}
+
+// ExponentialBackoff doubles the delay after every attempt, starting at base and capped at max
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
+		if d <= 0 || d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// EnsureIndexes creates the index used to claim jobs, in the namespace of the tenant in
+// ctx when the client routes by tenant. Call it once at startup.
+func (q *Queue) EnsureIndexes(ctx context.Context) error {
+	ns, err := q.client.Resolve(ctx, mongoclient.Namespace{Database: q.opts.Database, Collection: q.opts.Collection})
+	if err != nil {
+		return err
+	}
+	err = q.client.EnsureIndexes(ctx, ns.Database, ns.Collection, []mongoclient.IndexSpec{
+		{Keys: bson.D{{Key: "queue", Value: 1}, {Key: "status", Value: 1}, {Key: "run_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create job queue index: %w", err)
+	}
+	return nil
+}
+
+// Enqueue stores a new job of the given type and returns its ID
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts EnqueueOptions) (string, error) {
+	raw, err := bson.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	now := q.now().UTC()
+	job := Job{
+		ID:          primitive.NewObjectID(),
+		Queue:       q.opts.Name,
+		Type:        jobType,
+		Payload:     raw,
+		Status:      StatusPending,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt.UTC(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = q.opts.MaxAttempts
+	}
+	if opts.RunAt.IsZero() {
+		job.RunAt = now
+	}
+
+	if _, err := q.jobs.Insert(ctx, job); err != nil {
+		return "", fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return job.ID.Hex(), nil
+}
+
+// Claim atomically takes the next job that is due, or whose previous worker's lock expired,
+// and marks it running for workerID. It returns ErrNoJob when there is nothing to do.
+// A job whose lock expired on its last attempt, typically because its handler crashed the
+// process, is marked dead instead of being run again.
+func (q *Queue) Claim(ctx context.Context, workerID string) (*Job, error) {
+	for {
+		job, err := q.claim(ctx, workerID)
+		if err != nil {
+			return nil, err
+		}
+		if job.Attempts <= job.MaxAttempts {
+			return job, nil
+		}
+
+		err = q.finish(ctx, job, bson.M{
+			"status":     StatusDead,
+			"attempts":   job.MaxAttempts,
+			"last_error": fmt.Sprintf("lock expired on attempt %d of %d", job.MaxAttempts, job.MaxAttempts),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// claim takes the next job for workerID and counts the attempt
+func (q *Queue) claim(ctx context.Context, workerID string) (*Job, error) {
+	now := q.now().UTC()
+
+	filter := bson.M{
+		"queue": q.opts.Name,
+		"$or": bson.A{
+			bson.M{"status": StatusPending, "run_at": bson.M{"$lte": now}},
+			bson.M{"status": StatusRunning, "locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       StatusRunning,
+			"locked_until": now.Add(q.opts.LockTimeout),
+			"locked_by":    workerID,
+			"updated_at":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := mongoclient.FindAndModifyOptions{ReturnAfter: true, Sort: bson.D{{Key: "run_at", Value: 1}}}
+
+	var job Job
+	err := q.jobs.FindOneAndUpdate(ctx, filter, update, opts, &job)
+	if errors.Is(err, mongoclient.ErrNotFound) {
+		return nil, ErrNoJob
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return &job, nil
+}
+
+// Complete marks a claimed job as done
+func (q *Queue) Complete(ctx context.Context, job *Job) error {
+	return q.finish(ctx, job, bson.M{"status": StatusDone})
+}
+
+// Fail records a failed attempt. The job is rescheduled with backoff,
+// or marked dead once it has used all its attempts.
+func (q *Queue) Fail(ctx context.Context, job *Job, cause error) error {
+	set := bson.M{"last_error": cause.Error()}
+	if job.Attempts >= job.MaxAttempts {
+		set["status"] = StatusDead
+	} else {
+		set["status"] = StatusPending
+		set["run_at"] = q.now().UTC().Add(q.opts.Backoff(job.Attempts))
+	}
+	return q.finish(ctx, job, set)
+}
+
+// finish updates a job only if it is still held by the worker that claimed it,
+// so a worker whose lock expired cannot overwrite the newer claim.
+func (q *Queue) finish(ctx context.Context, job *Job, set bson.M) error {
+	set["updated_at"] = q.now().UTC()
+	filter := bson.M{"_id": job.ID, "status": StatusRunning, "locked_by": job.LockedBy}
+	update := bson.M{"$set": set, "$unset": bson.M{"locked_until": "", "locked_by": ""}}
+
+	result, err := q.jobs.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update job %s: %w", job.ID.Hex(), err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("job %s is no longer held by %s", job.ID.Hex(), job.LockedBy)
+	}
+	return nil
+}
+
+// Dead lists jobs that exhausted their attempts, oldest first
+func (q *Queue) Dead(ctx context.Context, limit int64) ([]Job, error) {
+	opts := mongoclient.QueryOptions{Sort: bson.D{{Key: "updated_at", Value: 1}}, Limit: limit}
+	var jobs []Job
+	if err := q.jobs.Find(ctx, bson.M{"queue": q.opts.Name, "status": StatusDead}, opts, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to list dead jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// Retry moves a dead job back to pending with a fresh set of attempts
+func (q *Queue) Retry(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid job id %q: %w", id, err)
+	}
+	now := q.now().UTC()
+	result, err := q.jobs.UpdateOne(ctx,
+		bson.M{"_id": oid, "status": StatusDead},
+		bson.M{"$set": bson.M{"status": StatusPending, "attempts": 0, "run_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to retry job %s: %w", id, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("dead job %s not found", id)
+	}
+	return nil
+}
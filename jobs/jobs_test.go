@@ -0,0 +1,191 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient/mongoclientmock"
+)
+
+// testQueue is a Queue on an in-memory store whose clock only moves when the test sets now
+type testQueue struct {
+	*Queue
+	now time.Time
+}
+
+func newTestQueue(t *testing.T, opts QueueOptions) *testQueue {
+	t.Helper()
+	opts.Database, opts.Collection = "app", "jobs"
+	opts.LockTimeout = time.Minute
+	opts.Backoff = func(attempt int) time.Duration { return time.Duration(attempt) * 10 * time.Second }
+
+	q := &testQueue{Queue: newQueue(mongoclientmock.New(), opts), now: time.Now().Truncate(time.Millisecond)}
+	q.Queue.now = func() time.Time { return q.now }
+	return q
+}
+
+func (q *testQueue) mustClaim(t *testing.T, workerID string) *Job {
+	t.Helper()
+	job, err := q.Claim(context.Background(), workerID)
+	if err != nil {
+		t.Fatalf("Claim() = %v", err)
+	}
+	return job
+}
+
+func (q *testQueue) wantNoJob(t *testing.T) {
+	t.Helper()
+	if job, err := q.Claim(context.Background(), "other"); !errors.Is(err, ErrNoJob) {
+		t.Fatalf("Claim() = %v, %v, want ErrNoJob", job, err)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, QueueOptions{})
+
+	later, err := q.Enqueue(ctx, "report", map[string]string{"id": "later"}, EnqueueOptions{RunAt: q.now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err := q.Enqueue(ctx, "email", map[string]string{"to": "a@example.com"}, EnqueueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := q.mustClaim(t, "w1")
+	if job.ID.Hex() != due || job.Status != StatusRunning || job.Attempts != 1 || job.LockedBy != "w1" {
+		t.Fatalf("claimed %+v, want the due job running for w1 on attempt 1", job)
+	}
+	var payload map[string]string
+	if err := job.Decode(&payload); err != nil || payload["to"] != "a@example.com" {
+		t.Fatalf("Decode() = %v, %v", payload, err)
+	}
+
+	// The claimed job is locked and the other one is not due yet
+	q.wantNoJob(t)
+	if err := q.Complete(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	q.now = q.now.Add(time.Hour)
+	if job := q.mustClaim(t, "w2"); job.ID.Hex() != later {
+		t.Fatalf("claimed %s, want the delayed job %s", job.ID.Hex(), later)
+	}
+}
+
+func TestClaimExpiredLock(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, QueueOptions{})
+	if _, err := q.Enqueue(ctx, "email", struct{}{}, EnqueueOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	first := q.mustClaim(t, "w1")
+	q.now = q.now.Add(time.Minute)
+	second := q.mustClaim(t, "w2")
+	if second.ID != first.ID || second.Attempts != 2 || second.LockedBy != "w2" {
+		t.Fatalf("reclaimed %+v, want attempt 2 for w2", second)
+	}
+
+	// The worker whose lock expired cannot overwrite the newer claim
+	if err := q.Complete(ctx, first); err == nil {
+		t.Fatal("Complete() by the expired worker = nil, want an error")
+	}
+	if err := q.Complete(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	q.now = q.now.Add(time.Hour)
+	q.wantNoJob(t)
+}
+
+func TestClaimExpiredLockOnLastAttempt(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, QueueOptions{MaxAttempts: 2})
+	id, err := q.Enqueue(ctx, "crash", struct{}{}, EnqueueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every attempt crashes the worker, so its lock expires instead of the job failing
+	for attempt := 1; attempt <= 2; attempt++ {
+		if job := q.mustClaim(t, "w1"); job.Attempts != attempt {
+			t.Fatalf("attempt = %d, want %d", job.Attempts, attempt)
+		}
+		q.now = q.now.Add(time.Minute)
+	}
+	q.wantNoJob(t)
+
+	dead, err := q.Dead(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID.Hex() != id || dead[0].Attempts != 2 || dead[0].LastError == "" {
+		t.Fatalf("Dead() = %+v, want the crashed job after 2 attempts", dead)
+	}
+}
+
+func TestFail(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, QueueOptions{MaxAttempts: 2})
+	id, err := q.Enqueue(ctx, "email", struct{}{}, EnqueueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first failure backs off for Backoff(1)
+	if err := q.Fail(ctx, q.mustClaim(t, "w1"), errors.New("smtp down")); err != nil {
+		t.Fatal(err)
+	}
+	q.now = q.now.Add(9 * time.Second)
+	q.wantNoJob(t)
+	q.now = q.now.Add(time.Second)
+	job := q.mustClaim(t, "w1")
+	if job.Attempts != 2 || job.LastError != "smtp down" {
+		t.Fatalf("retried %+v, want attempt 2 after smtp down", job)
+	}
+
+	// The last failure makes the job dead
+	if err := q.Fail(ctx, job, errors.New("smtp still down")); err != nil {
+		t.Fatal(err)
+	}
+	q.now = q.now.Add(time.Hour)
+	q.wantNoJob(t)
+
+	dead, err := q.Dead(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID.Hex() != id || dead[0].Status != StatusDead || dead[0].LastError != "smtp still down" {
+		t.Fatalf("Dead() = %+v", dead)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, QueueOptions{MaxAttempts: 1})
+	id, err := q.Enqueue(ctx, "email", struct{}{}, EnqueueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Retry(ctx, id); err == nil {
+		t.Fatal("Retry() of a pending job = nil, want an error")
+	}
+	if err := q.Fail(ctx, q.mustClaim(t, "w1"), errors.New("boom")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Retry(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	job := q.mustClaim(t, "w1")
+	if job.ID.Hex() != id || job.Attempts != 1 {
+		t.Fatalf("claimed %+v after Retry, want attempt 1 of the retried job", job)
+	}
+	if dead, err := q.Dead(ctx, 10); err != nil || len(dead) != 0 {
+		t.Fatalf("Dead() = %v, %v, want none", dead, err)
+	}
+}
@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Handler processes one job. Returning an error schedules a retry.
+type Handler func(ctx context.Context, job *Job) error
+
+// WorkerOptions configures Run
+type WorkerOptions struct {
+	// Concurrency is the number of jobs processed in parallel. Defaults to 1.
+	Concurrency int
+	// PollInterval is how long an idle worker waits before looking for work again. Defaults to 1s.
+	PollInterval time.Duration
+	// WorkerID identifies this process in job locks. Defaults to the hostname and PID.
+	WorkerID string
+	// OnError is called when claiming or updating a job fails, or a handler fails.
+	OnError func(job *Job, err error)
+}
+
+// Run claims and processes jobs with the handler registered for their type until ctx is cancelled.
+// Jobs with no registered handler are failed so they end up dead instead of blocking the queue.
+// Run waits for in-flight jobs to finish before returning.
+func (q *Queue) Run(ctx context.Context, handlers map[string]Handler, opts WorkerOptions) error {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.WorkerID == "" {
+		host, _ := os.Hostname()
+		opts.WorkerID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			q.work(ctx, handlers, opts, fmt.Sprintf("%s/%d", opts.WorkerID, n))
+		}(i)
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (q *Queue) work(ctx context.Context, handlers map[string]Handler, opts WorkerOptions, workerID string) {
+	for ctx.Err() == nil {
+		job, err := q.Claim(ctx, workerID)
+		if err != nil {
+			if !errors.Is(err, ErrNoJob) && ctx.Err() == nil {
+				report(opts, nil, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(opts.PollInterval):
+			}
+			continue
+		}
+
+		// Finish the job with a context that survives shutdown so its state is recorded,
+		// keeping the values of ctx such as the tenant
+		handleErr := q.handle(ctx, handlers, job)
+		finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		if handleErr != nil {
+			report(opts, job, handleErr)
+			err = q.Fail(finishCtx, job, handleErr)
+		} else {
+			err = q.Complete(finishCtx, job)
+		}
+		cancel()
+		if err != nil {
+			report(opts, job, err)
+		}
+	}
+}
+
+func (q *Queue) handle(ctx context.Context, handlers map[string]Handler, job *Job) (err error) {
+	handler, ok := handlers[job.Type]
+	if !ok {
+		job.Attempts = job.MaxAttempts // no point retrying
+		return fmt.Errorf("no handler registered for job type %q", job.Type)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, q.opts.LockTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+func report(opts WorkerOptions, job *Job, err error) {
+	if opts.OnError != nil {
+		opts.OnError(job, err)
+	}
+}
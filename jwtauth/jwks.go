@@ -0,0 +1,179 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWK is a single JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// PublicKey converts the JWK into an *rsa.PublicKey or *ecdsa.PublicKey
+func (k JWK) PublicKey() (interface{}, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus for key %q: %w", k.KeyID, err)
+		}
+		e, err := b64.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent for key %q: %w", k.KeyID, err)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q for key %q", k.Curve, k.KeyID)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate for key %q: %w", k.KeyID, err)
+		}
+		y, err := b64.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate for key %q: %w", k.KeyID, err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q for key %q", k.KeyType, k.KeyID)
+	}
+}
+
+// JWKSOptions configures a JWKS key source
+type JWKSOptions struct {
+	// RefreshInterval is how long fetched keys are cached. Defaults to 1h.
+	RefreshInterval time.Duration
+	// MinRefreshInterval rate-limits refetches triggered by unknown key IDs. Defaults to 1m.
+	MinRefreshInterval time.Duration
+	// HTTPClient defaults to a client with a 10s timeout.
+	HTTPClient *http.Client
+}
+
+// JWKS fetches verification keys from a JSON Web Key Set endpoint and caches them.
+// An unknown kid triggers a refetch (rate-limited), so key rotation is picked up without restarts.
+type JWKS struct {
+	url  string
+	opts JWKSOptions
+
+	mu        sync.RWMutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+	fetchMu   sync.Mutex
+}
+
+// NewJWKS creates a key source reading from the given JWKS URL.
+// Keys are fetched lazily on first use.
+func NewJWKS(url string, opts JWKSOptions) *JWKS {
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = time.Hour
+	}
+	if opts.MinRefreshInterval <= 0 {
+		opts.MinRefreshInterval = time.Minute
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &JWKS{url: url, opts: opts}
+}
+
+// Key returns the key with the given ID, fetching the key set when the cache is stale or the ID is unknown
+func (j *JWKS) Key(ctx context.Context, keyID string, alg Algorithm) (interface{}, error) {
+	j.mu.RLock()
+	key, ok := j.keys[keyID]
+	fresh := time.Since(j.fetchedAt) < j.opts.RefreshInterval
+	recent := time.Since(j.fetchedAt) < j.opts.MinRefreshInterval
+	j.mu.RUnlock()
+
+	if ok && fresh {
+		return key, nil
+	}
+	if !ok && recent {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	if err := j.Refresh(ctx); err != nil {
+		if ok {
+			return key, nil // serve the stale key rather than failing every request
+		}
+		return nil, err
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if key, ok := j.keys[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+}
+
+// Refresh fetches the key set now
+func (j *JWKS) Refresh(ctx context.Context) error {
+	j.fetchMu.Lock()
+	defer j.fetchMu.Unlock()
+
+	// Another goroutine may have refreshed while we waited
+	j.mu.RLock()
+	recent := time.Since(j.fetchedAt) < j.opts.MinRefreshInterval
+	j.mu.RUnlock()
+	if recent {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	resp, err := j.opts.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			continue // skip keys we cannot use rather than rejecting the whole set
+		}
+		keys[k.KeyID] = pub
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	j.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,168 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer serves the public halves of keys as a JWKS and counts the fetches
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fail    bool
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	s := &jwksServer{keys: map[string]*rsa.PublicKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(kid string, key *rsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key == nil {
+		delete(s.keys, kid)
+	} else {
+		s.keys[kid] = key
+	}
+}
+
+func (s *jwksServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.fetches.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	set := struct {
+		Keys []JWK `json:"keys"`
+	}{}
+	for kid, k := range s.keys {
+		set.Keys = append(set.Keys, JWK{
+			KeyType: "RSA",
+			KeyID:   kid,
+			Use:     "sig",
+			N:       b64.EncodeToString(k.N.Bytes()),
+			E:       b64.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+	set.Keys = append(set.Keys, JWK{KeyType: "RSA", KeyID: "enc", Use: "enc", N: "AQAB", E: "AQAB"})
+	json.NewEncoder(w).Encode(set)
+}
+
+// age moves the last fetch into the past, as if d had elapsed
+func (j *JWKS) age(d time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.fetchedAt = j.fetchedAt.Add(-d)
+}
+
+func TestJWKSKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	srv := newJWKSServer(t)
+	k1, k2 := rsaKey(t), rsaKey(t)
+	srv.set("k1", &k1.PublicKey)
+	jwks := NewJWKS(srv.URL, JWKSOptions{RefreshInterval: time.Hour, MinRefreshInterval: time.Minute})
+
+	key, err := jwks.Key(ctx, "k1", RS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.(*rsa.PublicKey).N.Cmp(k1.N) != 0 {
+		t.Error("Key(k1) returned a different key")
+	}
+	if _, err := jwks.Key(ctx, "enc", RS256); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Key(enc) error = %v; encryption keys must not verify signatures", err)
+	}
+
+	// The provider rotates to k2. Within MinRefreshInterval, tokens with the new kid
+	// fail without hitting the endpoint, however many arrive.
+	srv.set("k2", &k2.PublicKey)
+	for i := 0; i < 10; i++ {
+		if _, err := jwks.Key(ctx, "k2", RS256); !errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("Key(k2) error = %v, want ErrUnknownKey", err)
+		}
+	}
+	if n := srv.fetches.Load(); n != 1 {
+		t.Fatalf("%d fetches, want 1; unknown kids must not trigger a refetch within MinRefreshInterval", n)
+	}
+
+	jwks.age(2 * time.Minute)
+	if _, err := jwks.Key(ctx, "k2", RS256); err != nil {
+		t.Fatalf("Key(k2) after MinRefreshInterval error = %v", err)
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Errorf("%d fetches, want 2", n)
+	}
+
+	// k1 is retired; it stays cached until the set is refetched
+	srv.set("k1", nil)
+	if _, err := jwks.Key(ctx, "k1", RS256); err != nil {
+		t.Errorf("Key(k1) before refresh error = %v", err)
+	}
+	jwks.age(2 * time.Hour)
+	if _, err := jwks.Key(ctx, "k1", RS256); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Key(k1) after refresh error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestJWKSConcurrentUnknownKeyFetchesOnce(t *testing.T) {
+	ctx := context.Background()
+	srv := newJWKSServer(t)
+	k1 := rsaKey(t)
+	srv.set("k1", &k1.PublicKey)
+	jwks := NewJWKS(srv.URL, JWKSOptions{})
+	if _, err := jwks.Key(ctx, "k1", RS256); err != nil {
+		t.Fatal(err)
+	}
+	jwks.age(2 * time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jwks.Key(ctx, "forged", RS256)
+		}()
+	}
+	wg.Wait()
+	if n := srv.fetches.Load(); n != 2 {
+		t.Errorf("%d fetches for 20 concurrent unknown kids, want 2", n)
+	}
+}
+
+func TestJWKSServesStaleKeyWhenRefreshFails(t *testing.T) {
+	ctx := context.Background()
+	srv := newJWKSServer(t)
+	k1 := rsaKey(t)
+	srv.set("k1", &k1.PublicKey)
+	jwks := NewJWKS(srv.URL, JWKSOptions{})
+	if _, err := jwks.Key(ctx, "k1", RS256); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mu.Lock()
+	srv.fail = true
+	srv.mu.Unlock()
+	jwks.age(2 * time.Hour)
+	if _, err := jwks.Key(ctx, "k1", RS256); err != nil {
+		t.Errorf("Key(k1) with the endpoint down error = %v, want the stale key", err)
+	}
+	if _, err := jwks.Key(ctx, "k2", RS256); err == nil {
+		t.Error("Key(k2) with the endpoint down succeeded")
+	}
+}
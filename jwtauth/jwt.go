@@ -0,0 +1,155 @@
+package jwtauth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Algorithm is a JWS signing algorithm
+type Algorithm string
+
+const (
+	RS256 Algorithm = "RS256"
+	ES256 Algorithm = "ES256"
+	HS256 Algorithm = "HS256"
+)
+
+var (
+	ErrMalformedToken       = errors.New("malformed token")
+	ErrAlgorithmNotAllowed  = errors.New("signing algorithm not allowed")
+	ErrInvalidSignature     = errors.New("invalid token signature")
+	ErrUnknownKey           = errors.New("unknown signing key")
+	ErrTokenExpired         = errors.New("token expired")
+	ErrTokenNotYetValid     = errors.New("token not valid yet")
+	ErrInvalidIssuer        = errors.New("invalid token issuer")
+	ErrInvalidAudience      = errors.New("invalid token audience")
+	ErrMissingBearerToken   = errors.New("missing bearer token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+)
+
+// Header is the JOSE header of a token
+type Header struct {
+	Algorithm Algorithm `json:"alg"`
+	Type      string    `json:"typ,omitempty"`
+	KeyID     string    `json:"kid,omitempty"`
+}
+
+// Audience accepts both the single-string and the array form of the aud claim
+type Audience []string
+
+// UnmarshalJSON decodes "aud" given as a string or an array of strings
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("aud must be a string or an array of strings")
+	}
+	*a = many
+	return nil
+}
+
+// MarshalJSON encodes a single audience as a plain string
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// Contains reports whether aud is one of the audiences
+func (a Audience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims holds the registered claims (RFC 7519 section 4.1).
+// Embed it in your own struct to extract custom claims with ClaimsAs.
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+}
+
+// Token is a parsed and validated token
+type Token struct {
+	Raw     string
+	Header  Header
+	Claims  Claims
+	payload []byte
+}
+
+// Decode unmarshals the full claim set into v
+func (t *Token) Decode(v interface{}) error {
+	if err := json.Unmarshal(t.payload, v); err != nil {
+		return fmt.Errorf("failed to decode token claims: %w", err)
+	}
+	return nil
+}
+
+// ClaimsAs decodes the token's claims into a typed struct
+func ClaimsAs[T any](t *Token) (T, error) {
+	var v T
+	err := t.Decode(&v)
+	return v, err
+}
+
+// Expiry returns the exp claim as a time, or the zero time if unset
+func (c Claims) Expiry() time.Time {
+	if c.ExpiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+var b64 = base64.RawURLEncoding
+
+// split decodes the three parts of a compact JWS without verifying anything
+func split(raw string) (header Header, payload []byte, signingInput string, signature []byte, err error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return header, nil, "", nil, ErrMalformedToken
+	}
+
+	h, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return header, nil, "", nil, fmt.Errorf("%w: header: %v", ErrMalformedToken, err)
+	}
+	if err := json.Unmarshal(h, &header); err != nil {
+		return header, nil, "", nil, fmt.Errorf("%w: header: %v", ErrMalformedToken, err)
+	}
+	if payload, err = b64.DecodeString(parts[1]); err != nil {
+		return header, nil, "", nil, fmt.Errorf("%w: payload: %v", ErrMalformedToken, err)
+	}
+	if signature, err = b64.DecodeString(parts[2]); err != nil {
+		return header, nil, "", nil, fmt.Errorf("%w: signature: %v", ErrMalformedToken, err)
+	}
+	return header, payload, parts[0] + "." + parts[1], signature, nil
+}
+
+// encodeSegment marshals v as a base64url JSON segment
+func encodeSegment(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(bytes.TrimRight(buf.Bytes(), "\n")), nil
+}
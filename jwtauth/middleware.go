@@ -0,0 +1,58 @@
+package jwtauth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type tokenKey struct{}
+
+// WithToken stores a validated token in ctx
+func WithToken(ctx context.Context, t *Token) context.Context {
+	return context.WithValue(ctx, tokenKey{}, t)
+}
+
+// FromContext returns the validated token stored by the middleware
+func FromContext(ctx context.Context) (*Token, bool) {
+	t, ok := ctx.Value(tokenKey{}).(*Token)
+	return t, ok
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer <token>" header
+func BearerToken(r *http.Request) (string, error) {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "bearer ") {
+		return "", ErrMissingBearerToken
+	}
+	token := strings.TrimSpace(h[7:])
+	if token == "" {
+		return "", ErrMissingBearerToken
+	}
+	return token, nil
+}
+
+// Middleware rejects requests without a valid bearer token with 401
+// and stores the validated token in the request context for FromContext.
+func Middleware(v *Validator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, err := BearerToken(r)
+			if err != nil {
+				unauthorized(w, "invalid_request")
+				return
+			}
+			tok, err := v.Validate(r.Context(), raw)
+			if err != nil {
+				unauthorized(w, "invalid_token")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithToken(r.Context(), tok)))
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, code string) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="`+code+`"`)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
@@ -0,0 +1,301 @@
+package jwtauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInactiveToken is returned when introspection reports a token as inactive
+var ErrInactiveToken = errors.New("token is not active")
+
+// tenantPlaceholder appears in the issuer of Azure AD / Entra multi-tenant discovery documents
+const tenantPlaceholder = "{tenantid}"
+
+// ProviderMetadata is the subset of the OIDC discovery document we use
+type ProviderMetadata struct {
+	Issuer                string   `json:"issuer"`
+	JWKSURI               string   `json:"jwks_uri"`
+	IntrospectionEndpoint string   `json:"introspection_endpoint,omitempty"`
+	SigningAlgorithms     []string `json:"id_token_signing_alg_values_supported,omitempty"`
+}
+
+// Discover fetches the provider's /.well-known/openid-configuration document
+func Discover(ctx context.Context, issuer string, client *http.Client) (*ProviderMetadata, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch OIDC discovery document: status %d", resp.StatusCode)
+	}
+
+	var md ProviderMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&md); err != nil {
+		return nil, fmt.Errorf("failed to decode OIDC discovery document: %w", err)
+	}
+	if md.Issuer == "" || md.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document is missing issuer or jwks_uri")
+	}
+	return &md, nil
+}
+
+// RoleMapper derives application roles from a validated token
+type RoleMapper func(t *Token) []string
+
+// ClaimRoles maps a string or string-array claim (e.g. "roles" or "groups") to roles,
+// optionally renaming values through mapping. Unmapped values are dropped when mapping is non-nil.
+func ClaimRoles(claim string, mapping map[string]string) RoleMapper {
+	return func(t *Token) []string {
+		var all map[string]interface{}
+		if err := t.Decode(&all); err != nil {
+			return nil
+		}
+
+		var values []string
+		switch v := all[claim].(type) {
+		case string:
+			values = strings.Fields(v)
+		case []interface{}:
+			for _, e := range v {
+				if s, ok := e.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+
+		if mapping == nil {
+			return values
+		}
+		roles := make([]string, 0, len(values))
+		for _, v := range values {
+			if r, ok := mapping[v]; ok {
+				roles = append(roles, r)
+			}
+		}
+		return roles
+	}
+}
+
+// OIDCOptions configures an OIDC verifier
+type OIDCOptions struct {
+	// Issuer is the provider URL used for discovery, e.g.
+	// https://login.microsoftonline.com/organizations/v2.0 for Entra multi-tenant apps.
+	Issuer string
+	// Audience is the expected aud claim, usually the client/application ID.
+	Audience string
+	// AllowedTenants restricts multi-tenant issuers to these tenant IDs. Empty allows any tenant.
+	AllowedTenants []string
+	// Leeway tolerates clock skew. Defaults to 1m.
+	Leeway time.Duration
+	// ClientID and ClientSecret authenticate introspection requests.
+	ClientID     string
+	ClientSecret string
+	// Introspect enables RFC 7662 introspection for tokens that are not JWTs.
+	Introspect bool
+	// Roles maps claims to application roles, available through Roles(ctx).
+	Roles RoleMapper
+	// HTTPClient defaults to a client with a 10s timeout.
+	HTTPClient *http.Client
+}
+
+// OIDCVerifier validates tokens issued by an OpenID Connect provider
+type OIDCVerifier struct {
+	opts      OIDCOptions
+	metadata  *ProviderMetadata
+	validator *Validator
+}
+
+// NewOIDCVerifier runs discovery against opts.Issuer and builds a verifier backed by the provider's JWKS
+func NewOIDCVerifier(ctx context.Context, opts OIDCOptions) (*OIDCVerifier, error) {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.Leeway <= 0 {
+		opts.Leeway = time.Minute
+	}
+
+	md, err := Discover(ctx, opts.Issuer, opts.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var algs []Algorithm
+	for _, a := range md.SigningAlgorithms {
+		if a == string(RS256) || a == string(ES256) {
+			algs = append(algs, Algorithm(a))
+		}
+	}
+
+	vopts := ValidatorOptions{
+		Keys:          NewJWKS(md.JWKSURI, JWKSOptions{HTTPClient: opts.HTTPClient}),
+		Algorithms:    algs,
+		Audience:      opts.Audience,
+		Leeway:        opts.Leeway,
+		RequireExpiry: true,
+	}
+	// Multi-tenant issuers are checked against the token's tid claim in Verify
+	if !strings.Contains(md.Issuer, tenantPlaceholder) {
+		vopts.Issuers = []string{md.Issuer}
+	}
+
+	v, err := NewValidator(vopts)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCVerifier{opts: opts, metadata: md, validator: v}, nil
+}
+
+// Metadata returns the discovered provider metadata
+func (o *OIDCVerifier) Metadata() ProviderMetadata {
+	return *o.metadata
+}
+
+// Verify validates raw as a JWT, or through introspection when enabled and raw is opaque
+func (o *OIDCVerifier) Verify(ctx context.Context, raw string) (*Token, error) {
+	if strings.Count(raw, ".") != 2 {
+		if o.opts.Introspect && o.metadata.IntrospectionEndpoint != "" {
+			return o.introspect(ctx, raw)
+		}
+		return nil, ErrMalformedToken
+	}
+
+	tok, err := o.validator.Validate(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(o.metadata.Issuer, tenantPlaceholder) {
+		if err := o.checkTenant(tok); err != nil {
+			return nil, err
+		}
+	}
+	return tok, nil
+}
+
+// checkTenant resolves the {tenantid} issuer template with the token's tid claim
+func (o *OIDCVerifier) checkTenant(tok *Token) error {
+	var c struct {
+		TenantID string `json:"tid"`
+	}
+	if err := tok.Decode(&c); err != nil || c.TenantID == "" {
+		return fmt.Errorf("%w: missing tid claim", ErrInvalidIssuer)
+	}
+	if want := strings.Replace(o.metadata.Issuer, tenantPlaceholder, c.TenantID, 1); tok.Claims.Issuer != want {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, tok.Claims.Issuer)
+	}
+	if len(o.opts.AllowedTenants) == 0 {
+		return nil
+	}
+	for _, t := range o.opts.AllowedTenants {
+		if t == c.TenantID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: tenant %q not allowed", ErrInvalidIssuer, c.TenantID)
+}
+
+// introspect validates an opaque token with the provider (RFC 7662)
+func (o *OIDCVerifier) introspect(ctx context.Context, raw string) (*Token, error) {
+	form := url.Values{"token": {raw}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.metadata.IntrospectionEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if o.opts.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(o.opts.ClientID), url.QueryEscape(o.opts.ClientSecret))
+	}
+
+	resp, err := o.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to introspect token: status %d", resp.StatusCode)
+	}
+
+	var payload json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+	var result struct {
+		Active bool `json:"active"`
+		Claims
+	}
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+	if !result.Active {
+		return nil, ErrInactiveToken
+	}
+
+	// An active response still has to be for this provider and this API, the same as a
+	// JWT. exp is optional in RFC 7662, and active already means the token has not expired.
+	tok := &Token{Raw: raw, Claims: result.Claims, payload: payload}
+	if err := o.validator.checkClaims(tok.Claims, false); err != nil {
+		return nil, err
+	}
+	if strings.Contains(o.metadata.Issuer, tenantPlaceholder) {
+		if err := o.checkTenant(tok); err != nil {
+			return nil, err
+		}
+	}
+	return tok, nil
+}
+
+type rolesKey struct{}
+
+// Roles returns the roles mapped for the request's token by OIDCVerifier.Middleware
+func Roles(ctx context.Context) []string {
+	r, _ := ctx.Value(rolesKey{}).([]string)
+	return r
+}
+
+// HasRole reports whether the request's token was mapped to role
+func HasRole(ctx context.Context, role string) bool {
+	for _, r := range Roles(ctx) {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware rejects requests without a valid token with 401 and stores the token
+// and its mapped roles in the request context
+func (o *OIDCVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := BearerToken(r)
+		if err != nil {
+			unauthorized(w, "invalid_request")
+			return
+		}
+		tok, err := o.Verify(r.Context(), raw)
+		if err != nil {
+			unauthorized(w, "invalid_token")
+			return
+		}
+
+		ctx := WithToken(r.Context(), tok)
+		if o.opts.Roles != nil {
+			ctx = context.WithValue(ctx, rolesKey{}, o.opts.Roles(tok))
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,203 @@
+package jwtauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// provider is a fake OIDC provider: discovery, a JWKS and an introspection endpoint
+type provider struct {
+	*jwksServer
+	issuer string
+	signer *Signer
+	// introspection maps opaque tokens to introspection responses
+	introspection map[string]map[string]interface{}
+	basicAuth     string
+}
+
+func newProvider(t *testing.T, issuer string) *provider {
+	p := &provider{jwksServer: newJWKSServer(t), issuer: issuer}
+	key := rsaKey(t)
+	p.set("k1", &key.PublicKey)
+	p.signer, _ = NewRS256Signer(key, "k1")
+
+	jwks := p.Config.Handler
+	p.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(ProviderMetadata{
+				Issuer:                p.issuer,
+				JWKSURI:               p.URL + "/keys",
+				IntrospectionEndpoint: p.URL + "/introspect",
+				SigningAlgorithms:     []string{"RS256", "HS256"},
+			})
+		case "/keys":
+			jwks.ServeHTTP(w, r)
+		case "/introspect":
+			user, pass, _ := r.BasicAuth()
+			p.basicAuth = user + ":" + pass
+			resp, ok := p.introspection[r.PostFormValue("token")]
+			if !ok {
+				resp = map[string]interface{}{"active": false}
+			}
+			json.NewEncoder(w).Encode(resp)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return p
+}
+
+func (p *provider) verifier(t *testing.T, opts OIDCOptions) *OIDCVerifier {
+	t.Helper()
+	opts.Issuer = p.URL
+	v, err := NewOIDCVerifier(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+type entraClaims struct {
+	Claims
+	TenantID string `json:"tid,omitempty"`
+}
+
+func TestOIDCMultiTenantIssuer(t *testing.T) {
+	const tenantA, tenantB = "11111111-aaaa", "22222222-bbbb"
+	p := newProvider(t, "https://login.example.com/{tenantid}/v2.0")
+	v := p.verifier(t, OIDCOptions{Audience: "orders-api"})
+	restricted := p.verifier(t, OIDCOptions{Audience: "orders-api", AllowedTenants: []string{tenantA}})
+
+	token := func(tid, iss string, change func(*Claims)) string {
+		c := Claims{
+			Issuer:    iss,
+			Subject:   "user-1",
+			Audience:  Audience{"orders-api"},
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		}
+		if change != nil {
+			change(&c)
+		}
+		return sign(t, p.signer, entraClaims{Claims: c, TenantID: tid})
+	}
+	issuer := func(tid string) string { return "https://login.example.com/" + tid + "/v2.0" }
+
+	tests := []struct {
+		name     string
+		verifier *OIDCVerifier
+		raw      string
+		err      error
+	}{
+		{"tenant A", v, token(tenantA, issuer(tenantA), nil), nil},
+		{"tenant B", v, token(tenantB, issuer(tenantB), nil), nil},
+		{"allowed tenant", restricted, token(tenantA, issuer(tenantA), nil), nil},
+		{"tenant not allowed", restricted, token(tenantB, issuer(tenantB), nil), ErrInvalidIssuer},
+		{"tid does not match issuer", restricted, token(tenantA, issuer(tenantB), nil), ErrInvalidIssuer},
+		{"missing tid", v, token("", issuer(tenantA), nil), ErrInvalidIssuer},
+		{"unsubstituted template", v, token(tenantA, "https://login.example.com/{tenantid}/v2.0", nil), ErrInvalidIssuer},
+		{"wrong audience", v, token(tenantA, issuer(tenantA), func(c *Claims) { c.Audience = Audience{"other-app"} }), ErrInvalidAudience},
+		{"no audience", v, token(tenantA, issuer(tenantA), func(c *Claims) { c.Audience = nil }), ErrInvalidAudience},
+		{"expired", v, token(tenantA, issuer(tenantA), func(c *Claims) { c.ExpiresAt = time.Now().Add(-time.Hour).Unix() }), ErrTokenExpired},
+		{"no exp", v, token(tenantA, issuer(tenantA), func(c *Claims) { c.ExpiresAt = 0 }), ErrTokenExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.verifier.Verify(context.Background(), tt.raw)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestOIDCSingleTenantIssuer(t *testing.T) {
+	p := newProvider(t, "https://auth.example.com")
+	v := p.verifier(t, OIDCOptions{Audience: "orders-api"})
+	claims := Claims{Issuer: "https://auth.example.com", Audience: Audience{"orders-api"}, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+	if _, err := v.Verify(context.Background(), sign(t, p.signer, claims)); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	claims.Issuer = "https://evil.example.com"
+	if _, err := v.Verify(context.Background(), sign(t, p.signer, claims)); !errors.Is(err, ErrInvalidIssuer) {
+		t.Errorf("Verify(wrong issuer) error = %v, want ErrInvalidIssuer", err)
+	}
+
+	// HS256 is advertised by the provider but never enabled for a JWKS-backed verifier
+	hs, _ := NewHS256Signer([]byte("0123456789abcdef0123456789abcdef"), "k1")
+	claims.Issuer = "https://auth.example.com"
+	if _, err := v.Verify(context.Background(), sign(t, hs, claims)); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Errorf("Verify(HS256) error = %v, want ErrAlgorithmNotAllowed", err)
+	}
+}
+
+func TestOIDCIntrospection(t *testing.T) {
+	p := newProvider(t, "https://auth.example.com")
+	active := func(change func(map[string]interface{})) map[string]interface{} {
+		resp := map[string]interface{}{
+			"active": true,
+			"iss":    "https://auth.example.com",
+			"sub":    "user-1",
+			"aud":    "orders-api",
+			"scope":  "orders:read",
+		}
+		if change != nil {
+			change(resp)
+		}
+		return resp
+	}
+	p.introspection = map[string]map[string]interface{}{
+		"valid":          active(nil),
+		"audience list":  active(func(r map[string]interface{}) { r["aud"] = []string{"billing", "orders-api"} }),
+		"wrong audience": active(func(r map[string]interface{}) { r["aud"] = "billing" }),
+		"no audience":    active(func(r map[string]interface{}) { delete(r, "aud") }),
+		"wrong issuer":   active(func(r map[string]interface{}) { r["iss"] = "https://evil.example.com" }),
+		"no issuer":      active(func(r map[string]interface{}) { delete(r, "iss") }),
+		"expired":        active(func(r map[string]interface{}) { r["exp"] = time.Now().Add(-time.Hour).Unix() }),
+	}
+	v := p.verifier(t, OIDCOptions{Audience: "orders-api", Introspect: true, ClientID: "orders-api", ClientSecret: "s3cret"})
+
+	tests := []struct {
+		token string
+		err   error
+	}{
+		{"valid", nil},
+		{"audience list", nil},
+		{"revoked", ErrInactiveToken},
+		{"wrong audience", ErrInvalidAudience},
+		{"no audience", ErrInvalidAudience},
+		{"wrong issuer", ErrInvalidIssuer},
+		{"no issuer", ErrInvalidIssuer},
+		{"expired", ErrTokenExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			tok, err := v.Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			var extra struct {
+				Scope string `json:"scope"`
+			}
+			if err := tok.Decode(&extra); err != nil || extra.Scope != "orders:read" || tok.Claims.Subject != "user-1" {
+				t.Errorf("token = %+v, scope %q, %v", tok.Claims, extra.Scope, err)
+			}
+		})
+	}
+	if p.basicAuth != "orders-api:s3cret" {
+		t.Errorf("introspection authenticated as %q", p.basicAuth)
+	}
+
+	noIntrospect := p.verifier(t, OIDCOptions{Audience: "orders-api"})
+	if _, err := noIntrospect.Verify(context.Background(), "valid"); !errors.Is(err, ErrMalformedToken) {
+		t.Errorf("Verify(opaque) without Introspect error = %v, want ErrMalformedToken", err)
+	}
+}
@@ -0,0 +1,142 @@
+package jwtauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// KeySource resolves the verification key for a token.
+// Keys are []byte for HS256, *rsa.PublicKey for RS256 and *ecdsa.PublicKey for ES256.
+type KeySource interface {
+	Key(ctx context.Context, keyID string, alg Algorithm) (interface{}, error)
+}
+
+// StaticKeys is a fixed set of verification keys indexed by key ID.
+// A single key stored under "" is used for tokens without a kid header.
+type StaticKeys map[string]interface{}
+
+// Key looks up the key by ID
+func (k StaticKeys) Key(ctx context.Context, keyID string, alg Algorithm) (interface{}, error) {
+	key, ok := k[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	return key, nil
+}
+
+// ValidatorOptions configures a Validator
+type ValidatorOptions struct {
+	// Keys resolves verification keys, e.g. StaticKeys or a JWKS.
+	Keys KeySource
+	// Algorithms allowed in the alg header. Defaults to RS256 and ES256;
+	// HS256 must be enabled explicitly.
+	Algorithms []Algorithm
+	// Issuers lists the accepted iss values. Empty accepts any issuer.
+	Issuers []string
+	// Audience must appear in the token's aud claim; tokens without aud are
+	// rejected. Empty skips the check.
+	Audience string
+	// Leeway tolerates clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+	// RequireExpiry rejects tokens without an exp claim.
+	RequireExpiry bool
+	// Now overrides the clock, for tests.
+	Now func() time.Time
+}
+
+// Validator verifies token signatures and registered claims
+type Validator struct {
+	opts ValidatorOptions
+}
+
+// NewValidator creates a Validator with the given options
+func NewValidator(opts ValidatorOptions) (*Validator, error) {
+	if opts.Keys == nil {
+		return nil, fmt.Errorf("validator requires a key source")
+	}
+	if len(opts.Algorithms) == 0 {
+		opts.Algorithms = []Algorithm{RS256, ES256}
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Validator{opts: opts}, nil
+}
+
+// Validate parses raw, verifies its signature and checks exp, nbf, iss and aud
+func (v *Validator) Validate(ctx context.Context, raw string) (*Token, error) {
+	header, payload, signingInput, sig, err := split(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if !v.allowed(header.Algorithm) {
+		return nil, fmt.Errorf("%w: %q", ErrAlgorithmNotAllowed, header.Algorithm)
+	}
+
+	key, err := v.opts.Keys.Key(ctx, header.KeyID, header.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(header.Algorithm, key, []byte(signingInput), sig); err != nil {
+		return nil, err
+	}
+
+	tok := &Token{Raw: raw, Header: header, payload: payload}
+	if err := json.Unmarshal(payload, &tok.Claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformedToken, err)
+	}
+	if err := v.checkClaims(tok.Claims, v.opts.RequireExpiry); err != nil {
+		return nil, err
+	}
+	return tok, nil
+}
+
+func (v *Validator) allowed(alg Algorithm) bool {
+	for _, a := range v.opts.Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// checkClaims checks exp, nbf, iat, iss and aud. A token without aud is rejected
+// whenever an audience is configured.
+func (v *Validator) checkClaims(c Claims, requireExpiry bool) error {
+	now := v.opts.Now()
+	leeway := v.opts.Leeway
+
+	if c.ExpiresAt == 0 && requireExpiry {
+		return fmt.Errorf("%w: missing exp claim", ErrTokenExpired)
+	}
+	if c.ExpiresAt != 0 && now.After(time.Unix(c.ExpiresAt, 0).Add(leeway)) {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != 0 && now.Add(leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return ErrTokenNotYetValid
+	}
+	if c.IssuedAt != 0 && now.Add(leeway).Before(time.Unix(c.IssuedAt, 0)) {
+		return fmt.Errorf("%w: issued in the future", ErrTokenNotYetValid)
+	}
+
+	if len(v.opts.Issuers) > 0 {
+		ok := false
+		for _, iss := range v.opts.Issuers {
+			if iss == c.Issuer {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrInvalidIssuer, c.Issuer)
+		}
+	}
+
+	if v.opts.Audience != "" && !c.Audience.Contains(v.opts.Audience) {
+		return fmt.Errorf("%w: expected %q", ErrInvalidAudience, v.opts.Audience)
+	}
+	return nil
+}
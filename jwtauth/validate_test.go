@@ -0,0 +1,200 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func sign(t *testing.T, s *Signer, claims interface{}) string {
+	t.Helper()
+	raw, err := s.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// unsigned builds a token with an arbitrary header and signature, as an attacker would
+func unsigned(t *testing.T, header Header, claims interface{}, sig []byte) string {
+	t.Helper()
+	h, err := encodeSegment(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := encodeSegment(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h + "." + p + "." + b64.EncodeToString(sig)
+}
+
+func validClaims() Claims {
+	return Claims{
+		Issuer:    "https://auth.example.com",
+		Subject:   "user-1",
+		Audience:  Audience{"orders-api"},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	key := rsaKey(t)
+	signer, err := NewRS256Signer(key, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewRS256Signer(rsaKey(t), "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewValidator(ValidatorOptions{
+		Keys:          StaticKeys{"k1": &key.PublicKey},
+		Issuers:       []string{"https://auth.example.com"},
+		Audience:      "orders-api",
+		Leeway:        time.Minute,
+		RequireExpiry: true,
+		Now:           func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(change func(*Claims)) Claims {
+		c := validClaims()
+		change(&c)
+		return c
+	}
+	tests := []struct {
+		name string
+		raw  string
+		err  error
+	}{
+		{"valid", sign(t, signer, validClaims()), nil},
+		{"one of several audiences", sign(t, signer, with(func(c *Claims) { c.Audience = Audience{"billing", "orders-api"} })), nil},
+		{"expired within leeway", sign(t, signer, with(func(c *Claims) { c.ExpiresAt = now.Add(-30 * time.Second).Unix() })), nil},
+		{"expired", sign(t, signer, with(func(c *Claims) { c.ExpiresAt = now.Add(-2 * time.Minute).Unix() })), ErrTokenExpired},
+		{"no exp", sign(t, signer, with(func(c *Claims) { c.ExpiresAt = 0 })), ErrTokenExpired},
+		{"not yet valid", sign(t, signer, with(func(c *Claims) { c.NotBefore = now.Add(time.Hour).Unix() })), ErrTokenNotYetValid},
+		{"issued in the future", sign(t, signer, with(func(c *Claims) { c.IssuedAt = now.Add(time.Hour).Unix() })), ErrTokenNotYetValid},
+		{"wrong issuer", sign(t, signer, with(func(c *Claims) { c.Issuer = "https://evil.example.com" })), ErrInvalidIssuer},
+		{"wrong audience", sign(t, signer, with(func(c *Claims) { c.Audience = Audience{"billing"} })), ErrInvalidAudience},
+		{"no audience", sign(t, signer, with(func(c *Claims) { c.Audience = nil })), ErrInvalidAudience},
+		{"signed by another key", sign(t, other, validClaims()), ErrInvalidSignature},
+		{"unknown kid", unsigned(t, Header{Algorithm: RS256, KeyID: "k2"}, validClaims(), []byte("sig")), ErrUnknownKey},
+		{"alg none", unsigned(t, Header{Algorithm: "none", KeyID: "k1"}, validClaims(), nil), ErrAlgorithmNotAllowed},
+		{"HS256 not enabled", unsigned(t, Header{Algorithm: HS256, KeyID: "k1"}, validClaims(), []byte("sig")), ErrAlgorithmNotAllowed},
+		{"two segments", "e30.e30", ErrMalformedToken},
+		{"bad base64", "e30.!!!.e30", ErrMalformedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, err := v.Validate(context.Background(), tt.raw)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && tok.Claims.Subject != "user-1" {
+				t.Errorf("Claims.Subject = %q", tok.Claims.Subject)
+			}
+			if err != nil && tok != nil {
+				t.Error("Validate() returned a token with an error")
+			}
+		})
+	}
+}
+
+func TestValidateTamperedPayload(t *testing.T) {
+	key := rsaKey(t)
+	signer, _ := NewRS256Signer(key, "k1")
+	v, _ := NewValidator(ValidatorOptions{Keys: StaticKeys{"k1": &key.PublicKey}, Now: func() time.Time { return now }})
+
+	parts := strings.Split(sign(t, signer, validClaims()), ".")
+	claims := validClaims()
+	claims.Subject = "admin"
+	payload, err := encodeSegment(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := parts[0] + "." + payload + "." + parts[2]
+	if _, err := v.Validate(context.Background(), tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Validate(tampered) error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+// TestValidateAlgorithmConfusion checks that an RSA public key can never be used as an
+// HMAC secret, even when both algorithms are enabled: an attacker who knows the public
+// key could otherwise sign any token with HS256.
+func TestValidateAlgorithmConfusion(t *testing.T) {
+	key := rsaKey(t)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forger, err := NewHS256Signer(der, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	v, _ := NewValidator(ValidatorOptions{
+		Keys:       StaticKeys{"k1": &key.PublicKey, "ec": &ec.PublicKey},
+		Algorithms: []Algorithm{RS256, ES256, HS256, "none"},
+		Now:        func() time.Time { return now },
+	})
+
+	tests := []struct {
+		name string
+		raw  string
+		err  error
+	}{
+		{"HS256 signed with the RSA public key", sign(t, forger, validClaims()), ErrUnknownKey},
+		{"ES256 header on an RSA key", unsigned(t, Header{Algorithm: ES256, KeyID: "k1"}, validClaims(), make([]byte, 64)), ErrUnknownKey},
+		{"RS256 header on an EC key", unsigned(t, Header{Algorithm: RS256, KeyID: "ec"}, validClaims(), make([]byte, 256)), ErrUnknownKey},
+		{"alg none even when listed", unsigned(t, Header{Algorithm: "none", KeyID: "k1"}, validClaims(), nil), ErrUnsupportedAlgorithm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Validate(context.Background(), tt.raw); !errors.Is(err, tt.err) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSignAndValidateAlgorithms(t *testing.T) {
+	rk := rsaKey(t)
+	ek, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	rs, _ := NewRS256Signer(rk, "rs")
+	es, _ := NewES256Signer(ek, "es")
+	hs, _ := NewHS256Signer(secret, "hs")
+	v, _ := NewValidator(ValidatorOptions{
+		Keys:       StaticKeys{"rs": &rk.PublicKey, "es": &ek.PublicKey, "hs": secret},
+		Algorithms: []Algorithm{RS256, ES256, HS256},
+		Now:        func() time.Time { return now },
+	})
+	for _, s := range []*Signer{rs, es, hs} {
+		if _, err := v.Validate(context.Background(), sign(t, s, validClaims())); err != nil {
+			t.Errorf("Validate(%s) error = %v", s.Algorithm(), err)
+		}
+	}
+}
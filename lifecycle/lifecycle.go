@@ -0,0 +1,187 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Component is a part of the application with a managed lifetime
+// (HTTP server, queue consumer, scheduler, database client, ...).
+// All three funcs are optional.
+type Component struct {
+	Name string
+	// Start brings the component up and must return once it is ready.
+	// Components are started one by one in registration order.
+	Start func(ctx context.Context) error
+	// Run is the component's long-running loop, started in the background once every
+	// component has started. It should return when ctx is cancelled. Returning early,
+	// with or without an error, shuts the whole application down.
+	Run func(ctx context.Context) error
+	// Stop shuts the component down. Components are stopped in reverse registration order.
+	Stop func(ctx context.Context) error
+	// StopTimeout bounds Stop. Defaults to Options.StopTimeout.
+	StopTimeout time.Duration
+}
+
+// Options configures an App
+type Options struct {
+	// Signals that trigger a graceful shutdown. Defaults to SIGINT and SIGTERM.
+	Signals []os.Signal
+	// StopTimeout is the default per-component stop timeout. Defaults to 10s.
+	StopTimeout time.Duration
+	// ShutdownTimeout bounds the whole shutdown sequence. Defaults to 30s.
+	ShutdownTimeout time.Duration
+	// Logf receives progress messages; nil keeps the App silent.
+	Logf func(format string, args ...interface{})
+}
+
+// App runs registered components and shuts them down in reverse order
+// on the first failure, on an OS signal or when the parent context is cancelled.
+type App struct {
+	opts Options
+
+	mu         sync.Mutex
+	components []Component
+}
+
+// ErrComponentExited is reported when a component's Run returns before shutdown was requested
+var ErrComponentExited = errors.New("component exited unexpectedly")
+
+// New creates an App with the given options
+func New(opts Options) *App {
+	if len(opts.Signals) == 0 {
+		opts.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	if opts.StopTimeout <= 0 {
+		opts.StopTimeout = 10 * time.Second
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = 30 * time.Second
+	}
+	return &App{opts: opts}
+}
+
+// Register adds a component. Register dependencies first: they start first and stop last.
+func (a *App) Register(c Component) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.components = append(a.components, c)
+}
+
+// Run starts every component and blocks until shutdown.
+// It returns nil after a signal- or context-triggered shutdown that stopped everything cleanly,
+// and otherwise the error that caused the shutdown joined with any stop errors.
+func (a *App) Run(ctx context.Context) error {
+	a.mu.Lock()
+	components := make([]Component, len(a.components))
+	copy(components, a.components)
+	a.mu.Unlock()
+
+	sigCtx, stopSignals := signal.NotifyContext(ctx, a.opts.Signals...)
+	defer stopSignals()
+
+	// Start in order; on failure, stop whatever already started
+	started := 0
+	for _, c := range components {
+		if c.Start != nil {
+			a.logf("starting %s", c.Name)
+			if err := c.Start(sigCtx); err != nil {
+				err = fmt.Errorf("failed to start %s: %w", c.Name, err)
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), a.opts.ShutdownTimeout)
+				defer cancel()
+				return errors.Join(err, a.shutdown(shutdownCtx, components[:started]))
+			}
+		}
+		started++
+	}
+
+	runCtx, cancelRun := context.WithCancel(sigCtx)
+	defer cancelRun()
+
+	failures := make(chan error, len(components))
+	var running sync.WaitGroup
+	for _, c := range components {
+		if c.Run == nil {
+			continue
+		}
+		running.Add(1)
+		go func(c Component) {
+			defer running.Done()
+			err := c.Run(runCtx)
+			if runCtx.Err() != nil {
+				return // shutting down, exit was requested
+			}
+			if err == nil {
+				err = ErrComponentExited
+			}
+			failures <- fmt.Errorf("%s: %w", c.Name, err)
+		}(c)
+	}
+
+	a.logf("started %d components", len(components))
+
+	var cause error
+	select {
+	case <-sigCtx.Done():
+		a.logf("shutdown requested")
+	case cause = <-failures:
+		a.logf("shutting down after failure: %v", cause)
+	}
+	cancelRun()
+
+	// One deadline covers stopping the components and waiting for their Run loops
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), a.opts.ShutdownTimeout)
+	defer cancelShutdown()
+
+	stopErr := a.shutdown(shutdownCtx, components)
+
+	// Give Run loops the rest of the shutdown budget to return
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		stopErr = errors.Join(stopErr, fmt.Errorf("timed out waiting for components to exit"))
+	}
+
+	return errors.Join(cause, stopErr)
+}
+
+// shutdown stops components in reverse order before ctx, the overall shutdown deadline, expires
+func (a *App) shutdown(ctx context.Context, components []Component) error {
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		if c.Stop == nil {
+			continue
+		}
+
+		timeout := c.StopTimeout
+		if timeout <= 0 {
+			timeout = a.opts.StopTimeout
+		}
+		stopCtx, stopCancel := context.WithTimeout(ctx, timeout)
+
+		a.logf("stopping %s", c.Name)
+		if err := c.Stop(stopCtx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", c.Name, err))
+		}
+		stopCancel()
+	}
+	return errors.Join(errs...)
+}
+
+func (a *App) logf(format string, args ...interface{}) {
+	if a.opts.Logf != nil {
+		a.opts.Logf(format, args...)
+	}
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cdcloud-io/go-libs/appconfig"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry is the process-wide Prometheus registry shared by every package in this repo.
+// Packages register their collectors here so a single scrape endpoint exposes everything.
+var registry = prometheus.NewRegistry()
+
+// Registry returns the shared Prometheus registry
+func Registry() *prometheus.Registry {
+	return registry
+}
+
+// Registerer returns the shared registry as a prometheus.Registerer,
+// which is what instrumented packages should accept.
+func Registerer() prometheus.Registerer {
+	return registry
+}
+
+// Gatherer returns the shared registry as a prometheus.Gatherer
+func Gatherer() prometheus.Gatherer {
+	return registry
+}
+
+// Handler serves the shared registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Setup configures the shared registry from the metrics section of the application config.
+// With metrics.runtime enabled it registers the Go runtime and process collectors.
+func Setup(cfg appconfig.Config) error {
+	if !cfg.Metrics.Enabled {
+		return nil
+	}
+
+	if cfg.Metrics.Runtime {
+		if err := RegisterRuntimeCollectors(registry); err != nil {
+			return fmt.Errorf("failed to register runtime collectors: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/appconfig"
+	promBridge "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Push exporter names accepted in the metrics.push.exporter config value
+const (
+	PushNone   = ""
+	PushOTLP   = "otlp"
+	PushStatsD = "statsd"
+)
+
+// Pusher periodically pushes the shared registry to a remote backend.
+// Call Shutdown on exit so the last interval is flushed.
+type Pusher interface {
+	Shutdown(ctx context.Context) error
+}
+
+type noopPusher struct{}
+
+func (noopPusher) Shutdown(context.Context) error { return nil }
+
+// StartPush starts pushing the shared registry as configured in metrics.push, for
+// deployments that cannot be scraped. The Prometheus handler keeps working alongside it.
+// When metrics or pushing is disabled, the returned Pusher does nothing.
+func StartPush(ctx context.Context, cfg appconfig.Config) (Pusher, error) {
+	p := cfg.Metrics.Push
+	if !cfg.Metrics.Enabled || p.Exporter == PushNone {
+		return noopPusher{}, nil
+	}
+
+	interval := p.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	switch strings.ToLower(p.Exporter) {
+	case PushOTLP:
+		return startOTLP(ctx, cfg, interval)
+	case PushStatsD:
+		return StartStatsD(StatsDOptions{
+			Address:  p.Endpoint,
+			Prefix:   p.Prefix,
+			Interval: interval,
+			Tagged:   p.Tagged,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported metrics push exporter %q", p.Exporter)
+	}
+}
+
+// otlpPusher forwards the Prometheus registry through the OpenTelemetry SDK,
+// so metrics keep their Prometheus names and labels on the collector side
+type otlpPusher struct {
+	mp *sdkmetric.MeterProvider
+}
+
+func startOTLP(ctx context.Context, cfg appconfig.Config, interval time.Duration) (Pusher, error) {
+	p := cfg.Metrics.Push
+
+	// Build the resource before the exporter, so a failure here cannot leak its connection
+	attrs := []attribute.KeyValue{
+		attribute.String("service.name", cfg.App.Name),
+		attribute.String("service.version", cfg.App.Version),
+	}
+	if cfg.App.Env != "" {
+		attrs = append(attrs, attribute.String("deployment.environment", cfg.App.Env))
+	}
+	res, err := resource.New(ctx, resource.WithFromEnv(), resource.WithHost(), resource.WithAttributes(attrs...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics resource: %w", err)
+	}
+
+	var exporter sdkmetric.Exporter
+	switch strings.ToLower(p.Protocol) {
+	case "", "grpc":
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithTimeout(10 * time.Second)}
+		if p.Endpoint != "" {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(p.Endpoint))
+		}
+		if p.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		exporter, err = otlpmetricgrpc.New(ctx, opts...)
+	case "http", "http/protobuf":
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithTimeout(10 * time.Second)}
+		if p.Endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(p.Endpoint))
+		}
+		if p.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		exporter, err = otlpmetrichttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", p.Protocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+	}
+
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(interval),
+		sdkmetric.WithProducer(promBridge.NewMetricProducer(promBridge.WithGatherer(registry))),
+	)
+	return &otlpPusher{mp: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res))}, nil
+}
+
+// Shutdown pushes the final interval and stops the exporter
+func (p *otlpPusher) Shutdown(ctx context.Context) error {
+	if err := p.mp.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown OTLP metrics push: %w", err)
+	}
+	return nil
+}
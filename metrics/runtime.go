@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterRuntimeCollectors registers Go runtime metrics (GC pauses, heap, goroutines,
+// scheduler latency), process metrics (CPU, memory, open/max file descriptors)
+// and an open socket count against reg.
+// Collectors that are already registered are skipped, so calling it twice is harmless.
+func RegisterRuntimeCollectors(reg prometheus.Registerer) error {
+	cs := []prometheus.Collector{
+		collectors.NewGoCollector(
+			collectors.WithGoCollectorRuntimeMetrics(
+				collectors.MetricsGC,
+				collectors.MetricsMemory,
+				collectors.MetricsScheduler,
+			),
+		),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		newSocketCollector(),
+	}
+
+	for _, c := range cs {
+		if err := reg.Register(c); err != nil {
+			var are prometheus.AlreadyRegisteredError
+			if errors.As(err, &are) {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// socketCollector counts the sockets held open by the process.
+// It reads /proc/self/fd, so it only reports on Linux.
+type socketCollector struct {
+	desc *prometheus.Desc
+}
+
+func newSocketCollector() *socketCollector {
+	return &socketCollector{
+		desc: prometheus.NewDesc("process_open_sockets", "Number of open sockets.", nil, nil),
+	}
+}
+
+func (c *socketCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *socketCollector) Collect(ch chan<- prometheus.Metric) {
+	n, err := countSockets()
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
+
+func countSockets() (int, error) {
+	const fdDir = "/proc/self/fd"
+
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, e := range entries {
+		target, err := os.Readlink(fdDir + "/" + e.Name())
+		if err != nil {
+			continue // fd was closed while iterating
+		}
+		if strings.HasPrefix(target, "socket:") {
+			n++
+		}
+	}
+	return n, nil
+}
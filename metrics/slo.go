@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SLO declares a service level objective for one operation or endpoint.
+// For example {Name: "checkout", Objective: 0.99, LatencyTarget: 300ms} reads
+// "99% of checkout requests succeed in under 300ms"; the remaining 1% is the error budget.
+type SLO struct {
+	Name          string
+	Objective     float64
+	LatencyTarget time.Duration
+}
+
+// SLORecorder records request outcomes against an SLO.
+// It exports, labelled with slo="<name>":
+//
+//	slo_requests_total              every recorded request
+//	slo_bad_requests_total{reason}  requests that failed (reason="error") or were too slow (reason="latency")
+//	slo_request_duration_seconds    latency histogram with a bucket boundary at the target
+//	slo_objective_ratio             the objective, e.g. 0.99
+//	slo_latency_target_seconds      the latency target
+//
+// Burn rate over a window is then:
+//
+//	(rate(slo_bad_requests_total[1h]) / rate(slo_requests_total[1h])) / (1 - slo_objective_ratio)
+type SLORecorder struct {
+	slo      SLO
+	total    prometheus.Counter
+	errors   prometheus.Counter
+	slow     prometheus.Counter
+	duration prometheus.Histogram
+}
+
+// NewSLO validates slo and registers its metrics against reg.
+// Several SLOs can share a registry; they are distinguished by the slo label.
+func NewSLO(reg prometheus.Registerer, slo SLO) (*SLORecorder, error) {
+	if slo.Name == "" {
+		return nil, fmt.Errorf("slo name is required")
+	}
+	if slo.Objective <= 0 || slo.Objective >= 1 {
+		return nil, fmt.Errorf("slo %s: objective must be between 0 and 1, got %v", slo.Name, slo.Objective)
+	}
+	if slo.LatencyTarget <= 0 {
+		return nil, fmt.Errorf("slo %s: latency target must be positive", slo.Name)
+	}
+
+	labels := prometheus.Labels{"slo": slo.Name}
+	target := slo.LatencyTarget.Seconds()
+
+	bad := func(reason string) prometheus.Counter {
+		return prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "slo_bad_requests_total",
+			Help:        "Requests that did not meet the SLO.",
+			ConstLabels: prometheus.Labels{"slo": slo.Name, "reason": reason},
+		})
+	}
+
+	r := &SLORecorder{
+		slo: slo,
+		total: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "slo_requests_total",
+			Help:        "Requests recorded against the SLO.",
+			ConstLabels: labels,
+		}),
+		errors: bad("error"),
+		slow:   bad("latency"),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "slo_request_duration_seconds",
+			Help:        "Latency of requests recorded against the SLO.",
+			ConstLabels: labels,
+			Buckets:     []float64{target / 4, target / 2, target, target * 2, target * 4, target * 10},
+		}),
+	}
+
+	objective := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "slo_objective_ratio",
+		Help:        "Fraction of requests that must meet the SLO.",
+		ConstLabels: labels,
+	}, func() float64 { return slo.Objective })
+	latencyTarget := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "slo_latency_target_seconds",
+		Help:        "Latency a request must stay under to meet the SLO.",
+		ConstLabels: labels,
+	}, func() float64 { return target })
+
+	collectors := []prometheus.Collector{r.total, r.errors, r.slow, r.duration, objective, latencyTarget}
+	for i, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			// Undo the partial registration so a retry with the same name can succeed
+			for _, registered := range collectors[:i] {
+				reg.Unregister(registered)
+			}
+			return nil, fmt.Errorf("failed to register slo %s metrics: %w", slo.Name, err)
+		}
+	}
+	return r, nil
+}
+
+// Observe records one request outcome.
+// A request is bad if err is non-nil or it took longer than the latency target.
+func (r *SLORecorder) Observe(d time.Duration, err error) {
+	r.total.Inc()
+	r.duration.Observe(d.Seconds())
+
+	switch {
+	case err != nil:
+		r.errors.Inc()
+	case d > r.slo.LatencyTarget:
+		r.slow.Inc()
+	}
+}
+
+// Start returns a function that records the request when called with its outcome:
+//
+//	done := rec.Start()
+//	err := doWork()
+//	done(err)
+func (r *SLORecorder) Start() func(err error) {
+	start := time.Now()
+	return func(err error) {
+		r.Observe(time.Since(start), err)
+	}
+}
+
+// SLO returns the objective this recorder was created with
+func (r *SLORecorder) SLO() SLO {
+	return r.slo
+}
+
+// Middleware records every request handled by next against the SLO.
+// Responses with a 5xx status count as errors.
+func (r *SLORecorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(sw, req)
+
+		var err error
+		if sw.status >= 500 {
+			err = fmt.Errorf("status %d", sw.status)
+		}
+		r.Observe(time.Since(start), err)
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,228 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxStatsDPacket keeps UDP datagrams under a typical MTU
+const maxStatsDPacket = 1432
+
+// StatsDOptions configures a StatsD pusher
+type StatsDOptions struct {
+	// Address of the StatsD agent. Defaults to 127.0.0.1:8125.
+	Address string
+	// Prefix is prepended to every metric name, e.g. "orders."
+	Prefix string
+	// Interval between pushes. Defaults to 30s.
+	Interval time.Duration
+	// Tagged sends labels as DogStatsD tags (|#k:v). Otherwise label values are appended to the name.
+	Tagged bool
+	// Gatherer defaults to the shared registry.
+	Gatherer prometheus.Gatherer
+}
+
+// StatsDPusher periodically gathers a registry and sends it to a StatsD agent over UDP.
+// Counters are sent as deltas since the previous push, gauges as absolute values, and
+// histograms and summaries as their _count and _sum.
+type StatsDPusher struct {
+	opts StatsDOptions
+	conn net.Conn
+
+	mu   sync.Mutex
+	last map[string]float64 // previous counter values, keyed by name and labels
+
+	stop      chan struct{}
+	done      chan struct{}
+	stopOnce  sync.Once
+	closeOnce sync.Once
+}
+
+// StartStatsD connects to the agent and starts pushing every Interval
+func StartStatsD(opts StatsDOptions) (*StatsDPusher, error) {
+	if opts.Address == "" {
+		opts.Address = "127.0.0.1:8125"
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.Gatherer == nil {
+		opts.Gatherer = registry
+	}
+
+	conn, err := net.Dial("udp", opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD at %s: %w", opts.Address, err)
+	}
+
+	p := &StatsDPusher{
+		opts: opts,
+		conn: conn,
+		last: map[string]float64{},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go p.run()
+	return p, nil
+}
+
+func (p *StatsDPusher) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			if err := p.Push(); err != nil {
+				log.Printf("metrics: %v", err)
+			}
+		}
+	}
+}
+
+// Push gathers and sends the registry now
+func (p *StatsDPusher) Push() error {
+	families, err := p.opts.Gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics for StatsD: %w", err)
+	}
+
+	p.mu.Lock()
+	lines := p.lines(families)
+	p.mu.Unlock()
+
+	var buf []byte
+	for _, line := range lines {
+		if len(buf) > 0 && len(buf)+1+len(line) > maxStatsDPacket {
+			if _, err := p.conn.Write(buf); err != nil {
+				return fmt.Errorf("failed to send metrics to StatsD: %w", err)
+			}
+			buf = buf[:0]
+		}
+		if len(buf) > 0 {
+			buf = append(buf, '\n')
+		}
+		buf = append(buf, line...)
+	}
+	if len(buf) > 0 {
+		if _, err := p.conn.Write(buf); err != nil {
+			return fmt.Errorf("failed to send metrics to StatsD: %w", err)
+		}
+	}
+	return nil
+}
+
+// Shutdown sends a final push and closes the connection. Calling it again is a no-op.
+func (p *StatsDPusher) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	var err error
+	p.closeOnce.Do(func() {
+		err = p.Push()
+		p.conn.Close()
+	})
+	return err
+}
+
+// lines converts gathered families to StatsD lines; p.mu must be held
+func (p *StatsDPusher) lines(families []*dto.MetricFamily) []string {
+	var lines []string
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				lines = append(lines, p.counter(name, m.GetLabel(), m.GetCounter().GetValue()))
+			case dto.MetricType_GAUGE:
+				lines = append(lines, p.line(name, m.GetLabel(), m.GetGauge().GetValue(), "g"))
+			case dto.MetricType_UNTYPED:
+				lines = append(lines, p.line(name, m.GetLabel(), m.GetUntyped().GetValue(), "g"))
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				lines = append(lines,
+					p.counter(name+"_count", m.GetLabel(), float64(h.GetSampleCount())),
+					p.counter(name+"_sum", m.GetLabel(), h.GetSampleSum()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				lines = append(lines,
+					p.counter(name+"_count", m.GetLabel(), float64(s.GetSampleCount())),
+					p.counter(name+"_sum", m.GetLabel(), s.GetSampleSum()))
+			}
+		}
+	}
+	return lines
+}
+
+// counter emits the increase since the previous push; a reset counter sends its full value
+func (p *StatsDPusher) counter(name string, labels []*dto.LabelPair, value float64) string {
+	key := name
+	for _, l := range labels {
+		key += "\x00" + l.GetName() + "=" + l.GetValue()
+	}
+	delta := value - p.last[key]
+	if delta < 0 {
+		delta = value
+	}
+	p.last[key] = value
+	return p.line(name, labels, delta, "c")
+}
+
+func (p *StatsDPusher) line(name string, labels []*dto.LabelPair, value float64, typ string) string {
+	var b strings.Builder
+	b.WriteString(p.opts.Prefix)
+	b.WriteString(name)
+
+	sorted := append([]*dto.LabelPair(nil), labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+
+	if !p.opts.Tagged {
+		for _, l := range sorted {
+			b.WriteByte('.')
+			b.WriteString(sanitizeStatsD(l.GetValue()))
+		}
+	}
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	b.WriteByte('|')
+	b.WriteString(typ)
+
+	if p.opts.Tagged && len(sorted) > 0 {
+		b.WriteString("|#")
+		for i, l := range sorted {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(sanitizeStatsD(l.GetName()))
+			b.WriteByte(':')
+			b.WriteString(sanitizeStatsD(l.GetValue()))
+		}
+	}
+	return b.String()
+}
+
+// sanitizeStatsD replaces characters that are part of the StatsD line protocol
+func sanitizeStatsD(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '\n', ' ', '.':
+			return '_'
+		}
+		return r
+	}, s)
+}
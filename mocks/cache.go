@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/cache"
+)
+
+// CacheStore is a mock cache.Store. Unset funcs behave as a cache that always misses.
+type CacheStore struct {
+	Recorder
+
+	GetFunc    func(ctx context.Context, key string) ([]byte, bool, error)
+	SetFunc    func(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	DeleteFunc func(ctx context.Context, keys ...string) error
+}
+
+var _ cache.Store = (*CacheStore)(nil)
+
+func (m *CacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.record("Get", key)
+	if m.GetFunc == nil {
+		return nil, false, nil
+	}
+	return m.GetFunc(ctx, key)
+}
+
+func (m *CacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.record("Set", key, value, ttl)
+	if m.SetFunc == nil {
+		return nil
+	}
+	return m.SetFunc(ctx, key, value, ttl)
+}
+
+func (m *CacheStore) Delete(ctx context.Context, keys ...string) error {
+	m.record("Delete", keys)
+	if m.DeleteFunc == nil {
+		return nil
+	}
+	return m.DeleteFunc(ctx, keys...)
+}
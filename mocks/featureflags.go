@@ -0,0 +1,29 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/cdcloud-io/go-libs/featureflags"
+)
+
+// FlagEvaluator is a mock featureflags.Evaluator. Flags listed in Values are returned as is;
+// other flags fall back to BoolFunc or the default value.
+type FlagEvaluator struct {
+	Recorder
+
+	Values   map[string]bool
+	BoolFunc func(ctx context.Context, key string, ec featureflags.EvalContext, defaultValue bool) bool
+}
+
+var _ featureflags.Evaluator = (*FlagEvaluator)(nil)
+
+func (m *FlagEvaluator) Bool(ctx context.Context, key string, ec featureflags.EvalContext, defaultValue bool) bool {
+	m.record("Bool", key, ec, defaultValue)
+	if v, ok := m.Values[key]; ok {
+		return v
+	}
+	if m.BoolFunc == nil {
+		return defaultValue
+	}
+	return m.BoolFunc(ctx, key, ec, defaultValue)
+}
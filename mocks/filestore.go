@@ -0,0 +1,109 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/filestore"
+)
+
+// FileStore is a mock filestore.Store. With no funcs set it behaves as an in-memory store.
+type FileStore struct {
+	Recorder
+
+	PutFunc       func(ctx context.Context, key string, r io.Reader, opts filestore.PutOptions) error
+	GetFunc       func(ctx context.Context, key string) (io.ReadCloser, filestore.Object, error)
+	DeleteFunc    func(ctx context.Context, key string) error
+	ListFunc      func(ctx context.Context, prefix string) ([]filestore.Object, error)
+	SignedURLFunc func(ctx context.Context, key string, opts filestore.SignedURLOptions) (string, error)
+
+	mu      sync.Mutex
+	objects map[string]memObject
+}
+
+type memObject struct {
+	data []byte
+	obj  filestore.Object
+}
+
+var _ filestore.Store = (*FileStore)(nil)
+
+func (m *FileStore) Put(ctx context.Context, key string, r io.Reader, opts filestore.PutOptions) error {
+	m.record("Put", key, opts)
+	if m.PutFunc != nil {
+		return m.PutFunc(ctx, key, r, opts)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.objects == nil {
+		m.objects = map[string]memObject{}
+	}
+	m.objects[key] = memObject{data: data, obj: filestore.Object{
+		Key:          key,
+		Size:         int64(len(data)),
+		ContentType:  opts.ContentType,
+		LastModified: time.Now(),
+		Metadata:     opts.Metadata,
+	}}
+	return nil
+}
+
+func (m *FileStore) Get(ctx context.Context, key string) (io.ReadCloser, filestore.Object, error) {
+	m.record("Get", key)
+	if m.GetFunc != nil {
+		return m.GetFunc(ctx, key)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.objects[key]
+	if !ok {
+		return nil, filestore.Object{}, fmt.Errorf("%w: %s", filestore.ErrNotFound, key)
+	}
+	return io.NopCloser(bytes.NewReader(o.data)), o.obj, nil
+}
+
+func (m *FileStore) Delete(ctx context.Context, key string) error {
+	m.record("Delete", key)
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, key)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *FileStore) List(ctx context.Context, prefix string) ([]filestore.Object, error) {
+	m.record("List", prefix)
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, prefix)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []filestore.Object
+	for k, o := range m.objects {
+		if strings.HasPrefix(k, prefix) {
+			out = append(out, o.obj)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+func (m *FileStore) SignedURL(ctx context.Context, key string, opts filestore.SignedURLOptions) (string, error) {
+	m.record("SignedURL", key, opts)
+	if m.SignedURLFunc != nil {
+		return m.SignedURLFunc(ctx, key, opts)
+	}
+	return "https://files.example.test/" + key + "?signed=1", nil
+}
@@ -0,0 +1,28 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/cdcloud-io/go-libs/jwtauth"
+)
+
+// KeySource is a mock jwtauth.KeySource
+type KeySource struct {
+	Recorder
+
+	Keys    map[string]interface{}
+	KeyFunc func(ctx context.Context, keyID string, alg jwtauth.Algorithm) (interface{}, error)
+}
+
+var _ jwtauth.KeySource = (*KeySource)(nil)
+
+func (m *KeySource) Key(ctx context.Context, keyID string, alg jwtauth.Algorithm) (interface{}, error) {
+	m.record("Key", keyID, alg)
+	if k, ok := m.Keys[keyID]; ok {
+		return k, nil
+	}
+	if m.KeyFunc == nil {
+		return nil, jwtauth.ErrUnknownKey
+	}
+	return m.KeyFunc(ctx, keyID, alg)
+}
@@ -0,0 +1,117 @@
+package mocks
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Call is one recorded method call
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// Any matches any argument in CalledWith and AssertCalledWith
+var Any = anyArg{}
+
+type anyArg struct{}
+
+// Recorder records the calls made to a mock. Every mock in this package embeds one.
+type Recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (r *Recorder) record(method string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns the recorded calls to method, or every call if method is empty
+func (r *Recorder) Calls(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Call
+	for _, c := range r.calls {
+		if method == "" || c.Method == method {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// CallCount returns how many times method was called
+func (r *Recorder) CallCount(method string) int {
+	return len(r.Calls(method))
+}
+
+// CalledWith reports whether method was called with args; Any matches any argument.
+// Context arguments are not recorded, so args start after ctx.
+func (r *Recorder) CalledWith(method string, args ...interface{}) bool {
+	for _, c := range r.Calls(method) {
+		if matchArgs(c.Args, args) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset forgets every recorded call
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// AssertCalled fails the test unless method was called exactly times times
+func (r *Recorder) AssertCalled(t testing.TB, method string, times int) {
+	t.Helper()
+	if n := r.CallCount(method); n != times {
+		t.Errorf("expected %s to be called %d times, got %d%s", method, times, n, r.describe(method))
+	}
+}
+
+// AssertNotCalled fails the test if method was called
+func (r *Recorder) AssertNotCalled(t testing.TB, method string) {
+	t.Helper()
+	r.AssertCalled(t, method, 0)
+}
+
+// AssertCalledWith fails the test unless method was called with args
+func (r *Recorder) AssertCalledWith(t testing.TB, method string, args ...interface{}) {
+	t.Helper()
+	if !r.CalledWith(method, args...) {
+		t.Errorf("expected %s to be called with %v%s", method, args, r.describe(method))
+	}
+}
+
+func (r *Recorder) describe(method string) string {
+	calls := r.Calls(method)
+	if len(calls) == 0 {
+		return ""
+	}
+	lines := make([]string, len(calls))
+	for i, c := range calls {
+		lines[i] = fmt.Sprintf("  %s%v", c.Method, c.Args)
+	}
+	return "\nrecorded calls:\n" + strings.Join(lines, "\n")
+}
+
+func matchArgs(got, want []interface{}) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range want {
+		if _, ok := want[i].(anyArg); ok {
+			continue
+		}
+		if !reflect.DeepEqual(got[i], want[i]) {
+			return false
+		}
+	}
+	return true
+}
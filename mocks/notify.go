@@ -0,0 +1,24 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/cdcloud-io/go-libs/notify"
+)
+
+// NotifySender is a mock notify.Sender
+type NotifySender struct {
+	Recorder
+
+	SendFunc func(ctx context.Context, msg notify.Message) error
+}
+
+var _ notify.Sender = (*NotifySender)(nil)
+
+func (m *NotifySender) Send(ctx context.Context, msg notify.Message) error {
+	m.record("Send", msg)
+	if m.SendFunc == nil {
+		return nil
+	}
+	return m.SendFunc(ctx, msg)
+}
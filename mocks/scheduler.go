@@ -0,0 +1,28 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/scheduler"
+)
+
+// Locker is a mock scheduler.Locker. Unset, every lock is granted.
+type Locker struct {
+	Recorder
+
+	TryLockFunc func(ctx context.Context, name string, ttl time.Duration) (func(context.Context) error, bool, error)
+}
+
+var _ scheduler.Locker = (*Locker)(nil)
+
+func (m *Locker) TryLock(ctx context.Context, name string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	m.record("TryLock", name, ttl)
+	if m.TryLockFunc == nil {
+		return func(context.Context) error {
+			m.record("Unlock", name)
+			return nil
+		}, true, nil
+	}
+	return m.TryLockFunc(ctx, name, ttl)
+}
@@ -0,0 +1,58 @@
+package mongoclient
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Authentication mechanisms for Credential.Mechanism
+const (
+	AuthSCRAMSHA256 = "SCRAM-SHA-256"
+	AuthSCRAMSHA1   = "SCRAM-SHA-1"
+	AuthX509        = "MONGODB-X509"
+	AuthAWS         = "MONGODB-AWS"
+)
+
+// Credential authenticates the connection, so secrets can be injected at runtime instead
+// of being interpolated into the URI
+type Credential struct {
+	// Mechanism defaults to negotiating SCRAM with the server.
+	Mechanism string
+	// Source is the database holding the user. Defaults to "admin", or "$external" for
+	// X.509 and AWS.
+	Source string
+	// Username and Password for SCRAM. For AWS they are the access key ID and secret; leave
+	// them empty to use the environment, e.g. an IAM role. For X.509 Username is optional
+	// and the client certificate comes from ClientOptions.TLS.
+	Username string
+	Password string
+	// AWSSessionToken is the session token of temporary AWS credentials.
+	AWSSessionToken string
+}
+
+// credential converts Credential to driver options
+func (c Credential) credential() (options.Credential, error) {
+	cred := options.Credential{
+		AuthMechanism: c.Mechanism,
+		AuthSource:    c.Source,
+		Username:      c.Username,
+		Password:      c.Password,
+		PasswordSet:   c.Password != "",
+	}
+	switch c.Mechanism {
+	case AuthX509:
+		if c.Password != "" {
+			return cred, errors.New("X.509 authentication takes no password")
+		}
+	case AuthAWS:
+		if c.AWSSessionToken != "" {
+			cred.AuthMechanismProperties = map[string]string{"AWS_SESSION_TOKEN": c.AWSSessionToken}
+		}
+	case "", AuthSCRAMSHA256, AuthSCRAMSHA1:
+		if c.Username == "" {
+			return cred, errors.New("SCRAM authentication needs a username")
+		}
+	}
+	return cred, nil
+}
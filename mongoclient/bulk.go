@@ -0,0 +1,133 @@
+package mongoclient
+
+import (
+	"context"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WriteModel is one write of a BulkWrite: InsertModel, UpdateModel, ReplaceModel or DeleteModel
+type WriteModel interface {
+	writeModel() mongo.WriteModel
+}
+
+// InsertModel inserts Document
+type InsertModel struct {
+	Document interface{}
+}
+
+// UpdateModel applies Update to the first document matching Filter, or to all of them with Many
+type UpdateModel struct {
+	Filter bson.M
+	Update interface{}
+	Upsert bool
+	Many   bool
+}
+
+// ReplaceModel replaces the first document matching Filter with Replacement
+type ReplaceModel struct {
+	Filter      bson.M
+	Replacement interface{}
+	Upsert      bool
+}
+
+// DeleteModel deletes the first document matching Filter, or all of them with Many
+type DeleteModel struct {
+	Filter bson.M
+	Many   bool
+}
+
+func (m InsertModel) writeModel() mongo.WriteModel {
+	return mongo.NewInsertOneModel().SetDocument(m.Document)
+}
+
+func (m UpdateModel) writeModel() mongo.WriteModel {
+	if m.Many {
+		return mongo.NewUpdateManyModel().SetFilter(filterOrAll(m.Filter)).SetUpdate(m.Update).SetUpsert(m.Upsert)
+	}
+	return mongo.NewUpdateOneModel().SetFilter(filterOrAll(m.Filter)).SetUpdate(m.Update).SetUpsert(m.Upsert)
+}
+
+func (m ReplaceModel) writeModel() mongo.WriteModel {
+	return mongo.NewReplaceOneModel().SetFilter(filterOrAll(m.Filter)).SetReplacement(m.Replacement).SetUpsert(m.Upsert)
+}
+
+func (m DeleteModel) writeModel() mongo.WriteModel {
+	if m.Many {
+		return mongo.NewDeleteManyModel().SetFilter(filterOrAll(m.Filter))
+	}
+	return mongo.NewDeleteOneModel().SetFilter(filterOrAll(m.Filter))
+}
+
+// filterOrAll turns a nil filter into an empty one, which the driver requires
+func filterOrAll(filter bson.M) bson.M {
+	if filter == nil {
+		return bson.M{}
+	}
+	return filter
+}
+
+// stamp applies ClientOptions.Timestamps to a write model
+func (c *Client) stamp(m WriteModel) (WriteModel, error) {
+	var err error
+	switch m := m.(type) {
+	case InsertModel:
+		m.Document, err = c.timestamps.StampInsert(m.Document)
+		return m, err
+	case UpdateModel:
+		m.Update, err = c.timestamps.StampUpdate(m.Update)
+		return m, err
+	case ReplaceModel:
+		m.Replacement, err = c.timestamps.StampReplacement(m.Replacement)
+		return m, err
+	}
+	return m, nil
+}
+
+// BulkOptions configures BulkWrite and InsertMany
+type BulkOptions struct {
+	// Unordered lets the server apply writes in any order and continue past failed writes.
+	// By default writes are applied in order and stop at the first failure.
+	Unordered bool
+}
+
+// BulkWrite sends models to the collection in QueryParams in as few round trips as possible;
+// the driver splits large batches automatically. When individual writes fail, the returned
+// result still counts the writes that were applied.
+func (c *Client) BulkWrite(ctx context.Context, params QueryParams, models []WriteModel, opts BulkOptions) (*mongo.BulkWriteResult, error) {
+	if err := c.writable("mongoclient.BulkWrite"); err != nil {
+		return nil, err
+	}
+	if len(models) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.BulkWrite")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	writes := make([]mongo.WriteModel, len(models))
+	for i, m := range models {
+		m, err := c.stamp(m)
+		if err != nil {
+			return nil, errs.Wrap(err, errs.Invalid, "mongoclient.BulkWrite", "failed to encode write model")
+		}
+		writes[i] = m.writeModel()
+	}
+
+	collection := c.collection(params)
+	result, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(!opts.Unordered))
+	if err != nil {
+		return result, WrapError(err, "mongoclient.BulkWrite", "failed to execute bulk write")
+	}
+	return result, nil
+}
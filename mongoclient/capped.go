@@ -0,0 +1,314 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// codeCappedPositionLost is the server error of a tailable cursor overtaken by deletions
+const codeCappedPositionLost = 136
+
+// CappedSpec describes a capped collection for EnsureCappedCollection. A capped collection
+// keeps documents in insertion order and deletes the oldest once it is full, which suits
+// logs and event feeds read with Tail.
+type CappedSpec struct {
+	// MaxBytes is the size of the collection. Required.
+	MaxBytes int64
+	// MaxDocuments also caps the number of documents. Zero caps only the size.
+	MaxDocuments int64
+}
+
+// cappedInfo is the part of a listCollections entry that EnsureCappedCollection compares
+type cappedInfo struct {
+	Options struct {
+		Capped bool  `bson:"capped"`
+		Size   int64 `bson:"size"`
+		Max    int64 `bson:"max"`
+	} `bson:"options"`
+}
+
+// EnsureCappedCollection creates a capped collection. It is safe to call on every startup:
+// an existing capped collection is resized in place when spec changed (MongoDB 6.0 or
+// later). A collection that exists without being capped returns an errs.Conflict error,
+// since converting it would rewrite its data.
+func (c *Client) EnsureCappedCollection(ctx context.Context, database, collection string, spec CappedSpec) error {
+	if err := c.writable("mongoclient.EnsureCappedCollection"); err != nil {
+		return err
+	}
+	if spec.MaxBytes <= 0 {
+		return errs.New(errs.Invalid, "capped collection requires a size").With("collection", collection)
+	}
+	db := c.Database(database)
+	cursor, err := db.ListCollections(ctx, bson.M{"name": collection})
+	if err != nil {
+		return WrapError(err, "mongoclient.EnsureCappedCollection", "failed to list collections")
+	}
+	var existing []cappedInfo
+	if err := cursor.All(ctx, &existing); err != nil {
+		return WrapError(err, "mongoclient.EnsureCappedCollection", "failed to list collections")
+	}
+
+	if len(existing) == 0 {
+		opts := options.CreateCollection().SetCapped(true).SetSizeInBytes(spec.MaxBytes)
+		if spec.MaxDocuments > 0 {
+			opts.SetMaxDocuments(spec.MaxDocuments)
+		}
+		err := db.CreateCollection(ctx, collection, opts)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Name == "NamespaceExists" {
+			// Created concurrently, e.g. by another replica starting up; compare it instead.
+			return c.EnsureCappedCollection(ctx, database, collection, spec)
+		}
+		if err != nil {
+			return WrapError(err, "mongoclient.EnsureCappedCollection", "failed to create capped collection")
+		}
+		return nil
+	}
+
+	info := existing[0].Options
+	if !info.Capped {
+		return errs.New(errs.Conflict, fmt.Sprintf("collection %s exists and is not capped", collection)).
+			With("collection", collection)
+	}
+	// The server rounds the size up to a multiple of 256 bytes
+	size := (spec.MaxBytes + 255) / 256 * 256
+	var mod bson.D
+	if info.Size != size && info.Size != spec.MaxBytes {
+		mod = append(mod, bson.E{Key: "cappedSize", Value: spec.MaxBytes})
+	}
+	if info.Max != spec.MaxDocuments && !(spec.MaxDocuments == 0 && info.Max < 0) {
+		mod = append(mod, bson.E{Key: "cappedMax", Value: spec.MaxDocuments})
+	}
+	if len(mod) == 0 {
+		return nil
+	}
+	err = db.RunCommand(ctx, append(bson.D{{Key: "collMod", Value: collection}}, mod...)).Err()
+	if err != nil {
+		return WrapError(err, "mongoclient.EnsureCappedCollection", "failed to resize capped collection")
+	}
+	return nil
+}
+
+// TailOptions controls where Tail starts and how it recovers from failures
+type TailOptions struct {
+	// ResumeField is an ever-increasing field, such as an ObjectID _id assigned by one
+	// writer or a sequence number, used to continue after the last delivered document when
+	// the cursor is reopened. Defaults to "_id".
+	ResumeField string
+	// After starts after the document whose ResumeField has this value, e.g. one persisted
+	// by the consumer. It takes precedence over SkipExisting.
+	After interface{}
+	// SkipExisting delivers only documents inserted after Tail is called. By default every
+	// document in the collection is delivered first.
+	SkipExisting bool
+	// Buffer is the capacity of the returned channel. Defaults to 0, unbuffered.
+	Buffer int
+	// MaxAwait bounds how long the server waits for new documents per round trip.
+	// Defaults to 1s.
+	MaxAwait time.Duration
+	// MaxRetries is the number of consecutive transient failures after which Tail gives up.
+	// Defaults to 5; negative retries forever.
+	MaxRetries int
+	// RetryBackoff is the wait before the first reopen, doubled per consecutive failure up
+	// to 30s. It is also the wait before reopening the cursor of an empty collection.
+	// Defaults to 500ms.
+	RetryBackoff time.Duration
+}
+
+// TailEvent is one document delivered by Tail
+type TailEvent struct {
+	Document bson.Raw
+	// Err is set on the last value sent before the channel closes when tailing failed.
+	Err error
+}
+
+// Decode decodes the document into v
+func (e TailEvent) Decode(v interface{}) error {
+	if err := bson.Unmarshal(e.Document, v); err != nil {
+		return errs.Wrap(err, errs.Invalid, "mongoclient.TailEvent.Decode", "failed to decode tailed document")
+	}
+	return nil
+}
+
+// Tail follows the capped collection in QueryParams with a tailable cursor and sends each
+// document matching params.Filter to the returned channel, in insertion order, like tail -f.
+// Transient failures, and a cursor overtaken by the collection wrapping around, reopen it
+// after the last delivered document, see TailOptions.ResumeField. The channel is closed
+// when ctx is done; when tailing fails for good the last value sent carries Err.
+func (c *Client) Tail(ctx context.Context, params QueryParams, opts TailOptions) (<-chan TailEvent, error) {
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ResumeField == "" {
+		opts.ResumeField = "_id"
+	}
+	if opts.MaxAwait <= 0 {
+		opts.MaxAwait = time.Second
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 5
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 500 * time.Millisecond
+	}
+
+	t := &tailer{client: c, params: params, opts: opts, last: opts.After}
+	if t.last == nil && opts.SkipExisting {
+		if err := t.seekEnd(ctx); err != nil {
+			return nil, WrapError(err, "mongoclient.Tail", "failed to find the end of the collection")
+		}
+	}
+	cursor, err := t.open(ctx)
+	if err != nil {
+		return nil, WrapError(err, "mongoclient.Tail", "failed to open tailable cursor")
+	}
+
+	ch := make(chan TailEvent, opts.Buffer)
+	go t.run(ctx, cursor, ch)
+	return ch, nil
+}
+
+type tailer struct {
+	client *Client
+	params QueryParams
+	opts   TailOptions
+	last   interface{} // ResumeField of the last delivered document
+}
+
+// seekEnd positions the tailer after the newest document
+func (t *tailer) seekEnd(ctx context.Context) error {
+	opts := options.FindOne().SetSort(bson.D{{Key: "$natural", Value: -1}})
+	raw, err := t.client.collection(t.params).FindOne(ctx, bson.M{}, opts).Raw()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	t.last, err = t.resumeValue(raw)
+	return err
+}
+
+// open starts a tailable cursor after the last delivered document
+func (t *tailer) open(ctx context.Context) (*mongo.Cursor, error) {
+	filter := filterOrAll(t.params.Filter)
+	if t.last != nil {
+		after := bson.M{t.opts.ResumeField: bson.M{"$gt": t.last}}
+		if len(t.params.Filter) > 0 {
+			filter = bson.M{"$and": bson.A{t.params.Filter, after}}
+		} else {
+			filter = after
+		}
+	}
+	opts := options.Find().
+		SetCursorType(options.TailableAwait).
+		SetMaxAwaitTime(t.opts.MaxAwait).
+		SetSort(bson.D{{Key: "$natural", Value: 1}})
+	if len(t.params.Options.Projection) > 0 {
+		opts.SetProjection(t.params.Options.Projection)
+	}
+	if t.params.Options.BatchSize > 0 {
+		opts.SetBatchSize(t.params.Options.BatchSize)
+	}
+	return t.client.collection(t.params).Find(ctx, filter, opts)
+}
+
+func (t *tailer) run(ctx context.Context, cursor *mongo.Cursor, ch chan<- TailEvent) {
+	defer close(ch)
+
+	failures := 0
+	for {
+		err := t.drain(ctx, cursor, ch, &failures)
+		cursor.Close(context.WithoutCancel(ctx))
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			// The server closes the cursor of an empty collection at once; wait for documents.
+			if !sleep(ctx, t.opts.RetryBackoff) {
+				return
+			}
+		}
+
+		for {
+			if err != nil {
+				if !t.retryable(err) || (t.opts.MaxRetries >= 0 && failures >= t.opts.MaxRetries) {
+					break
+				}
+				failures++
+				if !sleep(ctx, backoff(t.opts.RetryBackoff, failures)) {
+					return
+				}
+			}
+			cursor, err = t.open(ctx)
+			if err == nil {
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			var e *errs.Error
+			if !errors.As(err, &e) {
+				err = WrapError(err, "mongoclient.Tail", "tailable cursor failed")
+			}
+			t.send(ctx, ch, TailEvent{Err: err})
+			return
+		}
+	}
+}
+
+// drain delivers documents until the cursor ends, returning its failure, or nil when the
+// server closed it
+func (t *tailer) drain(ctx context.Context, cursor *mongo.Cursor, ch chan<- TailEvent, failures *int) error {
+	for cursor.Next(ctx) {
+		doc := append(bson.Raw(nil), cursor.Current...)
+		last, err := t.resumeValue(doc)
+		if err != nil {
+			return err
+		}
+		if !t.send(ctx, ch, TailEvent{Document: doc}) {
+			return nil
+		}
+		t.last = last
+		*failures = 0
+	}
+	return cursor.Err()
+}
+
+// resumeValue returns the ResumeField of doc
+func (t *tailer) resumeValue(doc bson.Raw) (interface{}, error) {
+	v, err := doc.LookupErr(strings.Split(t.opts.ResumeField, ".")...)
+	if err != nil {
+		return nil, errs.New(errs.Invalid, "tailed document has no resume field").With("field", t.opts.ResumeField)
+	}
+	return v, nil
+}
+
+// retryable reports whether reopening the cursor may succeed
+func (t *tailer) retryable(err error) bool {
+	var se mongo.ServerError
+	if errors.As(err, &se) && se.HasErrorCode(codeCappedPositionLost) {
+		return true
+	}
+	return resumable(err)
+}
+
+func (t *tailer) send(ctx context.Context, ch chan<- TailEvent, e TailEvent) bool {
+	select {
+	case ch <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,22 @@
+package mongoclient
+
+import "go.mongodb.org/mongo-driver/mongo/options"
+
+// Collation strengths: how many levels of difference make two strings unequal
+const (
+	// CollationBase ignores case and diacritics: "resume" equals "Résumé".
+	CollationBase = 1
+	// CollationCaseInsensitive ignores case but not diacritics: "resume" equals "Resume".
+	CollationCaseInsensitive = 2
+	// CollationExact, the server default, compares case and diacritics.
+	CollationExact = 3
+)
+
+// CaseInsensitive returns a collation for locale, e.g. "en", that compares strings
+// regardless of case, for QueryOptions.Collation and IndexSpec.Collation:
+//
+//	params.Options.Collation = mongoclient.CaseInsensitive("en")
+//	params.Filter = bson.M{"email": "Jane@Example.com"} // also matches jane@example.com
+func CaseInsensitive(locale string) *options.Collation {
+	return &options.Collation{Locale: locale, Strength: CollationCaseInsensitive}
+}
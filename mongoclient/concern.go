@@ -0,0 +1,34 @@
+package mongoclient
+
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// ReadOptions selects the members that serve reads and the consistency they guarantee.
+// Nil fields keep the default: the client's for a query, the server's for the client.
+type ReadOptions struct {
+	// Preference routes reads, e.g. readpref.SecondaryPreferred() for analytics, or
+	// readpref.Nearest(readpref.WithTags("region", "eu")) to pick members by tag.
+	Preference *readpref.ReadPref
+	// Concern sets the read concern level, e.g. readconcern.Majority() to read only
+	// data that cannot be rolled back.
+	Concern *readconcern.ReadConcern
+}
+
+// collection returns the collection in params with its per-operation overrides applied
+func (c *Client) collection(params QueryParams) *mongo.Collection {
+	opts := options.Collection()
+	if params.Read.Preference != nil {
+		opts.SetReadPreference(params.Read.Preference)
+	}
+	if params.Read.Concern != nil {
+		opts.SetReadConcern(params.Read.Concern)
+	}
+	if params.WriteConcern != nil {
+		opts.SetWriteConcern(params.WriteConcern)
+	}
+	return c.Database(params.Database).Collection(params.Collection, opts)
+}
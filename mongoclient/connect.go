@@ -0,0 +1,51 @@
+package mongoclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// connectBackoff is the delay before the second background connection attempt, doubled
+// after each failure up to 30s
+const connectBackoff = 500 * time.Millisecond
+
+// connectLoop pings MongoDB until it answers, then closes c.ready. The driver keeps
+// reconnecting to servers on its own afterwards.
+func (c *Client) connectLoop(ctx context.Context, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	for failures := 0; ; {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := c.Ping(pingCtx, readpref.Primary())
+		cancel()
+		if err == nil {
+			close(c.ready)
+			return
+		}
+		failures++
+		if !sleep(ctx, backoff(connectBackoff, failures)) {
+			return
+		}
+	}
+}
+
+// Ready returns a channel that is closed once MongoDB has answered a ping. It is closed on
+// return from NewClient unless ClientOptions.Lazy is set.
+func (c *Client) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// WaitReady blocks until MongoDB has answered a ping or ctx is done. It returns an
+// errs.Unavailable error when ctx ends first.
+func (c *Client) WaitReady(ctx context.Context) error {
+	select {
+	case <-c.ready:
+		return nil
+	case <-ctx.Done():
+		return errs.Wrap(ctx.Err(), errs.Unavailable, "mongoclient.WaitReady", "MongoDB is not reachable yet")
+	}
+}
@@ -0,0 +1,127 @@
+package mongoclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strings"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EncryptionOptions enables automatic client-side field level encryption (CSFLE): fields
+// named in the schema map are encrypted before they leave the process and decrypted on
+// read, so the server and its backups only hold ciphertext.
+//
+// CSFLE needs libmongocrypt and a binary built with the cse build tag (go build -tags cse);
+// without them NewClient returns an error. Automatic encryption also needs MongoDB
+// Enterprise or Atlas, plus the crypt_shared library or mongocryptd on the host.
+type EncryptionOptions struct {
+	// KMSProviders holds the credentials of the key management services that protect the
+	// data keys, by provider: "aws", "azure", "gcp", "kmip" or "local", e.g.
+	// {"local": {"key": masterKey}} with a 96-byte master key for development.
+	KMSProviders map[string]map[string]interface{}
+	// KeyVaultNamespace is the "<database>.<collection>" that stores the data keys, e.g.
+	// "encryption.__keyVault".
+	KeyVaultNamespace string
+	// SchemaMap maps "<database>.<collection>" to the JSON schema that marks its encrypted
+	// fields. Collections without an entry use the schema in their server-side validator.
+	SchemaMap map[string]interface{}
+	// CryptSharedLibPath is the path of the crypt_shared library. When empty, the driver
+	// spawns mongocryptd from the PATH instead.
+	CryptSharedLibPath string
+	// BypassAutoEncryption turns off encryption of writes and queries while still
+	// decrypting reads, e.g. for a reporting service that must not write PII.
+	BypassAutoEncryption bool
+	// KMSTLS configures TLS to the key management services by provider, e.g. for "kmip".
+	KMSTLS map[string]*tls.Config
+}
+
+func (o *EncryptionOptions) validate() error {
+	if !cseEnabled {
+		return errors.New("encryption requires building with the cse build tag (go build -tags cse)")
+	}
+	if len(o.KMSProviders) == 0 {
+		return errors.New("encryption requires at least one KMS provider")
+	}
+	if db, coll, ok := strings.Cut(o.KeyVaultNamespace, "."); !ok || db == "" || coll == "" {
+		return errors.New(`encryption key vault namespace must be "<database>.<collection>"`)
+	}
+	return nil
+}
+
+// autoEncryption converts EncryptionOptions to driver options
+func (o *EncryptionOptions) autoEncryption() (*options.AutoEncryptionOptions, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	opts := options.AutoEncryption().
+		SetKmsProviders(o.KMSProviders).
+		SetKeyVaultNamespace(o.KeyVaultNamespace)
+	if len(o.SchemaMap) > 0 {
+		opts.SetSchemaMap(o.SchemaMap)
+	}
+	if o.CryptSharedLibPath != "" {
+		opts.SetExtraOptions(map[string]interface{}{
+			"cryptSharedLibPath":     o.CryptSharedLibPath,
+			"cryptSharedLibRequired": true,
+		})
+	}
+	if o.BypassAutoEncryption {
+		opts.SetBypassAutoEncryption(true)
+	}
+	if len(o.KMSTLS) > 0 {
+		opts.SetTLSConfig(o.KMSTLS)
+	}
+	return opts, nil
+}
+
+// DataKeyOptions configures CreateDataKey
+type DataKeyOptions struct {
+	// MasterKey locates the key that encrypts the data key in the KMS, e.g.
+	// bson.M{"region": "eu-west-1", "key": keyARN} for "aws". The "local" provider takes none.
+	MasterKey interface{}
+	// AltNames name the data key, so schemas and explicit encryption can refer to it by
+	// name rather than by ID.
+	AltNames []string
+}
+
+// CreateDataKey creates a data key in the key vault, encrypted by kmsProvider, and returns
+// its ID for use as the keyId of encrypted fields in ClientOptions.Encryption.SchemaMap.
+// The client must have been created with ClientOptions.Encryption. A duplicate alt name
+// returns an errs.Conflict error.
+func (c *Client) CreateDataKey(ctx context.Context, kmsProvider string, opts DataKeyOptions) (primitive.Binary, error) {
+	if err := c.writable("mongoclient.CreateDataKey"); err != nil {
+		return primitive.Binary{}, err
+	}
+	if c.encryption == nil {
+		return primitive.Binary{}, errs.New(errs.Invalid, "CreateDataKey requires ClientOptions.Encryption")
+	}
+	ceOpts := options.ClientEncryption().
+		SetKmsProviders(c.encryption.KMSProviders).
+		SetKeyVaultNamespace(c.encryption.KeyVaultNamespace)
+	if len(c.encryption.KMSTLS) > 0 {
+		ceOpts.SetTLSConfig(c.encryption.KMSTLS)
+	}
+	ce, err := mongo.NewClientEncryption(c.Client, ceOpts)
+	if err != nil {
+		return primitive.Binary{}, errs.Wrap(err, errs.Invalid, "mongoclient.CreateDataKey", "failed to set up client encryption")
+	}
+	defer ce.Close(context.WithoutCancel(ctx))
+
+	keyOpts := options.DataKey()
+	if opts.MasterKey != nil {
+		keyOpts.SetMasterKey(opts.MasterKey)
+	}
+	if len(opts.AltNames) > 0 {
+		keyOpts.SetKeyAltNames(opts.AltNames)
+	}
+	id, err := ce.CreateDataKey(ctx, kmsProvider, keyOpts)
+	if err != nil {
+		return primitive.Binary{}, WrapError(err, "mongoclient.CreateDataKey", "failed to create data key")
+	}
+	return id, nil
+}
@@ -0,0 +1,7 @@
+//go:build !cse
+
+package mongoclient
+
+// cseEnabled reports whether the binary was built with the cse build tag, without which the
+// driver panics when encryption is configured
+const cseEnabled = false
@@ -0,0 +1,114 @@
+package mongoclient
+
+import (
+	"errors"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+)
+
+// Sentinel errors matched with errors.Is by the errors the Client returns, e.g.
+// errors.Is(err, mongoclient.ErrDuplicateKey). They complement the errs kinds, which group
+// several of them: a duplicate key and a write conflict are both errs.Conflict.
+var (
+	// ErrNotFound: no document or GridFS file matched.
+	ErrNotFound = errors.New("mongoclient: not found")
+	// ErrDuplicateKey: a write violated a unique index.
+	ErrDuplicateKey = errors.New("mongoclient: duplicate key")
+	// ErrWriteConflict: a concurrent operation or transaction modified the same document;
+	// the operation can be retried.
+	ErrWriteConflict = errors.New("mongoclient: write conflict")
+	// ErrTimeout: the operation or server selection ran out of time.
+	ErrTimeout = errors.New("mongoclient: timeout")
+	// ErrUnavailable: MongoDB could not be reached.
+	ErrUnavailable = errors.New("mongoclient: unavailable")
+	// ErrReadOnly: a write was attempted on a client created with ClientOptions.ReadOnly.
+	ErrReadOnly = errors.New("mongoclient: read-only client")
+	// ErrAlreadyProcessed: InsertIdempotent found a document with the same idempotency key.
+	ErrAlreadyProcessed = errors.New("mongoclient: already processed")
+	// ErrLockHeld: AcquireLock found the lock held by another owner.
+	ErrLockHeld = errors.New("mongoclient: lock held")
+	// ErrLockLost: a lock expired and was taken by another owner before it was refreshed.
+	ErrLockLost = errors.New("mongoclient: lock lost")
+	// ErrThrottled: ClientOptions.WriteThrottle rejected a write, or it waited too long.
+	ErrThrottled = errors.New("mongoclient: write throttled")
+)
+
+// codeWriteConflict is the server error code of a write conflict
+const codeWriteConflict = 112
+
+// sentinel returns the sentinel error that err matches, or nil
+func sentinel(err error) error {
+	var se mongo.ServerError
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments), errors.Is(err, gridfs.ErrFileNotFound):
+		return ErrNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return ErrDuplicateKey
+	case errors.As(err, &se) && se.HasErrorCode(codeWriteConflict):
+		return ErrWriteConflict
+	case mongo.IsTimeout(err):
+		return ErrTimeout
+	case mongo.IsNetworkError(err):
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// classify maps driver errors to errs kinds so callers can react without importing the driver
+func classify(err error) errs.Kind {
+	switch sentinel(err) {
+	case ErrNotFound:
+		return errs.NotFound
+	case ErrDuplicateKey, ErrWriteConflict:
+		return errs.Conflict
+	case ErrTimeout:
+		return errs.Timeout
+	case ErrUnavailable:
+		return errs.Unavailable
+	}
+	return errs.Unknown
+}
+
+// driverError is a driver error that also matches its sentinel error
+type driverError struct {
+	sentinel error
+	err      error
+}
+
+func (e *driverError) Error() string { return e.err.Error() }
+
+// Unwrap returns the driver error alone, since the driver follows only single-error
+// chains when it looks for labels such as TransientTransactionError
+func (e *driverError) Unwrap() error { return e.err }
+
+func (e *driverError) Is(target error) bool { return target == e.sentinel }
+
+// WrapError annotates a driver error the way the Client does: as an *errs.Error of the
+// matching kind, with op and message, that also matches the sentinel errors such as
+// ErrNotFound. Other errors keep their kind, e.g. context errors become errs.Timeout or
+// errs.Canceled. It is meant for adapters that use the driver directly. It returns nil if
+// err is nil.
+func WrapError(err error, op, message string) error {
+	if err == nil {
+		return nil
+	}
+	cause := err
+	if s := sentinel(err); s != nil {
+		cause = &driverError{sentinel: s, err: err}
+	}
+	return errs.Wrap(cause, classify(err), op, message)
+}
+
+// Found turns the error of QueryOne, QueryMongoDBStruct or a find-and-modify operation into
+// found=false for a missing document, so callers that expect misses need not inspect the
+// error:
+//
+//	found, err := mongoclient.Found(client.QueryOne(ctx, params, &user))
+func Found(err error) (bool, error) {
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
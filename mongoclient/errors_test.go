@@ -0,0 +1,68 @@
+package mongoclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestWrapErrorKeepsDriverLabels(t *testing.T) {
+	conflict := mongo.CommandError{Code: codeWriteConflict, Name: "WriteConflict", Labels: []string{"TransientTransactionError"}}
+	network := mongo.CommandError{Code: 6, Labels: []string{"NetworkError"}}
+
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+		kind     errs.Kind
+		label    string
+	}{
+		{"write conflict", conflict, ErrWriteConflict, errs.Conflict, "TransientTransactionError"},
+		{"network error", network, ErrUnavailable, errs.Unavailable, "NetworkError"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WrapError(tt.err, "mongoclient.Test", "failed")
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("errors.Is(%v) = false", tt.sentinel)
+			}
+			if kind := errs.KindOf(err); kind != tt.kind {
+				t.Errorf("kind = %v, want %v", kind, tt.kind)
+			}
+			var ce mongo.CommandError
+			if !errors.As(err, &ce) {
+				t.Fatal("errors.As(mongo.CommandError) = false")
+			}
+			// session.WithTransaction retries on labels found by the driver's own walk,
+			// which follows only Unwrap() error
+			var se mongo.ServerError
+			if !errors.As(err, &se) || !se.HasErrorLabel(tt.label) {
+				t.Errorf("label %s lost", tt.label)
+			}
+			if !hasLabel(err, tt.label) {
+				t.Errorf("label %s not reachable through Unwrap() error", tt.label)
+			}
+		})
+	}
+
+	if !mongo.IsNetworkError(WrapError(network, "mongoclient.Test", "failed")) {
+		t.Error("mongo.IsNetworkError = false after WrapError")
+	}
+}
+
+// hasLabel walks err like the driver's errorHasLabel
+func hasLabel(err error, label string) bool {
+	for err != nil {
+		if le, ok := err.(mongo.LabeledError); ok && le.HasErrorLabel(label) {
+			return true
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = u.Unwrap()
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package mongoclient
+
+import (
+	"context"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Verbosities of Explain, from the cheapest to the most detailed
+const (
+	// ExplainQueryPlanner returns the winning plan without running the query.
+	ExplainQueryPlanner = "queryPlanner"
+	// ExplainExecutionStats also runs the winning plan and reports the documents and index
+	// keys it examined and how long it took.
+	ExplainExecutionStats = "executionStats"
+	// ExplainAllPlansExecution also reports the partial execution of the rejected plans.
+	ExplainAllPlansExecution = "allPlansExecution"
+)
+
+// Explain returns the server's query plan for the find that QueryMany would run with params,
+// including its sort, limit, skip, projection, hint, collation and soft-delete scoping, e.g. to check in
+// staging that a query uses the expected index:
+//
+//	plan, err := client.Explain(ctx, params, mongoclient.ExplainExecutionStats)
+//	stats := plan["executionStats"].(bson.M) // totalDocsExamined, totalKeysExamined, ...
+//
+// An empty verbosity means ExplainQueryPlanner. The executionStats verbosities run the
+// query, so they cost as much as the query itself. The reply is returned as the server
+// sent it; its layout varies between server versions and topologies.
+func (c *Client) Explain(ctx context.Context, params QueryParams, verbosity string) (bson.M, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	switch verbosity {
+	case "":
+		verbosity = ExplainQueryPlanner
+	case ExplainQueryPlanner, ExplainExecutionStats, ExplainAllPlansExecution:
+	default:
+		return nil, errs.New(errs.Invalid, "unknown explain verbosity").With("verbosity", verbosity)
+	}
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	find := bson.D{
+		{Key: "find", Value: params.Collection},
+		{Key: "filter", Value: filterOrAll(params.Filter)},
+	}
+	o := params.Options
+	if len(o.Sort) > 0 {
+		find = append(find, bson.E{Key: "sort", Value: o.Sort})
+	}
+	if o.Limit > 0 {
+		find = append(find, bson.E{Key: "limit", Value: o.Limit})
+	}
+	if o.Skip > 0 {
+		find = append(find, bson.E{Key: "skip", Value: o.Skip})
+	}
+	if len(o.Projection) > 0 {
+		find = append(find, bson.E{Key: "projection", Value: o.Projection})
+	}
+	if o.Hint != nil {
+		find = append(find, bson.E{Key: "hint", Value: o.Hint})
+	}
+	if o.Collation != nil {
+		find = append(find, bson.E{Key: "collation", Value: o.Collation})
+	}
+	cmd := bson.D{{Key: "explain", Value: find}, {Key: "verbosity", Value: verbosity}}
+
+	opts := options.RunCmd()
+	if params.Read.Preference != nil {
+		opts.SetReadPreference(params.Read.Preference)
+	}
+	var plan bson.M
+	err = c.retry(ctx, func() error {
+		return c.Database(params.Database).RunCommand(ctx, cmd, opts).Decode(&plan)
+	})
+	if err != nil {
+		return nil, WrapError(err, "mongoclient.Explain", "failed to explain query")
+	}
+	return plan, nil
+}
@@ -0,0 +1,206 @@
+package mongoclient
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/wiremessage"
+)
+
+// command is a command received by a fakeServer, with the documents of its OP_MSG
+// document sequences, such as the statements of an update or delete, under their identifiers
+type command struct {
+	Name       string
+	Database   string
+	Collection string
+	Body       bson.Raw
+	Sequences  map[string][]bson.Raw
+}
+
+// fakeServer is a standalone MongoDB server that acknowledges every command and records
+// the ones a test sends, to check what the client puts on the wire without a real server
+type fakeServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	commands []command
+}
+
+// newFakeClient starts a fakeServer and connects a Client with opts to it
+func newFakeClient(t *testing.T, opts ClientOptions) (*Client, *fakeServer) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+
+	opts.URI = "mongodb://" + ln.Addr().String() + "/?directConnection=true"
+	opts.ConnectTimeout = 5 * time.Second
+	c, err := NewClient(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close(context.Background()) })
+	return c, s
+}
+
+// take returns the commands received since the last call
+func (s *fakeServer) take() []command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmds := s.commands
+	s.commands = nil
+	return cmds
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		wm := make([]byte, binary.LittleEndian.Uint32(size[:]))
+		copy(wm, size[:])
+		if _, err := io.ReadFull(conn, wm[4:]); err != nil {
+			return
+		}
+		_, requestID, _, opcode, rem, ok := wiremessage.ReadHeader(wm)
+		if !ok {
+			return
+		}
+
+		var reply []byte
+		switch opcode {
+		case wiremessage.OpQuery:
+			// The driver sends its first hello as OP_QUERY
+			reply = opReply(requestID, s.reply(command{Name: "hello"}))
+		case wiremessage.OpMsg:
+			cmd, ok := readMsg(rem)
+			if !ok {
+				return
+			}
+			reply = opMsg(requestID, s.reply(cmd))
+		default:
+			return
+		}
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+// reply records cmd and returns an acknowledgment that fits its kind
+func (s *fakeServer) reply(cmd command) bson.D {
+	switch cmd.Name {
+	case "hello", "isMaster", "ismaster":
+		return bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "isWritablePrimary", Value: true},
+			{Key: "ismaster", Value: true},
+			{Key: "helloOk", Value: true},
+			{Key: "minWireVersion", Value: 0},
+			{Key: "maxWireVersion", Value: 21},
+			{Key: "maxBsonObjectSize", Value: 16 * 1024 * 1024},
+			{Key: "maxMessageSizeBytes", Value: 48000000},
+			{Key: "maxWriteBatchSize", Value: 100000},
+			{Key: "logicalSessionTimeoutMinutes", Value: 30},
+		}
+	case "ping", "endSessions":
+		return bson.D{{Key: "ok", Value: 1}}
+	}
+
+	s.mu.Lock()
+	s.commands = append(s.commands, cmd)
+	s.mu.Unlock()
+	switch cmd.Name {
+	case "update":
+		return bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+	case "findAndModify":
+		return bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{{Key: "_id", Value: 1}}},
+			{Key: "lastErrorObject", Value: bson.D{{Key: "n", Value: 1}}},
+		}
+	default:
+		return bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}}
+	}
+}
+
+// readMsg reads the command in the body of an OP_MSG
+func readMsg(rem []byte) (command, bool) {
+	cmd := command{Sequences: map[string][]bson.Raw{}}
+	_, rem, ok := wiremessage.ReadMsgFlags(rem)
+	for ok && len(rem) > 0 {
+		var stype wiremessage.SectionType
+		stype, rem, ok = wiremessage.ReadMsgSectionType(rem)
+		if !ok {
+			break
+		}
+		switch stype {
+		case wiremessage.SingleDocument:
+			var doc bsoncore.Document
+			doc, rem, ok = wiremessage.ReadMsgSectionSingleDocument(rem)
+			cmd.Body = bson.Raw(doc)
+		case wiremessage.DocumentSequence:
+			var id string
+			var docs []bsoncore.Document
+			id, docs, rem, ok = wiremessage.ReadMsgSectionDocumentSequence(rem)
+			for _, d := range docs {
+				cmd.Sequences[id] = append(cmd.Sequences[id], bson.Raw(d))
+			}
+		default:
+			return cmd, false
+		}
+	}
+	if !ok || cmd.Body == nil {
+		return cmd, false
+	}
+	elems, err := cmd.Body.Elements()
+	if err != nil || len(elems) == 0 {
+		return cmd, false
+	}
+	cmd.Name = elems[0].Key()
+	cmd.Collection, _ = elems[0].Value().StringValueOK()
+	cmd.Database, _ = cmd.Body.Lookup("$db").StringValueOK()
+	return cmd, true
+}
+
+func opReply(responseTo int32, doc bson.D) []byte {
+	body, _ := bson.Marshal(doc)
+	idx, dst := wiremessage.AppendHeaderStart(nil, wiremessage.NextRequestID(), responseTo, wiremessage.OpReply)
+	dst = wiremessage.AppendReplyFlags(dst, 0)
+	dst = wiremessage.AppendReplyCursorID(dst, 0)
+	dst = wiremessage.AppendReplyStartingFrom(dst, 0)
+	dst = wiremessage.AppendReplyNumberReturned(dst, 1)
+	dst = append(dst, body...)
+	return bsoncore.UpdateLength(dst, idx, int32(len(dst[idx:])))
+}
+
+func opMsg(responseTo int32, doc bson.D) []byte {
+	body, _ := bson.Marshal(doc)
+	idx, dst := wiremessage.AppendHeaderStart(nil, wiremessage.NextRequestID(), responseTo, wiremessage.OpMsg)
+	dst = wiremessage.AppendMsgFlags(dst, 0)
+	dst = wiremessage.AppendMsgSectionType(dst, wiremessage.SingleDocument)
+	dst = append(dst, body...)
+	return bsoncore.UpdateLength(dst, idx, int32(len(dst[idx:])))
+}
@@ -0,0 +1,207 @@
+package mongoclient
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Filter is a query filter built from conditions such as Eq, In and Gt, so callers do not
+// spell out operators in bson.M maps. Conditions chained on a Filter must all match:
+//
+//	f := mongoclient.Eq("status", "active").Gte("age", 18).In("role", "admin", "owner")
+//	params := mongoclient.QueryParams{Database: "mydb", Collection: "users", Filter: f.M()}
+//
+// The zero Filter matches every document.
+type Filter struct {
+	d bson.D
+}
+
+// Eq matches documents whose field equals value, or whose array field contains it
+func Eq(field string, value interface{}) Filter { return Filter{}.Eq(field, value) }
+
+// Ne matches documents whose field does not equal value, including those without the field
+func Ne(field string, value interface{}) Filter { return Filter{}.Ne(field, value) }
+
+// Gt matches documents whose field is greater than value
+func Gt(field string, value interface{}) Filter { return Filter{}.Gt(field, value) }
+
+// Gte matches documents whose field is greater than or equal to value
+func Gte(field string, value interface{}) Filter { return Filter{}.Gte(field, value) }
+
+// Lt matches documents whose field is less than value
+func Lt(field string, value interface{}) Filter { return Filter{}.Lt(field, value) }
+
+// Lte matches documents whose field is less than or equal to value
+func Lte(field string, value interface{}) Filter { return Filter{}.Lte(field, value) }
+
+// In matches documents whose field equals one of values
+func In(field string, values ...interface{}) Filter { return Filter{}.In(field, values...) }
+
+// Nin matches documents whose field equals none of values
+func Nin(field string, values ...interface{}) Filter { return Filter{}.Nin(field, values...) }
+
+// Exists matches documents that have field, or lack it when exists is false
+func Exists(field string, exists bool) Filter { return Filter{}.Exists(field, exists) }
+
+// Regex matches documents whose string field matches pattern, with regex options such
+// as "i" for case-insensitive matching
+func Regex(field, pattern, options string) Filter { return Filter{}.Regex(field, pattern, options) }
+
+// ElemMatch matches documents whose array field has an element matching every condition
+// of elem, e.g. ElemMatch("items", Eq("sku", "A1").Gte("qty", 2))
+func ElemMatch(field string, elem Filter) Filter { return Filter{}.ElemMatch(field, elem) }
+
+// And matches documents that match every filter
+func And(filters ...Filter) Filter { return Filter{}.And(filters...) }
+
+// Or matches documents that match at least one filter
+func Or(filters ...Filter) Filter { return Filter{}.Or(filters...) }
+
+// Nor matches documents that match none of the filters
+func Nor(filters ...Filter) Filter { return Filter{}.Nor(filters...) }
+
+// Eq adds the condition of the Eq function
+func (f Filter) Eq(field string, value interface{}) Filter { return f.op(field, "$eq", value) }
+
+// Ne adds the condition of the Ne function
+func (f Filter) Ne(field string, value interface{}) Filter { return f.op(field, "$ne", value) }
+
+// Gt adds the condition of the Gt function
+func (f Filter) Gt(field string, value interface{}) Filter { return f.op(field, "$gt", value) }
+
+// Gte adds the condition of the Gte function
+func (f Filter) Gte(field string, value interface{}) Filter { return f.op(field, "$gte", value) }
+
+// Lt adds the condition of the Lt function
+func (f Filter) Lt(field string, value interface{}) Filter { return f.op(field, "$lt", value) }
+
+// Lte adds the condition of the Lte function
+func (f Filter) Lte(field string, value interface{}) Filter { return f.op(field, "$lte", value) }
+
+// In adds the condition of the In function
+func (f Filter) In(field string, values ...interface{}) Filter {
+	return f.op(field, "$in", bson.A(values))
+}
+
+// Nin adds the condition of the Nin function
+func (f Filter) Nin(field string, values ...interface{}) Filter {
+	return f.op(field, "$nin", bson.A(values))
+}
+
+// Exists adds the condition of the Exists function
+func (f Filter) Exists(field string, exists bool) Filter { return f.op(field, "$exists", exists) }
+
+// Regex adds the condition of the Regex function
+func (f Filter) Regex(field, pattern, options string) Filter {
+	return f.op(field, "$regex", primitive.Regex{Pattern: pattern, Options: options})
+}
+
+// ElemMatch adds the condition of the ElemMatch function
+func (f Filter) ElemMatch(field string, elem Filter) Filter {
+	return f.op(field, "$elemMatch", elem.D())
+}
+
+// And adds the condition of the And function
+func (f Filter) And(filters ...Filter) Filter {
+	for _, g := range filters {
+		for _, e := range g.d {
+			f = f.add(e)
+		}
+	}
+	return f
+}
+
+// Or adds the condition of the Or function
+func (f Filter) Or(filters ...Filter) Filter { return f.logical("$or", filters) }
+
+// Nor adds the condition of the Nor function
+func (f Filter) Nor(filters ...Filter) Filter { return f.logical("$nor", filters) }
+
+// D returns the filter as a bson.D, e.g. for the driver or Aggregate $match stages
+func (f Filter) D() bson.D {
+	d := make(bson.D, len(f.d))
+	copy(d, f.d)
+	return d
+}
+
+// M returns the filter as a bson.M for QueryParams.Filter
+func (f Filter) M() bson.M {
+	m := make(bson.M, len(f.d))
+	for _, e := range f.d {
+		m[e.Key] = e.Value
+	}
+	return m
+}
+
+// MarshalBSON encodes the filter as its document, so a Filter can be nested in other
+// filters and pipeline stages
+func (f Filter) MarshalBSON() ([]byte, error) {
+	return bson.Marshal(f.D())
+}
+
+func (f Filter) op(field, op string, value interface{}) Filter {
+	return f.add(bson.E{Key: field, Value: bson.D{{Key: op, Value: value}}})
+}
+
+func (f Filter) logical(op string, filters []Filter) Filter {
+	clauses := make(bson.A, len(filters))
+	for i, g := range filters {
+		clauses[i] = g.D()
+	}
+	return f.add(bson.E{Key: op, Value: clauses})
+}
+
+// add adds a condition without modifying f. Operators on a field that already has
+// conditions are merged into its operator document, e.g. {age: {$gte: 18, $lt: 65}};
+// a repeated operator or logical clause moves to an $and, so no condition is lost.
+func (f Filter) add(e bson.E) Filter {
+	d := make(bson.D, 0, len(f.d)+1)
+	merged := false
+	for _, x := range f.d {
+		if !merged && x.Key == e.Key {
+			if ops, ok := mergeOps(x.Value, e.Value); ok {
+				x.Value, merged = ops, true
+			}
+		}
+		d = append(d, x)
+	}
+	if merged {
+		return Filter{d: d}
+	}
+	for _, x := range d {
+		if x.Key == e.Key {
+			return Filter{d: addAnd(d, e)}
+		}
+	}
+	return Filter{d: append(d, e)}
+}
+
+// mergeOps merges two operator documents when they share no operator
+func mergeOps(a, b interface{}) (bson.D, bool) {
+	x, ok := a.(bson.D)
+	y, ok2 := b.(bson.D)
+	if !ok || !ok2 {
+		return nil, false
+	}
+	for _, ex := range x {
+		for _, ey := range y {
+			if ex.Key == ey.Key {
+				return nil, false
+			}
+		}
+	}
+	return append(append(bson.D{}, x...), y...), true
+}
+
+// addAnd appends e as a clause of the $and of d, creating it if needed
+func addAnd(d bson.D, e bson.E) bson.D {
+	clause := bson.D{e}
+	for i, x := range d {
+		if x.Key == "$and" {
+			clauses, _ := x.Value.(bson.A)
+			d[i].Value = append(append(bson.A{}, clauses...), clause)
+			return d
+		}
+	}
+	return append(d, bson.E{Key: "$and", Value: bson.A{clause}})
+}
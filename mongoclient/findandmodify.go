@@ -0,0 +1,165 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindAndModifyOptions controls the atomic find-and-modify operations
+type FindAndModifyOptions struct {
+	// ReturnAfter returns the document as it is after the change. By default the document
+	// is returned as it was before. Ignored by FindOneAndDelete.
+	ReturnAfter bool
+	// Upsert inserts a document when nothing matches. Combine it with ReturnAfter, since
+	// there is no document from before an insert. Ignored by FindOneAndDelete.
+	Upsert bool
+	// Sort picks the document to modify when several match, e.g. the oldest pending job.
+	Sort bson.D
+	// Projection selects the returned fields.
+	Projection bson.M
+}
+
+func (o FindAndModifyOptions) returnDocument() options.ReturnDocument {
+	if o.ReturnAfter {
+		return options.After
+	}
+	return options.Before
+}
+
+// FindOneAndUpdate atomically updates the first document matching the filter in QueryParams
+// and decodes it into result. It returns an errs.NotFound error when there is no document
+// to return.
+func (c *Client) FindOneAndUpdate(ctx context.Context, params QueryParams, update interface{}, opts FindAndModifyOptions, result interface{}) error {
+	if err := c.writable("mongoclient.FindOneAndUpdate"); err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.FindOneAndUpdate")
+	if err != nil {
+		return err
+	}
+	defer release()
+	params = c.scope(params)
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return err
+	}
+	update, err = c.timestamps.StampUpdate(update)
+	if err != nil {
+		return errs.Wrap(err, errs.Invalid, "mongoclient.FindOneAndUpdate", "failed to encode update")
+	}
+	findOpts := options.FindOneAndUpdate().SetReturnDocument(opts.returnDocument()).SetUpsert(opts.Upsert)
+	if len(opts.Sort) > 0 {
+		findOpts.SetSort(opts.Sort)
+	}
+	if len(opts.Projection) > 0 {
+		findOpts.SetProjection(opts.Projection)
+	}
+
+	collection := c.collection(params)
+	err = c.retry(ctx, func() error {
+		return collection.FindOneAndUpdate(ctx, filterOrAll(params.Filter), update, findOpts).Decode(result)
+	})
+	return findAndModifyError(err, "mongoclient.FindOneAndUpdate", "failed to find and update document")
+}
+
+// FindOneAndReplace atomically replaces the first document matching the filter in QueryParams
+// and decodes it into result. It returns an errs.NotFound error when there is no document
+// to return.
+func (c *Client) FindOneAndReplace(ctx context.Context, params QueryParams, replacement interface{}, opts FindAndModifyOptions, result interface{}) error {
+	if err := c.writable("mongoclient.FindOneAndReplace"); err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.FindOneAndReplace")
+	if err != nil {
+		return err
+	}
+	defer release()
+	params = c.scope(params)
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return err
+	}
+	replacement, err = c.timestamps.StampReplacement(replacement)
+	if err != nil {
+		return errs.Wrap(err, errs.Invalid, "mongoclient.FindOneAndReplace", "failed to encode replacement")
+	}
+	findOpts := options.FindOneAndReplace().SetReturnDocument(opts.returnDocument()).SetUpsert(opts.Upsert)
+	if len(opts.Sort) > 0 {
+		findOpts.SetSort(opts.Sort)
+	}
+	if len(opts.Projection) > 0 {
+		findOpts.SetProjection(opts.Projection)
+	}
+
+	collection := c.collection(params)
+	err = c.retry(ctx, func() error {
+		return collection.FindOneAndReplace(ctx, filterOrAll(params.Filter), replacement, findOpts).Decode(result)
+	})
+	return findAndModifyError(err, "mongoclient.FindOneAndReplace", "failed to find and replace document")
+}
+
+// FindOneAndDelete atomically deletes the first document matching the filter in QueryParams
+// and decodes the deleted document into result. It returns an errs.NotFound error when
+// nothing matches. In a soft-delete collection the document is marked as deleted instead,
+// see ClientOptions.SoftDelete.
+func (c *Client) FindOneAndDelete(ctx context.Context, params QueryParams, opts FindAndModifyOptions, result interface{}) error {
+	if err := c.writable("mongoclient.FindOneAndDelete"); err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.FindOneAndDelete")
+	if err != nil {
+		return err
+	}
+	defer release()
+	// SoftDelete names collections as callers do, so match it before tenant routing
+	soft := c.softDelete.Applies(params)
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return err
+	}
+	findOpts := options.FindOneAndDelete()
+	if len(opts.Sort) > 0 {
+		findOpts.SetSort(opts.Sort)
+	}
+	if len(opts.Projection) > 0 {
+		findOpts.SetProjection(opts.Projection)
+	}
+
+	collection := c.collection(params)
+	if soft {
+		update, err := c.timestamps.StampUpdate(c.softDelete.markDeleted())
+		if err != nil {
+			return errs.Wrap(err, errs.Invalid, "mongoclient.FindOneAndDelete", "failed to encode update")
+		}
+		updateOpts := options.FindOneAndUpdate().SetSort(findOpts.Sort).SetProjection(findOpts.Projection)
+		err = c.retry(ctx, func() error {
+			return collection.FindOneAndUpdate(ctx, c.softDelete.Exclude(params.Filter), update, updateOpts).Decode(result)
+		})
+		return findAndModifyError(err, "mongoclient.FindOneAndDelete", "failed to find and soft-delete document")
+	}
+	err = c.retry(ctx, func() error {
+		return collection.FindOneAndDelete(ctx, filterOrAll(params.Filter), findOpts).Decode(result)
+	})
+	return findAndModifyError(err, "mongoclient.FindOneAndDelete", "failed to find and delete document")
+}
+
+func findAndModifyError(err error, op, message string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return WrapError(err, op, "no documents found")
+	}
+	return WrapError(err, op, message)
+}
@@ -0,0 +1,155 @@
+package mongoclient
+
+import (
+	"context"
+	"io"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BucketParams identifies a GridFS bucket, which stores files in the collections
+// <Bucket>.files and <Bucket>.chunks
+type BucketParams struct {
+	Database string
+	// Bucket defaults to "fs".
+	Bucket string
+}
+
+// UploadOptions configures UploadStream
+type UploadOptions struct {
+	// Metadata is stored with the file and can be filtered on by ListFiles, e.g.
+	// bson.M{"content_type": "application/pdf", "owner": userID}.
+	Metadata interface{}
+	// ChunkSize is the size of the chunks the file is split into. Defaults to 255KiB.
+	ChunkSize int32
+}
+
+// bucket opens the GridFS bucket in params. The driver bounds bucket operations with
+// deadlines rather than contexts, so the deadline of ctx is applied to the bucket.
+func (c *Client) bucket(ctx context.Context, params BucketParams) (*gridfs.Bucket, error) {
+	if params.Bucket == "" {
+		params.Bucket = "fs"
+	}
+	// A tenant gets its own bucket, named like a collection of the tenant
+	ns, err := c.Resolve(ctx, Namespace{Database: params.Database, Collection: params.Bucket})
+	if err != nil {
+		return nil, err
+	}
+	b, err := gridfs.NewBucket(c.Database(ns.Database), options.GridFSBucket().SetName(ns.Collection))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		b.SetReadDeadline(deadline)
+		b.SetWriteDeadline(deadline)
+	}
+	return b, nil
+}
+
+// UploadStream stores the contents of source as a new file and returns its ID. Files are
+// never overwritten: uploading the same filename again adds a revision, and downloads by
+// ID are unaffected. The upload is aborted, leaving no partial file, when reading source
+// fails or ctx ends.
+func (c *Client) UploadStream(ctx context.Context, params BucketParams, filename string, source io.Reader, opts UploadOptions) (primitive.ObjectID, error) {
+	if err := c.writable("mongoclient.UploadStream"); err != nil {
+		return primitive.NilObjectID, err
+	}
+	b, err := c.bucket(ctx, params)
+	if err != nil {
+		return primitive.NilObjectID, errs.Wrap(err, errs.Unknown, "mongoclient.UploadStream", "failed to open bucket")
+	}
+	uploadOpts := options.GridFSUpload()
+	if opts.Metadata != nil {
+		uploadOpts.SetMetadata(opts.Metadata)
+	}
+	if opts.ChunkSize > 0 {
+		uploadOpts.SetChunkSizeBytes(opts.ChunkSize)
+	}
+
+	id, err := b.UploadFromStream(filename, contextReader{ctx: ctx, r: source}, uploadOpts)
+	if err != nil {
+		return primitive.NilObjectID, WrapError(err, "mongoclient.UploadStream", "failed to upload file")
+	}
+	return id, nil
+}
+
+// DownloadStream writes the contents of the file with fileID to w and returns the number of
+// bytes written. It returns an errs.NotFound error when there is no such file.
+func (c *Client) DownloadStream(ctx context.Context, params BucketParams, fileID interface{}, w io.Writer) (int64, error) {
+	b, err := c.bucket(ctx, params)
+	if err != nil {
+		return 0, errs.Wrap(err, errs.Unknown, "mongoclient.DownloadStream", "failed to open bucket")
+	}
+	stream, err := b.OpenDownloadStream(fileID)
+	if err != nil {
+		return 0, WrapError(err, "mongoclient.DownloadStream", "failed to open file")
+	}
+	defer stream.Close()
+
+	n, err := io.Copy(w, contextReader{ctx: ctx, r: stream})
+	if err != nil {
+		return n, WrapError(err, "mongoclient.DownloadStream", "failed to download file")
+	}
+	return n, nil
+}
+
+// DeleteFile deletes the file with fileID and its chunks. It returns an errs.NotFound error
+// when there is no such file.
+func (c *Client) DeleteFile(ctx context.Context, params BucketParams, fileID interface{}) error {
+	if err := c.writable("mongoclient.DeleteFile"); err != nil {
+		return err
+	}
+	b, err := c.bucket(ctx, params)
+	if err != nil {
+		return errs.Wrap(err, errs.Unknown, "mongoclient.DeleteFile", "failed to open bucket")
+	}
+	err = c.retry(ctx, func() error {
+		return b.DeleteContext(ctx, fileID)
+	})
+	if err != nil {
+		return WrapError(err, "mongoclient.DeleteFile", "failed to delete file")
+	}
+	return nil
+}
+
+// ListFiles returns the files matching filter, newest first. The filter applies to the
+// file documents, e.g. bson.M{"filename": "report.pdf"} or bson.M{"metadata.owner": userID};
+// a nil filter lists every file.
+func (c *Client) ListFiles(ctx context.Context, params BucketParams, filter bson.M) ([]gridfs.File, error) {
+	b, err := c.bucket(ctx, params)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Unknown, "mongoclient.ListFiles", "failed to open bucket")
+	}
+	findOpts := options.GridFSFind().SetSort(bson.D{{Key: "uploadDate", Value: -1}})
+
+	var files []gridfs.File
+	err = c.retry(ctx, func() error {
+		cursor, err := b.FindContext(ctx, filterOrAll(filter), findOpts)
+		if err != nil {
+			return err
+		}
+		files = nil
+		return cursor.All(ctx, &files) // All closes the cursor
+	})
+	if err != nil {
+		return nil, WrapError(err, "mongoclient.ListFiles", "failed to list files")
+	}
+	return files, nil
+}
+
+// contextReader fails reads once ctx is done, so streaming stops between chunks
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
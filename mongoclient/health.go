@@ -0,0 +1,86 @@
+package mongoclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// defaultHealthTimeout bounds a health check unless ctx has an earlier deadline
+const defaultHealthTimeout = 2 * time.Second
+
+// Topology kinds reported in HealthStatus
+const (
+	TopologyStandalone = "standalone"
+	TopologyReplicaSet = "replica set"
+	TopologySharded    = "sharded"
+)
+
+// HealthStatus describes the deployment as seen by a health check
+type HealthStatus struct {
+	// Latency is the round trip of the hello command.
+	Latency time.Duration `json:"latency_ns"`
+	// Topology is TopologyStandalone, TopologyReplicaSet or TopologySharded.
+	Topology string `json:"topology"`
+	// ReplicaSet and Primary are set for replica sets.
+	ReplicaSet string `json:"replica_set,omitempty"`
+	Primary    string `json:"primary,omitempty"`
+	// Hosts lists the data-bearing members of a replica set.
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// hello is the part of the hello reply that HealthStatus reports
+type hello struct {
+	SetName string   `bson:"setName"`
+	Primary string   `bson:"primary"`
+	Hosts   []string `bson:"hosts"`
+	Msg     string   `bson:"msg"`
+}
+
+// Health reports whether the primary is reachable, e.g. for a readiness probe. It returns
+// an errs.Unavailable or errs.Timeout error otherwise. The check gives up after the client's
+// HealthTimeout when ctx has no earlier deadline. Its signature matches health.CheckFunc:
+//
+//	registry.Register(health.Check{Name: "mongodb", Func: client.Health, Critical: true})
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.HealthStatus(ctx)
+	return err
+}
+
+// HealthStatus runs the same check as Health and also returns the topology of the deployment
+func (c *Client) HealthStatus(ctx context.Context) (HealthStatus, error) {
+	timeout := c.healthTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var reply hello
+	err := c.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}},
+		options.RunCmd().SetReadPreference(readpref.Primary())).Decode(&reply)
+	if err != nil {
+		kind := classify(err)
+		if kind == errs.Unknown {
+			kind = errs.Unavailable
+		}
+		return HealthStatus{}, errs.Wrap(err, kind, "mongoclient.Health", "MongoDB is unreachable")
+	}
+
+	status := HealthStatus{Latency: time.Since(start), Topology: TopologyStandalone}
+	switch {
+	case reply.Msg == "isdbgrid":
+		status.Topology = TopologySharded
+	case reply.SetName != "":
+		status.Topology = TopologyReplicaSet
+		status.ReplicaSet = reply.SetName
+		status.Primary = reply.Primary
+		status.Hosts = reply.Hosts
+	}
+	return status, nil
+}
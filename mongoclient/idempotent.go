@@ -0,0 +1,91 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// IdempotencyKeyField is the field InsertIdempotent stores the idempotency key in
+const IdempotencyKeyField = "idempotency_key"
+
+// idempotencyIndex is the name of the unique index created by EnsureIdempotencyIndex
+const idempotencyIndex = "idempotency_key_unique"
+
+// EnsureIdempotencyIndex ensures the unique index on IdempotencyKeyField that
+// InsertIdempotent relies on. Documents without a key are not indexed, so the collection
+// can also hold documents inserted otherwise. Like EnsureIndexes it is safe to call on
+// every startup.
+func (c *Client) EnsureIdempotencyIndex(ctx context.Context, database, collection string) error {
+	return c.EnsureIndexes(ctx, database, collection, []IndexSpec{{
+		Keys:          bson.D{{Key: IdempotencyKeyField, Value: 1}},
+		Name:          idempotencyIndex,
+		Unique:        true,
+		PartialFilter: bson.M{IdempotencyKeyField: bson.M{"$exists": true}},
+	}})
+}
+
+// InsertIdempotent inserts document with key stored in IdempotencyKeyField, e.g. a message
+// ID, and returns an error matching ErrAlreadyProcessed when a document with the same key
+// exists, so a consumer that receives a message twice writes its effect once:
+//
+//	_, err := client.InsertIdempotent(ctx, params, msg.ID, payment)
+//	if errors.Is(err, mongoclient.ErrAlreadyProcessed) {
+//	    return nil // redelivery; acknowledge it
+//	}
+//
+// The collection needs the index created by EnsureIdempotencyIndex; without it duplicates
+// are inserted.
+func (c *Client) InsertIdempotent(ctx context.Context, params QueryParams, key string, document interface{}) (*mongo.InsertOneResult, error) {
+	doc, err := WithIdempotencyKey(document, key)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Unknown, "mongoclient.InsertIdempotent", "failed to encode document")
+	}
+	result, err := c.InsertOne(ctx, params, doc)
+	if duplicateIdempotencyKey(err) {
+		return nil, errs.Wrap(ErrAlreadyProcessed, errs.Conflict, "mongoclient.InsertIdempotent", "idempotency key already processed")
+	}
+	return result, err
+}
+
+// WithIdempotencyKey returns document as a bson.D with key in IdempotencyKeyField,
+// replacing any key it already holds. It is meant for Store implementations.
+func WithIdempotencyKey(document interface{}, key string) (bson.D, error) {
+	if key == "" {
+		return nil, errs.New(errs.Invalid, "idempotency key is empty")
+	}
+	doc, err := toD(document)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclient.WithIdempotencyKey", "failed to encode document")
+	}
+	out := make(bson.D, 0, len(doc)+1)
+	for _, e := range doc {
+		if e.Key != IdempotencyKeyField {
+			out = append(out, e)
+		}
+	}
+	return append(out, bson.E{Key: IdempotencyKeyField, Value: key}), nil
+}
+
+// duplicateIdempotencyKey reports whether err is a duplicate key error on
+// IdempotencyKeyField rather than on another unique index such as _id
+func duplicateIdempotencyKey(err error) bool {
+	if !errors.Is(err, ErrDuplicateKey) {
+		return false
+	}
+	var we mongo.WriteException
+	if !errors.As(err, &we) {
+		return false
+	}
+	for _, e := range we.WriteErrors {
+		if strings.Contains(e.Message, "index: "+idempotencyIndex+" ") ||
+			strings.Contains(e.Message, "dup key: { "+IdempotencyKeyField+":") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ImportOptions controls how Import splits and parallelises a load
+type ImportOptions struct {
+	// BatchSize is the number of documents per InsertMany. Defaults to 1000.
+	BatchSize int
+	// Workers is the number of batches inserted concurrently. Defaults to 4; keep it below
+	// the connection pool size.
+	Workers int
+	// StopOnError stops starting new batches after the first failed one. By default every
+	// batch is attempted.
+	StopOnError bool
+}
+
+// ImportResult summarises an Import
+type ImportResult struct {
+	// Inserted is the number of documents written.
+	Inserted int
+	// Batches is the number of batches attempted.
+	Batches int
+	// Errors holds one entry per failed batch, in batch order.
+	Errors []BatchError
+}
+
+// BatchError is the failure of one Import batch
+type BatchError struct {
+	// Batch is the index of the batch; its documents start at Offset in the input.
+	Batch  int
+	Offset int
+	// Failed is the number of documents of the batch that were not written. When the server
+	// reported no per-document errors the whole batch is counted, although some of it may
+	// have been written.
+	Failed int
+	Err    error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("batch %d (documents %d+): %v", e.Batch, e.Offset, e.Err)
+}
+
+func (e BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Import inserts documents into the collection in QueryParams in unordered batches spread
+// over a bounded number of workers, for initial loads and backfills too large for one
+// InsertMany. A failed document does not stop the rest of its batch, and a failed batch
+// does not stop the others unless StopOnError is set. The returned error joins the
+// BatchErrors and has the errs kind of the first; the result counts what was written
+// either way. Documents without an _id get one from the driver, so re-running a failed
+// import duplicates the documents that were written; give them an _id to make it
+// idempotent, in which case the rerun fails only on ErrDuplicateKey.
+func (c *Client) Import(ctx context.Context, params QueryParams, documents []interface{}, opts ImportOptions) (ImportResult, error) {
+	if err := c.writable("mongoclient.Import"); err != nil {
+		return ImportResult{}, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+
+	// StopOnError stops handing out batches; the ones in flight still finish
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	batches := make(chan int)
+	var (
+		mu     sync.Mutex
+		result ImportResult
+	)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				offset := b * opts.BatchSize
+				batch := documents[offset:min(offset+opts.BatchSize, len(documents))]
+				failed, err := c.importBatch(ctx, params, batch)
+
+				mu.Lock()
+				result.Batches++
+				result.Inserted += len(batch) - failed
+				if err != nil {
+					result.Errors = append(result.Errors, BatchError{Batch: b, Offset: offset, Failed: failed, Err: err})
+					if opts.StopOnError {
+						stopOnce.Do(func() { close(stop) })
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	n := (len(documents) + opts.BatchSize - 1) / opts.BatchSize
+send:
+	for b := 0; b < n; b++ {
+		select {
+		case batches <- b:
+		case <-stop:
+			break send
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	if len(result.Errors) == 0 {
+		if result.Batches < n {
+			return result, WrapError(ctx.Err(), "mongoclient.Import", "import interrupted")
+		}
+		return result, nil
+	}
+	// Workers finish out of order
+	sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Batch < result.Errors[j].Batch })
+	joined := make([]error, len(result.Errors))
+	for i, e := range result.Errors {
+		joined[i] = e
+	}
+	return result, errs.Wrap(errors.Join(joined...), errs.Unknown, "mongoclient.Import",
+		fmt.Sprintf("%d of %d batches failed", len(result.Errors), result.Batches))
+}
+
+// importBatch inserts one batch and returns how many of its documents were not written
+func (c *Client) importBatch(ctx context.Context, params QueryParams, batch []interface{}) (int, error) {
+	_, err := c.InsertMany(ctx, params, batch, BulkOptions{Unordered: true})
+	if err == nil {
+		return 0, nil
+	}
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) && len(bwe.WriteErrors) > 0 && bwe.WriteConcernError == nil {
+		return len(bwe.WriteErrors), err
+	}
+	return len(batch), err
+}
@@ -0,0 +1,147 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Server error codes for an index that exists with different options or keys
+const (
+	codeIndexOptionsConflict  = 85
+	codeIndexKeySpecsConflict = 86
+)
+
+// IndexSpec describes one index for EnsureIndexes
+type IndexSpec struct {
+	// Keys lists the indexed fields in order, e.g. bson.D{{Key: "tenant", Value: 1}, {Key: "created_at", Value: -1}}.
+	// More than one key makes a compound index.
+	Keys bson.D
+	// Name defaults to the server-generated name, e.g. "tenant_1_created_at_-1".
+	Name string
+	// Unique rejects documents that duplicate the indexed values.
+	Unique bool
+	// Sparse skips documents that lack the indexed fields.
+	Sparse bool
+	// TTL deletes documents this long after the time in the single indexed date field.
+	// Zero means no expiry. The server removes expired documents about once a minute.
+	TTL time.Duration
+	// ExpireAt deletes documents at the time in the single indexed date field, for fields
+	// that hold the expiry time rather than a creation time.
+	ExpireAt bool
+	// PartialFilter indexes only documents matching this filter, e.g. bson.M{"deleted_at": nil}.
+	PartialFilter bson.M
+	// Collation makes the index compare strings by language rules, e.g.
+	// CaseInsensitive("en"). Queries use it only when they set the same collation.
+	Collation *options.Collation
+}
+
+func (s IndexSpec) expires() bool {
+	return s.TTL > 0 || s.ExpireAt
+}
+
+func (s IndexSpec) model() mongo.IndexModel {
+	opts := options.Index()
+	if s.Name != "" {
+		opts.SetName(s.Name)
+	}
+	if s.Unique {
+		opts.SetUnique(true)
+	}
+	if s.Sparse {
+		opts.SetSparse(true)
+	}
+	if s.expires() {
+		opts.SetExpireAfterSeconds(int32(s.TTL / time.Second))
+	}
+	if len(s.PartialFilter) > 0 {
+		opts.SetPartialFilterExpression(s.PartialFilter)
+	}
+	if s.Collation != nil {
+		opts.SetCollation(s.Collation)
+	}
+	return mongo.IndexModel{Keys: s.Keys, Options: opts}
+}
+
+// EnsureIndexes creates the indexes described by specs on a collection. It is safe to call
+// on every startup: existing identical indexes are left alone, and a changed TTL is applied
+// in place. An index that exists with other options, e.g. no longer unique, is not touched
+// and returns an errs.Conflict error, since rebuilding it is a migration.
+func (c *Client) EnsureIndexes(ctx context.Context, database, collection string, specs []IndexSpec) error {
+	if err := c.writable("mongoclient.EnsureIndexes"); err != nil {
+		return err
+	}
+	coll := c.Database(database).Collection(collection)
+	for _, spec := range specs {
+		if len(spec.Keys) == 0 {
+			return errs.New(errs.Invalid, "index spec has no keys").With("collection", collection)
+		}
+		_, err := coll.Indexes().CreateOne(ctx, spec.model())
+		if err != nil && isIndexConflict(err) && spec.expires() {
+			// Apply the new expiry, then check that nothing else differs.
+			if err = c.updateTTL(ctx, database, collection, spec); err == nil {
+				_, err = coll.Indexes().CreateOne(ctx, spec.model())
+			}
+		}
+		switch {
+		case err == nil:
+		case isIndexConflict(err):
+			return errs.Wrap(err, errs.Conflict, "mongoclient.EnsureIndexes",
+				fmt.Sprintf("index %s on %s exists with different options", indexLabel(spec), collection))
+		default:
+			return WrapError(err, "mongoclient.EnsureIndexes", "failed to create index")
+		}
+	}
+	return nil
+}
+
+// EnsureTTL ensures a TTL index that deletes documents ttl after the time in field, e.g.
+// the last_seen of a session or the issued_at of a token. Like EnsureIndexes it is safe to
+// call on every startup and applies a changed ttl in place. Use ttl 0 when field holds the
+// expiry time itself.
+func (c *Client) EnsureTTL(ctx context.Context, database, collection, field string, ttl time.Duration) error {
+	if err := c.writable("mongoclient.EnsureTTL"); err != nil {
+		return err
+	}
+	if field == "" || ttl < 0 {
+		return errs.New(errs.Invalid, "TTL index requires a field and a non-negative expiry").With("collection", collection)
+	}
+	spec := IndexSpec{Keys: bson.D{{Key: field, Value: 1}}, TTL: ttl, ExpireAt: ttl == 0}
+	return c.EnsureIndexes(ctx, database, collection, []IndexSpec{spec})
+}
+
+// updateTTL changes the expiry of the existing index on spec.Keys
+func (c *Client) updateTTL(ctx context.Context, database, collection string, spec IndexSpec) error {
+	return c.Database(database).RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "index", Value: bson.D{
+			{Key: "keyPattern", Value: spec.Keys},
+			{Key: "expireAfterSeconds", Value: int64(spec.TTL / time.Second)},
+		}},
+	}).Err()
+}
+
+func isIndexConflict(err error) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && (se.HasErrorCode(codeIndexOptionsConflict) || se.HasErrorCode(codeIndexKeySpecsConflict))
+}
+
+func indexLabel(spec IndexSpec) string {
+	if spec.Name != "" {
+		return spec.Name
+	}
+	label := ""
+	for i, k := range spec.Keys {
+		if i > 0 {
+			label += "_"
+		}
+		label += fmt.Sprintf("%s_%v", k.Key, k.Value)
+	}
+	return label
+}
@@ -0,0 +1,192 @@
+package mongoclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LockOptions configures Locks
+type LockOptions struct {
+	Database string
+	// Collection holds one document per lock. Defaults to "locks".
+	Collection string
+}
+
+// Locks hands out named distributed locks stored in a MongoDB collection, for leader
+// election and mutual exclusion between replicas of services that already depend on
+// MongoDB. Expiry is measured with the server clock, so replicas with skewed clocks agree
+// on when a lock expires. Create one with Client.Locks.
+type Locks struct {
+	client *Client
+	params QueryParams
+}
+
+// Lock is a held lock, returned by Locks.AcquireLock
+type Lock struct {
+	// Name is the name the lock was acquired under.
+	Name string
+	// Owner is the random ID of this holder.
+	Owner string
+	// Token increases with every acquisition of the name, so a resource can reject writes
+	// from a holder that lost the lock to a later one (a fencing token).
+	Token int64
+	// ExpiresAt is when the lock expires unless refreshed, by the server clock.
+	ExpiresAt time.Time
+
+	locks *Locks
+	ttl   time.Duration
+}
+
+// lockDoc is the document of a lock
+type lockDoc struct {
+	Owner     string    `bson:"owner"`
+	Token     int64     `bson:"token"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// Locks returns the distributed locks stored in opts.Database
+func (c *Client) Locks(opts LockOptions) *Locks {
+	if opts.Collection == "" {
+		opts.Collection = "locks"
+	}
+	return &Locks{client: c, params: QueryParams{Database: opts.Database, Collection: opts.Collection}}
+}
+
+// AcquireLock takes the lock name for ttl without waiting. It returns an errs.Conflict
+// error matching ErrLockHeld while another holder has an unexpired lock. A holder that
+// needs the lock longer than ttl must Refresh it, or use KeepAlive:
+//
+//	lock, err := locks.AcquireLock(ctx, "billing-leader", 30*time.Second)
+//	if errors.Is(err, mongoclient.ErrLockHeld) {
+//	    return nil // another replica leads
+//	}
+//	defer lock.Release(context.WithoutCancel(ctx))
+func (ls *Locks) AcquireLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	if err := ls.client.writable("mongoclient.AcquireLock"); err != nil {
+		return nil, err
+	}
+	if name == "" || ttl <= 0 {
+		return nil, errs.New(errs.Invalid, "lock requires a name and a positive TTL").With("lock", name)
+	}
+	ctx, cancel := ls.client.withTimeout(ctx)
+	defer cancel()
+
+	owner := lockOwner()
+	// The upsert conflicts on _id while another holder's lock has not expired
+	filter := bson.M{"_id": name, "$expr": bson.M{"$lte": bson.A{"$expires_at", "$$NOW"}}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.D{
+		{Key: "owner", Value: owner},
+		{Key: "token", Value: bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$token", 0}}, 1}}},
+		{Key: "expires_at", Value: bson.M{"$add": bson.A{"$$NOW", ttl.Milliseconds()}}},
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var doc lockDoc
+	err := ls.client.collection(ls.params).FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, errs.Wrap(ErrLockHeld, errs.Conflict, "mongoclient.AcquireLock", "lock is held by another owner")
+	}
+	if err != nil {
+		return nil, WrapError(err, "mongoclient.AcquireLock", "failed to acquire lock")
+	}
+	return &Lock{Name: name, Owner: owner, Token: doc.Token, ExpiresAt: doc.ExpiresAt, locks: ls, ttl: ttl}, nil
+}
+
+// TryLock takes the lock name for ttl like AcquireLock, but reports a held lock as
+// ok=false, so Locks satisfies scheduler.Locker
+func (ls *Locks) TryLock(ctx context.Context, name string, ttl time.Duration) (unlock func(context.Context) error, ok bool, err error) {
+	lock, err := ls.AcquireLock(ctx, name, ttl)
+	if errors.Is(err, ErrLockHeld) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return lock.Release, true, nil
+}
+
+// Refresh extends the lock by its TTL from now. It returns an errs.Conflict error
+// matching ErrLockLost when the lock expired and was taken by another holder.
+func (l *Lock) Refresh(ctx context.Context) error {
+	c := l.locks.client
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.D{
+		{Key: "expires_at", Value: bson.M{"$add": bson.A{"$$NOW", l.ttl.Milliseconds()}}},
+	}}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var doc lockDoc
+	err := c.collection(l.locks.params).FindOneAndUpdate(ctx, bson.M{"_id": l.Name, "owner": l.Owner}, update, opts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return errs.Wrap(ErrLockLost, errs.Conflict, "mongoclient.Lock.Refresh", "lock was lost")
+	}
+	if err != nil {
+		return WrapError(err, "mongoclient.Lock.Refresh", "failed to refresh lock")
+	}
+	l.ExpiresAt = doc.ExpiresAt
+	return nil
+}
+
+// Release gives the lock up. Releasing a lock that expired or was already released is
+// not an error.
+func (l *Lock) Release(ctx context.Context) error {
+	c := l.locks.client
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	// Expire the lock instead of deleting it, so the next holder's Token is still higher
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{{Key: "expires_at", Value: "$$NOW"}}}},
+		{{Key: "$unset", Value: "owner"}},
+	}
+	_, err := c.collection(l.locks.params).UpdateOne(ctx, bson.M{"_id": l.Name, "owner": l.Owner}, update)
+	if err != nil {
+		return WrapError(err, "mongoclient.Lock.Release", "failed to release lock")
+	}
+	return nil
+}
+
+// KeepAlive refreshes the lock every third of its TTL until ctx is done, and returns a
+// context that is cancelled, with cause ErrLockLost, when the lock is lost or cannot be
+// refreshed before it expires. Run the work that needs the lock under that context, e.g.
+// the duties of an elected leader.
+func (l *Lock) KeepAlive(ctx context.Context) context.Context {
+	held, cancel := context.WithCancelCause(ctx)
+	// ExpiresAt is by the server clock; track the expiry by the local one
+	expires := time.Now().Add(l.ttl)
+	go func() {
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-held.Done():
+				return
+			case <-ticker.C:
+			}
+			start := time.Now()
+			err := l.Refresh(held)
+			if err == nil {
+				expires = start.Add(l.ttl)
+				continue
+			}
+			if errors.Is(err, ErrLockLost) || !time.Now().Before(expires) {
+				cancel(ErrLockLost)
+				return
+			}
+		}
+	}()
+	return held
+}
+
+// lockOwner returns a random owner ID
+func lockOwner() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
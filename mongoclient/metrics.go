@@ -0,0 +1,232 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// clientMetrics records command and connection pool metrics. It exports:
+//
+//	mongodb_command_duration_seconds{command,collection}     latency histogram of every command
+//	mongodb_command_errors_total{command,collection}         failed commands
+//	mongodb_pool_checkouts_total                             connections checked out of the pool
+//	mongodb_pool_checkout_failures_total{reason}             failed checkouts, e.g. reason="timeout"
+//	mongodb_pool_checkout_duration_seconds                   time spent waiting for a connection
+//	mongodb_pool_connections                                 open connections
+//	mongodb_pool_connections_in_use                          connections checked out
+//
+// With a TenantResolver the collection label is the collection named in QueryParams, not
+// the tenant's, so the number of series does not grow with the number of tenants.
+type clientMetrics struct {
+	duration         *prometheus.HistogramVec
+	errors           *prometheus.CounterVec
+	checkouts        prometheus.Counter
+	checkoutFailures *prometheus.CounterVec
+	checkoutDuration prometheus.Histogram
+	connections      prometheus.Gauge
+	inUse            prometheus.Gauge
+
+	mu      sync.Mutex
+	pending map[string]string // connection and request ID -> collection
+	logical sync.Map          // "database.collection" of a tenant -> collection before routing
+}
+
+// newClientMetrics registers the metrics against reg. Clients sharing a registry share
+// the metrics, so creating several clients is harmless.
+func newClientMetrics(reg prometheus.Registerer) (*clientMetrics, error) {
+	m := &clientMetrics{pending: make(map[string]string)}
+	var err error
+	if m.duration, err = register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mongodb_command_duration_seconds",
+		Help:    "Duration of MongoDB commands.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"command", "collection"})); err != nil {
+		return nil, err
+	}
+	if m.errors, err = register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongodb_command_errors_total",
+		Help: "MongoDB commands that failed.",
+	}, []string{"command", "collection"})); err != nil {
+		return nil, err
+	}
+	if m.checkouts, err = register(reg, prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "mongodb_pool_checkouts_total",
+		Help: "Connections checked out of the MongoDB connection pool.",
+	})); err != nil {
+		return nil, err
+	}
+	if m.checkoutFailures, err = register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongodb_pool_checkout_failures_total",
+		Help: "Failed connection checkouts from the MongoDB connection pool.",
+	}, []string{"reason"})); err != nil {
+		return nil, err
+	}
+	if m.checkoutDuration, err = register(reg, prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "mongodb_pool_checkout_duration_seconds",
+		Help:    "Time spent waiting for a connection from the MongoDB connection pool.",
+		Buckets: []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5},
+	})); err != nil {
+		return nil, err
+	}
+	if m.connections, err = register(reg, prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mongodb_pool_connections",
+		Help: "Open connections to MongoDB.",
+	})); err != nil {
+		return nil, err
+	}
+	if m.inUse, err = register(reg, prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mongodb_pool_connections_in_use",
+		Help: "Connections to MongoDB checked out of the pool.",
+	})); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// register registers c, or returns the collector already registered under the same name
+func register[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+func (m *clientMetrics) commandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			m.mu.Lock()
+			m.pending[requestKey(e.ConnectionID, e.RequestID)] = m.logicalCollection(e.DatabaseName, commandCollection(e))
+			m.mu.Unlock()
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			m.finished(&e.CommandFinishedEvent, false)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			m.finished(&e.CommandFinishedEvent, true)
+		},
+	}
+}
+
+func (m *clientMetrics) finished(e *event.CommandFinishedEvent, failed bool) {
+	key := requestKey(e.ConnectionID, e.RequestID)
+	m.mu.Lock()
+	collection := m.pending[key]
+	delete(m.pending, key)
+	m.mu.Unlock()
+
+	m.duration.WithLabelValues(e.CommandName, collection).Observe(e.Duration.Seconds())
+	if failed {
+		m.errors.WithLabelValues(e.CommandName, collection).Inc()
+	}
+}
+
+func (m *clientMetrics) poolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.GetSucceeded:
+				m.checkouts.Inc()
+				m.checkoutDuration.Observe(e.Duration.Seconds())
+				m.inUse.Inc()
+			case event.GetFailed:
+				m.checkoutFailures.WithLabelValues(e.Reason).Inc()
+			case event.ConnectionReturned:
+				m.inUse.Dec()
+			case event.ConnectionCreated:
+				m.connections.Inc()
+			case event.ConnectionClosed:
+				m.connections.Dec()
+			}
+		},
+	}
+}
+
+// tenantResolver wraps resolve to remember the collection each tenant collection was
+// routed from
+func (m *clientMetrics) tenantResolver(resolve TenantResolver) TenantResolver {
+	return func(ctx context.Context, ns Namespace) (Namespace, error) {
+		resolved, err := resolve(ctx, ns)
+		if err == nil && resolved.Collection != ns.Collection {
+			m.logical.Store(resolved.Database+"."+resolved.Collection, ns.Collection)
+		}
+		return resolved, err
+	}
+}
+
+// logicalCollection returns the collection that collection was routed from, including
+// for the files and chunks collections of a routed GridFS bucket
+func (m *clientMetrics) logicalCollection(database, collection string) string {
+	if logical, ok := m.logical.Load(database + "." + collection); ok {
+		return logical.(string)
+	}
+	if bucket, suffix, ok := strings.Cut(collection, "."); ok {
+		if logical, ok := m.logical.Load(database + "." + bucket); ok {
+			return logical.(string) + "." + suffix
+		}
+	}
+	return collection
+}
+
+func requestKey(connectionID string, requestID int64) string {
+	return connectionID + "/" + strconv.FormatInt(requestID, 10)
+}
+
+// commandCollection returns the collection a command targets, or "" for database commands.
+// CRUD commands name it in their first element, e.g. {find: "orders", ...}.
+func commandCollection(e *event.CommandStartedEvent) string {
+	first, err := e.Command.IndexErr(0)
+	if err != nil {
+		return ""
+	}
+	field := first.Value()
+	if e.CommandName == "getMore" {
+		field = e.Command.Lookup("collection")
+	}
+	if field.Type != bsontype.String {
+		return ""
+	}
+	return field.StringValue()
+}
+
+// chainCommandMonitors returns a monitor that passes every event to each of monitors
+func chainCommandMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	if len(monitors) == 1 {
+		return monitors[0]
+	}
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
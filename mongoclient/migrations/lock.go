@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lockID is the _id of the lock document in the migrations collection
+const lockID = "lock"
+
+// lock waits until the lock is free or expired and takes it under a random owner ID. The
+// returned function releases it.
+func (r *Runner) lock(ctx context.Context) (owner string, unlock func(), err error) {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	owner = hex.EncodeToString(b)
+
+	for {
+		now := time.Now().UTC()
+		// The upsert conflicts on _id while another runner holds an unexpired lock
+		_, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": lockID, "expires_at": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(r.opts.LockTTL)}},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return "", nil, fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return "", nil, fmt.Errorf("migrations: waiting for the lock held by another runner: %w", ctx.Err())
+		case <-time.After(r.opts.LockPollInterval):
+		}
+	}
+
+	return owner, func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		_, _ = r.collection.DeleteOne(ctx, bson.M{"_id": lockID, "owner": owner})
+	}, nil
+}
+
+// keepLock extends the lock every third of LockTTL until ctx ends, and cancels ctx when
+// the lock is lost so no migration runs unprotected
+func (r *Runner) keepLock(ctx context.Context, owner string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(r.opts.LockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		result, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": lockID, "owner": owner},
+			bson.M{"$set": bson.M{"expires_at": time.Now().UTC().Add(r.opts.LockTTL)}},
+		)
+		if err == nil && result.MatchedCount == 0 {
+			cancel(errors.New("migrations: lost the migration lock"))
+			return
+		}
+	}
+}
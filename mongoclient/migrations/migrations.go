@@ -0,0 +1,238 @@
+// Package migrations applies versioned, forward-only migrations to a MongoDB database:
+// creating collections and indexes, backfilling data and the like. Applied versions are
+// recorded in a schema_migrations collection, and a lock document in the same collection
+// keeps replicas that start together from running the same migration twice.
+//
+//	runner, err := migrations.New(client, migrations.Options{Database: "mydb"},
+//		migrations.Migration{Version: 1, Description: "create users", Up: createUsers},
+//		migrations.Migration{Version: 2, Description: "backfill user status", Up: backfillStatus},
+//	)
+//	applied, err := runner.Up(ctx)
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrDirty is returned by Up when an earlier run failed part-way through a migration. Fix
+// the data by hand, then delete the migration's document from the migrations collection
+// to run it again, or call Force to mark it applied.
+var ErrDirty = errors.New("a migration failed part-way and must be resolved by hand")
+
+// Migration is one versioned change to the database
+type Migration struct {
+	// Version orders the migrations and must be unique and positive, e.g. 1, 2, 3 or a
+	// timestamp such as 202401150930.
+	Version uint64
+	// Description is recorded with the applied version.
+	Description string
+	// Up applies the change. MongoDB cannot roll back most schema changes, so Up should be
+	// safe to re-run after a partial failure, e.g. by backfilling only documents that still
+	// lack the new field.
+	Up func(ctx context.Context, db *mongo.Database) error
+}
+
+// Options configures a Runner
+type Options struct {
+	Database string
+	// Collection records applied versions and holds the lock. Defaults to "schema_migrations".
+	Collection string
+	// LockTTL is how long the lock is held without being refreshed, so a crashed runner
+	// does not block others forever. It is refreshed while migrations run. Defaults to 5m.
+	LockTTL time.Duration
+	// LockPollInterval is how often Up retries a lock held by another runner, until its
+	// context ends. Defaults to 1s.
+	LockPollInterval time.Duration
+}
+
+// Status is the state of one migration
+type Status struct {
+	Version     uint64
+	Description string
+	Applied     bool
+	// Dirty is set when the migration started but did not finish.
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// record is the document stored for a started or applied migration
+type record struct {
+	Version     uint64    `bson:"_id"`
+	Description string    `bson:"description"`
+	Dirty       bool      `bson:"dirty"`
+	StartedAt   time.Time `bson:"started_at"`
+	AppliedAt   time.Time `bson:"applied_at,omitempty"`
+}
+
+// Runner applies migrations to one database
+type Runner struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+	migrations []Migration
+	opts       Options
+}
+
+// New creates a Runner for migrations, which may be given in any order. It returns an
+// error when a version is zero or repeated, or a migration has no Up function.
+func New(client *mongoclient.Client, opts Options, migrations ...Migration) (*Runner, error) {
+	if opts.Database == "" {
+		return nil, errors.New("migrations: database is required")
+	}
+	if opts.Collection == "" {
+		opts.Collection = "schema_migrations"
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = 5 * time.Minute
+	}
+	if opts.LockPollInterval <= 0 {
+		opts.LockPollInterval = time.Second
+	}
+
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version == 0 {
+			return nil, errors.New("migrations: version 0 is reserved")
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migrations: version %d is defined twice", m.Version)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migrations: version %d has no Up function", m.Version)
+		}
+	}
+
+	db := client.Database(opts.Database)
+	return &Runner{db: db, collection: db.Collection(opts.Collection), migrations: sorted, opts: opts}, nil
+}
+
+// Up takes the lock, then applies the migrations that have not been applied yet in version
+// order and returns their versions. It stops at the first failure, leaving that migration
+// dirty, and returns ErrDirty while any migration is dirty.
+func (r *Runner) Up(ctx context.Context) ([]uint64, error) {
+	owner, unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go r.keepLock(ctx, owner, cancel)
+
+	records, err := r.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		if rec.Dirty {
+			return nil, fmt.Errorf("migrations: version %d: %w", rec.Version, ErrDirty)
+		}
+	}
+
+	var applied []uint64
+	for _, m := range r.migrations {
+		if _, ok := records[m.Version]; ok {
+			continue
+		}
+		if err := r.apply(ctx, m); err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				err = cause
+			}
+			return applied, fmt.Errorf("migrations: version %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+		applied = append(applied, m.Version)
+	}
+	return applied, nil
+}
+
+// apply runs one migration, marking it dirty until it succeeds
+func (r *Runner) apply(ctx context.Context, m Migration) error {
+	_, err := r.collection.InsertOne(ctx, record{
+		Version:     m.Version,
+		Description: m.Description,
+		Dirty:       true,
+		StartedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record migration start: %w", err)
+	}
+	if err := m.Up(ctx, r.db); err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateOne(ctx,
+		bson.M{"_id": m.Version},
+		bson.M{"$set": bson.M{"dirty": false, "applied_at": time.Now().UTC()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	return nil
+}
+
+// Status lists every known migration, in version order, with whether it has been applied
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	records, err := r.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, len(r.migrations))
+	for i, m := range r.migrations {
+		statuses[i] = Status{Version: m.Version, Description: m.Description}
+		if rec, ok := records[m.Version]; ok {
+			statuses[i].Applied = !rec.Dirty
+			statuses[i].Dirty = rec.Dirty
+			statuses[i].AppliedAt = rec.AppliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// Force marks version as applied without running it, e.g. after fixing a dirty migration
+// by hand
+func (r *Runner) Force(ctx context.Context, version uint64) error {
+	desc := ""
+	for _, m := range r.migrations {
+		if m.Version == version {
+			desc = m.Description
+		}
+	}
+	now := time.Now().UTC()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": version},
+		bson.M{
+			"$set":         bson.M{"dirty": false, "applied_at": now},
+			"$setOnInsert": bson.M{"description": desc, "started_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to force migration %d: %w", version, err)
+	}
+	return nil
+}
+
+// records returns the started and applied migrations by version
+func (r *Runner) records(ctx context.Context) (map[uint64]record, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$ne": lockID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	var list []record
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %w", err)
+	}
+	records := make(map[uint64]record, len(list))
+	for _, rec := range list {
+		records[rec.Version] = rec
+	}
+	return records, nil
+}
@@ -2,21 +2,77 @@ package mongoclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/cdcloud-io/go-libs/errs"
+	"github.com/cdcloud-io/go-libs/page"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 // Client wraps the MongoDB client and provides additional functionality
 // In a Hexagonal Architecture, this acts as the **Adapter** for MongoDB.
 type Client struct {
 	*mongo.Client
+	retryPolicy      RetryPolicy
+	healthTimeout    time.Duration
+	ready            chan struct{}      // closed once MongoDB has answered a ping
+	stop             context.CancelFunc // stops the background connection loop of a lazy client
+	encryption       *EncryptionOptions
+	softDelete       SoftDeleteOptions
+	timestamps       TimestampOptions
+	operationTimeout time.Duration
+	readOnly         bool
+	tenantResolver   TenantResolver
+	writeThrottle    *throttle // nil without ClientOptions.WriteThrottle
 }
 
+// Store lists the document operations of Client
+// In a Hexagonal Architecture, this is the **Port**: application code depends on it,
+// and tests can substitute mongoclientmock.Store, an in-memory implementation, for the
+// Client adapter.
+type Store interface {
+	QueryOne(ctx context.Context, params QueryParams, result interface{}) error
+	QueryOneFound(ctx context.Context, params QueryParams, result interface{}) (bool, error)
+	QueryMany(ctx context.Context, params QueryParams) ([]interface{}, error)
+	QueryStream(ctx context.Context, params QueryParams, fn func(doc bson.Raw) error) error
+	QueryBatches(ctx context.Context, params QueryParams, fn func(batch []bson.Raw) error) error
+	Paginate(ctx context.Context, params QueryParams, req page.PageRequest) (page.PageResponse[bson.Raw], error)
+	InsertOne(ctx context.Context, params QueryParams, document interface{}) (*mongo.InsertOneResult, error)
+	InsertMany(ctx context.Context, params QueryParams, documents []interface{}, opts BulkOptions) ([]interface{}, error)
+	InsertIdempotent(ctx context.Context, params QueryParams, key string, document interface{}) (*mongo.InsertOneResult, error)
+	UpdateOne(ctx context.Context, params QueryParams, update interface{}) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, params QueryParams, update interface{}) (*mongo.UpdateResult, error)
+	ReplaceOne(ctx context.Context, params QueryParams, replacement interface{}) (*mongo.UpdateResult, error)
+	DeleteOne(ctx context.Context, params QueryParams) (*mongo.DeleteResult, error)
+	DeleteMany(ctx context.Context, params QueryParams) (*mongo.DeleteResult, error)
+	QueryMongoDBStruct(ctx context.Context, params QueryParams, result interface{}) error
+	QueryMongoDB(ctx context.Context, params QueryParams) (map[string]interface{}, error)
+	CountDocuments(ctx context.Context, params QueryParams) (int64, error)
+	EstimatedDocumentCount(ctx context.Context, params QueryParams) (int64, error)
+	Distinct(ctx context.Context, params QueryParams, fieldName string) ([]interface{}, error)
+	Aggregate(ctx context.Context, params QueryParams, pipeline mongo.Pipeline, result interface{}) error
+	FindOneAndUpdate(ctx context.Context, params QueryParams, update interface{}, opts FindAndModifyOptions, result interface{}) error
+	FindOneAndReplace(ctx context.Context, params QueryParams, replacement interface{}, opts FindAndModifyOptions, result interface{}) error
+	FindOneAndDelete(ctx context.Context, params QueryParams, opts FindAndModifyOptions, result interface{}) error
+	BulkWrite(ctx context.Context, params QueryParams, models []WriteModel, opts BulkOptions) (*mongo.BulkWriteResult, error)
+	Watch(ctx context.Context, params QueryParams, pipeline mongo.Pipeline) (<-chan ChangeEvent, error)
+}
+
+// Repository is the former name of Store.
+//
+// Deprecated: use Store.
+type Repository = Store
+
+var _ Store = (*Client)(nil)
+
 // ClientOptions represents options for creating a new Client
 // These options abstract connection details that can be passed from outside
 // the business logic, allowing for flexibility in different environments.
@@ -24,6 +80,138 @@ type ClientOptions struct {
 	URI                    string
 	ConnectTimeout         time.Duration
 	ServerSelectionTimeout time.Duration
+
+	// Connection pool settings, per server. Zero values keep the driver defaults.
+	// MaxPoolSize caps open connections; the driver default is 100.
+	MaxPoolSize uint64
+	// MinPoolSize keeps this many connections open even when idle.
+	MinPoolSize uint64
+	// MaxConnIdleTime closes connections idle for longer than this.
+	MaxConnIdleTime time.Duration
+	// MaxConnecting caps connections being established at once; the driver default is 2.
+	MaxConnecting uint64
+
+	// TLS configures encryption in transit. TLS settings in the URI also apply.
+	TLS TLSOptions
+	// Credential authenticates the connection when set, replacing credentials in the URI.
+	Credential *Credential
+	// Read sets the default read preference and read concern; QueryParams.Read overrides it.
+	Read ReadOptions
+	// WriteConcern sets the default acknowledgment of writes; QueryParams.WriteConcern
+	// overrides it. Nil keeps the server default.
+	WriteConcern *writeconcern.WriteConcern
+	// Retry retries operations that fail with transient errors. The zero value disables it.
+	Retry RetryPolicy
+	// OperationTimeout bounds each operation, retries included, whose context has no
+	// deadline, so a runaway query cannot hold a connection forever. A deadline set by the
+	// caller always wins. QueryStream, QueryBatches, Watch, Tail, transactions and GridFS
+	// transfers are not bounded, since they run as long as their caller needs. Zero
+	// disables it.
+	OperationTimeout time.Duration
+	// Tracing emits OpenTelemetry spans for every command.
+	Tracing TracingOptions
+	// Metrics, when set, registers command latency, error and connection pool metrics,
+	// e.g. against metrics.Registerer().
+	Metrics prometheus.Registerer
+	// QueryLog logs every command, and slow ones at Warn level, when it has a Logger.
+	QueryLog QueryLogOptions
+	// HealthTimeout bounds Health and HealthStatus. Defaults to 2s.
+	HealthTimeout time.Duration
+	// Lazy makes NewClient return without waiting for MongoDB, so a service can start before
+	// the database is reachable. The client pings in the background with backoff until it
+	// answers; operations issued meanwhile wait up to ServerSelectionTimeout for a server.
+	// Use Ready or WaitReady to wait for the first successful ping.
+	Lazy bool
+	// Encryption enables automatic client-side field level encryption of the fields named
+	// in its schema map.
+	Encryption *EncryptionOptions
+	// SoftDelete lists collections whose deletes only mark documents as deleted, which
+	// queries then skip.
+	SoftDelete SoftDeleteOptions
+	// Timestamps sets created and updated times on inserts, updates and replacements.
+	Timestamps TimestampOptions
+	// ReadOnly makes every write method, from InsertOne to EnsureIndexes and GridFS uploads,
+	// return an errs.Forbidden error matching ErrReadOnly without contacting MongoDB, for
+	// reporting services that must never change production data. It does not guard the
+	// embedded driver client or the migrations package; pair it with a database user
+	// limited to the read role for a hard guarantee.
+	ReadOnly bool
+	// TenantResolver, when set, routes every document operation to the database or
+	// collection of the tenant in its context, see DatabasePerTenant. Operations whose
+	// context has no tenant fail.
+	TenantResolver TenantResolver
+	// WriteThrottle limits the rate and concurrency of document writes, queueing or
+	// rejecting bursts. The zero value disables it.
+	WriteThrottle WriteThrottle
+}
+
+// clientOptions converts ClientOptions to driver options, returning the metrics they register
+func (o ClientOptions) clientOptions() (*options.ClientOptions, *clientMetrics, error) {
+	opts := options.Client().ApplyURI(o.URI).
+		SetServerSelectionTimeout(o.ServerSelectionTimeout)
+	if o.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(o.MaxPoolSize)
+	}
+	if o.MinPoolSize > 0 {
+		opts.SetMinPoolSize(o.MinPoolSize)
+	}
+	if o.MaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(o.MaxConnIdleTime)
+	}
+	if o.MaxConnecting > 0 {
+		opts.SetMaxConnecting(o.MaxConnecting)
+	}
+	if o.TLS.enabled() {
+		cfg, err := o.TLS.config()
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.SetTLSConfig(cfg)
+	}
+	if o.Credential != nil {
+		cred, err := o.Credential.credential()
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.SetAuth(cred)
+	}
+	if o.Read.Preference != nil {
+		opts.SetReadPreference(o.Read.Preference)
+	}
+	if o.Read.Concern != nil {
+		opts.SetReadConcern(o.Read.Concern)
+	}
+	if o.WriteConcern != nil {
+		opts.SetWriteConcern(o.WriteConcern)
+	}
+	if o.Encryption != nil {
+		enc, err := o.Encryption.autoEncryption()
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.SetAutoEncryptionOptions(enc)
+	}
+
+	var monitors []*event.CommandMonitor
+	if o.Tracing.Enabled {
+		monitors = append(monitors, o.Tracing.monitor())
+	}
+	var metrics *clientMetrics
+	if o.Metrics != nil {
+		var err error
+		if metrics, err = newClientMetrics(o.Metrics); err != nil {
+			return nil, nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+		monitors = append(monitors, metrics.commandMonitor())
+		opts.SetPoolMonitor(metrics.poolMonitor())
+	}
+	if o.QueryLog.Logger != nil {
+		monitors = append(monitors, newQueryLog(o.QueryLog).commandMonitor())
+	}
+	if len(monitors) > 0 {
+		opts.SetMonitor(chainCommandMonitors(monitors...))
+	}
+	return opts, metrics, nil
 }
 
 // QueryParams abstracts the MongoDB query parameters
@@ -33,150 +221,878 @@ type QueryParams struct {
 	Database   string
 	Collection string
 	Filter     bson.M
+	// Options sorts, pages and projects the results of QueryOne, QueryMany, QueryMongoDBStruct
+	// and QueryMongoDB.
+	Options QueryOptions
+	// UpdateOptions controls upserts, array filters and collation of UpdateOne, UpdateMany and ReplaceOne.
+	UpdateOptions UpdateOptions
+	// WatchOptions sets the starting point and retry behaviour of Watch.
+	WatchOptions WatchOptions
+	// Read overrides the client's read preference and read concern for this operation.
+	Read ReadOptions
+	// WriteConcern overrides the client's write concern for this operation, e.g.
+	// writeconcern.Majority() for a payment or writeconcern.W1() for a bulk import.
+	WriteConcern *writeconcern.WriteConcern
+	// IncludeDeleted turns off soft delete for this operation: queries also match deleted
+	// documents and deletes remove documents for good. See ClientOptions.SoftDelete.
+	IncludeDeleted bool
+}
+
+// UpdateOptions controls how UpdateOne, UpdateMany and ReplaceOne apply a write
+type UpdateOptions struct {
+	// Upsert inserts a document built from the filter and update, or the replacement, when
+	// nothing matches. The upserted ID is returned in UpdateResult.UpsertedID.
+	Upsert bool
+	// ArrayFilters select the array elements that $[<identifier>] operators update,
+	// e.g. []interface{}{bson.M{"item.status": "pending"}}. Ignored by ReplaceOne.
+	ArrayFilters []interface{}
+	// Collation sets language-specific string comparison for the filter.
+	Collation *options.Collation
+	// Hint forces the index used to find the documents to update, by name, e.g.
+	// "status_1_created_at_1", or by key document, e.g. bson.D{{Key: "status", Value: 1}}.
+	Hint interface{}
+}
+
+// updateOptions converts UpdateOptions to driver options
+func (o UpdateOptions) updateOptions() *options.UpdateOptions {
+	opts := options.Update()
+	if o.Upsert {
+		opts.SetUpsert(true)
+	}
+	if len(o.ArrayFilters) > 0 {
+		opts.SetArrayFilters(options.ArrayFilters{Filters: o.ArrayFilters})
+	}
+	if o.Collation != nil {
+		opts.SetCollation(o.Collation)
+	}
+	if o.Hint != nil {
+		opts.SetHint(o.Hint)
+	}
+	return opts
+}
+
+// QueryOptions controls which documents a query returns and in what shape.
+// The zero value returns all matching documents in natural order with all fields.
+type QueryOptions struct {
+	// Sort orders the results, e.g. bson.D{{Key: "created_at", Value: -1}}.
+	Sort bson.D
+	// Limit caps the number of documents returned by QueryMany; 0 means no limit.
+	Limit int64
+	// Skip omits this many matching documents first.
+	Skip int64
+	// Projection selects the returned fields, e.g. bson.M{"name": 1, "email": 1}.
+	Projection bson.M
+	// BatchSize is the number of documents fetched per round trip, which bounds the memory
+	// QueryStream holds; 0 leaves it to the server.
+	BatchSize int32
+	// Hint forces the index the query uses, by name, e.g. "status_1_created_at_1", or by
+	// key document, e.g. bson.D{{Key: "status", Value: 1}}, for when the planner picks a
+	// poor index on skewed data. The query fails if the index does not exist.
+	Hint interface{}
+	// Collation sets language-specific string comparison for the filter and sort, e.g.
+	// CaseInsensitive("en") to match usernames regardless of case. Only indexes created
+	// with the same collation can serve the query.
+	Collation *options.Collation
+}
+
+// findOptions converts QueryOptions to driver options for Find
+func (o QueryOptions) findOptions() *options.FindOptions {
+	opts := options.Find()
+	if len(o.Sort) > 0 {
+		opts.SetSort(o.Sort)
+	}
+	if o.Limit > 0 {
+		opts.SetLimit(o.Limit)
+	}
+	if o.Skip > 0 {
+		opts.SetSkip(o.Skip)
+	}
+	if len(o.Projection) > 0 {
+		opts.SetProjection(o.Projection)
+	}
+	if o.BatchSize > 0 {
+		opts.SetBatchSize(o.BatchSize)
+	}
+	if o.Hint != nil {
+		opts.SetHint(o.Hint)
+	}
+	if o.Collation != nil {
+		opts.SetCollation(o.Collation)
+	}
+	return opts
+}
+
+// findOneOptions converts QueryOptions to driver options for FindOne; Limit does not apply
+func (o QueryOptions) findOneOptions() *options.FindOneOptions {
+	opts := options.FindOne()
+	if len(o.Sort) > 0 {
+		opts.SetSort(o.Sort)
+	}
+	if o.Skip > 0 {
+		opts.SetSkip(o.Skip)
+	}
+	if len(o.Projection) > 0 {
+		opts.SetProjection(o.Projection)
+	}
+	if o.Hint != nil {
+		opts.SetHint(o.Hint)
+	}
+	if o.Collation != nil {
+		opts.SetCollation(o.Collation)
+	}
+	return opts
 }
 
 // NewClient creates and returns a new Client with the given options
 // This function allows for external systems to create an instance of a MongoDB client.
 // In a hexagonal architecture, this might be called from an Adapter that integrates with the infrastructure layer.
 func NewClient(opts ClientOptions) (*Client, error) {
+	clientOpts, metrics, err := opts.clientOptions()
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclient.NewClient", "invalid client options")
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), opts.ConnectTimeout)
 	defer cancel()
 
-	clientOpts := options.Client().ApplyURI(opts.URI).
-		SetServerSelectionTimeout(opts.ServerSelectionTimeout)
-
 	// Connect to MongoDB using the specified options
 	mongoClient, err := mongo.Connect(ctx, clientOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+		return nil, WrapError(err, "mongoclient.NewClient", "failed to connect to MongoDB")
+	}
+	c := &Client{
+		Client:           mongoClient,
+		retryPolicy:      opts.Retry,
+		healthTimeout:    opts.HealthTimeout,
+		ready:            make(chan struct{}),
+		encryption:       opts.Encryption,
+		softDelete:       opts.SoftDelete,
+		timestamps:       opts.Timestamps,
+		operationTimeout: opts.OperationTimeout,
+		readOnly:         opts.ReadOnly,
+		tenantResolver:   opts.TenantResolver,
+		writeThrottle:    newThrottle(opts.WriteThrottle),
+	}
+	if metrics != nil && opts.TenantResolver != nil {
+		c.tenantResolver = metrics.tenantResolver(opts.TenantResolver)
+	}
+
+	// A lazy client connects in the background; the driver does not need a server to start
+	if opts.Lazy {
+		loopCtx, stop := context.WithCancel(context.Background())
+		c.stop = stop
+		go c.connectLoop(loopCtx, opts.ConnectTimeout)
+		return c, nil
 	}
 
 	// Ping MongoDB to ensure the connection is successful
 	if err := mongoClient.Ping(ctx, readpref.Primary()); err != nil {
 		mongoClient.Disconnect(ctx)
-		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+		return nil, WrapError(err, "mongoclient.NewClient", "failed to ping MongoDB")
 	}
 
 	// Return the wrapped MongoDB client
-	return &Client{Client: mongoClient}, nil
+	close(c.ready)
+	return c, nil
 }
 
 // Close disconnects the client from MongoDB
 // This is part of the infrastructure layer, closing the connection to the database.
 func (c *Client) Close(ctx context.Context) error {
+	if c.stop != nil {
+		c.stop()
+	}
 	return c.Disconnect(ctx)
 }
 
 // QueryOne executes a query to find a single document using QueryParams
 // This abstracts the MongoDB-specific query logic, making it reusable by passing `QueryParams`.
 // It acts as an **Adapter** method that can be called from the application core via Ports.
+// It returns an errs.NotFound error matching ErrNotFound when nothing matches.
 func (c *Client) QueryOne(ctx context.Context, params QueryParams, result interface{}) error {
-	collection := c.Database(params.Database).Collection(params.Collection)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return err
+	}
+	collection := c.collection(params)
 
 	// Execute the FindOne query based on the filter provided in QueryParams
-	err := collection.FindOne(ctx, params.Filter).Decode(result)
-	if err == mongo.ErrNoDocuments {
-		return nil // Return nil if no documents are found
+	err = c.retry(ctx, func() error {
+		return collection.FindOne(ctx, params.Filter, params.Options.findOneOptions()).Decode(result)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return WrapError(err, "mongoclient.QueryOne", "no documents found")
 	}
 	if err != nil {
-		return fmt.Errorf("failed to execute FindOne query: %w", err)
+		return WrapError(err, "mongoclient.QueryOne", "failed to execute FindOne query")
 	}
 
 	return nil
 }
 
+// QueryOneFound is QueryOne for callers that expect misses: it returns found=false and a
+// nil error when nothing matches, leaving result untouched, and reserves the error for
+// failures
+func (c *Client) QueryOneFound(ctx context.Context, params QueryParams, result interface{}) (bool, error) {
+	return Found(c.QueryOne(ctx, params, result))
+}
+
 // QueryMany executes a query to find multiple documents using QueryParams
 // This function can be used to find multiple documents and returns them as an array of interfaces.
 // It's abstracted, so the core application does not need to handle MongoDB-specific logic.
 func (c *Client) QueryMany(ctx context.Context, params QueryParams) ([]interface{}, error) {
-	collection := c.Database(params.Database).Collection(params.Collection)
-
-	// Execute the Find query and get a cursor to iterate over the results
-	cursor, err := collection.Find(ctx, params.Filter)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute Find query: %w", err)
+		return nil, err
 	}
-	defer cursor.Close(ctx)
+	collection := c.collection(params)
 
 	var results []interface{}
-
-	// Decode all the documents returned by the query
-	if err := cursor.All(ctx, &results); err != nil {
-		return nil, fmt.Errorf("failed to decode query results: %w", err)
+	err = c.retry(ctx, func() error {
+		// Execute the Find query and get a cursor to iterate over the results
+		cursor, err := collection.Find(ctx, params.Filter, params.Options.findOptions())
+		if err != nil {
+			return err
+		}
+		// Decode all the documents returned by the query; All closes the cursor
+		results = nil
+		return cursor.All(ctx, &results)
+	})
+	if err != nil {
+		return nil, WrapError(err, "mongoclient.QueryMany", "failed to execute Find query")
 	}
 
 	return results, nil
 }
 
+// QueryStream runs a query like QueryMany but hands the matching documents to fn one at a
+// time as they arrive, so memory stays bounded however many documents match. Decode each
+// document with bson.Unmarshal; doc is only valid until fn returns. Iteration stops at the
+// first error from fn, which is returned unchanged.
+func (c *Client) QueryStream(ctx context.Context, params QueryParams, fn func(doc bson.Raw) error) error {
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return err
+	}
+	collection := c.collection(params)
+
+	// Only opening the cursor is retried, since fn may already have seen documents later on
+	var cursor *mongo.Cursor
+	err = c.retry(ctx, func() (err error) {
+		cursor, err = collection.Find(ctx, filterOrAll(params.Filter), params.Options.findOptions())
+		return err
+	})
+	if err != nil {
+		return WrapError(err, "mongoclient.QueryStream", "failed to execute Find query")
+	}
+	defer cursor.Close(context.WithoutCancel(ctx))
+
+	for cursor.Next(ctx) {
+		if err := fn(cursor.Current); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return WrapError(err, "mongoclient.QueryStream", "failed to read query results")
+	}
+	return nil
+}
+
+// QueryBatches runs a query like QueryStream but hands fn each batch of documents as the
+// server returns it, so an ETL job can process or write a whole round trip at once and
+// tune its size with Options.BatchSize. fn may keep the batch. Iteration stops at the
+// first error from fn, which is returned unchanged.
+func (c *Client) QueryBatches(ctx context.Context, params QueryParams, fn func(batch []bson.Raw) error) error {
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return err
+	}
+	collection := c.collection(params)
+
+	// Only opening the cursor is retried, since fn may already have seen batches later on
+	var cursor *mongo.Cursor
+	err = c.retry(ctx, func() (err error) {
+		cursor, err = collection.Find(ctx, filterOrAll(params.Filter), params.Options.findOptions())
+		return err
+	})
+	if err != nil {
+		return WrapError(err, "mongoclient.QueryBatches", "failed to execute Find query")
+	}
+	defer cursor.Close(context.WithoutCancel(ctx))
+
+	var batch []bson.Raw
+	for cursor.Next(ctx) {
+		batch = append(batch, append(bson.Raw(nil), cursor.Current...))
+		// The batch is complete once the cursor has no buffered documents left
+		if cursor.RemainingBatchLength() == 0 {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return WrapError(err, "mongoclient.QueryBatches", "failed to read query results")
+	}
+	return nil
+}
+
 // InsertOne inserts a single document using QueryParams
 // This function allows for inserting a document into MongoDB while abstracting the MongoDB-specific logic.
 func (c *Client) InsertOne(ctx context.Context, params QueryParams, document interface{}) (*mongo.InsertOneResult, error) {
+	if err := c.writable("mongoclient.InsertOne"); err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.InsertOne")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	document, err = c.timestamps.StampInsert(document)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclient.InsertOne", "failed to encode document")
+	}
+	var id interface{}
+	if c.retryPolicy.MaxAttempts > 1 {
+		document, id, err = withID(document)
+		if err != nil {
+			return nil, errs.Wrap(err, errs.Invalid, "mongoclient.InsertOne", "failed to encode document")
+		}
+	}
 	// Insert the document into the specified collection
-	result, err := c.Database(params.Database).Collection(params.Collection).InsertOne(ctx, document)
+	var result *mongo.InsertOneResult
+	attempt := 0
+	err = c.retry(ctx, func() (err error) {
+		attempt++
+		result, err = c.collection(params).InsertOne(ctx, document)
+		// A retry that collides with the _id generated above means an earlier attempt was
+		// applied and only its acknowledgment was lost
+		if err != nil && id != nil && attempt > 1 && duplicateID(err) {
+			result, err = &mongo.InsertOneResult{InsertedID: id}, nil
+		}
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert document: %w", err)
+		return nil, WrapError(err, "mongoclient.InsertOne", "failed to insert document")
 	}
 	return result, nil
 }
 
+// InsertMany inserts documents in one batch and returns their IDs in input order.
+// With unordered inserts the server continues past failed documents. On failure the IDs are
+// still returned, and the error (a mongo.BulkWriteException) lists the failed documents by index.
+func (c *Client) InsertMany(ctx context.Context, params QueryParams, documents []interface{}, opts BulkOptions) ([]interface{}, error) {
+	if err := c.writable("mongoclient.InsertMany"); err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.InsertMany")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if c.timestamps.Enabled {
+		stamped := make([]interface{}, len(documents))
+		for i, d := range documents {
+			var err error
+			if stamped[i], err = c.timestamps.StampInsert(d); err != nil {
+				return nil, errs.Wrap(err, errs.Invalid, "mongoclient.InsertMany", "failed to encode document")
+			}
+		}
+		documents = stamped
+	}
+	collection := c.collection(params)
+	result, err := collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(!opts.Unordered))
+	if err != nil {
+		var ids []interface{}
+		if result != nil {
+			ids = result.InsertedIDs
+		}
+		return ids, WrapError(err, "mongoclient.InsertMany", "failed to insert documents")
+	}
+	return result.InsertedIDs, nil
+}
+
 // UpdateOne updates a single document using QueryParams
 // This abstracts the update operation to ensure the core logic does not depend on MongoDB internals.
 func (c *Client) UpdateOne(ctx context.Context, params QueryParams, update interface{}) (*mongo.UpdateResult, error) {
+	if err := c.writable("mongoclient.UpdateOne"); err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.UpdateOne")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	params = c.scope(params)
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	update, err = c.timestamps.StampUpdate(update)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclient.UpdateOne", "failed to encode update")
+	}
 	// Update the document based on the filter provided in QueryParams
-	result, err := c.Database(params.Database).Collection(params.Collection).UpdateOne(ctx, params.Filter, update)
+	var result *mongo.UpdateResult
+	err = c.retry(ctx, func() (err error) {
+		result, err = c.collection(params).UpdateOne(ctx, params.Filter, update, params.UpdateOptions.updateOptions())
+		return err
+	})
+	if err != nil {
+		return nil, WrapError(err, "mongoclient.UpdateOne", "failed to update document")
+	}
+	return result, nil
+}
+
+// ReplaceOne replaces the first document matching the filter in QueryParams with
+// replacement, keeping its _id. Set UpdateOptions.Upsert to insert replacement when
+// nothing matches.
+func (c *Client) ReplaceOne(ctx context.Context, params QueryParams, replacement interface{}) (*mongo.UpdateResult, error) {
+	if err := c.writable("mongoclient.ReplaceOne"); err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.ReplaceOne")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	params = c.scope(params)
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	replacement, err = c.timestamps.StampReplacement(replacement)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclient.ReplaceOne", "failed to encode replacement")
+	}
+	opts := options.Replace()
+	if params.UpdateOptions.Upsert {
+		opts.SetUpsert(true)
+	}
+	if params.UpdateOptions.Collation != nil {
+		opts.SetCollation(params.UpdateOptions.Collation)
+	}
+	if params.UpdateOptions.Hint != nil {
+		opts.SetHint(params.UpdateOptions.Hint)
+	}
+
+	collection := c.collection(params)
+	var result *mongo.UpdateResult
+	err = c.retry(ctx, func() (err error) {
+		result, err = collection.ReplaceOne(ctx, filterOrAll(params.Filter), replacement, opts)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update document: %w", err)
+		return nil, WrapError(err, "mongoclient.ReplaceOne", "failed to replace document")
+	}
+	return result, nil
+}
+
+// UpdateMany applies update to every document matching the filter in QueryParams.
+// A nil filter is rejected; pass bson.M{} to update all documents.
+func (c *Client) UpdateMany(ctx context.Context, params QueryParams, update interface{}) (*mongo.UpdateResult, error) {
+	if err := c.writable("mongoclient.UpdateMany"); err != nil {
+		return nil, err
+	}
+	if params.Filter == nil {
+		return nil, errs.New(errs.Invalid, "UpdateMany requires a filter")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.UpdateMany")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	params = c.scope(params)
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	update, err = c.timestamps.StampUpdate(update)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclient.UpdateMany", "failed to encode update")
+	}
+	var result *mongo.UpdateResult
+	err = c.retry(ctx, func() (err error) {
+		result, err = c.collection(params).UpdateMany(ctx, params.Filter, update, params.UpdateOptions.updateOptions())
+		return err
+	})
+	if err != nil {
+		return nil, WrapError(err, "mongoclient.UpdateMany", "failed to update documents")
 	}
 	return result, nil
 }
 
 // DeleteOne deletes a single document using QueryParams
 // Abstracts the delete operation, keeping the core logic independent of the MongoDB implementation.
+// In a soft-delete collection the document is marked as deleted instead, see ClientOptions.SoftDelete.
 func (c *Client) DeleteOne(ctx context.Context, params QueryParams) (*mongo.DeleteResult, error) {
+	if err := c.writable("mongoclient.DeleteOne"); err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.DeleteOne")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	// SoftDelete names collections as callers do, so match it before tenant routing
+	soft := c.softDelete.Applies(params)
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if soft {
+		update, err := c.timestamps.StampUpdate(c.softDelete.markDeleted())
+		if err != nil {
+			return nil, errs.Wrap(err, errs.Invalid, "mongoclient.DeleteOne", "failed to encode update")
+		}
+		var result *mongo.UpdateResult
+		err = c.retry(ctx, func() (err error) {
+			result, err = c.collection(params).UpdateOne(ctx, c.softDelete.Exclude(params.Filter), update)
+			return err
+		})
+		if err != nil {
+			return nil, WrapError(err, "mongoclient.DeleteOne", "failed to soft-delete document")
+		}
+		return &mongo.DeleteResult{DeletedCount: result.ModifiedCount}, nil
+	}
 	// Delete the document based on the filter provided in QueryParams
-	result, err := c.Database(params.Database).Collection(params.Collection).DeleteOne(ctx, params.Filter)
+	var result *mongo.DeleteResult
+	err = c.retry(ctx, func() (err error) {
+		result, err = c.collection(params).DeleteOne(ctx, params.Filter)
+		return err
+	})
+	if err != nil {
+		return nil, WrapError(err, "mongoclient.DeleteOne", "failed to delete document")
+	}
+	return result, nil
+}
+
+// DeleteMany deletes every document matching the filter in QueryParams.
+// A nil filter is rejected so a missing filter cannot empty the collection; pass bson.M{} to delete all.
+// In a soft-delete collection the documents are marked as deleted instead, see ClientOptions.SoftDelete.
+func (c *Client) DeleteMany(ctx context.Context, params QueryParams) (*mongo.DeleteResult, error) {
+	if err := c.writable("mongoclient.DeleteMany"); err != nil {
+		return nil, err
+	}
+	if params.Filter == nil {
+		return nil, errs.New(errs.Invalid, "DeleteMany requires a filter")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.DeleteMany")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	// SoftDelete names collections as callers do, so match it before tenant routing
+	soft := c.softDelete.Applies(params)
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if soft {
+		update, err := c.timestamps.StampUpdate(c.softDelete.markDeleted())
+		if err != nil {
+			return nil, errs.Wrap(err, errs.Invalid, "mongoclient.DeleteMany", "failed to encode update")
+		}
+		var result *mongo.UpdateResult
+		err = c.retry(ctx, func() (err error) {
+			result, err = c.collection(params).UpdateMany(ctx, c.softDelete.Exclude(params.Filter), update)
+			return err
+		})
+		if err != nil {
+			return nil, WrapError(err, "mongoclient.DeleteMany", "failed to soft-delete documents")
+		}
+		return &mongo.DeleteResult{DeletedCount: result.ModifiedCount}, nil
+	}
+	var result *mongo.DeleteResult
+	err = c.retry(ctx, func() (err error) {
+		result, err = c.collection(params).DeleteMany(ctx, params.Filter)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to delete document: %w", err)
+		return nil, WrapError(err, "mongoclient.DeleteMany", "failed to delete documents")
 	}
 	return result, nil
 }
 
 // QueryMongoDBStruct executes a MongoDB query with abstracted parameters
 // and decodes the result directly into the provided struct.
+// Like QueryOne, it returns an errs.NotFound error matching ErrNotFound when nothing matches.
 func (c *Client) QueryMongoDBStruct(ctx context.Context, params QueryParams, result interface{}) error {
-	collection := c.Database(params.Database).Collection(params.Collection)
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return err
+	}
+	collection := c.collection(params)
 
 	// Execute the query and decode the result into the provided struct
-	err := collection.FindOne(ctx, params.Filter).Decode(result)
-	if err == mongo.ErrNoDocuments {
-		return fmt.Errorf("no documents found")
+	err = c.retry(ctx, func() error {
+		return collection.FindOne(ctx, params.Filter, params.Options.findOneOptions()).Decode(result)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return WrapError(err, "mongoclient.QueryMongoDBStruct", "no documents found")
+	}
+	if err != nil {
+		return WrapError(err, "mongoclient.QueryMongoDBStruct", "failed to query MongoDB")
+	}
+
+	return nil
+}
+
+// CountDocuments returns the number of documents matching the filter in QueryParams.
+// Options.Skip and Options.Limit apply, so a limit caps the count.
+func (c *Client) CountDocuments(ctx context.Context, params QueryParams) (int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+	collection := c.collection(params)
+
+	opts := options.Count()
+	if params.Options.Skip > 0 {
+		opts.SetSkip(params.Options.Skip)
+	}
+	if params.Options.Limit > 0 {
+		opts.SetLimit(params.Options.Limit)
+	}
+	if params.Options.Hint != nil {
+		opts.SetHint(params.Options.Hint)
+	}
+	if params.Options.Collation != nil {
+		opts.SetCollation(params.Options.Collation)
+	}
+	var n int64
+	err = c.retry(ctx, func() (err error) {
+		n, err = collection.CountDocuments(ctx, filterOrAll(params.Filter), opts)
+		return err
+	})
+	if err != nil {
+		return 0, WrapError(err, "mongoclient.CountDocuments", "failed to count documents")
+	}
+	return n, nil
+}
+
+// EstimatedDocumentCount returns the number of documents in the collection from its
+// metadata, without scanning. It ignores the filter and may be off after an unclean
+// shutdown or while orphaned documents exist on a sharded cluster.
+func (c *Client) EstimatedDocumentCount(ctx context.Context, params QueryParams) (int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+	collection := c.collection(params)
+
+	var n int64
+	err = c.retry(ctx, func() (err error) {
+		n, err = collection.EstimatedDocumentCount(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, WrapError(err, "mongoclient.EstimatedDocumentCount", "failed to estimate document count")
+	}
+	return n, nil
+}
+
+// Distinct returns the distinct values of fieldName among the documents matching the
+// filter in QueryParams, e.g. to list the options of a facet. Array fields contribute each
+// element. The result must fit in a single 16MB reply; aggregate with $group for more.
+func (c *Client) Distinct(ctx context.Context, params QueryParams, fieldName string) ([]interface{}, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	collection := c.collection(params)
+
+	opts := options.Distinct()
+	if params.Options.Collation != nil {
+		opts.SetCollation(params.Options.Collation)
+	}
+	var values []interface{}
+	err = c.retry(ctx, func() (err error) {
+		values, err = collection.Distinct(ctx, fieldName, filterOrAll(params.Filter), opts)
+		return err
+	})
+	if err != nil {
+		return nil, WrapError(err, "mongoclient.Distinct", "failed to query distinct values")
+	}
+	return values, nil
+}
+
+// Aggregate runs an aggregation pipeline on the collection in QueryParams and decodes all
+// resulting documents into result, which must be a pointer to a slice.
+// params.Filter, when set, is prepended to the pipeline as a $match stage.
+func (c *Client) Aggregate(ctx context.Context, params QueryParams, pipeline mongo.Pipeline, result interface{}) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return err
 	}
+	collection := c.collection(params)
+
+	if len(params.Filter) > 0 {
+		pipeline = append(mongo.Pipeline{{{Key: "$match", Value: params.Filter}}}, pipeline...)
+	}
+	opts := options.Aggregate()
+	if params.Options.Collation != nil {
+		opts.SetCollation(params.Options.Collation)
+	}
+
+	err = c.retry(ctx, func() error {
+		cursor, err := collection.Aggregate(ctx, pipeline, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, result) // All closes the cursor
+	})
+	if err != nil {
+		return WrapError(err, "mongoclient.Aggregate", "failed to execute aggregation")
+	}
+	return nil
+}
+
+// AggregateStream runs an aggregation like Aggregate but hands the resulting documents to
+// fn one at a time as they arrive, like QueryStream, e.g. for exports. Stages may spill to
+// disk, since large results are what it is for. Options.BatchSize and Collation apply.
+// Iteration stops at the first error from fn, which is returned unchanged.
+func (c *Client) AggregateStream(ctx context.Context, params QueryParams, pipeline mongo.Pipeline, fn func(doc bson.Raw) error) error {
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return err
+	}
+	collection := c.collection(params)
+
+	if len(params.Filter) > 0 {
+		pipeline = append(mongo.Pipeline{{{Key: "$match", Value: params.Filter}}}, pipeline...)
+	}
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+	opts := options.Aggregate().SetAllowDiskUse(true)
+	if params.Options.BatchSize > 0 {
+		opts.SetBatchSize(params.Options.BatchSize)
+	}
+	if params.Options.Collation != nil {
+		opts.SetCollation(params.Options.Collation)
+	}
+
+	// Only opening the cursor is retried, since fn may already have seen documents later on
+	var cursor *mongo.Cursor
+	err = c.retry(ctx, func() (err error) {
+		cursor, err = collection.Aggregate(ctx, pipeline, opts)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to query MongoDB: %w", err)
+		return WrapError(err, "mongoclient.AggregateStream", "failed to execute aggregation")
 	}
+	defer cursor.Close(context.WithoutCancel(ctx))
 
+	for cursor.Next(ctx) {
+		if err := fn(cursor.Current); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return WrapError(err, "mongoclient.AggregateStream", "failed to read aggregation results")
+	}
 	return nil
 }
 
-/*
+// RunTransaction runs fn in a transaction on a new session and commits it, or aborts it if
+// fn returns an error. The whole transaction is retried on transient transaction errors and
+// the commit on unknown commit results, so fn must be safe to run more than once.
+// Every operation in fn must use sessCtx as its context. Transactions need a replica set.
+func (c *Client) RunTransaction(ctx context.Context, fn func(sessCtx mongo.SessionContext) error) error {
+	session, err := c.StartSession()
+	if err != nil {
+		return WrapError(err, "mongoclient.RunTransaction", "failed to start session")
+	}
+	defer session.EndSession(context.WithoutCancel(ctx))
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	if err != nil {
+		var e *errs.Error
+		if errors.As(err, &e) {
+			return err // fn's own error, already classified
+		}
+		return WrapError(err, "mongoclient.RunTransaction", "transaction failed")
+	}
+	return nil
+}
 
 // QueryMongoDB executes a MongoDB query with abstracted parameters
 // This method allows for more generic query operations, using the `map[string]interface{}` to handle unknown document structures.
-func (c *Client) QueryMongoDB(ctx context.Context, params QueryParams) (*map[string]interface{}, error) {
-	collection := c.Database(params.Database).Collection(params.Collection)
-
-	// Store the result in a map[string]interface{} since the structure is unknown
-	var result map[string]interface{}
+// BSON values are converted with Normalize, so the document marshals to plain JSON: ObjectIDs
+// and Decimal128s become strings and dates time.Time. Like QueryOne, it returns an
+// errs.NotFound error matching ErrNotFound when nothing matches.
+func (c *Client) QueryMongoDB(ctx context.Context, params QueryParams) (map[string]interface{}, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	collection := c.collection(params)
 
-	// Execute the query and decode the result into the generic map
-	err := collection.FindOne(ctx, params.Filter).Decode(&result)
-	if err == mongo.ErrNoDocuments {
-		return nil, fmt.Errorf("no documents found")
+	// Decode into a bson.M since the structure is unknown
+	var doc bson.M
+	err = c.retry(ctx, func() error {
+		return collection.FindOne(ctx, params.Filter, params.Options.findOneOptions()).Decode(&doc)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, WrapError(err, "mongoclient.QueryMongoDB", "no documents found")
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to query MongoDB: %w", err)
+		return nil, WrapError(err, "mongoclient.QueryMongoDB", "failed to query MongoDB")
 	}
 
-	return &result, nil
+	return normalizeMap(doc), nil
 }
-*/
@@ -0,0 +1,585 @@
+package mongoclientmock
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// unsupported returns the error for query and update features the mock does not implement
+func unsupported(what string) error {
+	return errs.Newf(errs.Invalid, "mongoclientmock: %s is not supported", what)
+}
+
+// matches reports whether doc satisfies filter
+func matches(doc bson.D, filter bson.D) (bool, error) {
+	for _, e := range filter {
+		ok, err := matchElem(doc, e)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchElem(doc bson.D, e bson.E) (bool, error) {
+	switch e.Key {
+	case "$and", "$or", "$nor":
+		clauses, ok := e.Value.(bson.A)
+		if !ok || len(clauses) == 0 {
+			return false, errs.New(errs.Invalid, e.Key+" requires a non-empty array")
+		}
+		for _, c := range clauses {
+			sub, ok := c.(bson.D)
+			if !ok {
+				return false, errs.New(errs.Invalid, e.Key+" entries must be documents")
+			}
+			ok, err := matches(doc, sub)
+			if err != nil {
+				return false, err
+			}
+			switch {
+			case e.Key == "$and" && !ok:
+				return false, nil
+			case e.Key == "$or" && ok:
+				return true, nil
+			case e.Key == "$nor" && ok:
+				return false, nil
+			}
+		}
+		return e.Key != "$or", nil
+	}
+	if strings.HasPrefix(e.Key, "$") {
+		return false, unsupported("query operator " + e.Key)
+	}
+
+	values := lookup(doc, e.Key)
+	if ops, ok := operators(e.Value); ok {
+		for _, op := range ops {
+			ok, err := matchOperator(values, op, ops)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return matchEqual(values, e.Value), nil
+}
+
+// operators returns v as a list of query operators when it is a document of $-prefixed keys
+func operators(v interface{}) (bson.D, bool) {
+	d, ok := v.(bson.D)
+	if !ok || len(d) == 0 || !strings.HasPrefix(d[0].Key, "$") {
+		return nil, false
+	}
+	return d, true
+}
+
+// matchEqual reports whether any of values, or any element of an array among them, equals v.
+// A nil v also matches a missing field.
+func matchEqual(values []interface{}, v interface{}) bool {
+	if v == nil && len(values) == 0 {
+		return true
+	}
+	if re, ok := v.(primitive.Regex); ok {
+		return matchRegex(values, re.Pattern, re.Options)
+	}
+	for _, x := range values {
+		if equal(x, v) {
+			return true
+		}
+		if arr, ok := x.(bson.A); ok {
+			for _, el := range arr {
+				if equal(el, v) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchOperator applies one operator of ops, which holds its siblings, e.g. $options for $regex
+func matchOperator(values []interface{}, op bson.E, ops bson.D) (bool, error) {
+	switch op.Key {
+	case "$eq":
+		return matchEqual(values, op.Value), nil
+	case "$ne":
+		return !matchEqual(values, op.Value), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		for _, x := range expand(values) {
+			c, ok := compare(x, op.Value)
+			if !ok {
+				continue
+			}
+			if op.Key == "$gt" && c > 0 || op.Key == "$gte" && c >= 0 || op.Key == "$lt" && c < 0 || op.Key == "$lte" && c <= 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$in", "$nin":
+		list, ok := op.Value.(bson.A)
+		if !ok {
+			return false, errs.New(errs.Invalid, op.Key+" requires an array")
+		}
+		in := false
+		for _, v := range list {
+			if matchEqual(values, v) {
+				in = true
+				break
+			}
+		}
+		return in == (op.Key == "$in"), nil
+	case "$exists":
+		return (len(values) > 0) == truthy(op.Value), nil
+	case "$not":
+		ops, ok := operators(op.Value)
+		if !ok {
+			return false, errs.New(errs.Invalid, "$not requires an operator document")
+		}
+		for _, o := range ops {
+			ok, err := matchOperator(values, o, ops)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$regex":
+		switch p := op.Value.(type) {
+		case string:
+			options := ""
+			for _, o := range ops {
+				if o.Key == "$options" {
+					options, _ = o.Value.(string)
+				}
+			}
+			return matchRegex(values, p, options), nil
+		case primitive.Regex:
+			return matchRegex(values, p.Pattern, p.Options), nil
+		}
+		return false, errs.New(errs.Invalid, "$regex requires a string")
+	case "$options":
+		return true, nil // read together with $regex
+	case "$size":
+		n, ok := number(op.Value)
+		if !ok {
+			return false, errs.New(errs.Invalid, "$size requires a number")
+		}
+		for _, x := range values {
+			if arr, ok := x.(bson.A); ok && float64(len(arr)) == n {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$all":
+		list, ok := op.Value.(bson.A)
+		if !ok {
+			return false, errs.New(errs.Invalid, "$all requires an array")
+		}
+		for _, v := range list {
+			if !matchEqual(values, v) {
+				return false, nil
+			}
+		}
+		return len(list) > 0, nil
+	case "$elemMatch":
+		cond, ok := op.Value.(bson.D)
+		if !ok {
+			return false, errs.New(errs.Invalid, "$elemMatch requires a document")
+		}
+		for _, x := range values {
+			arr, _ := x.(bson.A)
+			for _, el := range arr {
+				var ok bool
+				var err error
+				if ops, isOps := operators(cond); isOps {
+					ok, err = matches(bson.D{{Key: "v", Value: el}}, bson.D{{Key: "v", Value: ops}})
+				} else if d, isDoc := el.(bson.D); isDoc {
+					ok, err = matches(d, cond)
+				}
+				if err != nil || ok {
+					return ok, err
+				}
+			}
+		}
+		return false, nil
+	}
+	return false, unsupported("query operator " + op.Key)
+}
+
+// matchRegex reports whether a string among values matches pattern. Of the server's
+// options, i, m and s are supported.
+func matchRegex(values []interface{}, pattern, options string) bool {
+	flags := ""
+	for _, o := range options {
+		if strings.ContainsRune("ims", o) {
+			flags += string(o)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false
+	}
+	for _, x := range expand(values) {
+		if s, ok := x.(string); ok && re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the values at a dotted path. Paths through arrays of documents yield the
+// field of each element, and numeric parts index arrays.
+func lookup(v interface{}, path string) []interface{} {
+	return lookupParts(v, strings.Split(path, "."))
+}
+
+func lookupParts(v interface{}, parts []string) []interface{} {
+	if len(parts) == 0 {
+		return []interface{}{v}
+	}
+	switch x := v.(type) {
+	case bson.D:
+		for _, e := range x {
+			if e.Key == parts[0] {
+				return lookupParts(e.Value, parts[1:])
+			}
+		}
+	case bson.A:
+		if i, err := strconv.Atoi(parts[0]); err == nil {
+			if i >= 0 && i < len(x) {
+				return lookupParts(x[i], parts[1:])
+			}
+			return nil
+		}
+		var out []interface{}
+		for _, el := range x {
+			if d, ok := el.(bson.D); ok {
+				out = append(out, lookupParts(d, parts)...)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// expand replaces arrays among values with their elements, for operators that match elements
+func expand(values []interface{}) []interface{} {
+	out := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if arr, ok := v.(bson.A); ok {
+			out = append(out, arr...)
+		} else {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// typeOrder ranks BSON types in the order the server sorts them
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int32, int64, float64, primitive.Decimal128:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case bson.D:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	}
+	return 12
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	if n, ok := number(v); ok {
+		return n != 0
+	}
+	return v != nil
+}
+
+// compare orders two values of the same type bracket; ok is false across brackets, where
+// query comparisons never match
+func compare(a, b interface{}) (c int, ok bool) {
+	if typeOrder(a) != typeOrder(b) {
+		return 0, false
+	}
+	switch x := a.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return 0, true
+	case int32, int64, float64:
+		fa, _ := number(x)
+		fb, ok := number(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		return strings.Compare(x, b.(string)), true
+	case primitive.ObjectID:
+		y := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:]), true
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0, true
+		case !x:
+			return -1, true
+		}
+		return 1, true
+	case primitive.DateTime:
+		y := b.(primitive.DateTime)
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case primitive.Timestamp:
+		return primitive.CompareTimestamp(x, b.(primitive.Timestamp)), true
+	}
+	if reflect.DeepEqual(a, b) {
+		return 0, true
+	}
+	return 0, false
+}
+
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case bson.D:
+		y, ok := b.(bson.D)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if x[i].Key != y[i].Key || !equal(x[i].Value, y[i].Value) {
+				return false
+			}
+		}
+		return true
+	case bson.A:
+		y, ok := b.(bson.A)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	c, ok := compare(a, b)
+	return ok && c == 0
+}
+
+// sortValue is the value a document sorts by: the first value at path, or nil when missing
+func sortValue(doc bson.D, path string) interface{} {
+	if values := lookup(doc, path); len(values) > 0 {
+		return values[0]
+	}
+	return nil
+}
+
+// compareSort orders values of any type, across brackets by type order
+func compareSort(a, b interface{}) int {
+	if ta, tb := typeOrder(a), typeOrder(b); ta != tb {
+		return ta - tb
+	}
+	c, _ := compare(a, b)
+	return c
+}
+
+// sortDocs orders docs by spec, e.g. bson.D{{Key: "created_at", Value: -1}}
+func sortDocs(docs []bson.D, spec bson.D) {
+	if len(spec) == 0 {
+		return
+	}
+	sort.SliceStable(docs, func(i, j int) bool { return less(docs[i], docs[j], spec) })
+}
+
+// less reports whether a sorts before b by spec
+func less(a, b bson.D, spec bson.D) bool {
+	for _, f := range spec {
+		c := compareSort(sortValue(a, f.Key), sortValue(b, f.Key))
+		if n, _ := number(f.Value); n < 0 {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+// project keeps or removes the fields named in projection. Either every field other than
+// _id is included with 1 or every field is excluded with 0, as on the server; a projection
+// of _id alone includes it. Kept fields stay in document order, and dotted paths reach
+// into embedded documents and arrays of them.
+func project(doc bson.D, projection bson.D) (bson.D, error) {
+	if len(projection) == 0 {
+		return doc, nil
+	}
+	keepID, mode := true, 0 // mode is 1 to include, -1 to exclude, 0 before a field other than _id
+	var paths [][]string
+	for _, p := range projection {
+		if _, ok := p.Value.(bson.D); ok {
+			return nil, unsupported("projection operator on " + p.Key)
+		}
+		if p.Key == "_id" {
+			keepID = truthy(p.Value)
+			continue
+		}
+		m := -1
+		if truthy(p.Value) {
+			m = 1
+		}
+		if mode != 0 && m != mode {
+			return nil, errs.New(errs.Invalid, "projection cannot mix inclusion and exclusion").With("field", p.Key)
+		}
+		mode = m
+		paths = append(paths, strings.Split(p.Key, "."))
+	}
+	if mode == 0 && keepID {
+		mode = 1
+	}
+
+	if mode == 1 {
+		out := includePaths(doc, paths)
+		if keepID {
+			if id, ok := getPath(doc, []string{"_id"}); ok {
+				out = append(bson.D{{Key: "_id", Value: id}}, out...)
+			}
+		}
+		return out, nil
+	}
+	if !keepID {
+		paths = append(paths, []string{"_id"})
+	}
+	return excludePaths(doc, paths), nil
+}
+
+// includePaths returns the fields of doc at paths. A path into a field that is neither a
+// document nor an array leaves the field out.
+func includePaths(doc bson.D, paths [][]string) bson.D {
+	out := bson.D{}
+	for _, e := range doc {
+		if e.Key == "_id" {
+			continue // placed by project
+		}
+		whole, rest := subPaths(e.Key, paths)
+		if whole {
+			out = append(out, e)
+			continue
+		}
+		if len(rest) == 0 {
+			continue
+		}
+		switch v := e.Value.(type) {
+		case bson.D:
+			out = append(out, bson.E{Key: e.Key, Value: includePaths(v, rest)})
+		case bson.A:
+			arr := bson.A{}
+			for _, el := range v {
+				if d, ok := el.(bson.D); ok {
+					arr = append(arr, includePaths(d, rest))
+				}
+			}
+			out = append(out, bson.E{Key: e.Key, Value: arr})
+		}
+	}
+	return out
+}
+
+// excludePaths returns doc without the fields at paths
+func excludePaths(doc bson.D, paths [][]string) bson.D {
+	out := bson.D{}
+	for _, e := range doc {
+		whole, rest := subPaths(e.Key, paths)
+		if whole {
+			continue
+		}
+		if len(rest) > 0 {
+			switch v := e.Value.(type) {
+			case bson.D:
+				e.Value = excludePaths(v, rest)
+			case bson.A:
+				arr := make(bson.A, len(v))
+				for i, el := range v {
+					if d, ok := el.(bson.D); ok {
+						el = excludePaths(d, rest)
+					}
+					arr[i] = el
+				}
+				e.Value = arr
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// subPaths reports whether paths name the field key itself, and returns the remainders of
+// the paths that continue below it
+func subPaths(key string, paths [][]string) (whole bool, rest [][]string) {
+	for _, p := range paths {
+		if p[0] != key {
+			continue
+		}
+		if len(p) == 1 {
+			return true, nil
+		}
+		rest = append(rest, p[1:])
+	}
+	return false, rest
+}
@@ -0,0 +1,256 @@
+package mongoclientmock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mustDoc encodes v as the driver would, like documents stored by the Store
+func mustDoc(t *testing.T, v interface{}) bson.D {
+	t.Helper()
+	d, err := toDoc(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestMatches(t *testing.T) {
+	oid1 := primitive.NewObjectIDFromTimestamp(time.Unix(1000, 0))
+	oid2 := primitive.NewObjectIDFromTimestamp(time.Unix(2000, 0))
+	day1 := primitive.NewDateTimeFromTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	day2 := primitive.NewDateTimeFromTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	items := bson.D{{Key: "items", Value: bson.A{
+		bson.D{{Key: "sku", Value: "a"}, {Key: "qty", Value: 1}, {Key: "price", Value: 10}},
+		bson.D{{Key: "sku", Value: "b"}, {Key: "qty", Value: 5}, {Key: "price", Value: 1}},
+	}}}
+
+	tests := []struct {
+		name   string
+		doc    bson.D
+		filter bson.D
+		want   bool
+	}{
+		// Equality and comparison across numeric types
+		{"int equals float", bson.D{{Key: "n", Value: int32(5)}}, bson.D{{Key: "n", Value: 5.0}}, true},
+		{"int64 gte int32", bson.D{{Key: "n", Value: int64(5)}}, bson.D{{Key: "n", Value: bson.D{{Key: "$gte", Value: int32(5)}}}}, true},
+		{"gt", bson.D{{Key: "n", Value: 5}}, bson.D{{Key: "n", Value: bson.D{{Key: "$gt", Value: 4}}}}, true},
+		{"lt false", bson.D{{Key: "n", Value: 5}}, bson.D{{Key: "n", Value: bson.D{{Key: "$lt", Value: 5}}}}, false},
+		{"range", bson.D{{Key: "n", Value: 5}}, bson.D{{Key: "n", Value: bson.D{{Key: "$gt", Value: 1}, {Key: "$lte", Value: 5}}}}, true},
+
+		// Comparisons never match across type brackets
+		{"string gt number", bson.D{{Key: "n", Value: "5"}}, bson.D{{Key: "n", Value: bson.D{{Key: "$gt", Value: 4}}}}, false},
+		{"number gt string", bson.D{{Key: "n", Value: 5}}, bson.D{{Key: "n", Value: bson.D{{Key: "$gt", Value: "4"}}}}, false},
+		{"bool is not number", bson.D{{Key: "b", Value: true}}, bson.D{{Key: "b", Value: 1}}, false},
+		{"null lt number", bson.D{{Key: "n", Value: nil}}, bson.D{{Key: "n", Value: bson.D{{Key: "$lt", Value: 5}}}}, false},
+		{"missing lt number", bson.D{}, bson.D{{Key: "n", Value: bson.D{{Key: "$lt", Value: 5}}}}, false},
+		{"string order", bson.D{{Key: "s", Value: "b"}}, bson.D{{Key: "s", Value: bson.D{{Key: "$gt", Value: "a"}}}}, true},
+		{"objectid order", bson.D{{Key: "_id", Value: oid2}}, bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: oid1}}}}, true},
+		{"date order", bson.D{{Key: "at", Value: day1}}, bson.D{{Key: "at", Value: bson.D{{Key: "$gte", Value: day2}}}}, false},
+
+		// Null and missing fields
+		{"null matches missing", bson.D{}, bson.D{{Key: "n", Value: nil}}, true},
+		{"null matches null", bson.D{{Key: "n", Value: nil}}, bson.D{{Key: "n", Value: bson.D{{Key: "$eq", Value: nil}}}}, true},
+		{"null does not match value", bson.D{{Key: "n", Value: 1}}, bson.D{{Key: "n", Value: nil}}, false},
+		{"ne matches missing", bson.D{}, bson.D{{Key: "n", Value: bson.D{{Key: "$ne", Value: 1}}}}, true},
+		{"exists", bson.D{{Key: "n", Value: nil}}, bson.D{{Key: "n", Value: bson.D{{Key: "$exists", Value: true}}}}, true},
+		{"not exists", bson.D{}, bson.D{{Key: "n", Value: bson.D{{Key: "$exists", Value: false}}}}, true},
+		{"nin matches missing", bson.D{}, bson.D{{Key: "n", Value: bson.D{{Key: "$nin", Value: bson.A{1, 2}}}}}, true},
+
+		// Embedded documents compare whole and in order
+		{"document equality", bson.D{{Key: "d", Value: bson.D{{Key: "x", Value: 1}, {Key: "y", Value: 2}}}},
+			bson.D{{Key: "d", Value: bson.D{{Key: "x", Value: 1}, {Key: "y", Value: 2}}}}, true},
+		{"document field order", bson.D{{Key: "d", Value: bson.D{{Key: "x", Value: 1}, {Key: "y", Value: 2}}}},
+			bson.D{{Key: "d", Value: bson.D{{Key: "y", Value: 2}, {Key: "x", Value: 1}}}}, false},
+		{"dotted path", bson.D{{Key: "d", Value: bson.D{{Key: "x", Value: 1}}}}, bson.D{{Key: "d.x", Value: 1}}, true},
+
+		// Arrays match on any element or as a whole
+		{"array element", bson.D{{Key: "tags", Value: bson.A{"x", "y"}}}, bson.D{{Key: "tags", Value: "y"}}, true},
+		{"array whole", bson.D{{Key: "tags", Value: bson.A{"x", "y"}}}, bson.D{{Key: "tags", Value: bson.A{"x", "y"}}}, true},
+		{"array whole order", bson.D{{Key: "tags", Value: bson.A{"x", "y"}}}, bson.D{{Key: "tags", Value: bson.A{"y", "x"}}}, false},
+		{"array element gt", bson.D{{Key: "n", Value: bson.A{1, 5}}}, bson.D{{Key: "n", Value: bson.D{{Key: "$gt", Value: 4}}}}, true},
+		{"array range on different elements", bson.D{{Key: "n", Value: bson.A{1, 8}}},
+			bson.D{{Key: "n", Value: bson.D{{Key: "$gt", Value: 1}, {Key: "$lt", Value: 8}}}}, true},
+		{"in array", bson.D{{Key: "tags", Value: bson.A{"x"}}}, bson.D{{Key: "tags", Value: bson.D{{Key: "$in", Value: bson.A{"x", "z"}}}}}, true},
+		{"nin array", bson.D{{Key: "tags", Value: bson.A{"x"}}}, bson.D{{Key: "tags", Value: bson.D{{Key: "$nin", Value: bson.A{"x"}}}}}, false},
+		{"size", bson.D{{Key: "tags", Value: bson.A{"x", "y"}}}, bson.D{{Key: "tags", Value: bson.D{{Key: "$size", Value: 2}}}}, true},
+		{"all", bson.D{{Key: "tags", Value: bson.A{"x", "y", "z"}}}, bson.D{{Key: "tags", Value: bson.D{{Key: "$all", Value: bson.A{"z", "x"}}}}}, true},
+		{"all missing one", bson.D{{Key: "tags", Value: bson.A{"x"}}}, bson.D{{Key: "tags", Value: bson.D{{Key: "$all", Value: bson.A{"x", "y"}}}}}, false},
+
+		// Dotted paths through arrays
+		{"path through array", items, bson.D{{Key: "items.sku", Value: "b"}}, true},
+		{"path with index", items, bson.D{{Key: "items.1.sku", Value: "b"}}, true},
+		{"path with wrong index", items, bson.D{{Key: "items.0.sku", Value: "b"}}, false},
+		{"path with index out of range", items, bson.D{{Key: "items.5.sku", Value: "b"}}, false},
+		{"conditions on different elements", items, bson.D{
+			{Key: "items.qty", Value: bson.D{{Key: "$gt", Value: 2}}},
+			{Key: "items.price", Value: bson.D{{Key: "$gt", Value: 5}}},
+		}, true},
+
+		// $elemMatch requires one element to satisfy every condition
+		{"elemMatch documents", items, bson.D{{Key: "items", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+			{Key: "qty", Value: bson.D{{Key: "$gt", Value: 2}}},
+			{Key: "price", Value: bson.D{{Key: "$gt", Value: 5}}},
+		}}}}}, false},
+		{"elemMatch one element", items, bson.D{{Key: "items", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+			{Key: "sku", Value: "b"},
+			{Key: "qty", Value: bson.D{{Key: "$gte", Value: 5}}},
+		}}}}}, true},
+		{"elemMatch scalars", bson.D{{Key: "n", Value: bson.A{1, 8}}},
+			bson.D{{Key: "n", Value: bson.D{{Key: "$elemMatch", Value: bson.D{{Key: "$gt", Value: 1}, {Key: "$lt", Value: 8}}}}}}, false},
+		{"elemMatch scalars match", bson.D{{Key: "n", Value: bson.A{1, 6}}},
+			bson.D{{Key: "n", Value: bson.D{{Key: "$elemMatch", Value: bson.D{{Key: "$gt", Value: 5}, {Key: "$lt", Value: 8}}}}}}, true},
+		{"elemMatch not an array", bson.D{{Key: "n", Value: 6}},
+			bson.D{{Key: "n", Value: bson.D{{Key: "$elemMatch", Value: bson.D{{Key: "$gt", Value: 5}}}}}}, false},
+
+		// $not inverts its operators and matches missing fields
+		{"not", bson.D{{Key: "n", Value: 5}}, bson.D{{Key: "n", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: 6}}}}}}, true},
+		{"not false", bson.D{{Key: "n", Value: 7}}, bson.D{{Key: "n", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: 6}}}}}}, false},
+		{"not missing", bson.D{}, bson.D{{Key: "n", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: 6}}}}}}, true},
+		{"not range inside", bson.D{{Key: "n", Value: 5}},
+			bson.D{{Key: "n", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: 1}, {Key: "$lt", Value: 10}}}}}}, false},
+		{"not range outside", bson.D{{Key: "n", Value: 20}},
+			bson.D{{Key: "n", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$gt", Value: 1}, {Key: "$lt", Value: 10}}}}}}, true},
+		{"not regex", bson.D{{Key: "s", Value: "abc"}},
+			bson.D{{Key: "s", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$regex", Value: "^b"}}}}}}, true},
+
+		// Regular expressions
+		{"regex", bson.D{{Key: "s", Value: "Hello"}}, bson.D{{Key: "s", Value: bson.D{{Key: "$regex", Value: "^hel"}, {Key: "$options", Value: "i"}}}}, true},
+		{"regex case", bson.D{{Key: "s", Value: "Hello"}}, bson.D{{Key: "s", Value: bson.D{{Key: "$regex", Value: "^hel"}}}}, false},
+		{"regex literal", bson.D{{Key: "s", Value: "Hello"}}, bson.D{{Key: "s", Value: primitive.Regex{Pattern: "llo$"}}}, true},
+		{"regex array", bson.D{{Key: "tags", Value: bson.A{"red", "blue"}}}, bson.D{{Key: "tags", Value: primitive.Regex{Pattern: "^bl"}}}, true},
+		{"regex number", bson.D{{Key: "n", Value: 12}}, bson.D{{Key: "n", Value: primitive.Regex{Pattern: "1"}}}, false},
+
+		// Logical operators
+		{"and", bson.D{{Key: "n", Value: 5}}, bson.D{{Key: "$and", Value: bson.A{
+			bson.D{{Key: "n", Value: bson.D{{Key: "$gt", Value: 1}}}},
+			bson.D{{Key: "n", Value: bson.D{{Key: "$lt", Value: 3}}}},
+		}}}, false},
+		{"or", bson.D{{Key: "n", Value: 5}}, bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "n", Value: 1}},
+			bson.D{{Key: "n", Value: 5}},
+		}}}, true},
+		{"nor", bson.D{{Key: "n", Value: 5}}, bson.D{{Key: "$nor", Value: bson.A{
+			bson.D{{Key: "n", Value: 1}},
+			bson.D{{Key: "m", Value: bson.D{{Key: "$exists", Value: true}}}},
+		}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matches(mustDoc(t, tt.doc), mustDoc(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesUnsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter bson.D
+	}{
+		{"top-level operator", bson.D{{Key: "$where", Value: "true"}}},
+		{"field operator", bson.D{{Key: "n", Value: bson.D{{Key: "$mod", Value: bson.A{2, 0}}}}}},
+		{"empty or", bson.D{{Key: "$or", Value: bson.A{}}}},
+		{"in without array", bson.D{{Key: "n", Value: bson.D{{Key: "$in", Value: 1}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := matches(bson.D{{Key: "n", Value: int32(1)}}, mustDoc(t, tt.filter))
+			if !errs.Is(err, errs.Invalid) {
+				t.Errorf("err = %v, want an errs.Invalid error", err)
+			}
+		})
+	}
+}
+
+func TestProject(t *testing.T) {
+	doc := bson.D{
+		{Key: "_id", Value: "u1"},
+		{Key: "name", Value: "Ada"},
+		{Key: "age", Value: 36},
+		{Key: "address", Value: bson.D{{Key: "city", Value: "London"}, {Key: "zip", Value: "N1"}}},
+		{Key: "orders", Value: bson.A{
+			bson.D{{Key: "sku", Value: "a"}, {Key: "qty", Value: 1}},
+			bson.D{{Key: "sku", Value: "b"}, {Key: "qty", Value: 2}},
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		projection bson.D
+		want       bson.D
+	}{
+		{"none", nil, doc},
+		{"include", bson.D{{Key: "name", Value: 1}},
+			bson.D{{Key: "_id", Value: "u1"}, {Key: "name", Value: "Ada"}}},
+		{"include in document order", bson.D{{Key: "age", Value: 1}, {Key: "name", Value: true}},
+			bson.D{{Key: "_id", Value: "u1"}, {Key: "name", Value: "Ada"}, {Key: "age", Value: 36}}},
+		{"include without _id", bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 0}},
+			bson.D{{Key: "name", Value: "Ada"}}},
+		{"_id only", bson.D{{Key: "_id", Value: 1}},
+			bson.D{{Key: "_id", Value: "u1"}}},
+		{"exclude _id only", bson.D{{Key: "_id", Value: 0}}, doc[1:]},
+		{"exclude", bson.D{{Key: "address", Value: 0}, {Key: "orders", Value: false}},
+			bson.D{{Key: "_id", Value: "u1"}, {Key: "name", Value: "Ada"}, {Key: "age", Value: 36}}},
+		{"include embedded field", bson.D{{Key: "address.city", Value: 1}}, bson.D{
+			{Key: "_id", Value: "u1"},
+			{Key: "address", Value: bson.D{{Key: "city", Value: "London"}}},
+		}},
+		{"exclude embedded field", bson.D{{Key: "address.zip", Value: 0}, {Key: "orders", Value: 0}}, bson.D{
+			{Key: "_id", Value: "u1"},
+			{Key: "name", Value: "Ada"},
+			{Key: "age", Value: 36},
+			{Key: "address", Value: bson.D{{Key: "city", Value: "London"}}},
+		}},
+		{"include field of array elements", bson.D{{Key: "orders.sku", Value: 1}, {Key: "_id", Value: 0}}, bson.D{
+			{Key: "orders", Value: bson.A{bson.D{{Key: "sku", Value: "a"}}, bson.D{{Key: "sku", Value: "b"}}}},
+		}},
+		{"exclude field of array elements", bson.D{{Key: "orders.qty", Value: 0}, {Key: "address", Value: 0}}, bson.D{
+			{Key: "_id", Value: "u1"},
+			{Key: "name", Value: "Ada"},
+			{Key: "age", Value: 36},
+			{Key: "orders", Value: bson.A{bson.D{{Key: "sku", Value: "a"}}, bson.D{{Key: "sku", Value: "b"}}}},
+		}},
+		{"include missing field", bson.D{{Key: "email", Value: 1}},
+			bson.D{{Key: "_id", Value: "u1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := project(mustDoc(t, doc), mustDoc(t, tt.projection))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := mustDoc(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("project = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestProjectInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		projection bson.D
+	}{
+		{"mixed", bson.D{{Key: "name", Value: 1}, {Key: "age", Value: 0}}},
+		{"operator", bson.D{{Key: "orders", Value: bson.D{{Key: "$slice", Value: 1}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := project(bson.D{{Key: "_id", Value: "u1"}}, mustDoc(t, tt.projection))
+			if !errs.Is(err, errs.Invalid) {
+				t.Errorf("err = %v, want an errs.Invalid error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,1075 @@
+// Package mongoclientmock provides an in-memory mongoclient.Store for unit tests of
+// application cores, so they run without a MongoDB instance.
+//
+// Filters support field equality, dotted paths, $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin,
+// $exists, $not, $regex, $size, $all, $elemMatch, $and, $or and $nor. Updates support $set,
+// $unset, $setOnInsert, $inc, $mul, $min, $max, $currentDate, $rename, $push (with $each
+// and $slice), $addToSet, $pull and $pop. Aggregate supports the $match, $sort, $skip, $limit, $project and $count
+// stages. Anything else returns an errs.Invalid error rather than a wrong result. Read and
+// write concerns, collations, index hints and indexes other than the unique _id are ignored.
+package mongoclientmock
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"github.com/cdcloud-io/go-libs/page"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// codeDuplicateKey is the server error code of a unique index violation
+const codeDuplicateKey = 11000
+
+// Store is an in-memory mongoclient.Store. The zero value is not usable; create one with New.
+// It is safe for concurrent use, and each operation is atomic.
+type Store struct {
+	mu          sync.Mutex
+	collections map[mongoclient.Namespace][]bson.D
+	watchers    []*watcher
+	clock       uint32
+	softDelete  mongoclient.SoftDeleteOptions
+	timestamps  mongoclient.TimestampOptions
+	readOnly    bool
+}
+
+var _ mongoclient.Store = (*Store)(nil)
+
+// New creates an empty Store
+func New() *Store {
+	return &Store{collections: make(map[mongoclient.Namespace][]bson.D)}
+}
+
+// Seed inserts documents into a collection, e.g. to set up a test. It fails on documents
+// that cannot be encoded or that duplicate an _id. It also works on a ReadOnly Store.
+func (s *Store) Seed(database, collection string, documents ...interface{}) error {
+	models := make([]mongoclient.WriteModel, len(documents))
+	for i, d := range documents {
+		models[i] = mongoclient.InsertModel{Document: d}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _, err := s.bulkWrite(mongoclient.Namespace{Database: database, Collection: collection}, models, mongoclient.BulkOptions{})
+	if err != nil {
+		return wrap(err, "mongoclientmock.Seed", "failed to insert documents")
+	}
+	return nil
+}
+
+// Documents returns a copy of every document in a collection, in insertion order, e.g. to
+// assert on the state a test left behind
+func (s *Store) Documents(database, collection string) []bson.D {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	docs := s.collections[mongoclient.Namespace{Database: database, Collection: collection}]
+	out := make([]bson.D, len(docs))
+	for i, d := range docs {
+		out[i] = clone(d)
+	}
+	return out
+}
+
+// Drop removes a collection and its documents, e.g. between the cases of a table test.
+// Unlike on the server, watchers receive no drop event.
+func (s *Store) Drop(database, collection string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.collections, mongoclient.Namespace{Database: database, Collection: collection})
+}
+
+// Reset removes every document from every collection, so one Store can serve several
+// tests, e.g. a fake shared by the handlers under test. Open Watch channels stay open.
+func (s *Store) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.collections = make(map[mongoclient.Namespace][]bson.D)
+}
+
+// SoftDelete makes deletes in opts.Collections mark documents as deleted, and queries skip
+// them, as ClientOptions.SoftDelete does for the Client
+func (s *Store) SoftDelete(opts mongoclient.SoftDeleteOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.softDelete = opts
+}
+
+// Timestamps makes inserts, updates and replacements set audit timestamps, as
+// ClientOptions.Timestamps does for the Client
+func (s *Store) Timestamps(opts mongoclient.TimestampOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timestamps = opts
+}
+
+// ReadOnly makes every write return an errs.Forbidden error matching
+// mongoclient.ErrReadOnly, as ClientOptions.ReadOnly does for the Client
+func (s *Store) ReadOnly() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readOnly = true
+}
+
+// writable returns the error of a write to a ReadOnly Store
+func (s *Store) writable(op string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly {
+		return errs.Wrap(mongoclient.ErrReadOnly, errs.Forbidden, op, "store is read-only")
+	}
+	return nil
+}
+
+// scope excludes soft-deleted documents from params.Filter, like the Client
+func (s *Store) scope(params mongoclient.QueryParams) (mongoclient.QueryParams, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.softDelete.Applies(params) {
+		return params, false
+	}
+	params.Filter = s.softDelete.Exclude(params.Filter)
+	return params, true
+}
+
+// markDeleted is the update that soft-deletes documents
+func (s *Store) markDeleted() bson.D {
+	return bson.D{{Key: "$set", Value: bson.D{{Key: s.softDelete.FieldName(), Value: time.Now().UTC()}}}}
+}
+
+func namespace(params mongoclient.QueryParams) mongoclient.Namespace {
+	return mongoclient.Namespace{Database: params.Database, Collection: params.Collection}
+}
+
+// find returns the indexes of the documents in ns matching filter, in sort order
+func (s *Store) find(ns mongoclient.Namespace, filter interface{}, sortSpec bson.D) ([]int, error) {
+	f, err := toDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+	if sortSpec, err = toDoc(sortSpec); err != nil {
+		return nil, err
+	}
+	docs := s.collections[ns]
+	var idx []int
+	for i, d := range docs {
+		ok, err := matches(d, f)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			idx = append(idx, i)
+		}
+	}
+	if len(sortSpec) > 0 {
+		sort.SliceStable(idx, func(a, b int) bool { return less(docs[idx[a]], docs[idx[b]], sortSpec) })
+	}
+	return idx, nil
+}
+
+// query returns copies of the documents matching params, sorted, paged and projected
+func (s *Store) query(params mongoclient.QueryParams) ([]bson.D, error) {
+	ns := namespace(params)
+	idx, err := s.find(ns, params.Filter, params.Options.Sort)
+	if err != nil {
+		return nil, err
+	}
+	idx = skipLimit(idx, params.Options.Skip, params.Options.Limit)
+
+	projection, err := toDoc(params.Options.Projection)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]bson.D, 0, len(idx))
+	for _, i := range idx {
+		d, err := project(clone(s.collections[ns][i]), projection)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func skipLimit[T any](items []T, skip, limit int64) []T {
+	if skip > 0 {
+		if skip >= int64(len(items)) {
+			return nil
+		}
+		items = items[skip:]
+	}
+	if limit > 0 && limit < int64(len(items)) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// decode decodes doc into result like a driver cursor would
+func decode(doc bson.D, result interface{}) error {
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		return errs.Wrap(err, errs.Internal, "mongoclientmock", "failed to encode document")
+	}
+	if err := bson.Unmarshal(b, result); err != nil {
+		return errs.Wrap(err, errs.Invalid, "mongoclientmock", "failed to decode document")
+	}
+	return nil
+}
+
+// decodeAll decodes docs into result, which must be a pointer to a slice
+func decodeAll(docs []bson.D, result interface{}) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice {
+		return errs.New(errs.Invalid, "result must be a pointer to a slice")
+	}
+	slice := reflect.MakeSlice(rv.Elem().Type(), len(docs), len(docs))
+	for i, d := range docs {
+		if err := decode(d, slice.Index(i).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	rv.Elem().Set(slice)
+	return nil
+}
+
+// insert adds doc to ns, giving it an ObjectID _id when it has none
+func (s *Store) insert(ns mongoclient.Namespace, document interface{}) (interface{}, error) {
+	doc, err := toDoc(document)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := getPath(doc, []string{"_id"})
+	if !ok {
+		id = primitive.NewObjectID()
+		doc = append(bson.D{{Key: "_id", Value: id}}, doc...)
+	}
+	if s.byID(ns, id) != nil {
+		return nil, duplicateKey(id)
+	}
+	s.collections[ns] = append(s.collections[ns], doc)
+	s.notify(ns, "insert", doc, nil)
+	return id, nil
+}
+
+func duplicateKey(id interface{}) error {
+	return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Code:    codeDuplicateKey,
+		Message: "E11000 duplicate key error dup key: { _id: " + fmtValue(id) + " }",
+	}}}
+}
+
+func fmtValue(v interface{}) string {
+	if oid, ok := v.(primitive.ObjectID); ok {
+		return "ObjectId('" + oid.Hex() + "')"
+	}
+	b, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, false, false)
+	if err != nil {
+		return "?"
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(b), `{"v":`), "}")
+}
+
+// update applies u to the documents matching filter, or to the first one unless many, and
+// returns the changed documents as they were before. A replacement u replaces the
+// document, keeping its _id.
+func (s *Store) update(ns mongoclient.Namespace, filter interface{}, u bson.D, upsert, many bool) (*mongo.UpdateResult, []bson.D, error) {
+	replace := !isUpdate(u)
+	if s.timestamps.Enabled {
+		var stamped interface{}
+		var err error
+		if replace {
+			stamped, err = s.timestamps.StampReplacement(u)
+		} else {
+			stamped, err = s.timestamps.StampUpdate(u)
+		}
+		if err == nil {
+			u, err = toDoc(stamped)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	idx, err := s.find(ns, filter, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !many && len(idx) > 1 {
+		idx = idx[:1]
+	}
+
+	result := &mongo.UpdateResult{}
+	var before []bson.D
+	if len(idx) == 0 {
+		if !upsert {
+			return result, nil, nil
+		}
+		f, err := toDoc(filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		doc := upsertDoc(f)
+		if replace {
+			doc = replacement(doc, u)
+		} else if err := applyUpdate(&doc, u, true); err != nil {
+			return nil, nil, err
+		}
+		id, err := s.insert(ns, doc)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.UpsertedCount, result.UpsertedID = 1, id
+		return result, nil, nil
+	}
+
+	for _, i := range idx {
+		old := s.collections[ns][i]
+		doc := clone(old)
+		if replace {
+			doc = replacement(doc, u)
+		} else if err := applyUpdate(&doc, u, false); err != nil {
+			return nil, nil, err
+		}
+		oldID, _ := getPath(old, []string{"_id"})
+		if newID, ok := getPath(doc, []string{"_id"}); !ok || !equal(oldID, newID) {
+			return nil, nil, errs.New(errs.Invalid, "the _id field cannot be changed")
+		}
+
+		result.MatchedCount++
+		if equal(old, doc) {
+			continue
+		}
+		result.ModifiedCount++
+		s.collections[ns][i] = doc
+		before = append(before, old)
+		op := "update"
+		if replace {
+			op = "replace"
+		}
+		s.notify(ns, op, doc, old)
+	}
+	return result, before, nil
+}
+
+// replacement is repl with the _id of doc, when repl has none
+func replacement(doc, repl bson.D) bson.D {
+	out := clone(repl)
+	if _, ok := getPath(out, []string{"_id"}); ok {
+		return out
+	}
+	if id, ok := getPath(doc, []string{"_id"}); ok {
+		out = append(bson.D{{Key: "_id", Value: id}}, out...)
+	}
+	return out
+}
+
+// remove deletes the documents matching filter, or the first one unless many, and returns them
+func (s *Store) remove(ns mongoclient.Namespace, filter interface{}, sortSpec bson.D, many bool) ([]bson.D, error) {
+	idx, err := s.find(ns, filter, sortSpec)
+	if err != nil {
+		return nil, err
+	}
+	if !many && len(idx) > 1 {
+		idx = idx[:1]
+	}
+	drop := make(map[int]bool, len(idx))
+	for _, i := range idx {
+		drop[i] = true
+	}
+
+	var removed []bson.D
+	kept := s.collections[ns][:0:0]
+	for i, d := range s.collections[ns] {
+		if drop[i] {
+			removed = append(removed, d)
+			s.notify(ns, "delete", nil, d)
+			continue
+		}
+		kept = append(kept, d)
+	}
+	s.collections[ns] = kept
+	return removed, nil
+}
+
+// QueryOne decodes the first document matching the filter into result. Like the Client,
+// it returns an errs.NotFound error matching mongoclient.ErrNotFound when nothing matches.
+func (s *Store) QueryOne(ctx context.Context, params mongoclient.QueryParams, result interface{}) error {
+	params, _ = s.scope(params)
+	s.mu.Lock()
+	params.Options.Limit = 1
+	docs, err := s.query(params)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return mongoclient.WrapError(mongo.ErrNoDocuments, "mongoclientmock.QueryOne", "no documents found")
+	}
+	return decode(docs[0], result)
+}
+
+// QueryOneFound decodes the first matching document into result and reports whether there
+// was one, like the Client
+func (s *Store) QueryOneFound(ctx context.Context, params mongoclient.QueryParams, result interface{}) (bool, error) {
+	return mongoclient.Found(s.QueryOne(ctx, params, result))
+}
+
+// QueryMany returns the matching documents as bson.D values, like the Client
+func (s *Store) QueryMany(ctx context.Context, params mongoclient.QueryParams) ([]interface{}, error) {
+	params, _ = s.scope(params)
+	s.mu.Lock()
+	docs, err := s.query(params)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, d := range docs {
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// QueryStream hands the matching documents to fn one at a time. The documents are
+// snapshotted first, so fn may write to the Store.
+func (s *Store) QueryStream(ctx context.Context, params mongoclient.QueryParams, fn func(doc bson.Raw) error) error {
+	params, _ = s.scope(params)
+	s.mu.Lock()
+	docs, err := s.query(params)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, d := range docs {
+		if err := ctx.Err(); err != nil {
+			return errs.Wrap(err, errs.Timeout, "mongoclientmock.QueryStream", "failed to read query results")
+		}
+		raw, err := bson.Marshal(d)
+		if err != nil {
+			return errs.Wrap(err, errs.Internal, "mongoclientmock.QueryStream", "failed to encode document")
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryBatches hands the matching documents to fn in batches of Options.BatchSize, or in
+// one batch when it is unset. The documents are snapshotted first, so fn may write to the
+// Store.
+func (s *Store) QueryBatches(ctx context.Context, params mongoclient.QueryParams, fn func(batch []bson.Raw) error) error {
+	size := int(params.Options.BatchSize)
+	var batch []bson.Raw
+	err := s.QueryStream(ctx, params, func(doc bson.Raw) error {
+		batch = append(batch, doc)
+		if size <= 0 || len(batch) < size {
+			return nil
+		}
+		b := batch
+		batch = nil
+		return fn(b)
+	})
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+	return fn(batch)
+}
+
+// Paginate pages through the matching documents. Cursors are opaque offsets, so unlike the
+// Client's keyset cursors they shift when earlier documents are inserted or deleted.
+func (s *Store) Paginate(ctx context.Context, params mongoclient.QueryParams, req page.PageRequest) (page.PageResponse[bson.Raw], error) {
+	params, _ = s.scope(params)
+	var resp page.PageResponse[bson.Raw]
+	if req.Limit <= 0 {
+		req.Limit = page.DefaultLimit
+	}
+	offset := req.Offset
+	if req.Cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(req.Cursor)
+		if err != nil {
+			return resp, errs.Wrap(page.ErrInvalidCursor, errs.Invalid, "mongoclientmock.Paginate", "invalid page cursor")
+		}
+		if offset, err = strconv.Atoi(string(b)); err != nil || offset < 0 {
+			return resp, errs.Wrap(page.ErrInvalidCursor, errs.Invalid, "mongoclientmock.Paginate", "invalid page cursor")
+		}
+	}
+
+	sortSpec := bson.D{}
+	for _, f := range req.Sort {
+		dir := 1
+		if f.Desc {
+			dir = -1
+		}
+		sortSpec = append(sortSpec, bson.E{Key: f.Field, Value: dir})
+	}
+	params.Options = mongoclient.QueryOptions{Sort: append(sortSpec, bson.E{Key: "_id", Value: 1})}
+
+	s.mu.Lock()
+	docs, err := s.query(params)
+	s.mu.Unlock()
+	if err != nil {
+		return resp, err
+	}
+	if req.IncludeTotal {
+		total := int64(len(docs))
+		resp.Total = &total
+	}
+	docs = skipLimit(docs, int64(offset), int64(req.Limit)+1)
+	if len(docs) > req.Limit {
+		docs = docs[:req.Limit]
+		resp.HasMore = true
+		resp.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset + req.Limit)))
+	}
+	resp.Items = make([]bson.Raw, len(docs))
+	for i, d := range docs {
+		if resp.Items[i], err = bson.Marshal(d); err != nil {
+			return resp, errs.Wrap(err, errs.Internal, "mongoclientmock.Paginate", "failed to encode document")
+		}
+	}
+	return resp, nil
+}
+
+// InsertOne inserts document, giving it an ObjectID _id when it has none. A duplicate _id
+// returns an errs.Conflict error wrapping a duplicate key mongo.WriteException.
+func (s *Store) InsertOne(ctx context.Context, params mongoclient.QueryParams, document interface{}) (*mongo.InsertOneResult, error) {
+	if err := s.writable("mongoclientmock.InsertOne"); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	document, err := s.timestamps.StampInsert(document)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclientmock.InsertOne", "failed to encode document")
+	}
+	id, err := s.insert(namespace(params), document)
+	if err != nil {
+		return nil, wrap(err, "mongoclientmock.InsertOne", "failed to insert document")
+	}
+	return &mongo.InsertOneResult{InsertedID: id}, nil
+}
+
+// InsertMany inserts documents in order. Ordered inserts stop at the first failure;
+// unordered ones continue. Failures are reported as a mongo.BulkWriteException.
+func (s *Store) InsertMany(ctx context.Context, params mongoclient.QueryParams, documents []interface{}, opts mongoclient.BulkOptions) ([]interface{}, error) {
+	if err := s.writable("mongoclientmock.InsertMany"); err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	models := make([]mongoclient.WriteModel, len(documents))
+	for i, d := range documents {
+		models[i] = mongoclient.InsertModel{Document: d}
+	}
+	s.mu.Lock()
+	_, ids, err := s.bulkWrite(namespace(params), models, opts)
+	s.mu.Unlock()
+	if err != nil {
+		return ids, wrap(err, "mongoclientmock.InsertMany", "failed to insert documents")
+	}
+	return ids, nil
+}
+
+// InsertIdempotent inserts document with key like mongoclient.Client.InsertIdempotent and
+// returns an error matching mongoclient.ErrAlreadyProcessed when a document of the
+// collection already holds key. No index is needed.
+func (s *Store) InsertIdempotent(ctx context.Context, params mongoclient.QueryParams, key string, document interface{}) (*mongo.InsertOneResult, error) {
+	if err := s.writable("mongoclientmock.InsertIdempotent"); err != nil {
+		return nil, err
+	}
+	doc, err := mongoclient.WithIdempotencyKey(document, key)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Unknown, "mongoclientmock.InsertIdempotent", "failed to encode document")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ns := namespace(params)
+	for _, existing := range s.collections[ns] {
+		if v, ok := getPath(existing, []string{mongoclient.IdempotencyKeyField}); ok && v == key {
+			return nil, errs.Wrap(mongoclient.ErrAlreadyProcessed, errs.Conflict, "mongoclientmock.InsertIdempotent", "idempotency key already processed")
+		}
+	}
+	stamped, err := s.timestamps.StampInsert(doc)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclientmock.InsertIdempotent", "failed to encode document")
+	}
+	id, err := s.insert(ns, stamped)
+	if err != nil {
+		return nil, wrap(err, "mongoclientmock.InsertIdempotent", "failed to insert document")
+	}
+	return &mongo.InsertOneResult{InsertedID: id}, nil
+}
+
+// UpdateOne applies update to the first document matching the filter
+func (s *Store) UpdateOne(ctx context.Context, params mongoclient.QueryParams, update interface{}) (*mongo.UpdateResult, error) {
+	if err := s.writable("mongoclientmock.UpdateOne"); err != nil {
+		return nil, err
+	}
+	params, _ = s.scope(params)
+	u, err := updateDoc(update)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, _, err := s.update(namespace(params), params.Filter, u, params.UpdateOptions.Upsert, false)
+	if err != nil {
+		return nil, wrap(err, "mongoclientmock.UpdateOne", "failed to update document")
+	}
+	return result, nil
+}
+
+// UpdateMany applies update to every document matching the filter. A nil filter is rejected,
+// as by the Client.
+func (s *Store) UpdateMany(ctx context.Context, params mongoclient.QueryParams, update interface{}) (*mongo.UpdateResult, error) {
+	if err := s.writable("mongoclientmock.UpdateMany"); err != nil {
+		return nil, err
+	}
+	if params.Filter == nil {
+		return nil, errs.New(errs.Invalid, "UpdateMany requires a filter")
+	}
+	params, _ = s.scope(params)
+	u, err := updateDoc(update)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, _, err := s.update(namespace(params), params.Filter, u, params.UpdateOptions.Upsert, true)
+	if err != nil {
+		return nil, wrap(err, "mongoclientmock.UpdateMany", "failed to update documents")
+	}
+	return result, nil
+}
+
+// ReplaceOne replaces the first document matching the filter, keeping its _id
+func (s *Store) ReplaceOne(ctx context.Context, params mongoclient.QueryParams, repl interface{}) (*mongo.UpdateResult, error) {
+	if err := s.writable("mongoclientmock.ReplaceOne"); err != nil {
+		return nil, err
+	}
+	params, _ = s.scope(params)
+	r, err := replacementDoc(repl)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, _, err := s.update(namespace(params), params.Filter, r, params.UpdateOptions.Upsert, false)
+	if err != nil {
+		return nil, wrap(err, "mongoclientmock.ReplaceOne", "failed to replace document")
+	}
+	return result, nil
+}
+
+// DeleteOne deletes the first document matching the filter
+func (s *Store) DeleteOne(ctx context.Context, params mongoclient.QueryParams) (*mongo.DeleteResult, error) {
+	if err := s.writable("mongoclientmock.DeleteOne"); err != nil {
+		return nil, err
+	}
+	params, soft := s.scope(params)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if soft {
+		result, _, err := s.update(namespace(params), params.Filter, s.markDeleted(), false, false)
+		if err != nil {
+			return nil, wrap(err, "mongoclientmock.DeleteOne", "failed to soft-delete document")
+		}
+		return &mongo.DeleteResult{DeletedCount: result.ModifiedCount}, nil
+	}
+	removed, err := s.remove(namespace(params), params.Filter, nil, false)
+	if err != nil {
+		return nil, wrap(err, "mongoclientmock.DeleteOne", "failed to delete document")
+	}
+	return &mongo.DeleteResult{DeletedCount: int64(len(removed))}, nil
+}
+
+// DeleteMany deletes every document matching the filter. A nil filter is rejected, as by
+// the Client.
+func (s *Store) DeleteMany(ctx context.Context, params mongoclient.QueryParams) (*mongo.DeleteResult, error) {
+	if err := s.writable("mongoclientmock.DeleteMany"); err != nil {
+		return nil, err
+	}
+	if params.Filter == nil {
+		return nil, errs.New(errs.Invalid, "DeleteMany requires a filter")
+	}
+	params, soft := s.scope(params)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if soft {
+		result, _, err := s.update(namespace(params), params.Filter, s.markDeleted(), false, true)
+		if err != nil {
+			return nil, wrap(err, "mongoclientmock.DeleteMany", "failed to soft-delete documents")
+		}
+		return &mongo.DeleteResult{DeletedCount: result.ModifiedCount}, nil
+	}
+	removed, err := s.remove(namespace(params), params.Filter, nil, true)
+	if err != nil {
+		return nil, wrap(err, "mongoclientmock.DeleteMany", "failed to delete documents")
+	}
+	return &mongo.DeleteResult{DeletedCount: int64(len(removed))}, nil
+}
+
+// QueryMongoDBStruct decodes the first matching document into result and returns an
+// errs.NotFound error when nothing matches
+func (s *Store) QueryMongoDBStruct(ctx context.Context, params mongoclient.QueryParams, result interface{}) error {
+	params, _ = s.scope(params)
+	s.mu.Lock()
+	params.Options.Limit = 1
+	docs, err := s.query(params)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return mongoclient.WrapError(mongo.ErrNoDocuments, "mongoclientmock.QueryMongoDBStruct", "no documents found")
+	}
+	return decode(docs[0], result)
+}
+
+// QueryMongoDB returns the first matching document with its values converted by
+// mongoclient.Normalize, and an errs.NotFound error when nothing matches
+func (s *Store) QueryMongoDB(ctx context.Context, params mongoclient.QueryParams) (map[string]interface{}, error) {
+	var doc bson.M
+	if err := s.QueryMongoDBStruct(ctx, params, &doc); err != nil {
+		return nil, err
+	}
+	return mongoclient.Normalize(doc).(map[string]interface{}), nil
+}
+
+// CountDocuments counts the matching documents, applying Options.Skip and Options.Limit
+func (s *Store) CountDocuments(ctx context.Context, params mongoclient.QueryParams) (int64, error) {
+	params, _ = s.scope(params)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, err := s.find(namespace(params), params.Filter, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(skipLimit(idx, params.Options.Skip, params.Options.Limit))), nil
+}
+
+// EstimatedDocumentCount returns the exact number of documents in the collection
+func (s *Store) EstimatedDocumentCount(ctx context.Context, params mongoclient.QueryParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.collections[namespace(params)])), nil
+}
+
+// Distinct returns the distinct values of fieldName among the matching documents, with
+// array fields contributing each element
+func (s *Store) Distinct(ctx context.Context, params mongoclient.QueryParams, fieldName string) ([]interface{}, error) {
+	params, _ = s.scope(params)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ns := namespace(params)
+	idx, err := s.find(ns, params.Filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for _, i := range idx {
+	next:
+		for _, v := range expand(lookup(s.collections[ns][i], fieldName)) {
+			for _, seen := range values {
+				if equal(seen, v) {
+					continue next
+				}
+			}
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// Aggregate runs the pipeline stages the mock supports and decodes the results into result,
+// which must be a pointer to a slice
+func (s *Store) Aggregate(ctx context.Context, params mongoclient.QueryParams, pipeline mongo.Pipeline, result interface{}) error {
+	params, _ = s.scope(params)
+	s.mu.Lock()
+	docs, err := s.query(mongoclient.QueryParams{Database: params.Database, Collection: params.Collection, Filter: params.Filter})
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if docs, err = aggregate(docs, pipeline); err != nil {
+		return err
+	}
+	return decodeAll(docs, result)
+}
+
+func aggregate(docs []bson.D, pipeline mongo.Pipeline) ([]bson.D, error) {
+	for _, stage := range pipeline {
+		stage, err := toDoc(stage)
+		if err != nil {
+			return nil, err
+		}
+		if len(stage) != 1 {
+			return nil, errs.New(errs.Invalid, "a pipeline stage must have exactly one field")
+		}
+		arg := stage[0].Value
+		switch stage[0].Key {
+		case "$match":
+			filter, _ := arg.(bson.D)
+			var kept []bson.D
+			for _, d := range docs {
+				ok, err := matches(d, filter)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					kept = append(kept, d)
+				}
+			}
+			docs = kept
+		case "$sort":
+			spec, _ := arg.(bson.D)
+			sortDocs(docs, spec)
+		case "$skip", "$limit":
+			n, ok := number(arg)
+			if !ok {
+				return nil, errs.New(errs.Invalid, stage[0].Key+" requires a number")
+			}
+			if stage[0].Key == "$skip" {
+				docs = skipLimit(docs, int64(n), 0)
+			} else {
+				docs = skipLimit(docs, 0, int64(n))
+			}
+		case "$project":
+			spec, _ := arg.(bson.D)
+			for i, d := range docs {
+				if docs[i], err = project(d, spec); err != nil {
+					return nil, err
+				}
+			}
+		case "$count":
+			field, ok := arg.(string)
+			if !ok {
+				return nil, errs.New(errs.Invalid, "$count requires a field name")
+			}
+			if len(docs) == 0 {
+				return nil, nil
+			}
+			docs = []bson.D{{{Key: field, Value: int32(len(docs))}}}
+		default:
+			return nil, unsupported("pipeline stage " + stage[0].Key)
+		}
+	}
+	return docs, nil
+}
+
+// FindOneAndUpdate atomically updates the first matching document in Sort order and decodes
+// it, before or after the update, into result. It returns an errs.NotFound error when there
+// is no document to return.
+func (s *Store) FindOneAndUpdate(ctx context.Context, params mongoclient.QueryParams, update interface{}, opts mongoclient.FindAndModifyOptions, result interface{}) error {
+	if err := s.writable("mongoclientmock.FindOneAndUpdate"); err != nil {
+		return err
+	}
+	u, err := updateDoc(update)
+	if err != nil {
+		return err
+	}
+	return s.findAndModify("mongoclientmock.FindOneAndUpdate", params, u, opts, result)
+}
+
+// FindOneAndReplace atomically replaces the first matching document in Sort order and
+// decodes it, before or after the replacement, into result. It returns an errs.NotFound
+// error when there is no document to return.
+func (s *Store) FindOneAndReplace(ctx context.Context, params mongoclient.QueryParams, repl interface{}, opts mongoclient.FindAndModifyOptions, result interface{}) error {
+	if err := s.writable("mongoclientmock.FindOneAndReplace"); err != nil {
+		return err
+	}
+	r, err := replacementDoc(repl)
+	if err != nil {
+		return err
+	}
+	return s.findAndModify("mongoclientmock.FindOneAndReplace", params, r, opts, result)
+}
+
+func (s *Store) findAndModify(op string, params mongoclient.QueryParams, update bson.D, opts mongoclient.FindAndModifyOptions, result interface{}) error {
+	params, _ = s.scope(params)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ns := namespace(params)
+
+	// Find the document first, so the one returned after the change can be found by _id
+	idx, err := s.find(ns, params.Filter, opts.Sort)
+	if err != nil {
+		return err
+	}
+	var filter interface{} = params.Filter
+	if len(idx) > 0 {
+		id, _ := getPath(s.collections[ns][idx[0]], []string{"_id"})
+		filter = bson.D{{Key: "_id", Value: id}}
+	}
+	res, before, err := s.update(ns, filter, update, opts.Upsert, false)
+	if err != nil {
+		return wrap(err, op, "failed to modify document")
+	}
+
+	var doc bson.D
+	switch {
+	case opts.ReturnAfter && res.UpsertedID != nil:
+		doc = s.byID(ns, res.UpsertedID)
+	case opts.ReturnAfter && len(idx) > 0:
+		doc = s.collections[ns][idx[0]]
+	case len(before) > 0:
+		doc = before[0]
+	case len(idx) > 0:
+		doc = s.collections[ns][idx[0]] // matched but unchanged
+	}
+	return s.returnDocument(op, doc, opts.Projection, result)
+}
+
+// FindOneAndDelete atomically deletes the first matching document in Sort order and decodes
+// it into result. It returns an errs.NotFound error when nothing matches.
+func (s *Store) FindOneAndDelete(ctx context.Context, params mongoclient.QueryParams, opts mongoclient.FindAndModifyOptions, result interface{}) error {
+	if err := s.writable("mongoclientmock.FindOneAndDelete"); err != nil {
+		return err
+	}
+	if _, soft := s.scope(params); soft {
+		s.mu.Lock()
+		update := s.markDeleted()
+		s.mu.Unlock()
+		opts = mongoclient.FindAndModifyOptions{Sort: opts.Sort, Projection: opts.Projection}
+		return s.findAndModify("mongoclientmock.FindOneAndDelete", params, update, opts, result)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed, err := s.remove(namespace(params), params.Filter, opts.Sort, false)
+	if err != nil {
+		return wrap(err, "mongoclientmock.FindOneAndDelete", "failed to find and delete document")
+	}
+	var doc bson.D
+	if len(removed) > 0 {
+		doc = removed[0]
+	}
+	return s.returnDocument("mongoclientmock.FindOneAndDelete", doc, opts.Projection, result)
+}
+
+func (s *Store) byID(ns mongoclient.Namespace, id interface{}) bson.D {
+	for _, d := range s.collections[ns] {
+		if cur, ok := getPath(d, []string{"_id"}); ok && equal(cur, id) {
+			return d
+		}
+	}
+	return nil
+}
+
+func (s *Store) returnDocument(op string, doc bson.D, projection bson.M, result interface{}) error {
+	if doc == nil {
+		return mongoclient.WrapError(mongo.ErrNoDocuments, op, "no documents found")
+	}
+	p, err := toDoc(projection)
+	if err != nil {
+		return err
+	}
+	if doc, err = project(clone(doc), p); err != nil {
+		return err
+	}
+	return decode(doc, result)
+}
+
+// BulkWrite applies models in order. Ordered writes stop at the first failure; unordered
+// ones continue. Failures are reported as a mongo.BulkWriteException, with the result
+// counting the writes that were applied.
+func (s *Store) BulkWrite(ctx context.Context, params mongoclient.QueryParams, models []mongoclient.WriteModel, opts mongoclient.BulkOptions) (*mongo.BulkWriteResult, error) {
+	if err := s.writable("mongoclientmock.BulkWrite"); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	result, _, err := s.bulkWrite(namespace(params), models, opts)
+	s.mu.Unlock()
+	if err != nil {
+		return result, wrap(err, "mongoclientmock.BulkWrite", "failed to execute bulk write")
+	}
+	return result, nil
+}
+
+func (s *Store) bulkWrite(ns mongoclient.Namespace, models []mongoclient.WriteModel, opts mongoclient.BulkOptions) (*mongo.BulkWriteResult, []interface{}, error) {
+	result := &mongo.BulkWriteResult{UpsertedIDs: make(map[int64]interface{})}
+	var ids []interface{}
+	var failures []mongo.BulkWriteError
+	for i, m := range models {
+		var err error
+		switch m := m.(type) {
+		case mongoclient.InsertModel:
+			var id, doc interface{}
+			if doc, err = s.timestamps.StampInsert(m.Document); err != nil {
+				err = errs.Wrap(err, errs.Invalid, "mongoclientmock", "failed to encode document")
+			} else if id, err = s.insert(ns, doc); err == nil {
+				result.InsertedCount++
+				ids = append(ids, id)
+			}
+		case mongoclient.UpdateModel:
+			var u bson.D
+			var r *mongo.UpdateResult
+			if u, err = updateDoc(m.Update); err == nil {
+				if r, _, err = s.update(ns, m.Filter, u, m.Upsert, m.Many); err == nil {
+					addUpdate(result, int64(i), r)
+				}
+			}
+		case mongoclient.ReplaceModel:
+			var repl bson.D
+			var r *mongo.UpdateResult
+			if repl, err = replacementDoc(m.Replacement); err == nil {
+				if r, _, err = s.update(ns, m.Filter, repl, m.Upsert, false); err == nil {
+					addUpdate(result, int64(i), r)
+				}
+			}
+		case mongoclient.DeleteModel:
+			var removed []bson.D
+			if removed, err = s.remove(ns, m.Filter, nil, m.Many); err == nil {
+				result.DeletedCount += int64(len(removed))
+			}
+		default:
+			err = unsupported("write model " + reflect.TypeOf(m).String())
+		}
+		if err == nil {
+			continue
+		}
+		failures = append(failures, bulkWriteError(i, err))
+		if !opts.Unordered {
+			break
+		}
+	}
+	if len(failures) > 0 {
+		return result, ids, mongo.BulkWriteException{WriteErrors: failures}
+	}
+	return result, ids, nil
+}
+
+func addUpdate(result *mongo.BulkWriteResult, index int64, r *mongo.UpdateResult) {
+	result.MatchedCount += r.MatchedCount
+	result.ModifiedCount += r.ModifiedCount
+	result.UpsertedCount += r.UpsertedCount
+	if r.UpsertedID != nil {
+		result.UpsertedIDs[index] = r.UpsertedID
+	}
+}
+
+func bulkWriteError(index int, err error) mongo.BulkWriteError {
+	we := mongo.WriteError{Index: index, Message: err.Error()}
+	var wex mongo.WriteException
+	if errors.As(err, &wex) && len(wex.WriteErrors) > 0 {
+		we.Code, we.Message = wex.WriteErrors[0].Code, wex.WriteErrors[0].Message
+	}
+	return mongo.BulkWriteError{WriteError: we}
+}
+
+// wrap annotates err like the Client does, so duplicate keys match
+// mongoclient.ErrDuplicateKey; errors of the mock itself are errs.Invalid
+func wrap(err error, op, message string) error {
+	wrapped := mongoclient.WrapError(err, op, message)
+	if errs.KindOf(wrapped) == errs.Unknown {
+		return errs.Wrap(err, errs.Invalid, op, message)
+	}
+	return wrapped
+}
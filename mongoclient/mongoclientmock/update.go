@@ -0,0 +1,407 @@
+package mongoclientmock
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toDoc converts a document, filter or update to bson.D the way the driver encodes it, so
+// numbers, times and nested structs compare as they would on the server
+func toDoc(v interface{}) (bson.D, error) {
+	if v == nil {
+		return bson.D{}, nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Pointer:
+		if rv.IsNil() {
+			return bson.D{}, nil
+		}
+	}
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclientmock", "failed to encode document")
+	}
+	var d bson.D
+	if err := bson.Unmarshal(b, &d); err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclientmock", "failed to decode document")
+	}
+	return d, nil
+}
+
+// clone deep-copies doc so stored documents never alias caller data
+func clone(doc bson.D) bson.D {
+	d, _ := toDoc(doc)
+	return d
+}
+
+func getPath(doc bson.D, parts []string) (interface{}, bool) {
+	var v interface{} = doc
+	for _, p := range parts {
+		switch x := v.(type) {
+		case bson.D:
+			found := false
+			for _, e := range x {
+				if e.Key == p {
+					v, found = e.Value, true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		case bson.A:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// setPath sets the value at parts, creating intermediate documents as needed
+func setPath(doc *bson.D, parts []string, value interface{}) error {
+	for i, e := range *doc {
+		if e.Key != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			(*doc)[i].Value = value
+			return nil
+		}
+		switch x := e.Value.(type) {
+		case bson.D:
+			err := setPath(&x, parts[1:], value)
+			(*doc)[i].Value = x
+			return err
+		case bson.A:
+			idx, err := strconv.Atoi(parts[1])
+			if err != nil || idx < 0 {
+				return errs.New(errs.Invalid, "cannot create field "+parts[1]+" in array "+parts[0])
+			}
+			for len(x) <= idx {
+				x = append(x, nil)
+			}
+			if len(parts) == 2 {
+				x[idx] = value
+			} else {
+				sub, _ := x[idx].(bson.D)
+				if err := setPath(&sub, parts[2:], value); err != nil {
+					return err
+				}
+				x[idx] = sub
+			}
+			(*doc)[i].Value = x
+			return nil
+		default:
+			return errs.New(errs.Invalid, "cannot create field "+parts[1]+" in non-document "+parts[0])
+		}
+	}
+	if len(parts) == 1 {
+		*doc = append(*doc, bson.E{Key: parts[0], Value: value})
+		return nil
+	}
+	var sub bson.D
+	if err := setPath(&sub, parts[1:], value); err != nil {
+		return err
+	}
+	*doc = append(*doc, bson.E{Key: parts[0], Value: sub})
+	return nil
+}
+
+func unsetPath(doc *bson.D, parts []string) {
+	for i, e := range *doc {
+		if e.Key != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			*doc = append((*doc)[:i:i], (*doc)[i+1:]...)
+			return
+		}
+		if x, ok := e.Value.(bson.D); ok {
+			unsetPath(&x, parts[1:])
+			(*doc)[i].Value = x
+		}
+		return
+	}
+}
+
+// upsertDoc is the document an upsert starts from: the equality conditions of filter
+func upsertDoc(filter bson.D) bson.D {
+	var doc bson.D
+	for _, e := range filter {
+		if strings.HasPrefix(e.Key, "$") {
+			if e.Key == "$and" {
+				clauses, _ := e.Value.(bson.A)
+				for _, c := range clauses {
+					if sub, ok := c.(bson.D); ok {
+						for _, se := range upsertDoc(sub) {
+							setPath(&doc, strings.Split(se.Key, "."), se.Value)
+						}
+					}
+				}
+			}
+			continue
+		}
+		v := e.Value
+		if ops, ok := operators(v); ok {
+			v = nil
+			for _, op := range ops {
+				if op.Key == "$eq" {
+					v = op.Value
+				}
+			}
+			if v == nil {
+				continue
+			}
+		}
+		setPath(&doc, strings.Split(e.Key, "."), v)
+	}
+	return doc
+}
+
+// isUpdate reports whether update consists of update operators rather than a replacement
+func isUpdate(update bson.D) bool {
+	return len(update) > 0 && strings.HasPrefix(update[0].Key, "$")
+}
+
+// updateDoc encodes an update, which must consist of update operators
+func updateDoc(v interface{}) (bson.D, error) {
+	u, err := toDoc(v)
+	if err == nil && !isUpdate(u) {
+		err = errs.New(errs.Invalid, "update document requires atomic operators")
+	}
+	return u, err
+}
+
+// replacementDoc encodes a replacement, which must not contain update operators
+func replacementDoc(v interface{}) (bson.D, error) {
+	r, err := toDoc(v)
+	if err == nil && isUpdate(r) {
+		err = errs.New(errs.Invalid, "replacement document cannot contain update operators")
+	}
+	return r, err
+}
+
+// applyUpdate applies the update operators in update to doc. $setOnInsert only applies
+// when inserting.
+func applyUpdate(doc *bson.D, update bson.D, inserting bool) error {
+	if !isUpdate(update) {
+		return errs.New(errs.Invalid, "update document requires atomic operators")
+	}
+	for _, op := range update {
+		fields, ok := op.Value.(bson.D)
+		if !ok {
+			return errs.New(errs.Invalid, op.Key+" requires a document")
+		}
+		for _, f := range fields {
+			// _id is immutable; setting it to its current value is allowed
+			if f.Key == "_id" && !inserting && op.Key != "$setOnInsert" {
+				if cur, ok := getPath(*doc, []string{"_id"}); op.Key != "$set" || !ok || !equal(cur, f.Value) {
+					return errs.New(errs.Invalid, "the _id field cannot be changed")
+				}
+			}
+			if err := applyOperator(doc, op.Key, strings.Split(f.Key, "."), f.Value, inserting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func applyOperator(doc *bson.D, op string, path []string, arg interface{}, inserting bool) error {
+	cur, exists := getPath(*doc, path)
+	switch op {
+	case "$set":
+		return setPath(doc, path, arg)
+	case "$setOnInsert":
+		if inserting {
+			return setPath(doc, path, arg)
+		}
+		return nil
+	case "$unset":
+		unsetPath(doc, path)
+		return nil
+	case "$inc", "$mul":
+		n, ok := number(arg)
+		if !ok {
+			return errs.New(errs.Invalid, op+" requires a number")
+		}
+		if !exists {
+			cur = zeroLike(arg)
+			if op == "$mul" {
+				return setPath(doc, path, cur)
+			}
+		}
+		c, ok := number(cur)
+		if !ok {
+			return errs.New(errs.Invalid, op+" applied to a non-numeric field "+strings.Join(path, "."))
+		}
+		if op == "$inc" {
+			return setPath(doc, path, arith(cur, arg, c+n))
+		}
+		return setPath(doc, path, arith(cur, arg, c*n))
+	case "$min", "$max":
+		if exists {
+			c := compareSort(arg, cur)
+			if op == "$min" && c >= 0 || op == "$max" && c <= 0 {
+				return nil
+			}
+		}
+		return setPath(doc, path, arg)
+	case "$currentDate":
+		return setPath(doc, path, primitive.NewDateTimeFromTime(time.Now()))
+	case "$rename":
+		to, ok := arg.(string)
+		if !ok {
+			return errs.New(errs.Invalid, "$rename requires a string")
+		}
+		if !exists {
+			return nil
+		}
+		unsetPath(doc, path)
+		return setPath(doc, strings.Split(to, "."), cur)
+	case "$push", "$addToSet":
+		arr, ok := cur.(bson.A)
+		if exists && !ok {
+			return errs.New(errs.Invalid, op+" applied to a non-array field "+strings.Join(path, "."))
+		}
+		items, slice, err := pushItems(op, arg)
+		if err != nil {
+			return err
+		}
+		arr = append(bson.A(nil), arr...)
+		for _, item := range items {
+			if op == "$addToSet" && matchEqual([]interface{}{arr}, item) {
+				continue
+			}
+			arr = append(arr, item)
+		}
+		if slice != nil {
+			switch n := *slice; {
+			case n >= 0 && n < len(arr):
+				arr = arr[:n]
+			case n < 0 && -n < len(arr):
+				arr = arr[len(arr)+n:]
+			}
+		}
+		return setPath(doc, path, arr)
+	case "$pull":
+		arr, ok := cur.(bson.A)
+		if !ok {
+			return nil
+		}
+		kept := bson.A{}
+		for _, el := range arr {
+			var remove bool
+			if cond, isOps := operators(arg); isOps {
+				m, err := matches(bson.D{{Key: "v", Value: el}}, bson.D{{Key: "v", Value: cond}})
+				if err != nil {
+					return err
+				}
+				remove = m
+			} else if cond, isDoc := arg.(bson.D); isDoc {
+				if d, ok := el.(bson.D); ok {
+					m, err := matches(d, cond)
+					if err != nil {
+						return err
+					}
+					remove = m
+				}
+			} else {
+				remove = equal(el, arg)
+			}
+			if !remove {
+				kept = append(kept, el)
+			}
+		}
+		return setPath(doc, path, kept)
+	case "$pop":
+		arr, ok := cur.(bson.A)
+		if !ok || len(arr) == 0 {
+			return nil
+		}
+		if n, _ := number(arg); n < 0 {
+			return setPath(doc, path, append(bson.A(nil), arr[1:]...))
+		}
+		return setPath(doc, path, append(bson.A(nil), arr[:len(arr)-1]...))
+	}
+	return unsupported("update operator " + op)
+}
+
+func zeroLike(v interface{}) interface{} {
+	switch v.(type) {
+	case int64:
+		return int64(0)
+	case float64:
+		return float64(0)
+	}
+	return int32(0)
+}
+
+// arith returns result in the wider numeric type of a and b, as the server does
+func arith(a, b interface{}, result float64) interface{} {
+	_, af := a.(float64)
+	_, bf := b.(float64)
+	_, al := a.(int64)
+	_, bl := b.(int64)
+	switch {
+	case af || bf:
+		return result
+	case al || bl, result > math.MaxInt32 || result < math.MinInt32:
+		// An int32 result that overflows widens to int64
+		return int64(result)
+	}
+	return int32(result)
+}
+
+// pushItems returns the values arg of $push or $addToSet appends: arg itself, or the array
+// of $each. $push also supports $slice, returned as the number of elements to keep.
+// Modifiers may come in any order, since a bson.M update has none.
+func pushItems(op string, arg interface{}) (bson.A, *int, error) {
+	d, ok := arg.(bson.D)
+	if !ok || len(d) == 0 || !hasKey(d, "$each") {
+		return bson.A{arg}, nil, nil
+	}
+	var (
+		items bson.A
+		slice *int
+	)
+	for _, e := range d {
+		switch {
+		case e.Key == "$each":
+			if items, ok = e.Value.(bson.A); !ok {
+				return nil, nil, errs.New(errs.Invalid, "$each requires an array")
+			}
+		case e.Key == "$slice" && op == "$push":
+			n, ok := number(e.Value)
+			if !ok || n != math.Trunc(n) {
+				return nil, nil, errs.New(errs.Invalid, "$slice requires an integer")
+			}
+			i := int(n)
+			slice = &i
+		default:
+			return nil, nil, unsupported(op + " modifier " + e.Key)
+		}
+	}
+	return items, slice, nil
+}
+
+func hasKey(d bson.D, key string) bool {
+	for _, e := range d {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
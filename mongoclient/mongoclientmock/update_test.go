@@ -0,0 +1,225 @@
+package mongoclientmock
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestApplyUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       bson.D
+		update    bson.D
+		inserting bool
+		want      bson.D
+	}{
+		{"set", bson.D{{Key: "a", Value: 1}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "a", Value: 2}, {Key: "b", Value: "x"}}}},
+			false, bson.D{{Key: "a", Value: 2}, {Key: "b", Value: "x"}}},
+		{"set creates embedded documents", bson.D{},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "a.b.c", Value: 1}}}},
+			false, bson.D{{Key: "a", Value: bson.D{{Key: "b", Value: bson.D{{Key: "c", Value: 1}}}}}}},
+		{"set array element", bson.D{{Key: "arr", Value: bson.A{1, 2}}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "arr.1", Value: 5}}}},
+			false, bson.D{{Key: "arr", Value: bson.A{1, 5}}}},
+		{"set array element pads with null", bson.D{{Key: "arr", Value: bson.A{1}}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "arr.2", Value: 3}}}},
+			false, bson.D{{Key: "arr", Value: bson.A{1, nil, 3}}}},
+		{"set same _id", bson.D{{Key: "_id", Value: "a"}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "_id", Value: "a"}}}},
+			false, bson.D{{Key: "_id", Value: "a"}}},
+		{"unset", bson.D{{Key: "a", Value: 1}, {Key: "b", Value: bson.D{{Key: "c", Value: 1}, {Key: "d", Value: 2}}}},
+			bson.D{{Key: "$unset", Value: bson.D{{Key: "a", Value: ""}, {Key: "b.c", Value: ""}, {Key: "missing", Value: ""}}}},
+			false, bson.D{{Key: "b", Value: bson.D{{Key: "d", Value: 2}}}}},
+		{"setOnInsert on update", bson.D{{Key: "a", Value: 1}},
+			bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: "b", Value: 2}}}},
+			false, bson.D{{Key: "a", Value: 1}}},
+		{"setOnInsert on insert", bson.D{{Key: "a", Value: 1}},
+			bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: "b", Value: 2}}}},
+			true, bson.D{{Key: "a", Value: 1}, {Key: "b", Value: 2}}},
+
+		// Arithmetic keeps the widest numeric type
+		{"inc", bson.D{{Key: "n", Value: int32(1)}},
+			bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: int32(2)}}}},
+			false, bson.D{{Key: "n", Value: int32(3)}}},
+		{"inc missing", bson.D{},
+			bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: int64(2)}}}},
+			false, bson.D{{Key: "n", Value: int64(2)}}},
+		{"inc widens to int64", bson.D{{Key: "n", Value: int32(1)}},
+			bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: int64(2)}}}},
+			false, bson.D{{Key: "n", Value: int64(3)}}},
+		{"inc widens to double", bson.D{{Key: "n", Value: int32(1)}},
+			bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: 0.5}}}},
+			false, bson.D{{Key: "n", Value: 1.5}}},
+		{"inc int32 overflow", bson.D{{Key: "n", Value: int32(math.MaxInt32)}},
+			bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: int32(1)}}}},
+			false, bson.D{{Key: "n", Value: int64(math.MaxInt32) + 1}}},
+		{"inc embedded", bson.D{{Key: "s", Value: bson.D{{Key: "n", Value: int32(1)}}}},
+			bson.D{{Key: "$inc", Value: bson.D{{Key: "s.n", Value: int32(-1)}}}},
+			false, bson.D{{Key: "s", Value: bson.D{{Key: "n", Value: int32(0)}}}}},
+		{"mul", bson.D{{Key: "n", Value: int32(3)}},
+			bson.D{{Key: "$mul", Value: bson.D{{Key: "n", Value: int32(4)}}}},
+			false, bson.D{{Key: "n", Value: int32(12)}}},
+		{"mul missing", bson.D{},
+			bson.D{{Key: "$mul", Value: bson.D{{Key: "n", Value: 2.5}}}},
+			false, bson.D{{Key: "n", Value: 0.0}}},
+
+		// $min and $max compare in BSON order
+		{"min lower", bson.D{{Key: "n", Value: int32(5)}},
+			bson.D{{Key: "$min", Value: bson.D{{Key: "n", Value: int32(3)}}}},
+			false, bson.D{{Key: "n", Value: int32(3)}}},
+		{"min higher", bson.D{{Key: "n", Value: int32(5)}},
+			bson.D{{Key: "$min", Value: bson.D{{Key: "n", Value: int32(7)}}}},
+			false, bson.D{{Key: "n", Value: int32(5)}}},
+		{"max missing", bson.D{},
+			bson.D{{Key: "$max", Value: bson.D{{Key: "n", Value: int32(7)}}}},
+			false, bson.D{{Key: "n", Value: int32(7)}}},
+		{"max across types", bson.D{{Key: "n", Value: int32(5)}},
+			bson.D{{Key: "$max", Value: bson.D{{Key: "n", Value: "a"}}}},
+			false, bson.D{{Key: "n", Value: "a"}}},
+
+		{"rename", bson.D{{Key: "a", Value: 1}, {Key: "b", Value: 2}},
+			bson.D{{Key: "$rename", Value: bson.D{{Key: "a", Value: "c.d"}}}},
+			false, bson.D{{Key: "b", Value: 2}, {Key: "c", Value: bson.D{{Key: "d", Value: 1}}}}},
+		{"rename missing", bson.D{{Key: "b", Value: 2}},
+			bson.D{{Key: "$rename", Value: bson.D{{Key: "a", Value: "c"}}}},
+			false, bson.D{{Key: "b", Value: 2}}},
+
+		// Arrays
+		{"push", bson.D{{Key: "tags", Value: bson.A{"x"}}},
+			bson.D{{Key: "$push", Value: bson.D{{Key: "tags", Value: "x"}}}},
+			false, bson.D{{Key: "tags", Value: bson.A{"x", "x"}}}},
+		{"push missing", bson.D{},
+			bson.D{{Key: "$push", Value: bson.D{{Key: "tags", Value: "x"}}}},
+			false, bson.D{{Key: "tags", Value: bson.A{"x"}}}},
+		{"push each", bson.D{{Key: "tags", Value: bson.A{"x"}}},
+			bson.D{{Key: "$push", Value: bson.D{{Key: "tags", Value: bson.D{{Key: "$each", Value: bson.A{"y", "z"}}}}}}},
+			false, bson.D{{Key: "tags", Value: bson.A{"x", "y", "z"}}}},
+		{"push each slice last", bson.D{{Key: "tags", Value: bson.A{"x"}}},
+			bson.D{{Key: "$push", Value: bson.D{{Key: "tags", Value: bson.D{
+				{Key: "$slice", Value: -2}, {Key: "$each", Value: bson.A{"y", "z"}},
+			}}}}},
+			false, bson.D{{Key: "tags", Value: bson.A{"y", "z"}}}},
+		{"push each slice first", bson.D{{Key: "tags", Value: bson.A{"x"}}},
+			bson.D{{Key: "$push", Value: bson.D{{Key: "tags", Value: bson.D{
+				{Key: "$each", Value: bson.A{"y", "z"}}, {Key: "$slice", Value: 1},
+			}}}}},
+			false, bson.D{{Key: "tags", Value: bson.A{"x"}}}},
+		{"push each slice beyond length", bson.D{{Key: "tags", Value: bson.A{"x"}}},
+			bson.D{{Key: "$push", Value: bson.D{{Key: "tags", Value: bson.D{
+				{Key: "$each", Value: bson.A{"y"}}, {Key: "$slice", Value: -5},
+			}}}}},
+			false, bson.D{{Key: "tags", Value: bson.A{"x", "y"}}}},
+		{"push array value", bson.D{{Key: "tags", Value: bson.A{}}},
+			bson.D{{Key: "$push", Value: bson.D{{Key: "tags", Value: bson.A{"y", "z"}}}}},
+			false, bson.D{{Key: "tags", Value: bson.A{bson.A{"y", "z"}}}}},
+		{"addToSet", bson.D{{Key: "tags", Value: bson.A{"x"}}},
+			bson.D{{Key: "$addToSet", Value: bson.D{{Key: "tags", Value: "x"}}}},
+			false, bson.D{{Key: "tags", Value: bson.A{"x"}}}},
+		{"addToSet each", bson.D{{Key: "tags", Value: bson.A{"x"}}},
+			bson.D{{Key: "$addToSet", Value: bson.D{{Key: "tags", Value: bson.D{{Key: "$each", Value: bson.A{"x", "y", "y"}}}}}}},
+			false, bson.D{{Key: "tags", Value: bson.A{"x", "y"}}}},
+		{"addToSet document", bson.D{{Key: "items", Value: bson.A{bson.D{{Key: "a", Value: 1}}}}},
+			bson.D{{Key: "$addToSet", Value: bson.D{{Key: "items", Value: bson.D{{Key: "a", Value: 1}}}}}},
+			false, bson.D{{Key: "items", Value: bson.A{bson.D{{Key: "a", Value: 1}}}}}},
+		{"pull value", bson.D{{Key: "tags", Value: bson.A{"x", "y", "x"}}},
+			bson.D{{Key: "$pull", Value: bson.D{{Key: "tags", Value: "x"}}}},
+			false, bson.D{{Key: "tags", Value: bson.A{"y"}}}},
+		{"pull condition", bson.D{{Key: "n", Value: bson.A{1, 5, 8}}},
+			bson.D{{Key: "$pull", Value: bson.D{{Key: "n", Value: bson.D{{Key: "$gte", Value: 5}}}}}},
+			false, bson.D{{Key: "n", Value: bson.A{1}}}},
+		{"pull document condition", bson.D{{Key: "items", Value: bson.A{
+			bson.D{{Key: "sku", Value: "a"}, {Key: "qty", Value: 0}},
+			bson.D{{Key: "sku", Value: "b"}, {Key: "qty", Value: 2}},
+		}}},
+			bson.D{{Key: "$pull", Value: bson.D{{Key: "items", Value: bson.D{{Key: "qty", Value: 0}}}}}},
+			false, bson.D{{Key: "items", Value: bson.A{bson.D{{Key: "sku", Value: "b"}, {Key: "qty", Value: 2}}}}}},
+		{"pull missing", bson.D{},
+			bson.D{{Key: "$pull", Value: bson.D{{Key: "tags", Value: "x"}}}},
+			false, bson.D{}},
+		{"pop last", bson.D{{Key: "n", Value: bson.A{1, 2, 3}}},
+			bson.D{{Key: "$pop", Value: bson.D{{Key: "n", Value: 1}}}},
+			false, bson.D{{Key: "n", Value: bson.A{1, 2}}}},
+		{"pop first", bson.D{{Key: "n", Value: bson.A{1, 2, 3}}},
+			bson.D{{Key: "$pop", Value: bson.D{{Key: "n", Value: -1}}}},
+			false, bson.D{{Key: "n", Value: bson.A{2, 3}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustDoc(t, tt.doc)
+			if err := applyUpdate(&doc, mustDoc(t, tt.update), tt.inserting); err != nil {
+				t.Fatal(err)
+			}
+			if want := mustDoc(t, tt.want); !reflect.DeepEqual(doc, want) {
+				t.Errorf("doc = %v, want %v", doc, want)
+			}
+		})
+	}
+}
+
+func TestApplyUpdateCurrentDate(t *testing.T) {
+	doc := bson.D{}
+	if err := applyUpdate(&doc, bson.D{{Key: "$currentDate", Value: bson.D{{Key: "at", Value: true}}}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := getPath(doc, []string{"at"}); reflect.TypeOf(v) != reflect.TypeOf(primitive.DateTime(0)) {
+		t.Errorf("at = %T, want primitive.DateTime", v)
+	}
+}
+
+func TestApplyUpdateInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    bson.D
+		update bson.D
+	}{
+		{"replacement", bson.D{}, bson.D{{Key: "a", Value: 1}}},
+		{"change _id", bson.D{{Key: "_id", Value: "a"}}, bson.D{{Key: "$set", Value: bson.D{{Key: "_id", Value: "b"}}}}},
+		{"unset _id", bson.D{{Key: "_id", Value: "a"}}, bson.D{{Key: "$unset", Value: bson.D{{Key: "_id", Value: ""}}}}},
+		{"inc non-numeric field", bson.D{{Key: "n", Value: "x"}}, bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: 1}}}}},
+		{"inc by non-number", bson.D{{Key: "n", Value: 1}}, bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: "1"}}}}},
+		{"set below scalar", bson.D{{Key: "a", Value: 5}}, bson.D{{Key: "$set", Value: bson.D{{Key: "a.b", Value: 1}}}}},
+		{"push to non-array", bson.D{{Key: "tags", Value: "x"}}, bson.D{{Key: "$push", Value: bson.D{{Key: "tags", Value: "y"}}}}},
+		{"push modifier", bson.D{}, bson.D{{Key: "$push", Value: bson.D{{Key: "tags", Value: bson.D{
+			{Key: "$each", Value: bson.A{"y"}}, {Key: "$position", Value: 0},
+		}}}}}},
+		{"addToSet slice", bson.D{}, bson.D{{Key: "$addToSet", Value: bson.D{{Key: "tags", Value: bson.D{
+			{Key: "$each", Value: bson.A{"y"}}, {Key: "$slice", Value: 1},
+		}}}}}},
+		{"unsupported operator", bson.D{}, bson.D{{Key: "$bit", Value: bson.D{{Key: "n", Value: bson.D{{Key: "and", Value: 1}}}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustDoc(t, tt.doc)
+			err := applyUpdate(&doc, mustDoc(t, tt.update), false)
+			if !errs.Is(err, errs.Invalid) {
+				t.Errorf("err = %v, want an errs.Invalid error", err)
+			}
+		})
+	}
+}
+
+func TestUpsertDoc(t *testing.T) {
+	filter := bson.D{
+		{Key: "tenant", Value: "acme"},
+		{Key: "status", Value: bson.D{{Key: "$eq", Value: "open"}}},
+		{Key: "n", Value: bson.D{{Key: "$gt", Value: 1}}},
+		{Key: "a.b", Value: 1},
+		{Key: "$and", Value: bson.A{bson.D{{Key: "kind", Value: "x"}}}},
+		{Key: "$or", Value: bson.A{bson.D{{Key: "skipped", Value: 1}}}},
+	}
+	want := mustDoc(t, bson.D{
+		{Key: "tenant", Value: "acme"},
+		{Key: "status", Value: "open"},
+		{Key: "a", Value: bson.D{{Key: "b", Value: 1}}},
+		{Key: "kind", Value: "x"},
+	})
+	if got := upsertDoc(mustDoc(t, filter)); !reflect.DeepEqual(got, want) {
+		t.Errorf("upsertDoc = %v, want %v", got, want)
+	}
+}
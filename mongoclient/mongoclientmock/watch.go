@@ -0,0 +1,195 @@
+package mongoclientmock
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// watcher delivers the change events of one Watch call. Events are queued without limit,
+// so writes never block on a slow consumer.
+type watcher struct {
+	ns           mongoclient.Namespace
+	filter       bson.D
+	fullDocument bool
+	out          chan mongoclient.ChangeEvent
+	ctx          context.Context
+
+	mu     sync.Mutex
+	queue  []mongoclient.ChangeEvent
+	signal chan struct{}
+}
+
+// covers reports whether the watcher's scope includes ns
+func (w *watcher) covers(ns mongoclient.Namespace) bool {
+	return (w.ns.Database == "" || w.ns.Database == ns.Database) &&
+		(w.ns.Collection == "" || w.ns.Collection == ns.Collection)
+}
+
+func (w *watcher) push(e mongoclient.ChangeEvent) {
+	w.mu.Lock()
+	w.queue = append(w.queue, e)
+	w.mu.Unlock()
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher) run() {
+	defer close(w.out)
+	for {
+		w.mu.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.mu.Unlock()
+		for _, e := range queue {
+			select {
+			case w.out <- e:
+			case <-w.ctx.Done():
+				return
+			}
+		}
+		select {
+		case <-w.signal:
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
+// Watch sends the changes made through the Store after the call to the returned channel,
+// until ctx is done. params.Filter and $match stages in pipeline filter the events; other
+// stages are not supported. ResumeAfter and StartAt are ignored.
+func (s *Store) Watch(ctx context.Context, params mongoclient.QueryParams, pipeline mongo.Pipeline) (<-chan mongoclient.ChangeEvent, error) {
+	filter, err := toDoc(params.Filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, stage := range pipeline {
+		stage, err := toDoc(stage)
+		if err != nil {
+			return nil, err
+		}
+		if len(stage) != 1 || stage[0].Key != "$match" {
+			return nil, unsupported("change stream stage other than $match")
+		}
+		match, _ := stage[0].Value.(bson.D)
+		filter = append(filter, bson.E{Key: "$and", Value: bson.A{match}})
+	}
+
+	w := &watcher{
+		ns:           namespace(params),
+		filter:       filter,
+		fullDocument: params.WatchOptions.FullDocument,
+		out:          make(chan mongoclient.ChangeEvent, params.WatchOptions.Buffer),
+		ctx:          ctx,
+		signal:       make(chan struct{}, 1),
+	}
+	s.mu.Lock()
+	s.watchers = append(s.watchers, w)
+	s.mu.Unlock()
+
+	go func() {
+		w.run()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, x := range s.watchers {
+			if x == w {
+				s.watchers = append(s.watchers[:i:i], s.watchers[i+1:]...)
+				break
+			}
+		}
+	}()
+	return w.out, nil
+}
+
+// notify queues the change of a document in ns for the watchers covering it. It is called
+// with s.mu held. after is nil for deletes and before is nil for inserts.
+func (s *Store) notify(ns mongoclient.Namespace, op string, after, before bson.D) {
+	if len(s.watchers) == 0 {
+		return
+	}
+	s.clock++
+	doc := after
+	if doc == nil {
+		doc = before
+	}
+	id, _ := getPath(doc, []string{"_id"})
+	key, _ := bson.Marshal(bson.D{{Key: "_id", Value: id}})
+	token, _ := bson.Marshal(bson.D{{Key: "_data", Value: strconv.FormatUint(uint64(s.clock), 16)}})
+
+	e := mongoclient.ChangeEvent{
+		OperationType: op,
+		Namespace:     ns,
+		DocumentKey:   key,
+		ClusterTime:   primitive.Timestamp{T: s.clock},
+		ResumeToken:   token,
+	}
+	if op == "update" {
+		e.Update = describeUpdate(before, after)
+	}
+
+	for _, w := range s.watchers {
+		if !w.covers(ns) || w.ctx.Err() != nil {
+			continue
+		}
+		we := e
+		if after != nil && (op != "update" || w.fullDocument) {
+			we.FullDocument, _ = bson.Marshal(after)
+		}
+		if len(w.filter) > 0 {
+			if ok, err := matches(eventDoc(we, id, after), w.filter); err != nil || !ok {
+				continue
+			}
+		}
+		w.push(we)
+	}
+}
+
+// eventDoc is the change event as the server's $match stages see it
+func eventDoc(e mongoclient.ChangeEvent, id interface{}, after bson.D) bson.D {
+	doc := bson.D{
+		{Key: "operationType", Value: e.OperationType},
+		{Key: "ns", Value: bson.D{{Key: "db", Value: e.Namespace.Database}, {Key: "coll", Value: e.Namespace.Collection}}},
+		{Key: "documentKey", Value: bson.D{{Key: "_id", Value: id}}},
+	}
+	if len(e.FullDocument) > 0 {
+		doc = append(doc, bson.E{Key: "fullDocument", Value: after})
+	}
+	if e.Update != nil {
+		updated, _ := toDoc(e.Update.UpdatedFields)
+		removed := make(bson.A, len(e.Update.RemovedFields))
+		for i, f := range e.Update.RemovedFields {
+			removed[i] = f
+		}
+		doc = append(doc, bson.E{Key: "updateDescription", Value: bson.D{
+			{Key: "updatedFields", Value: updated},
+			{Key: "removedFields", Value: removed},
+		}})
+	}
+	return doc
+}
+
+// describeUpdate lists the top-level fields that differ between before and after
+func describeUpdate(before, after bson.D) *mongoclient.UpdateDescription {
+	var updated bson.D
+	for _, e := range after {
+		if v, ok := getPath(before, []string{e.Key}); !ok || !equal(v, e.Value) {
+			updated = append(updated, e)
+		}
+	}
+	removed := []string{}
+	for _, e := range before {
+		if _, ok := getPath(after, []string{e.Key}); !ok {
+			removed = append(removed, e.Key)
+		}
+	}
+	raw, _ := bson.Marshal(updated)
+	return &mongoclient.UpdateDescription{UpdatedFields: raw, RemovedFields: removed}
+}
@@ -0,0 +1,218 @@
+// Package mongotest runs integration tests against a real MongoDB. It starts a container
+// with testcontainers, or connects to the server in MONGO_TEST_URI, and gives each test a
+// database of its own that is dropped when the test ends. It is a separate module, so
+// services importing mongoclient do not pull in the container dependencies.
+package mongotest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+// URIEnv names the environment variable that points the harness at an existing server,
+// e.g. a CI service container, instead of starting a container
+const URIEnv = "MONGO_TEST_URI"
+
+// StartTimeout bounds how long the container may take to become ready
+var StartTimeout = 2 * time.Minute
+
+// Options configures Start
+type Options struct {
+	// Image defaults to "mongo:7".
+	Image string
+	// ReplicaSet starts a single-node replica set, needed for transactions and change streams.
+	// It is ignored with MONGO_TEST_URI, whose server must be a replica set itself.
+	ReplicaSet bool
+}
+
+// Server is a running MongoDB with a connected client
+type Server struct {
+	Client *mongoclient.Client
+	URI    string
+}
+
+// DB is a database of its own for one test
+type DB struct {
+	Client *mongoclient.Client
+	URI    string
+	// Name is the unique name of the database.
+	Name string
+}
+
+var (
+	sharedOnce   sync.Once
+	sharedServer *server
+	sharedErr    error
+)
+
+// New returns a database of its own for t, dropped when t ends, on a server shared by the
+// tests of the package: the one in MONGO_TEST_URI, or a container started by the first
+// call and removed by the testcontainers reaper when the test binary exits. The test is
+// skipped when neither is available.
+//
+//	func TestOrders(t *testing.T) {
+//	    db := mongotest.New(t)
+//	    db.Seed(t, "orders", bson.M{"_id": "o1", "status": "open"})
+//	    repo := orders.NewRepository(db.Client, db.Name)
+//	    // ...
+//	}
+func New(t *testing.T) *DB {
+	t.Helper()
+	if os.Getenv(URIEnv) == "" {
+		testcontainers.SkipIfProviderIsNotHealthy(t)
+	}
+	sharedOnce.Do(func() {
+		sharedServer, sharedErr = start(Options{})
+	})
+	if sharedErr != nil {
+		t.Fatalf("mongotest: %v", sharedErr)
+	}
+	return sharedServer.New(t)
+}
+
+// Start starts a MongoDB container for t alone, or connects to the server in MONGO_TEST_URI
+// when it is set. The client is closed and the container terminated when t ends. Use it
+// over New for a replica set or a specific image.
+func Start(t *testing.T, opts Options) *Server {
+	t.Helper()
+	if os.Getenv(URIEnv) == "" {
+		testcontainers.SkipIfProviderIsNotHealthy(t)
+	}
+	s, err := start(opts)
+	if err != nil {
+		t.Fatalf("mongotest: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.close(ctx); err != nil {
+			t.Logf("mongotest: %v", err)
+		}
+	})
+	return s.Server
+}
+
+// New returns a database of its own on s for t, dropped when t ends. Call it in each
+// subtest, so subtests can run in parallel without seeing each other's data.
+func (s *Server) New(t *testing.T) *DB {
+	t.Helper()
+	db := &DB{Client: s.Client, URI: s.URI, Name: uniqueName("test")}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := db.Client.Database(db.Name).Drop(ctx); err != nil {
+			t.Logf("mongotest: failed to drop %s: %v", db.Name, err)
+		}
+	})
+	return db
+}
+
+// Seed inserts docs into collection of the database
+func (db *DB) Seed(t *testing.T, collection string, docs ...interface{}) {
+	t.Helper()
+	if len(docs) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := db.Client.Database(db.Name).Collection(collection).InsertMany(ctx, docs); err != nil {
+		t.Fatalf("mongotest: failed to seed %s: %v", collection, err)
+	}
+}
+
+// Reset drops the database, for subtests sharing one
+func (db *DB) Reset(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := db.Client.Database(db.Name).Drop(ctx); err != nil {
+		t.Fatalf("mongotest: failed to drop %s: %v", db.Name, err)
+	}
+}
+
+// server wraps a Server with the container it runs in, if any
+type server struct {
+	*Server
+	container testcontainers.Container
+}
+
+func start(opts Options) (*server, error) {
+	if uri := os.Getenv(URIEnv); uri != "" {
+		client, err := connect(uri)
+		if err != nil {
+			return nil, err
+		}
+		return &server{Server: &Server{Client: client, URI: uri}}, nil
+	}
+
+	if opts.Image == "" {
+		opts.Image = "mongo:7"
+	}
+	var customizers []testcontainers.ContainerCustomizer
+	if opts.ReplicaSet {
+		customizers = append(customizers, mongodb.WithReplicaSet("rs0"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), StartTimeout)
+	defer cancel()
+	container, err := mongodb.Run(ctx, opts.Image, customizers...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start mongo container: %w", err)
+	}
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		container.Terminate(context.WithoutCancel(ctx))
+		return nil, fmt.Errorf("failed to get mongo connection string: %w", err)
+	}
+	if opts.ReplicaSet {
+		// The replica set advertises the container IP, which the host cannot reach; connect directly instead
+		uri += "/?directConnection=true"
+	}
+	client, err := connect(uri)
+	if err != nil {
+		container.Terminate(context.WithoutCancel(ctx))
+		return nil, err
+	}
+	return &server{Server: &Server{Client: client, URI: uri}, container: container}, nil
+}
+
+func connect(uri string) (*mongoclient.Client, error) {
+	client, err := mongoclient.NewClient(mongoclient.ClientOptions{
+		URI:                    uri,
+		ConnectTimeout:         10 * time.Second,
+		ServerSelectionTimeout: 10 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mongo: %w", err)
+	}
+	return client, nil
+}
+
+// close disconnects the client and terminates the container
+func (s *server) close(ctx context.Context) error {
+	s.Client.Close(ctx)
+	if s.container == nil {
+		return nil
+	}
+	if err := s.container.Terminate(ctx); err != nil {
+		return fmt.Errorf("failed to terminate container: %w", err)
+	}
+	return nil
+}
+
+// uniqueName returns prefix with a random suffix
+func uniqueName(prefix string) string {
+	var b [4]byte
+	rand.Read(b[:])
+	return prefix + "-" + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,80 @@
+package mongoclient
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Normalize converts a decoded BSON value into plain Go types that marshal to predictable
+// JSON, recursing into documents and arrays:
+//
+//   - ObjectID becomes its hex string
+//   - DateTime and Timestamp become time.Time in UTC
+//   - Decimal128 becomes its decimal string, e.g. "19.99", so no precision is lost
+//   - UUID binaries become their canonical string, other binaries []byte
+//   - Regex becomes "/pattern/options", JavaScript and Symbol their string
+//   - Null and Undefined become nil
+//   - documents, whether bson.D or bson.M, become map[string]interface{} and arrays
+//     []interface{}
+//
+// Other values, such as strings, numbers and booleans, are returned unchanged.
+func Normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case primitive.DateTime:
+		return v.Time().UTC()
+	case primitive.Timestamp:
+		return time.Unix(int64(v.T), 0).UTC()
+	case primitive.Decimal128:
+		return v.String()
+	case primitive.Binary:
+		if (v.Subtype == bson.TypeBinaryUUID || v.Subtype == bson.TypeBinaryUUIDOld) && len(v.Data) == 16 {
+			d := v.Data
+			return fmt.Sprintf("%x-%x-%x-%x-%x", d[0:4], d[4:6], d[6:8], d[8:10], d[10:16])
+		}
+		return v.Data
+	case primitive.Regex:
+		return "/" + v.Pattern + "/" + v.Options
+	case primitive.JavaScript:
+		return string(v)
+	case primitive.Symbol:
+		return string(v)
+	case primitive.Null, primitive.Undefined:
+		return nil
+	case primitive.D:
+		m := make(map[string]interface{}, len(v))
+		for _, e := range v {
+			m[e.Key] = Normalize(e.Value)
+		}
+		return m
+	case primitive.M:
+		return normalizeMap(v)
+	case map[string]interface{}:
+		return normalizeMap(v)
+	case primitive.A:
+		return normalizeSlice(v)
+	case []interface{}:
+		return normalizeSlice(v)
+	}
+	return v
+}
+
+func normalizeMap(v map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(v))
+	for k, x := range v {
+		m[k] = Normalize(x)
+	}
+	return m
+}
+
+func normalizeSlice(v []interface{}) []interface{} {
+	s := make([]interface{}, len(v))
+	for i, x := range v {
+		s[i] = Normalize(x)
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package mongoclient
+
+import (
+	"fmt"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewID returns a new ObjectID as its 24-character hex string, so application code can
+// assign _id values without importing the driver. IDs sort by creation time to the second.
+func NewID() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// ParseID parses the hex string of an ObjectID, e.g. from a URL path, and returns an
+// errs.Invalid error when s is not one. The result can go straight into a filter or
+// document.
+func ParseID(s string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(s)
+	if err != nil {
+		return primitive.NilObjectID, errs.New(errs.Invalid, "invalid ID").With("id", s)
+	}
+	return oid, nil
+}
+
+// ValidID reports whether s is the hex string of an ObjectID
+func ValidID(s string) bool {
+	return primitive.IsValidObjectID(s)
+}
+
+// ByID returns the filter matching the document with _id id. A valid ObjectID hex string
+// matches an ObjectID _id; any other string, e.g. a ULID, matches a string _id as is:
+//
+//	err := client.QueryOne(ctx, mongoclient.QueryParams{Database: "mydb", Collection: "users", Filter: mongoclient.ByID(r.PathValue("id"))}, &user)
+func ByID(id string) bson.M {
+	if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+		return bson.M{"_id": oid}
+	}
+	return bson.M{"_id": id}
+}
+
+// ByIDs returns the filter matching the documents whose _id is one of ids, converted as by
+// ByID
+func ByIDs(ids ...string) bson.M {
+	in := make(bson.A, len(ids))
+	for i, id := range ids {
+		in[i] = ByID(id)["_id"]
+	}
+	return bson.M{"_id": bson.M{"$in": in}}
+}
+
+// IDString returns an _id as a string, e.g. the InsertedID of InsertOne: the hex string of
+// an ObjectID, a string as is, and other values formatted with fmt
+func IDString(id interface{}) string {
+	switch v := id.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(id)
+}
@@ -0,0 +1,83 @@
+package mongoclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// OutboxMessage is an event stored in the outbox collection in the same transaction as the
+// business write it describes, for a relay to publish afterwards. The relay finds pending
+// messages with bson.M{"published_at": nil}, sorted by _id.
+type OutboxMessage struct {
+	// ID defaults to a new ObjectID, which sorts in creation order.
+	ID primitive.ObjectID `bson:"_id"`
+	// Type names the event, e.g. "order.created".
+	Type string `bson:"type"`
+	// Key groups messages that must be published in order, e.g. the order ID.
+	Key     string      `bson:"key,omitempty"`
+	Payload interface{} `bson:"payload,omitempty"`
+	// CreatedAt defaults to the time of the write.
+	CreatedAt   time.Time  `bson:"created_at"`
+	PublishedAt *time.Time `bson:"published_at"`
+}
+
+// OutboxOptions locates the outbox collection
+type OutboxOptions struct {
+	Database string
+	// Collection defaults to "outbox".
+	Collection string
+}
+
+// WithOutbox runs fn in a transaction and stores the messages it returns in the outbox in
+// the same transaction, so the business write and its events are committed together or
+// not at all. fn must use sessCtx for its operations, e.g. client.InsertOne(sessCtx, ...),
+// and, like RunTransaction's fn, be safe to run more than once. Transactions need a
+// replica set.
+//
+//	err := client.WithOutbox(ctx, mongoclient.OutboxOptions{Database: "shop"},
+//		func(sessCtx mongo.SessionContext) ([]mongoclient.OutboxMessage, error) {
+//			if _, err := client.InsertOne(sessCtx, orders, order); err != nil {
+//				return nil, err
+//			}
+//			return []mongoclient.OutboxMessage{{Type: "order.created", Key: order.ID, Payload: order}}, nil
+//		})
+func (c *Client) WithOutbox(ctx context.Context, outbox OutboxOptions, fn func(sessCtx mongo.SessionContext) ([]OutboxMessage, error)) error {
+	if err := c.writable("mongoclient.WithOutbox"); err != nil {
+		return err
+	}
+	if outbox.Collection == "" {
+		outbox.Collection = "outbox"
+	}
+	return c.RunTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		messages, err := fn(sessCtx)
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+		now := time.Now().UTC()
+		docs := make([]interface{}, len(messages))
+		for i, m := range messages {
+			if m.Type == "" {
+				return errs.New(errs.Invalid, "outbox message requires a type")
+			}
+			if m.ID.IsZero() {
+				m.ID = primitive.NewObjectID()
+			}
+			if m.CreatedAt.IsZero() {
+				m.CreatedAt = now
+			}
+			docs[i] = m
+		}
+		params, err := c.route(sessCtx, QueryParams{Database: outbox.Database, Collection: outbox.Collection})
+		if err != nil {
+			return err
+		}
+		if _, err := c.collection(params).InsertMany(sessCtx, docs); err != nil {
+			return WrapError(err, "mongoclient.WithOutbox", "failed to write outbox messages")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,179 @@
+package mongoclient
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"github.com/cdcloud-io/go-libs/page"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// QueryPage returns one page of documents matching params.Filter, decoded into T.
+// With a cursor from a previous page it uses keyset pagination on the requested sort
+// fields plus _id, so pages stay stable while documents are inserted; otherwise it
+// falls back to req.Offset. Sort fields should be covered by an index.
+func QueryPage[T any](ctx context.Context, c *Client, params QueryParams, req page.PageRequest) (page.PageResponse[T], error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	params = c.scope(params)
+	var resp page.PageResponse[T]
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return resp, err
+	}
+	if req.Limit <= 0 {
+		req.Limit = page.DefaultLimit
+	}
+	collection := c.collection(params)
+	sortFields := withIDTieBreaker(req.Sort)
+
+	filter := bson.M{}
+	for k, v := range params.Filter {
+		filter[k] = v
+	}
+	findOpts := options.Find().SetSort(sortDoc(sortFields)).SetLimit(int64(req.Limit) + 1)
+	if req.Cursor != "" {
+		values, err := decodePageCursor(req.Cursor, len(sortFields))
+		if err != nil {
+			return resp, errs.Wrap(err, errs.Invalid, "mongoclient.QueryPage", "invalid page cursor")
+		}
+		keyset := keysetFilter(sortFields, values)
+		if len(filter) == 0 {
+			filter = keyset
+		} else {
+			filter = bson.M{"$and": bson.A{filter, keyset}}
+		}
+	} else if req.Offset > 0 {
+		findOpts.SetSkip(int64(req.Offset))
+	}
+	if params.Options.Collation != nil {
+		findOpts.SetCollation(params.Options.Collation)
+	}
+
+	var raws []bson.Raw
+	err = c.retry(ctx, func() error {
+		cursor, err := collection.Find(ctx, filter, findOpts)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		raws = raws[:0]
+		for cursor.Next(ctx) {
+			raws = append(raws, append(bson.Raw(nil), cursor.Current...))
+		}
+		return cursor.Err()
+	})
+	if err != nil {
+		return resp, WrapError(err, "mongoclient.QueryPage", "failed to execute Find query")
+	}
+
+	if len(raws) > req.Limit {
+		raws = raws[:req.Limit]
+		resp.HasMore = true
+	}
+	resp.Items = make([]T, len(raws))
+	for i, raw := range raws {
+		if err := bson.Unmarshal(raw, &resp.Items[i]); err != nil {
+			return resp, errs.Wrap(err, errs.Internal, "mongoclient.QueryPage", "failed to decode query results")
+		}
+	}
+	if resp.HasMore {
+		if resp.NextCursor, err = encodePageCursor(raws[len(raws)-1], sortFields); err != nil {
+			return resp, errs.Wrap(err, errs.Internal, "mongoclient.QueryPage", "failed to encode page cursor")
+		}
+	}
+
+	if req.IncludeTotal {
+		total, err := collection.CountDocuments(ctx, params.Filter)
+		if err != nil {
+			return resp, WrapError(err, "mongoclient.QueryPage", "failed to count documents")
+		}
+		resp.Total = &total
+	}
+	return resp, nil
+}
+
+// Paginate is QueryPage for callers that only hold a Store, which cannot have generic
+// methods. Items are the raw documents; decode each with bson.Unmarshal.
+func (c *Client) Paginate(ctx context.Context, params QueryParams, req page.PageRequest) (page.PageResponse[bson.Raw], error) {
+	return QueryPage[bson.Raw](ctx, c, params, req)
+}
+
+// withIDTieBreaker appends _id so the sort order is total and keyset cursors are unambiguous
+func withIDTieBreaker(sort []page.SortField) []page.SortField {
+	for _, f := range sort {
+		if f.Field == "_id" {
+			return sort
+		}
+	}
+	desc := len(sort) > 0 && sort[len(sort)-1].Desc
+	return append(append([]page.SortField(nil), sort...), page.SortField{Field: "_id", Desc: desc})
+}
+
+func sortDoc(sort []page.SortField) bson.D {
+	d := make(bson.D, len(sort))
+	for i, f := range sort {
+		dir := 1
+		if f.Desc {
+			dir = -1
+		}
+		d[i] = bson.E{Key: f.Field, Value: dir}
+	}
+	return d
+}
+
+// keysetFilter matches documents strictly after the cursor position:
+// (a > va) OR (a == va AND b > vb) OR ... with $lt for descending fields
+func keysetFilter(sort []page.SortField, values bson.A) bson.M {
+	or := make(bson.A, 0, len(sort))
+	for i, f := range sort {
+		clause := bson.M{}
+		for j := 0; j < i; j++ {
+			clause[sort[j].Field] = values[j]
+		}
+		op := "$gt"
+		if f.Desc {
+			op = "$lt"
+		}
+		clause[f.Field] = bson.M{op: values[i]}
+		or = append(or, clause)
+	}
+	return bson.M{"$or": or}
+}
+
+// Cursors hold the sort values of the last document as canonical Extended JSON,
+// so ObjectIDs and dates keep their BSON types across the round trip
+func encodePageCursor(last bson.Raw, sort []page.SortField) (string, error) {
+	values := make(bson.A, len(sort))
+	for i, f := range sort {
+		v, err := last.LookupErr(strings.Split(f.Field, ".")...)
+		if err != nil {
+			values[i] = nil
+			continue
+		}
+		values[i] = v
+	}
+	b, err := bson.MarshalExtJSON(bson.M{"v": values}, true, false)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodePageCursor(cursor string, n int) (bson.A, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, page.ErrInvalidCursor
+	}
+	var doc struct {
+		V bson.A `bson:"v"`
+	}
+	if err := bson.UnmarshalExtJSON(b, true, &doc); err != nil || len(doc.V) != n {
+		return nil, page.ErrInvalidCursor
+	}
+	return doc.V, nil
+}
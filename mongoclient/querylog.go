@@ -0,0 +1,185 @@
+package mongoclient
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// QueryLogOptions logs every command with its filter, duration and result size. Filters
+// are logged as their shape only, with every value replaced by "?", so logs hold no data.
+type QueryLogOptions struct {
+	// Logger receives the records. Nil disables query logging.
+	Logger *slog.Logger
+	// SlowThreshold logs commands that take at least this long at Warn level; all others are
+	// logged at Debug level. Defaults to 100ms.
+	SlowThreshold time.Duration
+}
+
+type queryLog struct {
+	opts QueryLogOptions
+
+	mu      sync.Mutex
+	pending map[string]startedCommand // connection and request ID -> command
+}
+
+type startedCommand struct {
+	ctx        context.Context
+	collection string
+	command    bson.Raw
+}
+
+func newQueryLog(opts QueryLogOptions) *queryLog {
+	if opts.SlowThreshold <= 0 {
+		opts.SlowThreshold = 100 * time.Millisecond
+	}
+	return &queryLog{opts: opts, pending: make(map[string]startedCommand)}
+}
+
+func (l *queryLog) commandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			l.mu.Lock()
+			l.pending[requestKey(e.ConnectionID, e.RequestID)] = startedCommand{
+				ctx:        ctx,
+				collection: commandCollection(e),
+				command:    append(bson.Raw(nil), e.Command...),
+			}
+			l.mu.Unlock()
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			l.finished(&e.CommandFinishedEvent, e.Reply, "")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			l.finished(&e.CommandFinishedEvent, nil, e.Failure)
+		},
+	}
+}
+
+func (l *queryLog) finished(e *event.CommandFinishedEvent, reply bson.Raw, failure string) {
+	key := requestKey(e.ConnectionID, e.RequestID)
+	l.mu.Lock()
+	started, ok := l.pending[key]
+	delete(l.pending, key)
+	l.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	level, msg := slog.LevelDebug, "mongodb command"
+	if e.Duration >= l.opts.SlowThreshold {
+		level, msg = slog.LevelWarn, "slow mongodb command"
+	}
+	if !l.opts.Logger.Enabled(started.ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("command", e.CommandName),
+		slog.String("database", e.DatabaseName),
+		slog.Duration("duration", e.Duration),
+	}
+	if started.collection != "" {
+		attrs = append(attrs, slog.String("collection", started.collection))
+	}
+	if filter, ok := commandFilter(e.CommandName, started.command); ok {
+		attrs = append(attrs, slog.String("filter", shape(filter)))
+	}
+	if failure != "" {
+		attrs = append(attrs, slog.String("error", failure))
+	} else if n, ok := resultSize(reply); ok {
+		attrs = append(attrs, slog.Int64("result_size", n))
+	}
+	l.opts.Logger.LogAttrs(started.ctx, level, msg, attrs...)
+}
+
+// commandFilter returns the filter or pipeline of a command
+func commandFilter(name string, cmd bson.Raw) (bson.RawValue, bool) {
+	var path []string
+	switch name {
+	case "find":
+		path = []string{"filter"}
+	case "update":
+		path = []string{"updates", "0", "q"}
+	case "delete":
+		path = []string{"deletes", "0", "q"}
+	case "count", "distinct", "findAndModify":
+		path = []string{"query"}
+	case "aggregate":
+		path = []string{"pipeline"}
+	default:
+		return bson.RawValue{}, false
+	}
+	v, err := cmd.LookupErr(path...)
+	return v, err == nil
+}
+
+// resultSize returns the number of documents a reply returned or affected
+func resultSize(reply bson.Raw) (int64, bool) {
+	if len(reply) == 0 {
+		return 0, false
+	}
+	for _, path := range [][]string{{"cursor", "firstBatch"}, {"cursor", "nextBatch"}, {"values"}} {
+		if v, err := reply.LookupErr(path...); err == nil && v.Type == bsontype.Array {
+			values, _ := v.Array().Values()
+			return int64(len(values)), true
+		}
+	}
+	for _, path := range [][]string{{"n"}, {"lastErrorObject", "n"}} {
+		if v, err := reply.LookupErr(path...); err == nil {
+			if n, ok := v.AsInt64OK(); ok {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// shape renders v as JSON with every value replaced by "?", keeping field names and
+// operators, e.g. {"age":{"$gt":"?"},"status":{"$in":["?"]}}. Repeated array elements of
+// the same shape are written once, so the length of a list does not show.
+func shape(v bson.RawValue) string {
+	var b strings.Builder
+	writeShape(&b, v)
+	return b.String()
+}
+
+func writeShape(b *strings.Builder, v bson.RawValue) {
+	switch v.Type {
+	case bsontype.EmbeddedDocument:
+		elems, _ := v.Document().Elements()
+		b.WriteByte('{')
+		for i, e := range elems {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(`"` + strings.ReplaceAll(e.Key(), `"`, `\"`) + `":`)
+			writeShape(b, e.Value())
+		}
+		b.WriteByte('}')
+	case bsontype.Array:
+		values, _ := v.Array().Values()
+		b.WriteByte('[')
+		prev := ""
+		for _, e := range values {
+			s := shape(e)
+			if s == prev {
+				continue
+			}
+			if prev != "" {
+				b.WriteByte(',')
+			}
+			b.WriteString(s)
+			prev = s
+		}
+		b.WriteByte(']')
+	default:
+		b.WriteString(`"?"`)
+	}
+}
@@ -0,0 +1,12 @@
+package mongoclient
+
+import "github.com/cdcloud-io/go-libs/errs"
+
+// writable returns an errs.Forbidden error matching ErrReadOnly when the client was created
+// with ClientOptions.ReadOnly
+func (c *Client) writable(op string) error {
+	if c.readOnly {
+		return errs.Wrap(ErrReadOnly, errs.Forbidden, op, "client is read-only")
+	}
+	return nil
+}
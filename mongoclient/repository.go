@@ -0,0 +1,194 @@
+package mongoclient
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"github.com/cdcloud-io/go-libs/page"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// RepositoryOptions configures a CollectionRepository
+type RepositoryOptions struct {
+	Database   string
+	Collection string
+	// Options are the default sort, limit, skip, projection, hint and collation of FindOne
+	// and Find. Fields
+	// set in the options of a call take precedence.
+	Options QueryOptions
+	// Read and WriteConcern apply to every operation, as in QueryParams.
+	Read         ReadOptions
+	WriteConcern *writeconcern.WriteConcern
+}
+
+// CollectionRepository runs the operations of a Store on one collection, so callers pass
+// only filters and documents instead of a full QueryParams each time. Create one with
+// Client.Repository or NewRepository.
+type CollectionRepository struct {
+	store Store
+	opts  RepositoryOptions
+}
+
+// NewRepository creates a CollectionRepository on store, which can be a Client or an
+// in-memory mongoclientmock.Store
+func NewRepository(store Store, opts RepositoryOptions) *CollectionRepository {
+	return &CollectionRepository{store: store, opts: opts}
+}
+
+// Repository returns a CollectionRepository for a collection with default options
+func (c *Client) Repository(database, collection string) *CollectionRepository {
+	return NewRepository(c, RepositoryOptions{Database: database, Collection: collection})
+}
+
+// Params returns the QueryParams of the collection with filter, e.g. for Store methods the
+// repository does not wrap, such as Watch or BulkWrite
+func (r *CollectionRepository) Params(filter bson.M) QueryParams {
+	return QueryParams{
+		Database:     r.opts.Database,
+		Collection:   r.opts.Collection,
+		Filter:       filter,
+		Options:      r.opts.Options,
+		Read:         r.opts.Read,
+		WriteConcern: r.opts.WriteConcern,
+	}
+}
+
+// query returns the QueryParams for a find, with opts overriding the defaults
+func (r *CollectionRepository) query(filter bson.M, opts QueryOptions) QueryParams {
+	params := r.Params(filter)
+	if len(opts.Sort) > 0 {
+		params.Options.Sort = opts.Sort
+	}
+	if opts.Limit > 0 {
+		params.Options.Limit = opts.Limit
+	}
+	if opts.Skip > 0 {
+		params.Options.Skip = opts.Skip
+	}
+	if len(opts.Projection) > 0 {
+		params.Options.Projection = opts.Projection
+	}
+	if opts.BatchSize > 0 {
+		params.Options.BatchSize = opts.BatchSize
+	}
+	if opts.Hint != nil {
+		params.Options.Hint = opts.Hint
+	}
+	if opts.Collation != nil {
+		params.Options.Collation = opts.Collation
+	}
+	return params
+}
+
+// FindOne decodes the first document matching filter into result. It returns an
+// errs.NotFound error matching ErrNotFound when nothing matches.
+func (r *CollectionRepository) FindOne(ctx context.Context, filter bson.M, opts QueryOptions, result interface{}) error {
+	return r.store.QueryOne(ctx, r.query(filter, opts), result)
+}
+
+// Find decodes every document matching filter into results, which must be a pointer to a
+// slice, e.g. *[]User
+func (r *CollectionRepository) Find(ctx context.Context, filter bson.M, opts QueryOptions, results interface{}) error {
+	rv := reflect.ValueOf(results)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice {
+		return errs.New(errs.Invalid, "Find requires a pointer to a slice")
+	}
+	slice := reflect.MakeSlice(rv.Elem().Type(), 0, 0)
+	elemType := slice.Type().Elem()
+	err := r.store.QueryStream(ctx, r.query(filter, opts), func(doc bson.Raw) error {
+		elem := reflect.New(elemType)
+		if err := bson.Unmarshal(doc, elem.Interface()); err != nil {
+			return errs.Wrap(err, errs.Internal, "mongoclient.Find", "failed to decode document")
+		}
+		slice = reflect.Append(slice, elem.Elem())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	rv.Elem().Set(slice)
+	return nil
+}
+
+// Paginate returns one page of the documents matching filter, see Store.Paginate
+func (r *CollectionRepository) Paginate(ctx context.Context, filter bson.M, req page.PageRequest) (page.PageResponse[bson.Raw], error) {
+	return r.store.Paginate(ctx, r.Params(filter), req)
+}
+
+// Count returns the number of documents matching filter
+func (r *CollectionRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
+	params := r.Params(filter)
+	params.Options = QueryOptions{}
+	return r.store.CountDocuments(ctx, params)
+}
+
+// Insert inserts document and returns its _id
+func (r *CollectionRepository) Insert(ctx context.Context, document interface{}) (interface{}, error) {
+	result, err := r.store.InsertOne(ctx, r.Params(nil), document)
+	if err != nil {
+		return nil, err
+	}
+	return result.InsertedID, nil
+}
+
+// InsertMany inserts documents in order and returns their _ids
+func (r *CollectionRepository) InsertMany(ctx context.Context, documents []interface{}) ([]interface{}, error) {
+	return r.store.InsertMany(ctx, r.Params(nil), documents, BulkOptions{})
+}
+
+// UpdateOne applies update to the first document matching filter
+func (r *CollectionRepository) UpdateOne(ctx context.Context, filter bson.M, update interface{}) (*mongo.UpdateResult, error) {
+	return r.store.UpdateOne(ctx, r.Params(filter), update)
+}
+
+// UpdateMany applies update to every document matching filter. A nil filter is rejected.
+func (r *CollectionRepository) UpdateMany(ctx context.Context, filter bson.M, update interface{}) (*mongo.UpdateResult, error) {
+	return r.store.UpdateMany(ctx, r.Params(filter), update)
+}
+
+// Upsert applies update to the first document matching filter, or inserts one when
+// nothing matches
+func (r *CollectionRepository) Upsert(ctx context.Context, filter bson.M, update interface{}) (*mongo.UpdateResult, error) {
+	params := r.Params(filter)
+	params.UpdateOptions.Upsert = true
+	return r.store.UpdateOne(ctx, params, update)
+}
+
+// ReplaceOne replaces the first document matching filter with replacement
+func (r *CollectionRepository) ReplaceOne(ctx context.Context, filter bson.M, replacement interface{}) (*mongo.UpdateResult, error) {
+	return r.store.ReplaceOne(ctx, r.Params(filter), replacement)
+}
+
+// FindOneAndUpdate atomically applies update to the first document matching filter and
+// decodes it into result, see Store.FindOneAndUpdate
+func (r *CollectionRepository) FindOneAndUpdate(ctx context.Context, filter bson.M, update interface{}, opts FindAndModifyOptions, result interface{}) error {
+	return r.store.FindOneAndUpdate(ctx, r.Params(filter), update, opts, result)
+}
+
+// DeleteOne deletes the first document matching filter and returns the number deleted
+func (r *CollectionRepository) DeleteOne(ctx context.Context, filter bson.M) (int64, error) {
+	result, err := r.store.DeleteOne(ctx, r.Params(filter))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteMany deletes every document matching filter and returns the number deleted. A nil
+// filter is rejected.
+func (r *CollectionRepository) DeleteMany(ctx context.Context, filter bson.M) (int64, error) {
+	result, err := r.store.DeleteMany(ctx, r.Params(filter))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// Aggregate runs pipeline on the documents matching filter and decodes the results into
+// result, which must be a pointer to a slice
+func (r *CollectionRepository) Aggregate(ctx context.Context, filter bson.M, pipeline mongo.Pipeline, result interface{}) error {
+	return r.store.Aggregate(ctx, r.Params(filter), pipeline, result)
+}
@@ -0,0 +1,136 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RetryPolicy retries operations that fail with transient errors: network errors, server
+// selection timeouts and errors the server labels as retryable. The driver already retries
+// a read or write once; the policy adds attempts with backoff on top, to ride out elections
+// and short outages. Writes are retried too, so use it with writes that are safe to repeat,
+// such as upserts and $set updates. InsertOne gives a document without an _id a new ObjectID
+// before the first attempt, so a retry after a lost acknowledgment cannot insert it twice.
+// InsertMany, BulkWrite, Watch and transactions are not retried by the policy.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. 0 or 1 disables retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt, doubled after each. Defaults to 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Defaults to 5s.
+	MaxBackoff time.Duration
+	// NoJitter disables the random spread of each delay over [delay/2, delay], which keeps
+	// replicas from retrying in lockstep.
+	NoJitter bool
+}
+
+// retry runs fn until it succeeds, fails with a permanent error or the policy gives up
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	p := c.retryPolicy
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 5 * time.Second
+	}
+
+	delay := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !transient(err) {
+			return err
+		}
+
+		wait := delay
+		if !p.NoJitter {
+			wait = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, p.MaxBackoff)
+	}
+}
+
+// withID returns document as a bson.D with a new ObjectID _id when it has none, so every
+// attempt of a retried insert sends the same _id. The new _id is returned, or nil when the
+// document has its own.
+func withID(document interface{}) (bson.D, interface{}, error) {
+	doc, err := toD(document)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, e := range doc {
+		if e.Key == "_id" {
+			return doc, nil, nil
+		}
+	}
+	id := primitive.NewObjectID()
+	return append(bson.D{{Key: "_id", Value: id}}, doc...), id, nil
+}
+
+// duplicateID reports whether err is a duplicate key error on _id rather than on another
+// unique index
+func duplicateID(err error) bool {
+	if !mongo.IsDuplicateKeyError(err) {
+		return false
+	}
+	var we mongo.WriteException
+	if !errors.As(err, &we) {
+		return false
+	}
+	for _, e := range we.WriteErrors {
+		if strings.Contains(e.Message, "index: _id_ ") || strings.Contains(e.Message, "dup key: { _id:") {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableCodes are the server errors of failovers and shutdowns that the driver retries reads on
+var retryableCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	262,   // ExceededTimeLimit
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// transient reports whether an operation may succeed when attempted again
+func transient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+	var se mongo.ServerError
+	if errors.As(err, &se) {
+		if se.HasErrorLabel("RetryableWriteError") {
+			return true
+		}
+		for _, code := range retryableCodes {
+			if se.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
+	// Server selection fails with a timeout while no primary is elected
+	return mongo.IsTimeout(err)
+}
@@ -0,0 +1,54 @@
+package mongoclient
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestWithID(t *testing.T) {
+	doc, id, err := withID(bson.M{"name": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := id.(primitive.ObjectID); !ok || len(doc) != 2 || doc[0].Key != "_id" || doc[0].Value != id {
+		t.Errorf("withID() = %v, %v; want a new ObjectID _id first", doc, id)
+	}
+
+	own := struct {
+		ID   string `bson:"_id"`
+		Name string `bson:"name"`
+	}{"order-1", "a"}
+	doc, id, err = withID(own)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != nil || len(doc) != 2 || doc[0].Value != "order-1" {
+		t.Errorf("withID() = %v, %v; want the document's own _id kept", doc, id)
+	}
+}
+
+func TestDuplicateID(t *testing.T) {
+	dup := func(msg string) error {
+		return mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: msg}}}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"_id index", dup(`E11000 duplicate key error collection: shop.orders index: _id_ dup key: { _id: ObjectId('665f1c2e8b3f4a0001a1b2c3') }`), true},
+		{"other unique index", dup(`E11000 duplicate key error collection: shop.orders index: sku_1 dup key: { sku: "a" }`), false},
+		{"other write error", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 121, Message: "Document failed validation"}}}, false},
+		{"network error", mongo.CommandError{Code: 6, Labels: []string{"NetworkError"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := duplicateID(tt.err); got != tt.want {
+				t.Errorf("duplicateID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
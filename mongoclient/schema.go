@@ -0,0 +1,196 @@
+package mongoclient
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	objectIDType   = reflect.TypeOf(primitive.ObjectID{})
+	dateTimeType   = reflect.TypeOf(primitive.DateTime(0))
+	decimalType    = reflect.TypeOf(primitive.Decimal128{})
+	binaryType     = reflect.TypeOf(primitive.Binary{})
+	rawType        = reflect.TypeOf(bson.Raw(nil))
+	docType        = reflect.TypeOf(bson.D(nil))
+	marshalerType  = reflect.TypeOf((*bson.Marshaler)(nil)).Elem()
+	valueMarshaler = reflect.TypeOf((*bson.ValueMarshaler)(nil)).Elem()
+)
+
+// SchemaFromStruct derives a $jsonSchema document from the struct v, or a pointer to one,
+// following its bson tags: each field gets the bsonType the driver encodes it as, nested
+// structs and slices are described recursively, and fields without omitempty are
+// required, since the driver always writes them. Nil pointers, slices and maps are
+// written as null, so those fields also accept null. Fields of types with their own BSON
+// encoding, such as id.ULID, and interface fields accept any type. Other fields are
+// allowed, so documents may carry fields the struct does not know.
+//
+// Refine the result for rules a struct cannot express, e.g.
+// schema["properties"].(bson.M)["email"].(bson.M)["pattern"] = "^.+@.+$".
+func SchemaFromStruct(v interface{}) (bson.M, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errs.New(errs.Invalid, "SchemaFromStruct requires a struct")
+	}
+	return structSchema(t, map[reflect.Type]bool{}), nil
+}
+
+// SchemaFromFile reads a $jsonSchema document from a JSON file, e.g. one shared with other
+// services. The file holds the schema itself or a validator of the form
+// {"$jsonSchema": {...}}; MongoDB Extended JSON such as {"$numberLong": "1"} is accepted.
+func SchemaFromFile(path string) (bson.M, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclient.SchemaFromFile", "failed to read schema file")
+	}
+	var schema bson.M
+	if err := bson.UnmarshalExtJSON(data, false, &schema); err != nil {
+		return nil, errs.Wrap(err, errs.Invalid, "mongoclient.SchemaFromFile", "failed to parse schema file")
+	}
+	if inner, ok := schema["$jsonSchema"].(bson.M); ok && len(schema) == 1 {
+		return inner, nil
+	}
+	return schema, nil
+}
+
+// structSchema describes the fields of struct type t. seen holds the structs being
+// described, so a recursive type is described as a plain object where it recurs.
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) bson.M {
+	if seen[t] {
+		return bson.M{"bsonType": "object"}
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	properties := bson.M{}
+	var required bson.A
+	addStructFields(t, properties, &required, seen)
+	schema := bson.M{"bsonType": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func addStructFields(t reflect.Type, properties bson.M, required *bson.A, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, omitempty, inline, skip := bsonTag(f)
+		if skip {
+			continue
+		}
+		if inline {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addStructFields(ft, properties, required, seen)
+			}
+			continue
+		}
+		properties[name] = typeSchema(f.Type, seen)
+		if !omitempty {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// bsonTag returns the encoded name and flags of a field the way the driver reads its tag
+func bsonTag(f reflect.StructField) (name string, omitempty, inline, skip bool) {
+	tag, ok := f.Tag.Lookup("bson")
+	if !ok && !strings.Contains(string(f.Tag), ":") {
+		tag = string(f.Tag)
+	}
+	if tag == "-" {
+		return "", false, false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, p := range parts[1:] {
+		switch p {
+		case "omitempty":
+			omitempty = true
+		case "inline":
+			inline = true
+		}
+	}
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	return name, omitempty, inline, false
+}
+
+// typeSchema describes the values of type t
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) bson.M {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+	var schema bson.M
+	switch {
+	case t == timeType, t == dateTimeType:
+		schema = bson.M{"bsonType": "date"}
+	case t == objectIDType:
+		schema = bson.M{"bsonType": "objectId"}
+	case t == decimalType:
+		schema = bson.M{"bsonType": "decimal"}
+	case t == binaryType:
+		schema = bson.M{"bsonType": "binData"}
+	case t == rawType, t == docType:
+		schema = bson.M{"bsonType": "object"}
+	case t.Implements(marshalerType), t.Implements(valueMarshaler),
+		reflect.PointerTo(t).Implements(marshalerType), reflect.PointerTo(t).Implements(valueMarshaler):
+		return bson.M{} // encoded by the type itself
+	default:
+		switch t.Kind() {
+		case reflect.String:
+			schema = bson.M{"bsonType": "string"}
+		case reflect.Bool:
+			schema = bson.M{"bsonType": "bool"}
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+			schema = bson.M{"bsonType": "int"}
+		case reflect.Int:
+			// int is written as a 32-bit integer when it fits
+			schema = bson.M{"bsonType": bson.A{"int", "long"}}
+		case reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+			schema = bson.M{"bsonType": "long"}
+		case reflect.Float32, reflect.Float64:
+			schema = bson.M{"bsonType": "double"}
+		case reflect.Slice, reflect.Array:
+			nullable = nullable || t.Kind() == reflect.Slice
+			if t.Elem().Kind() == reflect.Uint8 {
+				schema = bson.M{"bsonType": "binData"}
+				break
+			}
+			schema = bson.M{"bsonType": "array", "items": typeSchema(t.Elem(), seen)}
+		case reflect.Map:
+			schema = bson.M{"bsonType": "object"}
+			nullable = true
+		case reflect.Struct:
+			schema = structSchema(t, seen)
+		default:
+			return bson.M{} // interfaces hold any type
+		}
+	}
+	if nullable {
+		switch bt := schema["bsonType"].(type) {
+		case string:
+			schema["bsonType"] = bson.A{bt, "null"}
+		case bson.A:
+			schema["bsonType"] = append(bt, "null")
+		}
+	}
+	return schema
+}
@@ -0,0 +1,155 @@
+package mongoclient
+
+import (
+	"context"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SearchOptions controls SearchText
+type SearchOptions struct {
+	// Atlas searches with the $search stage of an Atlas Search index instead of a $text
+	// query on the collection's text index.
+	Atlas bool
+	// Index names the Atlas Search index. Defaults to "default".
+	Index string
+	// Paths lists the fields Atlas Search matches the query against. Defaults to every
+	// field the index covers.
+	Paths []string
+	// MaxEdits allows Atlas Search matches within this many single-character edits of a
+	// query term, 1 or 2, to tolerate typos. Zero matches terms exactly.
+	MaxEdits int
+	// Language sets the stemming and stop words of a $text query, e.g. "spanish". Defaults
+	// to the language of the text index.
+	Language string
+	// CaseSensitive and DiacriticSensitive tighten a $text query, which ignores case and
+	// diacritics by default.
+	CaseSensitive      bool
+	DiacriticSensitive bool
+	// ScoreField receives the relevance score of each result. Defaults to "score".
+	ScoreField string
+}
+
+func (o SearchOptions) scoreField() string {
+	if o.ScoreField == "" {
+		return "score"
+	}
+	return o.ScoreField
+}
+
+// SearchText runs a full-text search for query on the collection in params and decodes the
+// matches, most relevant first, into result, which must be a pointer to a slice. Each
+// match carries its relevance score in opts.ScoreField. By default it runs a $text query,
+// which needs a text index, e.g. IndexSpec{Keys: bson.D{{Key: "title", Value: "text"}}};
+// with opts.Atlas it runs the $search stage of an Atlas Search index instead.
+//
+// params.Filter narrows the matches further, and Options.Skip, Limit and Projection apply.
+// Options.Sort replaces the relevance order.
+func (c *Client) SearchText(ctx context.Context, params QueryParams, query string, opts SearchOptions, result interface{}) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	if query == "" {
+		return errs.New(errs.Invalid, "search requires a query")
+	}
+	params = c.scope(params)
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	var pipeline mongo.Pipeline
+	if opts.Atlas {
+		pipeline = atlasSearch(params, query, opts)
+	} else {
+		pipeline = textSearch(params, query, opts)
+	}
+	if params.Options.Skip > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: params.Options.Skip}})
+	}
+	if params.Options.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: params.Options.Limit}})
+	}
+	if len(params.Options.Projection) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: params.Options.Projection}})
+	}
+	// The score is added after the projection, so an inclusion projection keeps it
+	meta := "textScore"
+	if opts.Atlas {
+		meta = "searchScore"
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.D{
+		{Key: opts.scoreField(), Value: bson.D{{Key: "$meta", Value: meta}}},
+	}}})
+
+	collection := c.collection(params)
+	err = c.retry(ctx, func() error {
+		cursor, err := collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, result) // All closes the cursor
+	})
+	if err != nil {
+		return WrapError(err, "mongoclient.SearchText", "failed to search text")
+	}
+	return nil
+}
+
+// textSearch returns the stages of a $text query, which must be in the first $match
+func textSearch(params QueryParams, query string, opts SearchOptions) mongo.Pipeline {
+	text := bson.D{{Key: "$search", Value: query}}
+	if opts.Language != "" {
+		text = append(text, bson.E{Key: "$language", Value: opts.Language})
+	}
+	if opts.CaseSensitive {
+		text = append(text, bson.E{Key: "$caseSensitive", Value: true})
+	}
+	if opts.DiacriticSensitive {
+		text = append(text, bson.E{Key: "$diacriticSensitive", Value: true})
+	}
+	match := bson.D{{Key: "$text", Value: text}}
+	for k, v := range params.Filter {
+		match = append(match, bson.E{Key: k, Value: v})
+	}
+
+	sort := params.Options.Sort
+	if len(sort) == 0 {
+		sort = bson.D{{Key: opts.scoreField(), Value: bson.D{{Key: "$meta", Value: "textScore"}}}}
+	}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: sort}},
+	}
+}
+
+// atlasSearch returns the stages of an Atlas Search query, which returns matches by
+// relevance already
+func atlasSearch(params QueryParams, query string, opts SearchOptions) mongo.Pipeline {
+	var path interface{} = bson.D{{Key: "wildcard", Value: "*"}}
+	if len(opts.Paths) == 1 {
+		path = opts.Paths[0]
+	} else if len(opts.Paths) > 1 {
+		path = opts.Paths
+	}
+	text := bson.D{{Key: "query", Value: query}, {Key: "path", Value: path}}
+	if opts.MaxEdits > 0 {
+		text = append(text, bson.E{Key: "fuzzy", Value: bson.D{{Key: "maxEdits", Value: opts.MaxEdits}}})
+	}
+	index := opts.Index
+	if index == "" {
+		index = "default"
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$search", Value: bson.D{{Key: "index", Value: index}, {Key: "text", Value: text}}}},
+	}
+	if len(params.Filter) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: params.Filter}})
+	}
+	if len(params.Options.Sort) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: params.Options.Sort}})
+	}
+	return pipeline
+}
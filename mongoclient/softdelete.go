@@ -0,0 +1,71 @@
+package mongoclient
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SoftDeleteOptions lists collections whose deletes only mark documents as deleted. In
+// those collections DeleteOne, DeleteMany and FindOneAndDelete set Field to the current
+// time, and every query, count, update and aggregation skips documents where it is set.
+// QueryParams.IncludeDeleted turns this off for one operation. BulkWrite, Watch and
+// EstimatedDocumentCount are not affected.
+type SoftDeleteOptions struct {
+	// Collections are the soft-delete collections. An empty Database matches the
+	// collection in every database.
+	Collections []Namespace
+	// Field holds the deletion time. Defaults to "deleted_at".
+	Field string
+}
+
+// FieldName returns the field that holds the deletion time
+func (o SoftDeleteOptions) FieldName() string {
+	if o.Field == "" {
+		return "deleted_at"
+	}
+	return o.Field
+}
+
+// Applies reports whether soft delete is enabled for the collection in params and not
+// turned off by params.IncludeDeleted
+func (o SoftDeleteOptions) Applies(params QueryParams) bool {
+	if params.IncludeDeleted {
+		return false
+	}
+	for _, ns := range o.Collections {
+		if ns.Collection == params.Collection && (ns.Database == "" || ns.Database == params.Database) {
+			return true
+		}
+	}
+	return false
+}
+
+// Exclude returns a copy of filter that also requires the document not to be deleted. A
+// filter that already conditions on the deletion field is returned unchanged, so callers
+// can still query e.g. bson.M{"deleted_at": bson.M{"$lt": cutoff}}.
+func (o SoftDeleteOptions) Exclude(filter bson.M) bson.M {
+	field := o.FieldName()
+	if _, ok := filter[field]; ok {
+		return filter
+	}
+	scoped := make(bson.M, len(filter)+1)
+	for k, v := range filter {
+		scoped[k] = v
+	}
+	scoped[field] = nil // matches a missing or null field
+	return scoped
+}
+
+// markDeleted is the update that soft-deletes documents
+func (o SoftDeleteOptions) markDeleted() bson.M {
+	return bson.M{"$set": bson.M{o.FieldName(): time.Now().UTC()}}
+}
+
+// scope excludes soft-deleted documents from params.Filter, see ClientOptions.SoftDelete
+func (c *Client) scope(params QueryParams) QueryParams {
+	if c.softDelete.Applies(params) {
+		params.Filter = c.softDelete.Exclude(params.Filter)
+	}
+	return params
+}
@@ -0,0 +1,97 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type tenantKey struct{}
+
+func tenantID(ctx context.Context) (string, error) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	if !ok {
+		return "", errors.New("no tenant")
+	}
+	return id, nil
+}
+
+// Soft delete matches the names callers use, so it keeps applying when a TenantResolver
+// routes the delete to the tenant's own collection or database
+func TestSoftDeleteWithTenantResolver(t *testing.T) {
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	deletes := []struct {
+		name string
+		run  func(c *Client, params QueryParams) error
+	}{
+		{"DeleteOne", func(c *Client, params QueryParams) error {
+			_, err := c.DeleteOne(ctx, params)
+			return err
+		}},
+		{"DeleteMany", func(c *Client, params QueryParams) error {
+			_, err := c.DeleteMany(ctx, params)
+			return err
+		}},
+		{"FindOneAndDelete", func(c *Client, params QueryParams) error {
+			var deleted bson.M
+			return c.FindOneAndDelete(ctx, params, FindAndModifyOptions{}, &deleted)
+		}},
+	}
+	tests := []struct {
+		name           string
+		resolver       TenantResolver
+		collections    []Namespace
+		collection     string
+		wantDatabase   string
+		wantCollection string
+		soft           bool
+	}{
+		{"collection per tenant", CollectionPerTenant(tenantID), []Namespace{{Collection: "orders"}}, "orders", "shop", "acme_orders", true},
+		{"collection per tenant, explicit database", CollectionPerTenant(tenantID), []Namespace{{Database: "shop", Collection: "orders"}}, "orders", "shop", "acme_orders", true},
+		{"database per tenant, explicit database", DatabasePerTenant(tenantID), []Namespace{{Database: "shop", Collection: "orders"}}, "orders", "shop_acme", "orders", true},
+		{"other collection", CollectionPerTenant(tenantID), []Namespace{{Database: "shop", Collection: "orders"}}, "carts", "shop", "acme_carts", false},
+	}
+	for _, tt := range tests {
+		c, server := newFakeClient(t, ClientOptions{
+			TenantResolver: tt.resolver,
+			SoftDelete:     SoftDeleteOptions{Collections: tt.collections},
+		})
+		for _, d := range deletes {
+			t.Run(tt.name+"/"+d.name, func(t *testing.T) {
+				params := QueryParams{Database: "shop", Collection: tt.collection, Filter: bson.M{"status": "cancelled"}}
+				if err := d.run(c, params); err != nil {
+					t.Fatal(err)
+				}
+				cmds := server.take()
+				if len(cmds) != 1 {
+					t.Fatalf("sent %d commands, want 1", len(cmds))
+				}
+				cmd := cmds[0]
+				if cmd.Database != tt.wantDatabase || cmd.Collection != tt.wantCollection {
+					t.Errorf("%s ran on %s.%s, want %s.%s", cmd.Name, cmd.Database, cmd.Collection, tt.wantDatabase, tt.wantCollection)
+				}
+				if soft := softDeleted(cmd); soft != tt.soft {
+					t.Errorf("%s %s: soft delete = %v, want %v", cmd.Name, cmd.Body, soft, tt.soft)
+				}
+			})
+		}
+	}
+}
+
+// softDeleted reports whether cmd marks documents as deleted rather than removing them
+func softDeleted(cmd command) bool {
+	switch cmd.Name {
+	case "update":
+		for _, u := range cmd.Sequences["updates"] {
+			if _, err := u.LookupErr("u", "$set", "deleted_at"); err == nil {
+				return true
+			}
+		}
+	case "findAndModify":
+		_, err := cmd.Body.LookupErr("update", "$set", "deleted_at")
+		return err == nil
+	}
+	return false
+}
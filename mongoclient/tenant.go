@@ -0,0 +1,98 @@
+package mongoclient
+
+import (
+	"context"
+
+	"github.com/cdcloud-io/go-libs/errs"
+)
+
+// TenantResolver maps the database and collection an operation names to the ones of the
+// tenant in ctx, so one Client serves a database-per-tenant or collection-per-tenant
+// topology while application code keeps using fixed names. It must return an error when
+// ctx has no tenant, so a missing tenant never falls through to shared data. Build one with
+// DatabasePerTenant or CollectionPerTenant, or write one for other layouts.
+type TenantResolver func(ctx context.Context, ns Namespace) (Namespace, error)
+
+// DatabasePerTenant routes each operation to the database named after the one in
+// QueryParams and the tenant ID, e.g. "shop" becomes "shop_acme". tenantID returns the
+// tenant ID of a context and an error when it has none; tenant.Require fits:
+//
+//	ClientOptions{TenantResolver: mongoclient.DatabasePerTenant(tenant.Require)}
+func DatabasePerTenant(tenantID func(ctx context.Context) (string, error)) TenantResolver {
+	return func(ctx context.Context, ns Namespace) (Namespace, error) {
+		id, err := resolveTenant(ctx, tenantID)
+		if err != nil {
+			return ns, err
+		}
+		ns.Database = ns.Database + "_" + id
+		return ns, nil
+	}
+}
+
+// CollectionPerTenant routes each operation to the collection prefixed with the tenant ID
+// in the same database, e.g. "orders" becomes "acme_orders". tenantID is as for
+// DatabasePerTenant.
+func CollectionPerTenant(tenantID func(ctx context.Context) (string, error)) TenantResolver {
+	return func(ctx context.Context, ns Namespace) (Namespace, error) {
+		id, err := resolveTenant(ctx, tenantID)
+		if err != nil {
+			return ns, err
+		}
+		ns.Collection = id + "_" + ns.Collection
+		return ns, nil
+	}
+}
+
+// resolveTenant returns the tenant ID of ctx, rejecting IDs that could escape into another
+// database or collection name
+func resolveTenant(ctx context.Context, tenantID func(ctx context.Context) (string, error)) (string, error) {
+	id, err := tenantID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !validTenant(id) {
+		return "", errs.New(errs.Invalid, "invalid tenant ID for database routing").With("tenant", id)
+	}
+	return id, nil
+}
+
+// validTenant reports whether id is 1 to 64 letters, digits, '-' or '_'
+func validTenant(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve returns the namespace an operation on ns runs against for the tenant in ctx, or
+// ns itself without a TenantResolver. Use it to provision a tenant with the helpers that
+// take explicit names, such as EnsureIndexes.
+func (c *Client) Resolve(ctx context.Context, ns Namespace) (Namespace, error) {
+	if c.tenantResolver == nil {
+		return ns, nil
+	}
+	resolved, err := c.tenantResolver(ctx, ns)
+	if err != nil {
+		return ns, errs.Wrap(err, errs.Unknown, "mongoclient.Resolve", "failed to resolve tenant namespace")
+	}
+	return resolved, nil
+}
+
+// route applies the TenantResolver to the database and collection in params
+func (c *Client) route(ctx context.Context, params QueryParams) (QueryParams, error) {
+	if c.tenantResolver == nil {
+		return params, nil
+	}
+	ns, err := c.Resolve(ctx, Namespace{Database: params.Database, Collection: params.Collection})
+	if err != nil {
+		return params, err
+	}
+	params.Database, params.Collection = ns.Database, ns.Collection
+	return params, nil
+}
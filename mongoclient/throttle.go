@@ -0,0 +1,145 @@
+package mongoclient
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+)
+
+// WriteThrottle limits the document writes a Client sends, so a burst of traffic queues in
+// the service instead of saturating a small MongoDB tier. Each write operation counts
+// once, whatever the number of documents in it, so a bulk write or an InsertMany costs the
+// same as an InsertOne. The zero value disables throttling.
+type WriteThrottle struct {
+	// Rate is the sustained number of write operations per second. Zero does not limit
+	// the rate.
+	Rate float64
+	// Burst is the number of writes allowed at once above Rate after a quiet period.
+	// Defaults to Rate, rounded up.
+	Burst int
+	// MaxInFlight caps the writes running at once. Zero does not cap them.
+	MaxInFlight int
+	// Reject fails a write that cannot start at once with an errs.Unavailable error
+	// matching ErrThrottled instead of queueing it, e.g. for APIs that answer 503 and let
+	// clients retry.
+	Reject bool
+	// MaxWait bounds how long a queued write waits to start before failing with
+	// ErrThrottled. Zero waits as long as the context allows, within OperationTimeout.
+	MaxWait time.Duration
+}
+
+func (t WriteThrottle) enabled() bool {
+	return t.Rate > 0 || t.MaxInFlight > 0
+}
+
+// throttle enforces a WriteThrottle with a token bucket and a semaphore
+type throttle struct {
+	opts     WriteThrottle
+	inFlight chan struct{} // nil without MaxInFlight
+
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	last     time.Time
+}
+
+func newThrottle(opts WriteThrottle) *throttle {
+	if !opts.enabled() {
+		return nil
+	}
+	t := &throttle{opts: opts, last: time.Now()}
+	if opts.Rate > 0 {
+		t.capacity = float64(opts.Burst)
+		if t.capacity < 1 {
+			t.capacity = math.Ceil(opts.Rate)
+		}
+		t.tokens = t.capacity
+	}
+	if opts.MaxInFlight > 0 {
+		t.inFlight = make(chan struct{}, opts.MaxInFlight)
+	}
+	return t
+}
+
+// throttle waits until the write op may start under ClientOptions.WriteThrottle. The
+// returned function must be called when the write finishes.
+func (c *Client) throttle(ctx context.Context, op string) (release func(), err error) {
+	t := c.writeThrottle
+	if t == nil {
+		return func() {}, nil
+	}
+	var deadline <-chan time.Time
+	if t.opts.MaxWait > 0 && !t.opts.Reject {
+		timer := time.NewTimer(t.opts.MaxWait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	if t.inFlight != nil {
+		select {
+		case t.inFlight <- struct{}{}:
+		default:
+			if t.opts.Reject {
+				return nil, throttled(op, "too many writes in flight")
+			}
+			select {
+			case t.inFlight <- struct{}{}:
+			case <-deadline:
+				return nil, throttled(op, "timed out waiting for a write slot")
+			case <-ctx.Done():
+				return nil, errs.Wrap(ctx.Err(), errs.Unknown, op, "interrupted waiting for a write slot")
+			}
+		}
+	}
+	release = func() {
+		if t.inFlight != nil {
+			<-t.inFlight
+		}
+	}
+
+	if t.opts.Rate > 0 {
+		if err := t.take(ctx, op, deadline); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// take removes a token from the bucket, waiting for one unless the throttle rejects
+func (t *throttle) take(ctx context.Context, op string, deadline <-chan time.Time) error {
+	for {
+		t.mu.Lock()
+		now := time.Now()
+		t.tokens = min(t.capacity, t.tokens+now.Sub(t.last).Seconds()*t.opts.Rate)
+		t.last = now
+		if t.tokens >= 1 {
+			t.tokens--
+			t.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - t.tokens) / t.opts.Rate * float64(time.Second))
+		t.mu.Unlock()
+
+		if t.opts.Reject {
+			return throttled(op, "write rate exceeded")
+		}
+		if d, ok := ctx.Deadline(); ok && time.Until(d) < wait {
+			return throttled(op, "write rate exceeded beyond the deadline")
+		}
+		select {
+		case <-time.After(wait):
+		case <-deadline:
+			return throttled(op, "timed out waiting for write rate capacity")
+		case <-ctx.Done():
+			return errs.Wrap(ctx.Err(), errs.Unknown, op, "interrupted waiting for write rate capacity")
+		}
+	}
+}
+
+func throttled(op, message string) error {
+	return errs.Wrap(ErrThrottled, errs.Unavailable, op, message)
+}
@@ -0,0 +1,14 @@
+package mongoclient
+
+import "context"
+
+// withTimeout bounds ctx by ClientOptions.OperationTimeout when ctx has no deadline of its own
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.operationTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.operationTimeout)
+}
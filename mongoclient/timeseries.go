@@ -0,0 +1,162 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Granularities of a time-series collection, matching the interval between measurements
+// of one source
+const (
+	GranularitySeconds = "seconds"
+	GranularityMinutes = "minutes"
+	GranularityHours   = "hours"
+)
+
+// TimeSeriesSpec describes a time-series collection for EnsureTimeSeriesCollection
+type TimeSeriesSpec struct {
+	// TimeField names the date field holding the time of each measurement. Required.
+	TimeField string
+	// MetaField names the field identifying the source of a measurement, e.g. "device"
+	// holding {"id": ..., "site": ...}. Measurements are bucketed by its value, so it
+	// should rarely change for a given source.
+	MetaField string
+	// Granularity is GranularitySeconds (the server default), GranularityMinutes or
+	// GranularityHours. It can only be raised once the collection exists.
+	Granularity string
+	// ExpireAfter deletes measurements this long after their TimeField. Zero keeps them.
+	ExpireAfter time.Duration
+}
+
+// timeSeriesInfo is the part of a listCollections entry that EnsureTimeSeriesCollection compares
+type timeSeriesInfo struct {
+	Type    string `bson:"type"`
+	Options struct {
+		TimeSeries struct {
+			TimeField   string `bson:"timeField"`
+			MetaField   string `bson:"metaField"`
+			Granularity string `bson:"granularity"`
+		} `bson:"timeseries"`
+		ExpireAfterSeconds *int64 `bson:"expireAfterSeconds"`
+	} `bson:"options"`
+}
+
+// EnsureTimeSeriesCollection creates a time-series collection. It is safe to call on every
+// startup: an existing collection with the same fields is left alone, and a changed
+// ExpireAfter or a raised Granularity is applied in place. A collection that exists with
+// other fields, or is not a time-series collection, returns an errs.Conflict error.
+func (c *Client) EnsureTimeSeriesCollection(ctx context.Context, database, collection string, spec TimeSeriesSpec) error {
+	if err := c.writable("mongoclient.EnsureTimeSeriesCollection"); err != nil {
+		return err
+	}
+	if spec.TimeField == "" {
+		return errs.New(errs.Invalid, "time-series collection requires a time field").With("collection", collection)
+	}
+	db := c.Database(database)
+	cursor, err := db.ListCollections(ctx, bson.M{"name": collection})
+	if err != nil {
+		return WrapError(err, "mongoclient.EnsureTimeSeriesCollection", "failed to list collections")
+	}
+	var existing []timeSeriesInfo
+	if err := cursor.All(ctx, &existing); err != nil {
+		return WrapError(err, "mongoclient.EnsureTimeSeriesCollection", "failed to list collections")
+	}
+
+	if len(existing) == 0 {
+		tsOpts := options.TimeSeries().SetTimeField(spec.TimeField)
+		if spec.MetaField != "" {
+			tsOpts.SetMetaField(spec.MetaField)
+		}
+		if spec.Granularity != "" {
+			tsOpts.SetGranularity(spec.Granularity)
+		}
+		opts := options.CreateCollection().SetTimeSeriesOptions(tsOpts)
+		if spec.ExpireAfter > 0 {
+			opts.SetExpireAfterSeconds(int64(spec.ExpireAfter / time.Second))
+		}
+		err := db.CreateCollection(ctx, collection, opts)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Name == "NamespaceExists" {
+			// Created concurrently, e.g. by another replica starting up; compare it instead.
+			return c.EnsureTimeSeriesCollection(ctx, database, collection, spec)
+		}
+		if err != nil {
+			return WrapError(err, "mongoclient.EnsureTimeSeriesCollection", "failed to create time-series collection")
+		}
+		return nil
+	}
+
+	info := existing[0]
+	ts := info.Options.TimeSeries
+	if info.Type != "timeseries" || ts.TimeField != spec.TimeField || ts.MetaField != spec.MetaField {
+		return errs.New(errs.Conflict, fmt.Sprintf("collection %s exists with different time-series fields", collection)).
+			With("collection", collection)
+	}
+	var mod bson.D
+	if spec.Granularity != "" && spec.Granularity != ts.Granularity {
+		mod = append(mod, bson.E{Key: "timeseries", Value: bson.D{{Key: "granularity", Value: spec.Granularity}}})
+	}
+	var current int64
+	if info.Options.ExpireAfterSeconds != nil {
+		current = *info.Options.ExpireAfterSeconds
+	}
+	if want := int64(spec.ExpireAfter / time.Second); want != current {
+		var value interface{} = want
+		if want == 0 {
+			value = "off"
+		}
+		mod = append(mod, bson.E{Key: "expireAfterSeconds", Value: value})
+	}
+	if len(mod) == 0 {
+		return nil
+	}
+	err = db.RunCommand(ctx, append(bson.D{{Key: "collMod", Value: collection}}, mod...)).Err()
+	if err != nil {
+		return WrapError(err, "mongoclient.EnsureTimeSeriesCollection", "failed to update time-series collection")
+	}
+	return nil
+}
+
+// InsertMeasurements inserts a batch of time-series measurements into the collection in
+// params and returns how many were stored. The batch is unordered, which time-series
+// collections ingest fastest, so one bad measurement does not stop the others; the error
+// then lists the failed ones by index. ClientOptions.Timestamps does not apply, since each
+// measurement carries its own time.
+func (c *Client) InsertMeasurements(ctx context.Context, params QueryParams, measurements []interface{}) (int, error) {
+	if err := c.writable("mongoclient.InsertMeasurements"); err != nil {
+		return 0, err
+	}
+	if len(measurements) == 0 {
+		return 0, nil
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	release, err := c.throttle(ctx, "mongoclient.InsertMeasurements")
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	params, err = c.route(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+	result, err := c.collection(params).InsertMany(ctx, measurements, options.InsertMany().SetOrdered(false))
+	if err != nil {
+		inserted := 0
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) {
+			inserted = len(measurements) - len(bulkErr.WriteErrors)
+		} else if result != nil {
+			inserted = len(result.InsertedIDs)
+		}
+		return inserted, WrapError(err, "mongoclient.InsertMeasurements", "failed to insert measurements")
+	}
+	return len(result.InsertedIDs), nil
+}
@@ -0,0 +1,162 @@
+package mongoclient
+
+import (
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TimestampOptions makes the client maintain audit timestamps, so every service records
+// them the same way. Inserts set CreatedField and UpdatedField; updates and replacements set
+// UpdatedField, and upserts that insert also set CreatedField. Fields the caller sets
+// explicitly are left alone, except zero time.Time values on insert.
+type TimestampOptions struct {
+	Enabled bool
+	// CreatedField defaults to "created_at".
+	CreatedField string
+	// UpdatedField defaults to "updated_at".
+	UpdatedField string
+	// Now returns the current time, e.g. a fixed clock in tests. Defaults to time.Now.
+	Now func() time.Time
+}
+
+func (o TimestampOptions) fields() (created, updated string) {
+	created, updated = o.CreatedField, o.UpdatedField
+	if created == "" {
+		created = "created_at"
+	}
+	if updated == "" {
+		updated = "updated_at"
+	}
+	return created, updated
+}
+
+// now returns the current time at the millisecond precision MongoDB stores
+func (o TimestampOptions) now() primitive.DateTime {
+	if o.Now != nil {
+		return primitive.NewDateTimeFromTime(o.Now())
+	}
+	return primitive.NewDateTimeFromTime(time.Now())
+}
+
+// StampInsert returns document as a bson.D with the created and updated fields set. It
+// returns document unchanged when timestamps are disabled.
+func (o TimestampOptions) StampInsert(document interface{}) (interface{}, error) {
+	if !o.Enabled {
+		return document, nil
+	}
+	doc, err := toD(document)
+	if err != nil {
+		return nil, err
+	}
+	created, updated := o.fields()
+	now := o.now()
+	return setUnlessSet(setUnlessSet(doc, created, now), updated, now), nil
+}
+
+// StampReplacement returns replacement as a bson.D with the updated field set. It returns
+// replacement unchanged when timestamps are disabled.
+func (o TimestampOptions) StampReplacement(replacement interface{}) (interface{}, error) {
+	if !o.Enabled {
+		return replacement, nil
+	}
+	doc, err := toD(replacement)
+	if err != nil {
+		return nil, err
+	}
+	_, updated := o.fields()
+	return setUnlessSet(doc, updated, o.now()), nil
+}
+
+// StampUpdate adds the updated field to the $set of update, and the created field to its
+// $setOnInsert for upserts, unless an operator of update already sets them. An update
+// pipeline gets a final $set stage. It returns update unchanged when timestamps are disabled.
+func (o TimestampOptions) StampUpdate(update interface{}) (interface{}, error) {
+	if !o.Enabled {
+		return update, nil
+	}
+	created, updated := o.fields()
+	now := o.now()
+
+	if rv := reflect.ValueOf(update); rv.Kind() == reflect.Slice && isPipeline(rv.Type()) {
+		stages := make(bson.A, 0, rv.Len()+1)
+		for i := 0; i < rv.Len(); i++ {
+			stages = append(stages, rv.Index(i).Interface())
+		}
+		return append(stages, bson.D{{Key: "$set", Value: bson.D{{Key: updated, Value: now}}}}), nil
+	}
+
+	doc, err := toD(update)
+	if err != nil {
+		return nil, err
+	}
+	if !updatesField(doc, updated) {
+		doc = addToOperator(doc, "$set", updated, now)
+	}
+	if !updatesField(doc, created) {
+		doc = addToOperator(doc, "$setOnInsert", created, now)
+	}
+	return doc, nil
+}
+
+// isPipeline reports whether a slice type is an update pipeline rather than a document
+// (bson.D) or raw BSON (bson.Raw)
+func isPipeline(t reflect.Type) bool {
+	elem := t.Elem()
+	return elem != reflect.TypeOf(bson.E{}) && elem.Kind() != reflect.Uint8
+}
+
+// toD converts a document or update to bson.D the way the driver encodes it
+func toD(v interface{}) (bson.D, error) {
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var d bson.D
+	if err := bson.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// setUnlessSet sets field to value when it is missing, null or the zero time
+func setUnlessSet(doc bson.D, field string, value interface{}) bson.D {
+	zero := primitive.NewDateTimeFromTime(time.Time{})
+	for i, e := range doc {
+		if e.Key == field {
+			if e.Value == nil || e.Value == zero {
+				doc[i].Value = value
+			}
+			return doc
+		}
+	}
+	return append(doc, bson.E{Key: field, Value: value})
+}
+
+// updatesField reports whether an operator of update sets field
+func updatesField(update bson.D, field string) bool {
+	for _, op := range update {
+		fields, _ := op.Value.(bson.D)
+		for _, f := range fields {
+			if f.Key == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addToOperator adds field to the operator document op of update, creating it if needed
+func addToOperator(update bson.D, op, field string, value interface{}) bson.D {
+	for i, e := range update {
+		if e.Key == op {
+			if fields, ok := e.Value.(bson.D); ok {
+				update[i].Value = append(append(bson.D(nil), fields...), bson.E{Key: field, Value: value})
+				return update
+			}
+		}
+	}
+	return append(update, bson.E{Key: op, Value: bson.D{{Key: field, Value: value}}})
+}
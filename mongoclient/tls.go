@@ -0,0 +1,69 @@
+package mongoclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSOptions enables TLS for the connection. TLS is on when any field is set; Atlas and
+// other servers with publicly trusted certificates only need Enabled.
+type TLSOptions struct {
+	// Enabled turns on TLS with the system root CAs.
+	Enabled bool
+	// CAFile is a PEM file of CA certificates to trust instead of the system roots,
+	// for self-hosted clusters with a private CA.
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and key, for clusters that
+	// require client certificates or X.509 authentication. The key may be in CertFile.
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables server certificate verification. Only use it in development.
+	InsecureSkipVerify bool
+	// Config is used as the base configuration when set, for settings not covered above.
+	Config *tls.Config
+}
+
+func (o TLSOptions) enabled() bool {
+	return o.Enabled || o.CAFile != "" || o.CertFile != "" || o.InsecureSkipVerify || o.Config != nil
+}
+
+// config builds the tls.Config, loading the CA and client certificate files
+func (o TLSOptions) config() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.Config != nil {
+		cfg = o.Config.Clone()
+	}
+	if o.InsecureSkipVerify {
+		cfg.InsecureSkipVerify = true
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.CertFile != "" {
+		keyFile := o.KeyFile
+		if keyFile == "" {
+			keyFile = o.CertFile
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	} else if o.KeyFile != "" {
+		return nil, errors.New("KeyFile set without CertFile")
+	}
+	return cfg, nil
+}
@@ -0,0 +1,26 @@
+package mongoclient
+
+import (
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingOptions emits an OpenTelemetry client span for every command sent to MongoDB.
+// Spans are named after the collection and command, e.g. "orders.find", and carry the
+// database, collection and operation as attributes; the span covers the command's duration.
+type TracingOptions struct {
+	Enabled bool
+	// TracerProvider defaults to the global provider, e.g. the one installed by tracing.Setup.
+	TracerProvider trace.TracerProvider
+	// IncludeStatement adds the full command as the db.statement attribute. Filters and
+	// documents are recorded as is, so only enable it where spans may hold personal data.
+	IncludeStatement bool
+}
+
+func (o TracingOptions) monitor() *event.CommandMonitor {
+	return otelmongo.NewMonitor(
+		otelmongo.WithTracerProvider(o.TracerProvider),
+		otelmongo.WithCommandAttributeDisabled(!o.IncludeStatement),
+	)
+}
@@ -0,0 +1,218 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Validation levels and actions of a ValidatorSpec
+const (
+	// ValidationStrict validates every insert and update. The server default.
+	ValidationStrict = "strict"
+	// ValidationModerate validates inserts, and updates of documents that already pass,
+	// so existing invalid documents can still be updated while they are cleaned up.
+	ValidationModerate = "moderate"
+	// ValidationError rejects invalid writes. The server default.
+	ValidationError = "error"
+	// ValidationWarn accepts invalid writes and logs them on the server, e.g. to roll out
+	// a new schema before enforcing it.
+	ValidationWarn = "warn"
+)
+
+// ValidatorSpec describes the validator of a collection for EnsureValidator
+type ValidatorSpec struct {
+	// Schema is the $jsonSchema document, e.g. from SchemaFromStruct or SchemaFromFile.
+	Schema bson.M
+	// Level is ValidationStrict (default) or ValidationModerate.
+	Level string
+	// Action is ValidationError (default) or ValidationWarn.
+	Action string
+}
+
+func (s ValidatorSpec) level() string {
+	if s.Level == "" {
+		return ValidationStrict
+	}
+	return s.Level
+}
+
+func (s ValidatorSpec) action() string {
+	if s.Action == "" {
+		return ValidationError
+	}
+	return s.Action
+}
+
+// ValidatorDrift reports how the validator of a collection differs from a ValidatorSpec
+type ValidatorDrift struct {
+	// Missing is set when the collection does not exist.
+	Missing bool
+	// Schema, Level and Action are set for each part that differs.
+	Schema bool
+	Level  bool
+	Action bool
+	// Current is the collection's $jsonSchema, nil when it has none.
+	Current bson.M
+}
+
+// Drifted reports whether anything differs
+func (d ValidatorDrift) Drifted() bool {
+	return d.Missing || d.Schema || d.Level || d.Action
+}
+
+// validatorInfo is the part of a listCollections entry that CheckValidator compares
+type validatorInfo struct {
+	Options struct {
+		Validator        bson.M `bson:"validator"`
+		ValidationLevel  string `bson:"validationLevel"`
+		ValidationAction string `bson:"validationAction"`
+	} `bson:"options"`
+}
+
+// CheckValidator compares the validator of a collection with spec without changing
+// anything, e.g. for a dry run in CI or a startup check that only logs drift
+func (c *Client) CheckValidator(ctx context.Context, database, collection string, spec ValidatorSpec) (ValidatorDrift, error) {
+	if len(spec.Schema) == 0 {
+		return ValidatorDrift{}, errs.New(errs.Invalid, "validator spec has no schema").With("collection", collection)
+	}
+	cursor, err := c.Database(database).ListCollections(ctx, bson.M{"name": collection})
+	if err != nil {
+		return ValidatorDrift{}, WrapError(err, "mongoclient.CheckValidator", "failed to list collections")
+	}
+	var existing []validatorInfo
+	if err := cursor.All(ctx, &existing); err != nil {
+		return ValidatorDrift{}, WrapError(err, "mongoclient.CheckValidator", "failed to list collections")
+	}
+	if len(existing) == 0 {
+		return ValidatorDrift{Missing: true}, nil
+	}
+
+	info := existing[0].Options
+	current, _ := info.Validator["$jsonSchema"].(bson.M)
+	desired, err := roundTrip(spec.Schema)
+	if err != nil {
+		return ValidatorDrift{}, errs.Wrap(err, errs.Invalid, "mongoclient.CheckValidator", "failed to encode schema")
+	}
+	level, action := info.ValidationLevel, info.ValidationAction
+	if level == "" {
+		level = ValidationStrict
+	}
+	if action == "" {
+		action = ValidationError
+	}
+	return ValidatorDrift{
+		Schema:  !sameBSON(current, desired),
+		Level:   level != spec.level(),
+		Action:  action != spec.action(),
+		Current: current,
+	}, nil
+}
+
+// EnsureValidator sets the $jsonSchema validator of a collection, creating the collection
+// when it does not exist. It is safe to call on every startup: a matching validator is
+// left alone. It returns the drift it corrected. Documents already stored are not
+// checked; only later writes are validated.
+func (c *Client) EnsureValidator(ctx context.Context, database, collection string, spec ValidatorSpec) (ValidatorDrift, error) {
+	if err := c.writable("mongoclient.EnsureValidator"); err != nil {
+		return ValidatorDrift{}, err
+	}
+	drift, err := c.CheckValidator(ctx, database, collection, spec)
+	if err != nil || !drift.Drifted() {
+		return drift, err
+	}
+	validator := bson.M{"$jsonSchema": spec.Schema}
+
+	db := c.Database(database)
+	if drift.Missing {
+		opts := options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel(spec.level()).
+			SetValidationAction(spec.action())
+		err := db.CreateCollection(ctx, collection, opts)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Name == "NamespaceExists" {
+			// Created concurrently, e.g. by another replica starting up; compare it instead.
+			return c.EnsureValidator(ctx, database, collection, spec)
+		}
+		if err != nil {
+			return drift, WrapError(err, "mongoclient.EnsureValidator", "failed to create collection")
+		}
+		return drift, nil
+	}
+
+	err = db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: spec.level()},
+		{Key: "validationAction", Value: spec.action()},
+	}).Err()
+	if err != nil {
+		return drift, WrapError(err, "mongoclient.EnsureValidator", "failed to update validator")
+	}
+	return drift, nil
+}
+
+// roundTrip encodes and decodes v, so it holds the same types as a document read back
+// from the server
+func roundTrip(v bson.M) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out bson.M
+	err = bson.Unmarshal(data, &out)
+	return out, err
+}
+
+// sameBSON compares decoded documents regardless of key order and numeric type, since the
+// server may store 1 as a double or a long
+func sameBSON(a, b interface{}) bool {
+	switch x := a.(type) {
+	case bson.M:
+		y, ok := b.(bson.M)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !sameBSON(v, w) {
+				return false
+			}
+		}
+		return true
+	case bson.A:
+		y, ok := b.(bson.A)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !sameBSON(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,244 @@
+package mongoclient
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WatchOptions controls where a change stream starts and how it recovers from failures
+type WatchOptions struct {
+	// ResumeAfter continues after the event that carried this token, e.g. one persisted by
+	// the consumer. It takes precedence over StartAt.
+	ResumeAfter bson.Raw
+	// StartAt delivers changes from this cluster time on. Zero starts with changes made
+	// after Watch is called.
+	StartAt primitive.Timestamp
+	// FullDocument looks up the current document for update events. Inserts and replaces
+	// always carry the full document.
+	FullDocument bool
+	// Buffer is the capacity of the returned channel. Defaults to 0, unbuffered.
+	Buffer int
+	// MaxRetries is the number of consecutive transient failures after which Watch gives up.
+	// Defaults to 5; negative retries forever.
+	MaxRetries int
+	// RetryBackoff is the wait before the first reopen, doubled per consecutive failure up
+	// to 30s. Defaults to 500ms.
+	RetryBackoff time.Duration
+}
+
+// ChangeEvent is one change delivered by Watch
+type ChangeEvent struct {
+	// OperationType is "insert", "update", "replace", "delete", "drop", "rename", "dropDatabase" or "invalidate".
+	OperationType string              `bson:"operationType"`
+	Namespace     Namespace           `bson:"ns"`
+	DocumentKey   bson.Raw            `bson:"documentKey"`
+	FullDocument  bson.Raw            `bson:"fullDocument"`
+	Update        *UpdateDescription  `bson:"updateDescription"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	// ResumeToken resumes the stream after this event; see WatchOptions.ResumeAfter.
+	ResumeToken bson.Raw `bson:"-"`
+	// Err is set on the last value sent before the channel closes when the stream failed.
+	Err error `bson:"-"`
+}
+
+// Namespace is the database and collection a change applies to
+type Namespace struct {
+	Database   string `bson:"db"`
+	Collection string `bson:"coll"`
+}
+
+// UpdateDescription lists the fields changed by an update event
+type UpdateDescription struct {
+	UpdatedFields bson.Raw `bson:"updatedFields"`
+	RemovedFields []string `bson:"removedFields"`
+}
+
+// Decode decodes the full document of the event into v. It returns an errs.NotFound error
+// when the event carries none, e.g. for deletes or updates without WatchOptions.FullDocument.
+func (e ChangeEvent) Decode(v interface{}) error {
+	if len(e.FullDocument) == 0 {
+		return errs.New(errs.NotFound, "change event has no full document")
+	}
+	if err := bson.Unmarshal(e.FullDocument, v); err != nil {
+		return errs.Wrap(err, errs.Invalid, "mongoclient.ChangeEvent.Decode", "failed to decode change event document")
+	}
+	return nil
+}
+
+// Watch opens a change stream and sends each change to the returned channel, in order.
+// The stream covers the collection in QueryParams, the whole database when Collection is
+// empty, or the whole deployment when Database is empty too. params.Filter, when set, is
+// prepended to the pipeline as a $match stage on the change events, e.g.
+// bson.M{"operationType": "insert"}. params.WatchOptions sets the starting point.
+//
+// Transient failures reopen the stream after the last delivered event, so no change is
+// lost or repeated. The channel is closed when ctx is done; when the stream fails for good
+// the last value sent carries Err. Change streams need a replica set.
+func (c *Client) Watch(ctx context.Context, params QueryParams, pipeline mongo.Pipeline) (<-chan ChangeEvent, error) {
+	params, err := c.route(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.Filter) > 0 {
+		pipeline = append(mongo.Pipeline{{{Key: "$match", Value: params.Filter}}}, pipeline...)
+	}
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+	opts := params.WatchOptions
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 5
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 500 * time.Millisecond
+	}
+
+	w := &watcher{client: c, params: params, pipeline: pipeline, opts: opts, token: opts.ResumeAfter}
+	stream, err := w.open(ctx)
+	if err != nil {
+		return nil, WrapError(err, "mongoclient.Watch", "failed to open change stream")
+	}
+
+	ch := make(chan ChangeEvent, opts.Buffer)
+	go w.run(ctx, stream, ch)
+	return ch, nil
+}
+
+type watcher struct {
+	client   *Client
+	params   QueryParams
+	pipeline mongo.Pipeline
+	opts     WatchOptions
+	token    bson.Raw
+}
+
+// open starts the stream after the last delivered event, or at the configured start
+func (w *watcher) open(ctx context.Context) (*mongo.ChangeStream, error) {
+	csOpts := options.ChangeStream()
+	if w.opts.FullDocument {
+		csOpts.SetFullDocument(options.UpdateLookup)
+	}
+	switch {
+	case len(w.token) > 0:
+		csOpts.SetStartAfter(w.token)
+	case !w.opts.StartAt.IsZero():
+		csOpts.SetStartAtOperationTime(&w.opts.StartAt)
+	}
+
+	switch {
+	case w.params.Database == "":
+		return w.client.Client.Watch(ctx, w.pipeline, csOpts)
+	case w.params.Collection == "":
+		return w.client.Database(w.params.Database).Watch(ctx, w.pipeline, csOpts)
+	}
+	return w.client.collection(w.params).Watch(ctx, w.pipeline, csOpts)
+}
+
+func (w *watcher) run(ctx context.Context, stream *mongo.ChangeStream, ch chan<- ChangeEvent) {
+	defer close(ch)
+
+	failures := 0
+	for {
+		err := w.drain(ctx, stream, ch, &failures)
+		stream.Close(context.WithoutCancel(ctx))
+		if ctx.Err() != nil {
+			return
+		}
+
+		// The driver already resumes once on its own; reopen with backoff beyond that.
+		for err != nil && resumable(err) && (w.opts.MaxRetries < 0 || failures < w.opts.MaxRetries) {
+			failures++
+			if !sleep(ctx, backoff(w.opts.RetryBackoff, failures)) {
+				return
+			}
+			stream, err = w.open(ctx)
+			if err == nil {
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			var e *errs.Error
+			if !errors.As(err, &e) {
+				err = WrapError(err, "mongoclient.Watch", "change stream failed")
+			}
+			w.send(ctx, ch, ChangeEvent{Err: err})
+			return
+		}
+	}
+}
+
+// drain delivers events until the stream fails, returning the failure
+func (w *watcher) drain(ctx context.Context, stream *mongo.ChangeStream, ch chan<- ChangeEvent, failures *int) error {
+	for stream.Next(ctx) {
+		var e ChangeEvent
+		if err := stream.Decode(&e); err != nil {
+			return errs.Wrap(err, errs.Invalid, "mongoclient.Watch", "failed to decode change event")
+		}
+		e.ResumeToken = stream.ResumeToken()
+		if !w.send(ctx, ch, e) {
+			return nil
+		}
+		w.token = e.ResumeToken
+		*failures = 0
+	}
+	if err := stream.Err(); err != nil {
+		return err
+	}
+	// A stream without error ends only when it was invalidated, e.g. the collection was dropped.
+	return errs.New(errs.Unavailable, "change stream was invalidated")
+}
+
+func (w *watcher) send(ctx context.Context, ch chan<- ChangeEvent, e ChangeEvent) bool {
+	select {
+	case ch <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// resumable reports whether reopening the stream may succeed
+func resumable(err error) bool {
+	var e *errs.Error
+	if errors.As(err, &e) {
+		return false // decode failures and invalidation repeat on reopen
+	}
+	var se mongo.ServerError
+	if errors.As(err, &se) && se.HasErrorLabel("ResumableChangeStreamError") {
+		return true
+	}
+	switch classify(err) {
+	case errs.Timeout, errs.Unavailable:
+		return true
+	}
+	return false
+}
+
+func backoff(base time.Duration, failures int) time.Duration {
+	d := base << (failures - 1)
+	if d <= 0 || d > 30*time.Second {
+		d = 30 * time.Second
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/cdcloud-io/go-libs/notify
+
+go 1.22.4
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SendGridSender sends email through the SendGrid v3 Mail Send API
+type SendGridSender struct {
+	apiKey string
+	from   string
+	client *http.Client
+	url    string
+}
+
+// NewSendGridSender creates a SendGrid sender; from is the verified sender address
+func NewSendGridSender(apiKey, from string) *SendGridSender {
+	return &SendGridSender{
+		apiKey: apiKey,
+		from:   from,
+		client: &http.Client{Timeout: 10 * time.Second},
+		url:    "https://api.sendgrid.com/v3/mail/send",
+	}
+}
+
+// Send delivers msg to msg.To
+func (s *SendGridSender) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("failed to send email: no recipients")
+	}
+
+	type address struct {
+		Email string `json:"email"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	to := make([]address, len(msg.To))
+	for i, addr := range msg.To {
+		to[i] = address{Email: addr}
+	}
+	var contents []content
+	if msg.Text != "" {
+		contents = append(contents, content{Type: "text/plain", Value: msg.Text})
+	}
+	if msg.HTML != "" {
+		contents = append(contents, content{Type: "text/html", Value: msg.HTML})
+	}
+
+	payload := map[string]interface{}{
+		"personalizations": []map[string]interface{}{{"to": to}},
+		"from":             address{Email: s.from},
+		"subject":          msg.Subject,
+		"content":          contents,
+	}
+	return postJSON(ctx, s.client, s.url, map[string]string{"Authorization": "Bearer " + s.apiKey}, payload)
+}
+
+// WebhookSender posts messages as JSON to an HTTP endpoint:
+//
+//	{"subject": "...", "text": "...", "severity": "...", "to": [...]}
+type WebhookSender struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhookSender creates a generic webhook sender; headers are added to every request
+func NewWebhookSender(url string, headers map[string]string) *WebhookSender {
+	return &WebhookSender{url: url, headers: headers, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send posts msg to the webhook
+func (s *WebhookSender) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, s.client, s.url, s.headers, map[string]interface{}{
+		"subject":  msg.Subject,
+		"text":     msg.Text,
+		"severity": msg.Severity,
+		"to":       msg.To,
+	})
+}
+
+// TeamsSender posts messages to a Microsoft Teams incoming webhook (or Workflows webhook)
+// as an Adaptive Card
+type TeamsSender struct {
+	url    string
+	client *http.Client
+}
+
+// NewTeamsSender creates a Teams sender for the channel's webhook URL
+func NewTeamsSender(webhookURL string) *TeamsSender {
+	return &TeamsSender{url: webhookURL, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send posts msg as a card; the severity picks the title color
+func (s *TeamsSender) Send(ctx context.Context, msg Message) error {
+	color := "Default"
+	switch msg.Severity {
+	case "critical", "error":
+		color = "Attention"
+	case "warning":
+		color = "Warning"
+	case "ok", "resolved":
+		color = "Good"
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []map[string]interface{}{
+			{"type": "TextBlock", "text": msg.Subject, "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+			{"type": "TextBlock", "text": msg.Text, "wrap": true},
+		},
+	}
+	return postJSON(ctx, s.client, s.url, nil, map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	})
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notification endpoint responded %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownChannel is returned when notifying a channel that was not registered
+	ErrUnknownChannel = errors.New("unknown notification channel")
+	// ErrRateLimited is returned when a channel's rate limit is exhausted and the wait would outlast ctx
+	ErrRateLimited = errors.New("notification rate limit exceeded")
+)
+
+// Message is a notification. Senders use the fields that apply to them: email senders
+// use To, Subject, Text and HTML; chat and webhook senders use Subject and Text.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+	// Template, when set, renders Subject, Text and HTML from the Notifier's templates with Data.
+	Template string
+	Data     interface{}
+	// Severity is informational for chat channels, e.g. "info", "warning", "critical".
+	Severity string
+}
+
+// Sender delivers a message over one channel
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SenderFunc adapts a function to Sender
+type SenderFunc func(ctx context.Context, msg Message) error
+
+// Send calls f
+func (f SenderFunc) Send(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// ChannelOptions configures delivery over one channel
+type ChannelOptions struct {
+	// Attempts is the number of tries including the first. Defaults to 3.
+	Attempts int
+	// Backoff is the delay before the second attempt, doubled (with jitter) after each. Defaults to 1s.
+	Backoff time.Duration
+	// RateLimit allows this many messages per RatePeriod. 0 disables rate limiting.
+	RateLimit int
+	// RatePeriod defaults to one minute.
+	RatePeriod time.Duration
+}
+
+type channel struct {
+	sender  Sender
+	opts    ChannelOptions
+	limiter *limiter
+}
+
+// Notifier routes messages to named channels with retry and per-channel rate limiting
+type Notifier struct {
+	templates *Templates
+
+	mu       sync.RWMutex
+	channels map[string]*channel
+}
+
+// New creates a Notifier; templates may be nil if messages are never templated
+func New(templates *Templates) *Notifier {
+	return &Notifier{templates: templates, channels: map[string]*channel{}}
+}
+
+// Register adds a channel, replacing any existing channel with the same name
+func (n *Notifier) Register(name string, sender Sender, opts ChannelOptions) {
+	if opts.Attempts <= 0 {
+		opts.Attempts = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.RatePeriod <= 0 {
+		opts.RatePeriod = time.Minute
+	}
+	ch := &channel{sender: sender, opts: opts}
+	if opts.RateLimit > 0 {
+		ch.limiter = newLimiter(opts.RateLimit, opts.RatePeriod)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.channels[name] = ch
+}
+
+// Notify renders msg if it is templated and sends it over the named channel,
+// waiting for rate limit capacity and retrying failed attempts with backoff
+func (n *Notifier) Notify(ctx context.Context, channelName string, msg Message) error {
+	n.mu.RLock()
+	ch, ok := n.channels[channelName]
+	n.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownChannel, channelName)
+	}
+
+	if msg.Template != "" {
+		if n.templates == nil {
+			return fmt.Errorf("failed to render notification %q: no templates configured", msg.Template)
+		}
+		rendered, err := n.templates.Render(msg)
+		if err != nil {
+			return err
+		}
+		msg = rendered
+	}
+
+	if ch.limiter != nil {
+		if err := ch.limiter.wait(ctx); err != nil {
+			return fmt.Errorf("failed to send notification over %s: %w", channelName, err)
+		}
+	}
+
+	var err error
+	delay := ch.opts.Backoff
+	for attempt := 1; attempt <= ch.opts.Attempts; attempt++ {
+		if err = ch.sender.Send(ctx, msg); err == nil {
+			return nil
+		}
+		if attempt == ch.opts.Attempts {
+			break
+		}
+		jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to send notification over %s: %w", channelName, ctx.Err())
+		case <-time.After(delay + jitter):
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("failed to send notification over %s after %d attempts: %w", channelName, ch.opts.Attempts, err)
+}
+
+// Broadcast sends msg over every listed channel and joins the errors
+func (n *Notifier) Broadcast(ctx context.Context, msg Message, channels ...string) error {
+	var errs []error
+	for _, name := range channels {
+		if err := n.Notify(ctx, name, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// limiter is a token bucket refilled evenly over period
+type limiter struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	rate     float64 // tokens per second
+	last     time.Time
+}
+
+func newLimiter(n int, period time.Duration) *limiter {
+	return &limiter{
+		tokens:   float64(n),
+		capacity: float64(n),
+		rate:     float64(n) / period.Seconds(),
+		last:     time.Now(),
+	}
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(l.capacity, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return ErrRateLimited
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPOptions configures an SMTPSender
+type SMTPOptions struct {
+	// Host and Port of the relay, e.g. "smtp.office365.com" and 587
+	Host string
+	Port int
+	// Username and Password enable PLAIN auth; leave empty for unauthenticated relays.
+	Username string
+	Password string
+	From     string
+	// ImplicitTLS connects with TLS from the start (port 465). Otherwise STARTTLS is used when offered.
+	ImplicitTLS bool
+	// Timeout bounds dialing. Defaults to 10s.
+	Timeout time.Duration
+}
+
+// SMTPSender sends email through an SMTP relay
+type SMTPSender struct {
+	opts SMTPOptions
+}
+
+// NewSMTPSender creates an SMTP sender
+func NewSMTPSender(opts SMTPOptions) *SMTPSender {
+	if opts.Port == 0 {
+		opts.Port = 587
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &SMTPSender{opts: opts}
+}
+
+// Send delivers msg to msg.To
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("failed to send email: no recipients")
+	}
+	for _, addr := range append([]string{s.opts.From}, msg.To...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return fmt.Errorf("failed to send email: invalid address %q", addr)
+		}
+	}
+	body, err := buildMIME(s.opts.From, msg)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.opts.Host, fmt.Sprint(s.opts.Port))
+	dialer := &net.Dialer{Timeout: s.opts.Timeout}
+	var conn net.Conn
+	if s.opts.ImplicitTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: s.opts.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.opts.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer c.Close()
+
+	if !s.opts.ImplicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: s.opts.Host}); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+	if s.opts.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate to SMTP server: %w", err)
+		}
+	}
+	if err := c.Mail(s.opts.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message data: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return c.Quit()
+}
+
+// buildMIME renders a text, HTML or multipart/alternative message
+func buildMIME(from string, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&buf, "%s: %s\r\n", k, v) }
+
+	header("From", from)
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	part := func(contentType, content string) error {
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n", contentType)
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(content)); err != nil {
+			return fmt.Errorf("failed to encode message body: %w", err)
+		}
+		if err := qp.Close(); err != nil {
+			return fmt.Errorf("failed to encode message body: %w", err)
+		}
+		buf.WriteString("\r\n")
+		return nil
+	}
+
+	switch {
+	case msg.HTML != "" && msg.Text != "":
+		var b [12]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, fmt.Errorf("failed to generate MIME boundary: %w", err)
+		}
+		boundary := hex.EncodeToString(b[:])
+		header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+		buf.WriteString("\r\n")
+		for _, p := range []struct{ ct, content string }{{"text/plain", msg.Text}, {"text/html", msg.HTML}} {
+			buf.WriteString("--" + boundary + "\r\n")
+			if err := part(p.ct, p.content); err != nil {
+				return nil, err
+			}
+		}
+		buf.WriteString("--" + boundary + "--\r\n")
+	case msg.HTML != "":
+		if err := part("text/html", msg.HTML); err != nil {
+			return nil, err
+		}
+	default:
+		if err := part("text/plain", msg.Text); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates renders templated messages. A template named "welcome" is made of up to three
+// files: welcome.subject.tmpl, welcome.txt.tmpl and welcome.html.tmpl. HTML templates are
+// auto-escaped; subject and text templates are not.
+type Templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// LoadTemplates parses every *.tmpl file in fsys matching pattern, e.g. LoadTemplates(embedFS, "templates/*.tmpl")
+func LoadTemplates(fsys fs.FS, pattern string) (*Templates, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification templates: %w", err)
+	}
+
+	t := &Templates{text: texttemplate.New(""), html: htmltemplate.New("")}
+	for _, f := range files {
+		b, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read notification template %s: %w", f, err)
+		}
+		name := f[strings.LastIndex(f, "/")+1:]
+		if strings.HasSuffix(name, ".html.tmpl") {
+			_, err = t.html.New(name).Parse(string(b))
+		} else {
+			_, err = t.text.New(name).Parse(string(b))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse notification template %s: %w", f, err)
+		}
+	}
+	return t, nil
+}
+
+// Render fills Subject, Text and HTML from msg.Template and msg.Data.
+// Parts without a template file keep their existing value.
+func (t *Templates) Render(msg Message) (Message, error) {
+	found := false
+
+	if tmpl := t.text.Lookup(msg.Template + ".subject.tmpl"); tmpl != nil {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, msg.Data); err != nil {
+			return msg, fmt.Errorf("failed to render subject of %q: %w", msg.Template, err)
+		}
+		msg.Subject = strings.TrimSpace(buf.String())
+		found = true
+	}
+	if tmpl := t.text.Lookup(msg.Template + ".txt.tmpl"); tmpl != nil {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, msg.Data); err != nil {
+			return msg, fmt.Errorf("failed to render text of %q: %w", msg.Template, err)
+		}
+		msg.Text = buf.String()
+		found = true
+	}
+	if tmpl := t.html.Lookup(msg.Template + ".html.tmpl"); tmpl != nil {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, msg.Data); err != nil {
+			return msg, fmt.Errorf("failed to render html of %q: %w", msg.Template, err)
+		}
+		msg.HTML = buf.String()
+		found = true
+	}
+
+	if !found {
+		return msg, fmt.Errorf("failed to render notification: template %q not found", msg.Template)
+	}
+	return msg, nil
+}
@@ -0,0 +1,201 @@
+package page
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultLimit is used when a request does not specify a limit
+	DefaultLimit = 20
+	// MaxLimit caps client-supplied limits
+	MaxLimit = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// SortField orders results by one field
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// PageRequest describes which slice of a list to return.
+// Cursor and Offset are alternatives: when Cursor is set, Offset is ignored.
+type PageRequest struct {
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset,omitempty"`
+	Cursor string      `json:"cursor,omitempty"`
+	Sort   []SortField `json:"sort,omitempty"`
+	// IncludeTotal asks the data source to count all matching items, which can be expensive.
+	IncludeTotal bool `json:"include_total,omitempty"`
+}
+
+// PageResponse is one page of results
+type PageResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total is only set when requested with IncludeTotal.
+	Total   *int64 `json:"total,omitempty"`
+	HasMore bool   `json:"has_more"`
+}
+
+// Map converts the items of a page, e.g. from storage documents to API types
+func Map[T, U any](p PageResponse[T], fn func(T) U) PageResponse[U] {
+	items := make([]U, len(p.Items))
+	for i, item := range p.Items {
+		items[i] = fn(item)
+	}
+	return PageResponse[U]{Items: items, NextCursor: p.NextCursor, Total: p.Total, HasMore: p.HasMore}
+}
+
+// Options restrict what clients may request in ParseRequest
+type Options struct {
+	DefaultLimit int
+	MaxLimit     int
+	// SortableFields lists the fields clients may sort by. Empty disallows client sorting.
+	SortableFields []string
+	// DefaultSort applies when the request has no sort parameter.
+	DefaultSort []SortField
+}
+
+// ParseRequest reads limit, offset, cursor, sort and include_total query parameters:
+//
+//	GET /orders?limit=50&sort=-created_at,name&cursor=eyJ...
+//
+// A leading '-' sorts a field in descending order.
+func ParseRequest(r *http.Request, opts Options) (PageRequest, error) {
+	return ParseQuery(r.URL.Query(), opts)
+}
+
+// ParseQuery is ParseRequest for already parsed query values
+func ParseQuery(q url.Values, opts Options) (PageRequest, error) {
+	if opts.DefaultLimit <= 0 {
+		opts.DefaultLimit = DefaultLimit
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = MaxLimit
+	}
+
+	req := PageRequest{Limit: opts.DefaultLimit, Cursor: q.Get("cursor"), Sort: opts.DefaultSort}
+
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return req, fmt.Errorf("limit must be a positive integer")
+		}
+		req.Limit = min(n, opts.MaxLimit)
+	}
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return req, fmt.Errorf("offset must be a non-negative integer")
+		}
+		req.Offset = n
+	}
+	if s := q.Get("include_total"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return req, fmt.Errorf("include_total must be a boolean")
+		}
+		req.IncludeTotal = b
+	}
+
+	if s := q.Get("sort"); s != "" {
+		sort, err := ParseSort(s, opts.SortableFields)
+		if err != nil {
+			return req, err
+		}
+		req.Sort = sort
+	}
+	return req, nil
+}
+
+// ParseSort parses "-created_at,name" into sort fields, allowing only the given fields
+func ParseSort(s string, allowed []string) ([]SortField, error) {
+	var sort []SortField
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		f := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !contains(allowed, f.Field) {
+			return nil, fmt.Errorf("cannot sort by %q", f.Field)
+		}
+		sort = append(sort, f)
+	}
+	return sort, nil
+}
+
+// SortString formats sort fields back into the query parameter form
+func SortString(sort []SortField) string {
+	parts := make([]string, len(sort))
+	for i, f := range sort {
+		if f.Desc {
+			parts[i] = "-" + f.Field
+		} else {
+			parts[i] = f.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// EncodeCursor serializes v into an opaque URL-safe cursor
+func EncodeCursor(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor reverses EncodeCursor
+func DecodeCursor(cursor string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// Link returns the URL of the next page for use in a Link header or response body,
+// or "" when there is no next page
+func Link[T any](r *http.Request, resp PageResponse[T]) string {
+	if !resp.HasMore {
+		return ""
+	}
+	u := *r.URL
+	q := u.Query()
+	if resp.NextCursor != "" {
+		q.Set("cursor", resp.NextCursor)
+		q.Del("offset")
+	} else {
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		if limit <= 0 {
+			limit = DefaultLimit
+		}
+		q.Set("offset", strconv.Itoa(offset+limit))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,7 @@
+module github.com/cdcloud-io/go-libs/passhash
+
+go 1.22.4
+
+require golang.org/x/crypto v0.25.0
+
+require golang.org/x/sys v0.22.0 // indirect
@@ -0,0 +1,170 @@
+package passhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrMismatch       = errors.New("password does not match")
+	ErrInvalidHash    = errors.New("invalid password hash")
+	ErrIncompatible   = errors.New("incompatible argon2 version")
+	ErrPolicyViolated = errors.New("password does not meet policy")
+)
+
+// Limits on the cost parameters of a hash, so that a crafted stored hash cannot make Verify
+// allocate gigabytes or run for minutes
+const (
+	maxMemory      = 1024 * 1024 // KiB, 1 GiB
+	maxIterations  = 64
+	maxParallelism = 64
+	maxKeyLength   = 1024
+)
+
+// Params are the argon2id cost parameters. Hash rejects parameters that Verify would not
+// accept: at most 1 GiB of memory, 64 iterations, 64 lanes and a 1024-byte key.
+type Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams follow the OWASP argon2id recommendation (19 MiB, 2 iterations, 1 lane)
+var DefaultParams = Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Hasher hashes and verifies passwords
+type Hasher struct {
+	Params Params
+	Policy *Policy
+}
+
+// New creates a Hasher with DefaultParams and no policy
+func New() *Hasher {
+	return &Hasher{Params: DefaultParams}
+}
+
+// Hash returns an encoded argon2id hash in the PHC string format:
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.Policy != nil {
+		if err := h.Policy.Check(password); err != nil {
+			return "", err
+		}
+	}
+
+	p := h.Params
+	if p.exceedsLimits() {
+		return "", fmt.Errorf("argon2 parameters exceed the supported limits")
+	}
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify checks password against an argon2id or bcrypt hash in constant time.
+// needsRehash is true when the hash is bcrypt or uses weaker parameters than h.Params,
+// so callers can store a fresh hash from Hash after a successful login.
+func (h *Hasher) Verify(password, encoded string) (needsRehash bool, err error) {
+	if strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$") {
+		if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, ErrMismatch
+			}
+			return false, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+		}
+		return true, nil
+	}
+
+	p, salt, key, err := decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, ErrMismatch
+	}
+	return p.weakerThan(h.Params), nil
+}
+
+// VerifyAndUpgrade verifies password and, when needed, returns a new hash with the current parameters.
+// upgraded is empty when the stored hash is already current.
+func (h *Hasher) VerifyAndUpgrade(password, encoded string) (upgraded string, err error) {
+	needsRehash, err := h.Verify(password, encoded)
+	if err != nil || !needsRehash {
+		return "", err
+	}
+	// The password already matched the stored hash, so skip the policy on rehash
+	nh := &Hasher{Params: h.Params}
+	return nh.Hash(password)
+}
+
+func (p Params) weakerThan(target Params) bool {
+	return p.Memory < target.Memory ||
+		p.Iterations < target.Iterations ||
+		p.Parallelism < target.Parallelism ||
+		p.KeyLength < target.KeyLength
+}
+
+func (p Params) exceedsLimits() bool {
+	return p.Memory > maxMemory ||
+		p.Iterations > maxIterations ||
+		p.Parallelism > maxParallelism ||
+		p.KeyLength > maxKeyLength
+}
+
+func decode(encoded string) (p Params, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return p, nil, nil, ErrIncompatible
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	if salt, err = base64.RawStdEncoding.Strict().DecodeString(parts[4]); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if key, err = base64.RawStdEncoding.Strict().DecodeString(parts[5]); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	// Zero parameters make argon2 panic, and an empty key would match any password
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 || len(salt) == 0 || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	if p.exceedsLimits() {
+		return p, nil, nil, ErrInvalidHash
+	}
+	return p, salt, key, nil
+}
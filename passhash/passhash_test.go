@@ -0,0 +1,175 @@
+package passhash
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap keeps the tests fast; the parameters do not change what is verified
+var cheap = Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHashAndVerify(t *testing.T) {
+	h := &Hasher{Params: cheap}
+	encoded, err := h.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		err      error
+	}{
+		{"right password", "correct horse battery staple", nil},
+		{"wrong password", "correct horse battery stapler", ErrMismatch},
+		{"empty password", "", ErrMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			needsRehash, err := h.Verify(tt.password, encoded)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.err)
+			}
+			if needsRehash {
+				t.Error("needsRehash = true for a hash with the current parameters")
+			}
+		})
+	}
+
+	other, err := h.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == encoded {
+		t.Error("two hashes of the same password are equal; the salt is not random")
+	}
+}
+
+func TestVerifyNeedsRehash(t *testing.T) {
+	old := &Hasher{Params: cheap}
+	encoded, err := old.Hash("hunter2hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("hunter2hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stronger := func(change func(*Params)) Params {
+		p := cheap
+		change(&p)
+		return p
+	}
+	tests := []struct {
+		name    string
+		params  Params
+		encoded string
+		want    bool
+	}{
+		{"same parameters", cheap, encoded, false},
+		{"weaker target", stronger(func(p *Params) { p.Memory = 32 }), encoded, false},
+		{"more memory", stronger(func(p *Params) { p.Memory = 128 }), encoded, true},
+		{"more iterations", stronger(func(p *Params) { p.Iterations = 2 }), encoded, true},
+		{"more lanes", stronger(func(p *Params) { p.Parallelism = 2 }), encoded, true},
+		{"longer key", stronger(func(p *Params) { p.KeyLength = 64 }), encoded, true},
+		{"bcrypt", cheap, string(bcryptHash), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Hasher{Params: tt.params}
+			needsRehash, err := h.Verify("hunter2hunter2", tt.encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if needsRehash != tt.want {
+				t.Errorf("needsRehash = %v, want %v", needsRehash, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAndUpgrade(t *testing.T) {
+	encoded, err := (&Hasher{Params: cheap}).Hash("hunter2hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := cheap
+	current.Iterations = 2
+	h := &Hasher{Params: current, Policy: &Policy{MinLength: 64}}
+
+	upgraded, err := h.VerifyAndUpgrade("hunter2hunter2", encoded)
+	if err != nil {
+		t.Fatalf("VerifyAndUpgrade() error = %v; the policy must not apply to a rehash", err)
+	}
+	if upgraded == "" {
+		t.Fatal("VerifyAndUpgrade() returned no new hash for weaker parameters")
+	}
+	if needsRehash, err := h.Verify("hunter2hunter2", upgraded); err != nil || needsRehash {
+		t.Errorf("Verify(upgraded) = %v, %v", needsRehash, err)
+	}
+	if again, err := h.VerifyAndUpgrade("hunter2hunter2", upgraded); err != nil || again != "" {
+		t.Errorf("VerifyAndUpgrade(upgraded) = %q, %v; want no new hash", again, err)
+	}
+	if _, err := h.VerifyAndUpgrade("wrong", encoded); !errors.Is(err, ErrMismatch) {
+		t.Errorf("VerifyAndUpgrade(wrong) error = %v, want ErrMismatch", err)
+	}
+}
+
+func TestVerifyRejectsMalformedHashes(t *testing.T) {
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	phc := func(version, params, salt, key string) string {
+		return fmt.Sprintf("$argon2id$%s$%s$%s$%s", version, params, salt, key)
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+		err     error
+	}{
+		{"well-formed", phc("v=19", "m=64,t=1,p=1", salt, key), ErrMismatch},
+		{"empty", "", ErrInvalidHash},
+		{"plain text", "hunter2", ErrInvalidHash},
+		{"argon2i", "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key, ErrInvalidHash},
+		{"missing key", "$argon2id$v=19$m=64,t=1,p=1$" + salt, ErrInvalidHash},
+		{"bad version", phc("v=x", "m=64,t=1,p=1", salt, key), ErrInvalidHash},
+		{"old version", phc("v=16", "m=64,t=1,p=1", salt, key), ErrIncompatible},
+		{"bad parameters", phc("v=19", "m=64;t=1;p=1", salt, key), ErrInvalidHash},
+		{"negative memory", phc("v=19", "m=-1,t=1,p=1", salt, key), ErrInvalidHash},
+		{"zero memory", phc("v=19", "m=0,t=1,p=1", salt, key), ErrInvalidHash},
+		{"zero iterations", phc("v=19", "m=64,t=0,p=1", salt, key), ErrInvalidHash},
+		{"zero parallelism", phc("v=19", "m=64,t=1,p=0", salt, key), ErrInvalidHash},
+		{"parallelism overflows", phc("v=19", "m=64,t=1,p=256", salt, key), ErrInvalidHash},
+		{"memory over limit", phc("v=19", "m=4294967295,t=1,p=1", salt, key), ErrInvalidHash},
+		{"iterations over limit", phc("v=19", "m=64,t=100000,p=1", salt, key), ErrInvalidHash},
+		{"parallelism over limit", phc("v=19", "m=64,t=1,p=255", salt, key), ErrInvalidHash},
+		{"empty salt", phc("v=19", "m=64,t=1,p=1", "", key), ErrInvalidHash},
+		{"empty key", phc("v=19", "m=64,t=1,p=1", salt, ""), ErrInvalidHash},
+		{"padded base64", phc("v=19", "m=64,t=1,p=1", salt+"==", key), ErrInvalidHash},
+		{"invalid base64", phc("v=19", "m=64,t=1,p=1", salt, "!!!"), ErrInvalidHash},
+		{"bcrypt garbage", "$2b$10$notavalidbcrypthash", ErrInvalidHash},
+	}
+	h := &Hasher{Params: cheap}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			needsRehash, err := h.Verify("hunter2", tt.encoded)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Verify(%q) error = %v, want %v", tt.encoded, err, tt.err)
+			}
+			if needsRehash {
+				t.Error("needsRehash = true on error")
+			}
+		})
+	}
+}
+
+func TestHashRejectsParametersOverLimits(t *testing.T) {
+	p := cheap
+	p.Iterations = maxIterations + 1
+	if _, err := (&Hasher{Params: p}).Hash("hunter2hunter2"); err == nil {
+		t.Error("Hash() accepted parameters that Verify would reject")
+	}
+}
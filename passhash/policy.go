@@ -0,0 +1,80 @@
+package passhash
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy describes the minimum requirements for a new password
+type Policy struct {
+	MinLength      int
+	MaxLength      int // argon2 accepts any length, but cap input to bound hashing cost
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSymbol  bool
+	MinCharClasses int
+	// Denylist holds common or breached passwords in lower case; candidates are compared case-insensitively.
+	Denylist map[string]struct{}
+}
+
+// DefaultPolicy follows NIST SP 800-63B: length over composition rules
+var DefaultPolicy = Policy{
+	MinLength: 12,
+	MaxLength: 128,
+}
+
+// Check returns an error wrapping ErrPolicyViolated describing the first failed rule
+func (p Policy) Check(password string) error {
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrPolicyViolated, p.MinLength)
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		return fmt.Errorf("%w: must be at most %d characters", ErrPolicyViolated, p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireUpper && !upper:
+		return fmt.Errorf("%w: must contain an uppercase letter", ErrPolicyViolated)
+	case p.RequireLower && !lower:
+		return fmt.Errorf("%w: must contain a lowercase letter", ErrPolicyViolated)
+	case p.RequireDigit && !digit:
+		return fmt.Errorf("%w: must contain a digit", ErrPolicyViolated)
+	case p.RequireSymbol && !symbol:
+		return fmt.Errorf("%w: must contain a symbol", ErrPolicyViolated)
+	}
+
+	if p.MinCharClasses > 0 {
+		classes := 0
+		for _, ok := range []bool{upper, lower, digit, symbol} {
+			if ok {
+				classes++
+			}
+		}
+		if classes < p.MinCharClasses {
+			return fmt.Errorf("%w: must contain at least %d character classes", ErrPolicyViolated, p.MinCharClasses)
+		}
+	}
+
+	if _, ok := p.Denylist[strings.ToLower(password)]; ok {
+		return fmt.Errorf("%w: password is too common", ErrPolicyViolated)
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+module github.com/cdcloud-io/go-libs/pipeline
+
+go 1.22.4
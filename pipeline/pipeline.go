@@ -0,0 +1,327 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// Pipeline ties stages together: every stage runs in its own goroutines under a shared context,
+// and the first stage error cancels the context so upstream and downstream stages stop.
+type Pipeline struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	once sync.Once
+	err  error
+}
+
+// New creates a Pipeline whose stages stop when ctx is cancelled
+func New(ctx context.Context) *Pipeline {
+	stageCtx, cancel := context.WithCancel(ctx)
+	return &Pipeline{parent: ctx, ctx: stageCtx, cancel: cancel}
+}
+
+// Context returns the pipeline context, cancelled on the first error
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Go runs fn as part of the pipeline. A returned error or panic fails the whole pipeline.
+func (p *Pipeline) Go(fn func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				p.fail(fmt.Errorf("pipeline stage panicked: %v\n%s", r, debug.Stack()))
+			}
+		}()
+		if err := fn(p.ctx); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+// Wait blocks until every stage has returned and reports the first error.
+// Cancellation of the parent context is reported as its error.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+
+	if p.err != nil {
+		return p.err
+	}
+	return p.parent.Err()
+}
+
+func (p *Pipeline) fail(err error) {
+	p.once.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// send delivers v unless the pipeline is cancelled first
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// From emits items in order
+func From[T any](p *Pipeline, items []T) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for _, v := range items {
+			if !send(ctx, out, v) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Generate emits whatever fn passes to emit; emit returns false once the pipeline is cancelled
+func Generate[T any](p *Pipeline, fn func(ctx context.Context, emit func(T) bool) error) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		return fn(ctx, func(v T) bool { return send(ctx, out, v) })
+	})
+	return out
+}
+
+// Map transforms each item sequentially, preserving order
+func Map[In, Out any](p *Pipeline, in <-chan In, fn func(ctx context.Context, v In) (Out, error)) <-chan Out {
+	out := make(chan Out)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for v := range in {
+			r, err := fn(ctx, v)
+			if err != nil {
+				return err
+			}
+			if !send(ctx, out, r) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Filter passes on the items for which keep returns true
+func Filter[T any](p *Pipeline, in <-chan T, keep func(ctx context.Context, v T) (bool, error)) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for v := range in {
+			ok, err := keep(ctx, v)
+			if err != nil {
+				return err
+			}
+			if ok && !send(ctx, out, v) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// FanOut transforms items with up to workers concurrent calls to fn.
+// Results are emitted as they complete, so output order is not preserved; see FanOutOrdered.
+func FanOut[In, Out any](p *Pipeline, in <-chan In, workers int, fn func(ctx context.Context, v In) (Out, error)) <-chan Out {
+	if workers <= 0 {
+		workers = 1
+	}
+	out := make(chan Out)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		p.Go(func(ctx context.Context) error {
+			defer wg.Done()
+			for v := range in {
+				r, err := fn(ctx, v)
+				if err != nil {
+					return err
+				}
+				if !send(ctx, out, r) {
+					return nil
+				}
+			}
+			return nil
+		})
+	}
+	p.Go(func(ctx context.Context) error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// FanOutOrdered is FanOut with results emitted in input order.
+// At most workers items are in flight, so a slow item holds back at most workers-1 finished ones.
+func FanOutOrdered[In, Out any](p *Pipeline, in <-chan In, workers int, fn func(ctx context.Context, v In) (Out, error)) <-chan Out {
+	if workers <= 0 {
+		workers = 1
+	}
+	out := make(chan Out)
+
+	// Each input gets a one-slot result channel; queueing those channels in input
+	// order and reading them in turn restores the original order.
+	pending := make(chan chan Out, workers)
+	sem := make(chan struct{}, workers)
+
+	p.Go(func(ctx context.Context) error {
+		defer close(pending)
+
+		for v := range in {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return nil
+			}
+			slot := make(chan Out, 1)
+			if !send(ctx, pending, slot) {
+				return nil
+			}
+
+			p.Go(func(ctx context.Context) error {
+				defer func() { <-sem }()
+				r, err := fn(ctx, v)
+				if err != nil {
+					close(slot)
+					return err
+				}
+				slot <- r
+				return nil
+			})
+		}
+		return nil
+	})
+
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for slot := range pending {
+			select {
+			case r, ok := <-slot:
+				if !ok {
+					return nil // the worker failed and already reported its error
+				}
+				if !send(ctx, out, r) {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Merge fans several channels into one, emitting items as they arrive
+func Merge[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		p.Go(func(ctx context.Context) error {
+			defer wg.Done()
+			for v := range in {
+				if !send(ctx, out, v) {
+					return nil
+				}
+			}
+			return nil
+		})
+	}
+	p.Go(func(ctx context.Context) error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// MergeOrdered merges channels that are each sorted according to less into a single sorted stream
+func MergeOrdered[T any](p *Pipeline, less func(a, b T) bool, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+
+		type head struct {
+			v  T
+			ok bool
+		}
+		heads := make([]head, len(ins))
+		recv := func(i int) bool {
+			select {
+			case v, ok := <-ins[i]:
+				heads[i] = head{v: v, ok: ok}
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for i := range ins {
+			if !recv(i) {
+				return nil
+			}
+		}
+
+		for {
+			min := -1
+			for i, h := range heads {
+				if h.ok && (min < 0 || less(h.v, heads[min].v)) {
+					min = i
+				}
+			}
+			if min < 0 {
+				return nil
+			}
+			if !send(ctx, out, heads[min].v) || !recv(min) {
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// ForEach consumes the channel, calling fn for each item
+func ForEach[T any](p *Pipeline, in <-chan T, fn func(ctx context.Context, v T) error) {
+	p.Go(func(ctx context.Context) error {
+		for v := range in {
+			if err := fn(ctx, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Collect drains the channel into a slice and waits for the pipeline to finish
+func Collect[T any](p *Pipeline, in <-chan T) ([]T, error) {
+	var items []T
+	ForEach(p, in, func(ctx context.Context, v T) error {
+		items = append(items, v)
+		return nil
+	})
+	if err := p.Wait(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,3 @@
+module github.com/cdcloud-io/go-libs/profiling
+
+go 1.22.4
@@ -0,0 +1,185 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// ProfileType names a runtime profile that can be collected
+type ProfileType string
+
+const (
+	ProfileCPU       ProfileType = "cpu"
+	ProfileHeap      ProfileType = "heap"
+	ProfileGoroutine ProfileType = "goroutine"
+)
+
+// Profile is a single collected profile in pprof (gzipped protobuf) format
+type Profile struct {
+	Type  ProfileType
+	Data  []byte
+	Start time.Time
+	End   time.Time
+}
+
+// Sink receives collected profiles and ships them to a backend
+type Sink interface {
+	Upload(ctx context.Context, p Profile) error
+}
+
+// SinkFunc adapts a plain function to the Sink interface.
+// Use it to push profiles to storage this package has no adapter for (e.g. blob storage).
+type SinkFunc func(ctx context.Context, p Profile) error
+
+// Upload calls f(ctx, p)
+func (f SinkFunc) Upload(ctx context.Context, p Profile) error {
+	return f(ctx, p)
+}
+
+// Options configures a Profiler
+type Options struct {
+	// Sink receives every collected profile. Required.
+	Sink Sink
+	// Types lists the profiles to collect. Defaults to cpu, heap and goroutine.
+	Types []ProfileType
+	// Interval between scheduled collections started with Start. Defaults to 1m.
+	Interval time.Duration
+	// CPUDuration is how long the CPU profile samples for. Defaults to 10s and
+	// is capped at Interval.
+	CPUDuration time.Duration
+}
+
+// Profiler collects pprof profiles on a schedule or on demand and pushes them to a Sink
+type Profiler struct {
+	opts Options
+
+	// mu serializes collections; the runtime only allows one CPU profile at a time.
+	mu sync.Mutex
+}
+
+// ErrCollectionInProgress is returned by Collect when another collection is still running
+var ErrCollectionInProgress = errors.New("profile collection already in progress")
+
+// New creates a Profiler with the given options
+func New(opts Options) (*Profiler, error) {
+	if opts.Sink == nil {
+		return nil, fmt.Errorf("profiling sink is required")
+	}
+	if len(opts.Types) == 0 {
+		opts.Types = []ProfileType{ProfileCPU, ProfileHeap, ProfileGoroutine}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.CPUDuration <= 0 {
+		opts.CPUDuration = 10 * time.Second
+	}
+	if opts.CPUDuration > opts.Interval {
+		opts.CPUDuration = opts.Interval
+	}
+	return &Profiler{opts: opts}, nil
+}
+
+// Start collects and uploads profiles every interval until ctx is cancelled.
+// Upload errors are not fatal; the next tick simply tries again.
+func (p *Profiler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.Collect(ctx)
+			}
+		}
+	}()
+}
+
+// Collect takes one round of the configured profiles and uploads them.
+// It returns ErrCollectionInProgress if a scheduled or triggered collection is already running.
+func (p *Profiler) Collect(ctx context.Context) error {
+	if !p.mu.TryLock() {
+		return ErrCollectionInProgress
+	}
+	defer p.mu.Unlock()
+
+	return p.run(ctx)
+}
+
+func (p *Profiler) run(ctx context.Context) error {
+	var errs []error
+	for _, t := range p.opts.Types {
+		prof, err := p.collect(ctx, t)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := p.opts.Sink.Upload(ctx, prof); err != nil {
+			errs = append(errs, fmt.Errorf("failed to upload %s profile: %w", t, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TriggerHandler returns an HTTP handler that starts an on-demand collection on POST.
+// It responds 202 once the collection has started and 409 if one is already running.
+func (p *Profiler) TriggerHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !p.mu.TryLock() {
+			http.Error(w, ErrCollectionInProgress.Error(), http.StatusConflict)
+			return
+		}
+
+		// The request context ends with the response, so collect in the background
+		go func() {
+			defer p.mu.Unlock()
+			p.run(context.Background())
+		}()
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func (p *Profiler) collect(ctx context.Context, t ProfileType) (Profile, error) {
+	var buf bytes.Buffer
+	prof := Profile{Type: t, Start: time.Now()}
+
+	switch t {
+	case ProfileCPU:
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return prof, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		timer := time.NewTimer(p.opts.CPUDuration)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+		pprof.StopCPUProfile()
+	default:
+		lookup := pprof.Lookup(string(t))
+		if lookup == nil {
+			return prof, fmt.Errorf("unknown profile type %q", t)
+		}
+		if err := lookup.WriteTo(&buf, 0); err != nil {
+			return prof, fmt.Errorf("failed to write %s profile: %w", t, err)
+		}
+	}
+
+	prof.End = time.Now()
+	prof.Data = buf.Bytes()
+	return prof, nil
+}
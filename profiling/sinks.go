@@ -0,0 +1,123 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PyroscopeSink pushes profiles to the Pyroscope (Grafana) /ingest HTTP API
+type PyroscopeSink struct {
+	// ServerURL is the base URL of the Pyroscope server, e.g. http://pyroscope:4040
+	ServerURL string
+	// AppName is the application name profiles are stored under.
+	AppName string
+	// Tags are attached to every profile, e.g. env=prod, version=1.2.3.
+	Tags map[string]string
+	// BasicAuthUser/BasicAuthPassword or AuthToken authenticate against hosted Pyroscope.
+	BasicAuthUser     string
+	BasicAuthPassword string
+	AuthToken         string
+	// HTTPClient defaults to a client with a 30s timeout.
+	HTTPClient *http.Client
+}
+
+// Upload sends a single profile to Pyroscope
+func (s *PyroscopeSink) Upload(ctx context.Context, p Profile) error {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("profile", "profile.pprof")
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(p.Data); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	q.Set("name", s.appName(p.Type))
+	q.Set("from", strconv.FormatInt(p.Start.Unix(), 10))
+	q.Set("until", strconv.FormatInt(p.End.Unix(), 10))
+	q.Set("format", "pprof")
+	q.Set("spyName", "gospy")
+
+	endpoint := strings.TrimRight(s.ServerURL, "/") + "/ingest?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create pyroscope request: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	switch {
+	case s.AuthToken != "":
+		req.Header.Set("Authorization", "Bearer "+s.AuthToken)
+	case s.BasicAuthUser != "":
+		req.SetBasicAuth(s.BasicAuthUser, s.BasicAuthPassword)
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send profile to pyroscope: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pyroscope ingest returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// appName renders the Pyroscope application name with tags, e.g. orders.cpu{env=prod}
+func (s *PyroscopeSink) appName(t ProfileType) string {
+	name := s.AppName + "." + string(t)
+	if len(s.Tags) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(s.Tags))
+	for k := range s.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tags := make([]string, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, k+"="+s.Tags[k])
+	}
+	return name + "{" + strings.Join(tags, ",") + "}"
+}
+
+// DirSink writes each profile to a file under Dir, named <type>-<unix-nanos>.pb.gz.
+// Point it at a mounted volume or a directory synced to blob storage.
+type DirSink struct {
+	Dir string
+}
+
+// Upload writes the profile to disk
+func (s *DirSink) Upload(ctx context.Context, p Profile) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	name := fmt.Sprintf("%s-%d.pb.gz", p.Type, p.Start.UnixNano())
+	if err := os.WriteFile(filepath.Join(s.Dir, name), p.Data, 0o644); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package redact
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Value returns a redacted copy of v as plain maps, slices and scalars, safe to log or marshal.
+//
+// Struct fields are redacted according to the `redact` tag:
+//
+//	Password string `redact:"true"`  // replaced with Placeholder
+//	Card     string `redact:"mask"`  // last 4 characters kept
+//	Note     string `redact:"false"` // never redacted, even if the name looks sensitive
+//
+// Untagged fields and map entries with sensitive names (see IsSensitiveKey) are redacted,
+// and remaining strings are scrubbed with String. Keys use the yaml, json or bson tag name when present.
+func Value(v interface{}) interface{} {
+	return value(reflect.ValueOf(v), 0)
+}
+
+const maxDepth = 32
+
+func value(v reflect.Value, depth int) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > maxDepth {
+		return "[MAX DEPTH]"
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return value(v.Elem(), depth+1)
+
+	case reflect.Struct:
+		// Opaque types such as time.Time have no exported fields; print them as-is
+		if !hasExportedFields(v.Type()) {
+			if v.CanInterface() {
+				if s, ok := v.Interface().(fmt.Stringer); ok {
+					return s.String()
+				}
+			}
+			return fmt.Sprint(v.Interface())
+		}
+		out := make(map[string]interface{}, v.NumField())
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := fieldName(f)
+			if name == "-" {
+				continue
+			}
+			switch f.Tag.Get("redact") {
+			case "true":
+				out[name] = redactedScalar(v.Field(i))
+				continue
+			case "mask":
+				out[name] = Mask(fmt.Sprint(v.Field(i).Interface()))
+				continue
+			case "false":
+				out[name] = value(v.Field(i), depth+1)
+				continue
+			}
+			if IsSensitiveKey(f.Name) {
+				out[name] = redactedScalar(v.Field(i))
+				continue
+			}
+			out[name] = value(v.Field(i), depth+1)
+		}
+		return out
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k := fmt.Sprint(iter.Key().Interface())
+			if IsSensitiveKey(k) {
+				out[k] = redactedScalar(iter.Value())
+				continue
+			}
+			out[k] = value(iter.Value(), depth+1)
+		}
+		return out
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("[%d bytes]", v.Len())
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = value(v.Index(i), depth+1)
+		}
+		return out
+
+	case reflect.String:
+		return String(v.String())
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil
+
+	default:
+		return v.Interface()
+	}
+}
+
+// redactedScalar keeps empty values empty so dumps still show which secrets are unset
+func redactedScalar(v reflect.Value) interface{} {
+	if !v.IsValid() || v.IsZero() {
+		return ""
+	}
+	return Placeholder
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldName(f reflect.StructField) string {
+	for _, key := range []string{"yaml", "json", "bson"} {
+		if tag, ok := f.Tag.Lookup(key); ok {
+			name, _, _ := strings.Cut(tag, ",")
+			if name != "" {
+				return name
+			}
+		}
+	}
+	return f.Name
+}
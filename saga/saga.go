@@ -0,0 +1,343 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Status is the state of a saga instance
+type Status string
+
+const (
+	// StatusRunning instances execute their steps in order
+	StatusRunning Status = "running"
+	// StatusCompensating instances undo their completed steps in reverse order after a step failed
+	StatusCompensating Status = "compensating"
+	// StatusCompleted instances ran all their steps
+	StatusCompleted Status = "completed"
+	// StatusCompensated instances were rolled back after a step failed
+	StatusCompensated Status = "compensated"
+	// StatusFailed instances could not be compensated and need manual attention, see Retry
+	StatusFailed Status = "failed"
+)
+
+// Step is one action of a saga with the compensation that undoes it. Steps run at least
+// once: after a crash or lost lock the current step runs again, so make both funcs idempotent.
+type Step[T any] struct {
+	Name string
+	// Action performs the step. Changes it makes to data are persisted when it succeeds.
+	Action func(ctx context.Context, data *T) error
+	// Compensate undoes a completed Action; nil if there is nothing to undo.
+	Compensate func(ctx context.Context, data *T) error
+	// Timeout bounds each attempt. Defaults to Options.StepTimeout.
+	Timeout time.Duration
+	// MaxAttempts before the saga is compensated. Defaults to Options.MaxAttempts.
+	MaxAttempts int
+}
+
+// Definition is a named sequence of steps operating on data of type T
+type Definition[T any] struct {
+	Name  string
+	Steps []Step[T]
+}
+
+// StepRecord is an entry of an instance's history
+type StepRecord struct {
+	Step       string    `bson:"step"`
+	Compensate bool      `bson:"compensate,omitempty"`
+	Attempt    int       `bson:"attempt"`
+	Error      string    `bson:"error,omitempty"`
+	At         time.Time `bson:"at"`
+}
+
+// Instance is the durable state of one saga execution
+type Instance struct {
+	ID     string `bson:"_id"`
+	Saga   string `bson:"saga"`
+	Status Status `bson:"status"`
+	// Step is the index of the step being executed or compensated.
+	Step        int          `bson:"step"`
+	Attempts    int          `bson:"attempts"`
+	Data        bson.Raw     `bson:"data"`
+	LastError   string       `bson:"last_error,omitempty"`
+	History     []StepRecord `bson:"history,omitempty"`
+	RunAt       time.Time    `bson:"run_at"`
+	LockedUntil time.Time    `bson:"locked_until,omitempty"`
+	LockedBy    string       `bson:"locked_by,omitempty"`
+	CreatedAt   time.Time    `bson:"created_at"`
+	UpdatedAt   time.Time    `bson:"updated_at"`
+}
+
+// Decode unmarshals the saga data into v
+func (i *Instance) Decode(v interface{}) error {
+	if err := bson.Unmarshal(i.Data, v); err != nil {
+		return fmt.Errorf("failed to decode data of saga %s: %w", i.ID, err)
+	}
+	return nil
+}
+
+// Options configures a Coordinator
+type Options struct {
+	Database string
+	// Collection defaults to "sagas".
+	Collection string
+	// LockTimeout is how long a claimed instance stays invisible to other workers; it is
+	// renewed after every step. An instance whose worker dies resumes once it expires.
+	// Must exceed the longest step timeout. Defaults to 5m.
+	LockTimeout time.Duration
+	// StepTimeout is the default timeout of each step attempt. Defaults to 1m.
+	StepTimeout time.Duration
+	// MaxAttempts is the default number of attempts per step and per compensation. Defaults to 3.
+	MaxAttempts int
+	// Backoff returns the delay before retrying after the given failed attempt.
+	// Defaults to exponential backoff starting at 5s, capped at 5m.
+	Backoff func(attempt int) time.Duration
+	// HistoryLimit caps the step records kept per instance. Defaults to 100.
+	HistoryLimit int
+}
+
+// StartOptions are per-instance settings for Start
+type StartOptions struct {
+	// ID identifies the instance, e.g. the order ID. Starting a saga with an existing ID
+	// is a no-op, so retried requests do not start it twice. Defaults to a new ObjectID.
+	ID string
+}
+
+var (
+	// ErrNoInstance is returned by claim when no instance is ready to run
+	ErrNoInstance = errors.New("no saga instance ready")
+	// ErrNotFound is returned for unknown instance IDs
+	ErrNotFound = errors.New("saga instance not found")
+)
+
+// Coordinator stores saga instances in a MongoDB collection and runs their steps.
+// Instances are claimed atomically, so any number of workers across replicas can share them.
+// Every operation goes through the mongoclient.Client, so a client with a TenantResolver
+// keeps the instances of each tenant apart and needs the tenant in the context of Start and Run.
+type Coordinator struct {
+	client    *mongoclient.Client
+	instances *mongoclient.CollectionRepository
+	opts      Options
+	defs      map[string]runner
+	now       func() time.Time
+}
+
+// New creates a Coordinator backed by the given client
+func New(client *mongoclient.Client, opts Options) *Coordinator {
+	c := newCoordinator(client, opts)
+	c.client = client
+	return c
+}
+
+// newCoordinator creates a Coordinator on any mongoclient.Store, e.g. a mongoclientmock.Store
+// in tests. Only EnsureIndexes needs the Client.
+func newCoordinator(store mongoclient.Store, opts Options) *Coordinator {
+	if opts.Collection == "" {
+		opts.Collection = "sagas"
+	}
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = 5 * time.Minute
+	}
+	if opts.StepTimeout <= 0 {
+		opts.StepTimeout = time.Minute
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = exponentialBackoff(5*time.Second, 5*time.Minute)
+	}
+	if opts.HistoryLimit <= 0 {
+		opts.HistoryLimit = 100
+	}
+	return &Coordinator{
+		instances: mongoclient.NewRepository(store, mongoclient.RepositoryOptions{Database: opts.Database, Collection: opts.Collection}),
+		opts:      opts,
+		defs:      map[string]runner{},
+		now:       time.Now,
+	}
+}
+
+func exponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
+		if d <= 0 || d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// Register adds a saga definition. Register every definition before calling Run.
+func Register[T any](c *Coordinator, def Definition[T]) error {
+	if def.Name == "" {
+		return errors.New("saga definition has no name")
+	}
+	if len(def.Steps) == 0 {
+		return fmt.Errorf("saga %q has no steps", def.Name)
+	}
+	for i, s := range def.Steps {
+		if s.Action == nil {
+			return fmt.Errorf("step %d of saga %q has no action", i, def.Name)
+		}
+	}
+	if _, ok := c.defs[def.Name]; ok {
+		return fmt.Errorf("saga %q is already registered", def.Name)
+	}
+	c.defs[def.Name] = &definition[T]{def: def}
+	return nil
+}
+
+// EnsureIndexes creates the index used to claim instances, in the namespace of the tenant
+// in ctx when the client routes by tenant. Call it once at startup.
+func (c *Coordinator) EnsureIndexes(ctx context.Context) error {
+	ns, err := c.client.Resolve(ctx, mongoclient.Namespace{Database: c.opts.Database, Collection: c.opts.Collection})
+	if err != nil {
+		return err
+	}
+	err = c.client.EnsureIndexes(ctx, ns.Database, ns.Collection, []mongoclient.IndexSpec{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "run_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create saga index: %w", err)
+	}
+	return nil
+}
+
+// Start stores a new instance of the named saga with its initial data and returns its ID.
+// A worker running Run picks it up.
+func Start[T any](ctx context.Context, c *Coordinator, name string, data T, opts StartOptions) (string, error) {
+	if _, ok := c.defs[name]; !ok {
+		return "", fmt.Errorf("saga %q is not registered", name)
+	}
+	raw, err := bson.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode saga data: %w", err)
+	}
+	if opts.ID == "" {
+		opts.ID = primitive.NewObjectID().Hex()
+	}
+
+	now := c.now().UTC()
+	inst := Instance{
+		ID:        opts.ID,
+		Saga:      name,
+		Status:    StatusRunning,
+		Data:      raw,
+		RunAt:     now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := c.instances.Insert(ctx, inst); err != nil {
+		if errors.Is(err, mongoclient.ErrDuplicateKey) {
+			return opts.ID, nil
+		}
+		return "", fmt.Errorf("failed to start saga: %w", err)
+	}
+	return opts.ID, nil
+}
+
+// Get returns the instance with the given ID
+func (c *Coordinator) Get(ctx context.Context, id string) (*Instance, error) {
+	var inst Instance
+	err := c.instances.FindOne(ctx, bson.M{"_id": id}, mongoclient.QueryOptions{}, &inst)
+	if errors.Is(err, mongoclient.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saga %s: %w", id, err)
+	}
+	return &inst, nil
+}
+
+// Retry resumes compensating a failed instance with a fresh set of attempts, e.g. after
+// the cause was fixed by hand
+func (c *Coordinator) Retry(ctx context.Context, id string) error {
+	now := c.now().UTC()
+	result, err := c.instances.UpdateOne(ctx,
+		bson.M{"_id": id, "status": StatusFailed},
+		bson.M{"$set": bson.M{"status": StatusCompensating, "attempts": 0, "run_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to retry saga %s: %w", id, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("failed saga %s not found", id)
+	}
+	return nil
+}
+
+// claim atomically takes the next instance that is due, or whose previous worker's lock
+// expired, and counts the attempt of its current step
+func (c *Coordinator) claim(ctx context.Context, workerID string) (*Instance, error) {
+	now := c.now().UTC()
+	names := make(bson.A, 0, len(c.defs))
+	for name := range c.defs {
+		names = append(names, name)
+	}
+
+	filter := bson.M{
+		"saga":   bson.M{"$in": names},
+		"status": bson.M{"$in": bson.A{StatusRunning, StatusCompensating}},
+		"run_at": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"locked_until": bson.M{"$exists": false}},
+			bson.M{"locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"locked_until": now.Add(c.opts.LockTimeout),
+			"locked_by":    workerID,
+			"updated_at":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := mongoclient.FindAndModifyOptions{
+		Sort:        bson.D{{Key: "run_at", Value: 1}},
+		ReturnAfter: true,
+	}
+
+	var inst Instance
+	err := c.instances.FindOneAndUpdate(ctx, filter, update, opts, &inst)
+	if errors.Is(err, mongoclient.ErrNotFound) {
+		return nil, ErrNoInstance
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim saga: %w", err)
+	}
+	return &inst, nil
+}
+
+// save updates an instance only while its worker still holds it, so a worker whose lock
+// expired cannot overwrite a newer claim. With release the lock is dropped, otherwise it is renewed.
+func (c *Coordinator) save(ctx context.Context, inst *Instance, set bson.M, record *StepRecord, release bool) error {
+	now := c.now().UTC()
+	set["updated_at"] = now
+	update := bson.M{"$set": set}
+	if record != nil {
+		record.At = now
+		update["$push"] = bson.M{"history": bson.M{"$each": bson.A{record}, "$slice": -c.opts.HistoryLimit}}
+	}
+	if release {
+		update["$unset"] = bson.M{"locked_until": "", "locked_by": ""}
+	} else {
+		set["locked_until"] = now.Add(c.opts.LockTimeout)
+	}
+
+	result, err := c.instances.UpdateOne(ctx, bson.M{"_id": inst.ID, "locked_by": inst.LockedBy}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update saga %s: %w", inst.ID, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("saga %s is no longer held by %s", inst.ID, inst.LockedBy)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient/mongoclientmock"
+)
+
+type order struct {
+	Log []string `bson:"log"`
+}
+
+// testCoordinator is a Coordinator on an in-memory store whose clock only moves when the
+// test sets now
+type testCoordinator struct {
+	*Coordinator
+	now time.Time
+}
+
+func newTestCoordinator(t *testing.T, steps ...Step[order]) *testCoordinator {
+	t.Helper()
+	opts := Options{
+		Database:    "app",
+		MaxAttempts: 2,
+		Backoff:     func(attempt int) time.Duration { return time.Duration(attempt) * 10 * time.Second },
+	}
+	c := &testCoordinator{Coordinator: newCoordinator(mongoclientmock.New(), opts), now: time.Now().Truncate(time.Millisecond)}
+	c.Coordinator.now = func() time.Time { return c.now }
+	if err := Register(c.Coordinator, Definition[order]{Name: "checkout", Steps: steps}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// runNext claims the next due instance and runs it like a worker of Run
+func (c *testCoordinator) runNext(t *testing.T) error {
+	t.Helper()
+	inst, err := c.claim(context.Background(), "w1")
+	if err != nil {
+		t.Fatalf("claim() = %v", err)
+	}
+	return c.defs[inst.Saga].run(context.Background(), c.Coordinator, inst)
+}
+
+func (c *testCoordinator) get(t *testing.T, id string) (*Instance, order) {
+	t.Helper()
+	inst, err := c.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data order
+	if err := inst.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	return inst, data
+}
+
+// logStep is a step that records its action and compensation in the saga data
+func logStep(name string) Step[order] {
+	return Step[order]{
+		Name: name,
+		Action: func(ctx context.Context, o *order) error {
+			o.Log = append(o.Log, name)
+			return nil
+		},
+		Compensate: func(ctx context.Context, o *order) error {
+			o.Log = append(o.Log, "undo "+name)
+			return nil
+		},
+	}
+}
+
+// failStep is a step whose action changes the data and then fails with err
+func failStep(name string, err error) Step[order] {
+	return Step[order]{
+		Name: name,
+		Action: func(ctx context.Context, o *order) error {
+			o.Log = append(o.Log, "partial "+name)
+			return err
+		},
+	}
+}
+
+func TestRunCompletes(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCoordinator(t, logStep("reserve"), logStep("charge"), logStep("ship"))
+
+	id, err := Start(ctx, c.Coordinator, "checkout", order{}, StartOptions{ID: "o1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Starting the same ID again is a no-op
+	if again, err := Start(ctx, c.Coordinator, "checkout", order{Log: []string{"other"}}, StartOptions{ID: "o1"}); err != nil || again != id {
+		t.Fatalf("Start() again = %s, %v", again, err)
+	}
+
+	if err := c.runNext(t); err != nil {
+		t.Fatal(err)
+	}
+	inst, data := c.get(t, id)
+	if inst.Status != StatusCompleted || len(inst.History) != 3 || inst.LockedBy != "" {
+		t.Fatalf("instance = %+v, want completed and unlocked with 3 history records", inst)
+	}
+	if want := []string{"reserve", "charge", "ship"}; !reflect.DeepEqual(data.Log, want) {
+		t.Fatalf("log = %v, want %v", data.Log, want)
+	}
+	if _, err := c.claim(ctx, "w1"); !errors.Is(err, ErrNoInstance) {
+		t.Fatalf("claim() after completion = %v, want ErrNoInstance", err)
+	}
+	if _, err := c.Get(ctx, "unknown"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() of an unknown ID = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPermanentErrorCompensates(t *testing.T) {
+	ctx := context.Background()
+	noUndo := logStep("notify")
+	noUndo.Compensate = nil
+	c := newTestCoordinator(t, logStep("reserve"), noUndo, logStep("charge"), failStep("ship", Permanent(errors.New("no carrier"))))
+
+	id, err := Start(ctx, c.Coordinator, "checkout", order{}, StartOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.runNext(t); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed attempt's changes are discarded and completed steps are undone in reverse
+	inst, data := c.get(t, id)
+	if inst.Status != StatusCompensated || inst.LastError != "no carrier" {
+		t.Fatalf("instance = %+v, want compensated after no carrier", inst)
+	}
+	want := []string{"reserve", "notify", "charge", "undo charge", "undo reserve"}
+	if !reflect.DeepEqual(data.Log, want) {
+		t.Fatalf("log = %v, want %v", data.Log, want)
+	}
+}
+
+func TestStepRetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	failures := 0
+	flaky := Step[order]{
+		Name: "charge",
+		Action: func(ctx context.Context, o *order) error {
+			o.Log = append(o.Log, "charge")
+			if failures < 1 {
+				failures++
+				return errors.New("gateway timeout")
+			}
+			return nil
+		},
+	}
+	c := newTestCoordinator(t, logStep("reserve"), flaky)
+
+	id, err := Start(ctx, c.Coordinator, "checkout", order{}, StartOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.runNext(t); err == nil {
+		t.Fatal("run() = nil, want the step error")
+	}
+	inst, _ := c.get(t, id)
+	if inst.Status != StatusRunning || inst.Step != 1 || !inst.RunAt.Equal(c.now.Add(10*time.Second)) {
+		t.Fatalf("instance = %+v, want step 1 due after Backoff(1)", inst)
+	}
+
+	c.now = c.now.Add(9 * time.Second)
+	if _, err := c.claim(ctx, "w1"); !errors.Is(err, ErrNoInstance) {
+		t.Fatalf("claim() during backoff = %v, want ErrNoInstance", err)
+	}
+	c.now = c.now.Add(time.Second)
+	if err := c.runNext(t); err != nil {
+		t.Fatal(err)
+	}
+	inst, data := c.get(t, id)
+	if inst.Status != StatusCompleted || !reflect.DeepEqual(data.Log, []string{"reserve", "charge"}) {
+		t.Fatalf("instance = %+v with log %v, want completed with one charge", inst, data.Log)
+	}
+}
+
+func TestExhaustedStepCompensates(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCoordinator(t, logStep("reserve"), failStep("charge", errors.New("declined")))
+
+	id, err := Start(ctx, c.Coordinator, "checkout", order{}, StartOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.runNext(t)
+	c.now = c.now.Add(time.Minute)
+	if err := c.runNext(t); err != nil {
+		t.Fatal(err)
+	}
+
+	inst, data := c.get(t, id)
+	if inst.Status != StatusCompensated || !reflect.DeepEqual(data.Log, []string{"reserve", "undo reserve"}) {
+		t.Fatalf("instance = %+v with log %v, want compensated after 2 attempts", inst, data.Log)
+	}
+}
+
+func TestRetryFailedCompensation(t *testing.T) {
+	ctx := context.Background()
+	broken := true
+	release := logStep("reserve")
+	release.Compensate = func(ctx context.Context, o *order) error {
+		if broken {
+			return Permanent(errors.New("inventory offline"))
+		}
+		o.Log = append(o.Log, "undo reserve")
+		return nil
+	}
+	c := newTestCoordinator(t, release, failStep("charge", Permanent(errors.New("declined"))))
+
+	id, err := Start(ctx, c.Coordinator, "checkout", order{}, StartOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Retry(ctx, id); err == nil {
+		t.Fatal("Retry() of a running saga = nil, want an error")
+	}
+	if err := c.runNext(t); err == nil {
+		t.Fatal("run() = nil, want the compensation error")
+	}
+	if inst, _ := c.get(t, id); inst.Status != StatusFailed || inst.LastError != "inventory offline" {
+		t.Fatalf("instance = %+v, want failed", inst)
+	}
+
+	broken = false
+	if err := c.Retry(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.runNext(t); err != nil {
+		t.Fatal(err)
+	}
+	inst, data := c.get(t, id)
+	if inst.Status != StatusCompensated || !reflect.DeepEqual(data.Log, []string{"reserve", "undo reserve"}) {
+		t.Fatalf("instance = %+v with log %v, want compensated after Retry", inst, data.Log)
+	}
+}
@@ -0,0 +1,231 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// WorkerOptions configures Run
+type WorkerOptions struct {
+	// Concurrency is the number of instances processed in parallel. Defaults to 1.
+	Concurrency int
+	// PollInterval is how long an idle worker waits before looking for work again. Defaults to 1s.
+	PollInterval time.Duration
+	// WorkerID identifies this process in instance locks. Defaults to the hostname and PID.
+	WorkerID string
+	// OnError is called when claiming or updating an instance fails, or a step fails.
+	OnError func(inst *Instance, err error)
+}
+
+// Run claims instances of the registered sagas and drives them forward until ctx is
+// cancelled. Each claimed instance runs its steps until it completes, is compensated,
+// or waits for a retry. Run waits for in-flight steps to finish before returning.
+func (c *Coordinator) Run(ctx context.Context, opts WorkerOptions) error {
+	if len(c.defs) == 0 {
+		return errors.New("no sagas registered")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.WorkerID == "" {
+		host, _ := os.Hostname()
+		opts.WorkerID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			c.work(ctx, opts, fmt.Sprintf("%s/%d", opts.WorkerID, n))
+		}(i)
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (c *Coordinator) work(ctx context.Context, opts WorkerOptions, workerID string) {
+	for ctx.Err() == nil {
+		inst, err := c.claim(ctx, workerID)
+		if err != nil {
+			if !errors.Is(err, ErrNoInstance) && ctx.Err() == nil {
+				report(opts, nil, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(opts.PollInterval):
+			}
+			continue
+		}
+
+		if err := c.defs[inst.Saga].run(ctx, c, inst); err != nil {
+			report(opts, inst, err)
+		}
+	}
+}
+
+func report(opts WorkerOptions, inst *Instance, err error) {
+	if opts.OnError != nil {
+		opts.OnError(inst, err)
+	}
+}
+
+// runner drives a claimed instance of one definition
+type runner interface {
+	run(ctx context.Context, c *Coordinator, inst *Instance) error
+}
+
+type definition[T any] struct {
+	def Definition[T]
+}
+
+func (d *definition[T]) run(ctx context.Context, c *Coordinator, inst *Instance) error {
+	// Record progress with a context that survives shutdown so the state is not lost
+	saveCtx := context.WithoutCancel(ctx)
+
+	var data T
+	persisted := inst.Data
+	if err := inst.Decode(&data); err != nil {
+		set := bson.M{"status": StatusFailed, "last_error": err.Error()}
+		if saveErr := c.save(saveCtx, inst, set, nil, true); saveErr != nil {
+			return errors.Join(err, saveErr)
+		}
+		return err
+	}
+
+	for {
+		compensating := inst.Status == StatusCompensating
+		if !compensating && inst.Step >= len(d.def.Steps) {
+			return c.save(saveCtx, inst, bson.M{"status": StatusCompleted, "attempts": 0}, nil, true)
+		}
+		if compensating && inst.Step < 0 {
+			return c.save(saveCtx, inst, bson.M{"status": StatusCompensated, "attempts": 0}, nil, true)
+		}
+
+		step := d.def.Steps[inst.Step]
+		fn := step.Action
+		if compensating {
+			fn = step.Compensate
+		}
+		var stepErr error
+		if fn != nil {
+			stepErr = c.attempt(ctx, step.Timeout, func(ctx context.Context) error { return fn(ctx, &data) })
+		}
+		record := &StepRecord{Step: step.Name, Compensate: compensating, Attempt: inst.Attempts}
+
+		if stepErr == nil {
+			raw, err := bson.Marshal(data)
+			if err != nil {
+				return fmt.Errorf("failed to encode saga data: %w", err)
+			}
+			next := inst.Step + 1
+			if compensating {
+				next = inst.Step - 1
+			}
+			if fn == nil {
+				record = nil // nothing ran
+			}
+			set := bson.M{"step": next, "attempts": 1, "data": bson.Raw(raw)}
+			if err := c.save(saveCtx, inst, set, record, false); err != nil {
+				return err
+			}
+			inst.Step, inst.Attempts, persisted = next, 1, raw
+			continue
+		}
+
+		// Discard changes the failed attempt made to data, as a crash would
+		data = *new(T)
+		if err := bson.Unmarshal(persisted, &data); err != nil {
+			return fmt.Errorf("failed to decode data of saga %s: %w", inst.ID, err)
+		}
+		record.Error = stepErr.Error()
+		if ctx.Err() != nil {
+			// Shutting down: hand the instance back without counting the interrupted attempt
+			set := bson.M{"attempts": inst.Attempts - 1, "run_at": c.now().UTC()}
+			if err := c.save(saveCtx, inst, set, record, true); err != nil {
+				return errors.Join(stepErr, err)
+			}
+			return nil
+		}
+
+		maxAttempts := step.MaxAttempts
+		if maxAttempts <= 0 {
+			maxAttempts = c.opts.MaxAttempts
+		}
+		if inst.Attempts < maxAttempts && !isPermanent(stepErr) {
+			set := bson.M{
+				"last_error": stepErr.Error(),
+				"run_at":     c.now().UTC().Add(c.opts.Backoff(inst.Attempts)),
+			}
+			if err := c.save(saveCtx, inst, set, record, true); err != nil {
+				return errors.Join(stepErr, err)
+			}
+			return fmt.Errorf("step %s of saga %s failed: %w", step.Name, inst.ID, stepErr)
+		}
+
+		if compensating {
+			set := bson.M{"status": StatusFailed, "last_error": stepErr.Error()}
+			if err := c.save(saveCtx, inst, set, record, true); err != nil {
+				return errors.Join(stepErr, err)
+			}
+			return fmt.Errorf("compensation %s of saga %s failed: %w", step.Name, inst.ID, stepErr)
+		}
+
+		// Out of attempts: undo the steps that completed, starting with the previous one
+		set := bson.M{"status": StatusCompensating, "step": inst.Step - 1, "attempts": 1, "last_error": stepErr.Error()}
+		if err := c.save(saveCtx, inst, set, record, false); err != nil {
+			return errors.Join(stepErr, err)
+		}
+		inst.Status, inst.Step, inst.Attempts = StatusCompensating, inst.Step-1, 1
+	}
+}
+
+// Permanent marks a step error as not worth retrying, e.g. a declined payment, so the saga
+// starts compensating right away
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// attempt runs fn with the step timeout, turning panics into errors
+func (c *Coordinator) attempt(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) (err error) {
+	if timeout <= 0 {
+		timeout = c.opts.StepTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("saga step panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
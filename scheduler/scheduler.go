@@ -0,0 +1,263 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MissedRunPolicy decides what happens to an activation that could not run
+// because the previous run of the same job was still in progress.
+type MissedRunPolicy int
+
+const (
+	// MissedRunSkip drops missed activations and waits for the next one (default)
+	MissedRunSkip MissedRunPolicy = iota
+	// MissedRunOnce runs the job once as soon as the in-progress run finishes,
+	// however many activations were missed meanwhile.
+	MissedRunOnce
+)
+
+// Job describes a scheduled unit of work
+type Job struct {
+	Name string
+	// Schedule is a cron expression or descriptor understood by Parse, e.g. "*/5 * * * *" or "@every 30s".
+	Schedule string
+	Func     func(ctx context.Context) error
+	// Timeout bounds a single run. Zero means no timeout.
+	Timeout time.Duration
+	// Jitter delays each activation by a random duration in [0, Jitter) to spread load across replicas.
+	Jitter time.Duration
+	// AllowOverlap lets a new run start while the previous one is still running.
+	AllowOverlap bool
+	// MissedRun is applied to activations skipped because of overlap prevention.
+	MissedRun MissedRunPolicy
+	// Distributed takes the scheduler's Locker for each activation so that only one replica
+	// executes it. The lock is keyed by the activation time and left to expire rather than
+	// released, so a replica whose timer fires late cannot run the same activation again.
+	Distributed bool
+}
+
+// Locker provides a distributed mutual-exclusion lock, e.g. one backed by MongoDB
+type Locker interface {
+	// TryLock attempts to take the named lock for ttl without waiting.
+	// It returns ok=false and a nil error when another holder has the lock.
+	TryLock(ctx context.Context, name string, ttl time.Duration) (unlock func(context.Context) error, ok bool, err error)
+}
+
+// Options configures a Scheduler
+type Options struct {
+	// Locker is required for jobs with Distributed set.
+	Locker Locker
+	// LockTTL is used for distributed jobs without a Timeout. Defaults to 5m.
+	LockTTL time.Duration
+	// Location is the time zone cron expressions are evaluated in. Defaults to UTC.
+	Location *time.Location
+	// OnError is called when a run fails, times out or panics.
+	OnError func(job string, err error)
+}
+
+// ErrPanic wraps panics recovered from a job
+var ErrPanic = errors.New("job panicked")
+
+type entry struct {
+	job      Job
+	schedule Schedule
+	running  atomic.Int32
+	// pending is the Unix nano time of a missed activation to run once, or 0
+	pending atomic.Int64
+}
+
+// Scheduler runs jobs on cron or interval schedules
+type Scheduler struct {
+	opts Options
+
+	mu      sync.Mutex
+	entries []*entry
+	started bool
+	cancel  context.CancelFunc
+	loops   sync.WaitGroup
+	runs    sync.WaitGroup
+}
+
+// New creates a Scheduler; add jobs with Add and call Start to run them
+func New(opts Options) *Scheduler {
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = 5 * time.Minute
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	return &Scheduler{opts: opts}
+}
+
+// Add registers a job. Jobs must be added before Start.
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Func == nil {
+		return fmt.Errorf("job requires a name and a func")
+	}
+	schedule, err := Parse(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+	if job.Distributed && s.opts.Locker == nil {
+		return fmt.Errorf("job %s: distributed jobs require a Locker", job.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return fmt.Errorf("job %s: cannot add jobs after Start", job.Name)
+	}
+	for _, e := range s.entries {
+		if e.job.Name == job.Name {
+			return fmt.Errorf("job %s already registered", job.Name)
+		}
+	}
+	s.entries = append(s.entries, &entry{job: job, schedule: schedule})
+	return nil
+}
+
+// Start runs every registered job on its schedule until ctx is cancelled or Stop is called
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, e := range s.entries {
+		s.loops.Add(1)
+		go s.loop(ctx, e)
+	}
+}
+
+// Stop stops scheduling new runs and waits for in-progress runs to finish or ctx to be done.
+// In-progress runs see their context cancelled.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.loops.Wait()
+		s.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	defer s.loops.Done()
+
+	for {
+		next := e.schedule.Next(time.Now().In(s.opts.Location))
+		if next.IsZero() {
+			return
+		}
+		delay := time.Until(next)
+		if e.job.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(e.job.Jitter)))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !e.job.AllowOverlap && e.running.Load() > 0 {
+			if e.job.MissedRun == MissedRunOnce {
+				e.pending.Store(next.UnixNano())
+			}
+			continue
+		}
+
+		e.running.Add(1)
+		s.runs.Add(1)
+		go func() {
+			defer s.runs.Done()
+			defer e.running.Add(-1)
+
+			s.run(ctx, e, next)
+			for at := e.pending.Swap(0); at != 0 && ctx.Err() == nil; at = e.pending.Swap(0) {
+				s.run(ctx, e, time.Unix(0, at).In(s.opts.Location))
+			}
+		}()
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, e *entry, activation time.Time) {
+	if e.job.Distributed {
+		// The lock is never released: it must outlive the jittered timers of the other
+		// replicas for this activation, and it expires by itself before the next one.
+		ttl := e.job.Timeout
+		if ttl <= 0 {
+			ttl = s.opts.LockTTL
+		}
+		if ttl < e.job.Jitter {
+			ttl = e.job.Jitter
+		}
+		if following := e.schedule.Next(activation); !following.IsZero() {
+			if untilFollowing := time.Until(following); untilFollowing > 0 && untilFollowing < ttl {
+				ttl = untilFollowing
+			}
+		}
+		name := "scheduler:" + e.job.Name + ":" + activation.UTC().Format(time.RFC3339Nano)
+		_, ok, err := s.opts.Locker.TryLock(ctx, name, ttl)
+		if err != nil {
+			s.reportError(e.job.Name, fmt.Errorf("failed to acquire lock: %w", err))
+			return
+		}
+		if !ok {
+			return // another replica runs this activation
+		}
+	}
+
+	if err := s.execute(ctx, e.job); err != nil {
+		s.reportError(e.job.Name, err)
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, job Job) (err error) {
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+
+	return job.Func(ctx)
+}
+
+func (s *Scheduler) reportError(job string, err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(job, err)
+	}
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memLocker is a Locker shared by the Schedulers of a test, like one MongoDB collection
+type memLocker struct {
+	mu       sync.Mutex
+	expires  map[string]time.Time
+	attempts map[string]int
+	acquired map[string]int
+}
+
+func newMemLocker() *memLocker {
+	return &memLocker{expires: map[string]time.Time{}, attempts: map[string]int{}, acquired: map[string]int{}}
+}
+
+func (l *memLocker) TryLock(_ context.Context, name string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.attempts[name]++
+	if time.Now().Before(l.expires[name]) {
+		return nil, false, nil
+	}
+	l.expires[name] = time.Now().Add(ttl)
+	l.acquired[name]++
+	return func(context.Context) error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.expires, name)
+		return nil
+	}, true, nil
+}
+
+func TestDistributedJobRunsOncePerActivation(t *testing.T) {
+	locker := newMemLocker()
+	var runs atomic.Int32
+	job := Job{
+		Name:        "report",
+		Schedule:    "@every 500ms",
+		Jitter:      300 * time.Millisecond,
+		Distributed: true,
+		Func: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	}
+
+	ctx := context.Background()
+	replicas := []*Scheduler{New(Options{Locker: locker}), New(Options{Locker: locker})}
+	for _, s := range replicas {
+		if err := s.Add(job); err != nil {
+			t.Fatal(err)
+		}
+		s.Start(ctx)
+	}
+	time.Sleep(2600 * time.Millisecond)
+	for _, s := range replicas {
+		if err := s.Stop(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	locker.mu.Lock()
+	defer locker.mu.Unlock()
+	if len(locker.acquired) < 3 {
+		t.Fatalf("%d activations, want at least 3", len(locker.acquired))
+	}
+	contended := 0
+	for name, n := range locker.acquired {
+		if n != 1 {
+			t.Errorf("activation %s ran %d times, want 1", name, n)
+		}
+		if locker.attempts[name] == 2 {
+			contended++
+		}
+	}
+	if contended == 0 {
+		t.Error("no activation was attempted by both replicas")
+	}
+	if got := int(runs.Load()); got != len(locker.acquired) {
+		t.Errorf("%d runs for %d activations", got, len(locker.acquired))
+	}
+}
+
+func TestEveryIsAlignedAcrossReplicas(t *testing.T) {
+	s := Every(time.Minute)
+	a := s.Next(time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC))
+	b := s.Next(time.Date(2024, 1, 1, 10, 0, 40, 0, time.UTC))
+	want := time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC)
+	if !a.Equal(want) || !b.Equal(want) {
+		t.Errorf("Next = %v and %v, want %v", a, b, want)
+	}
+}
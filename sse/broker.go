@@ -0,0 +1,261 @@
+package sse
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Options configures a Broker
+type Options struct {
+	// Topic picks the topic a request subscribes to, e.g. from a path or query parameter.
+	// Defaults to a single topic "".
+	Topic func(r *http.Request) string
+	// Heartbeat is how often a comment is sent to idle clients. Defaults to 15s.
+	Heartbeat time.Duration
+	// Buffer is the number of events queued per client. A client whose buffer is full
+	// is disconnected so it cannot hold up the others; it resumes with Last-Event-ID. Defaults to 32.
+	Buffer int
+	// History is the number of recent events kept per topic for Last-Event-ID resume. Defaults to 100.
+	History int
+	// Retry is sent to clients on connect as the reconnect delay. Zero leaves the browser default.
+	Retry time.Duration
+	// ResetEvent is the event type sent to a client whose Last-Event-ID is no longer in
+	// history, so it refetches the state it missed. Its ID is the latest event of the topic,
+	// from which the client resumes. Defaults to "reset".
+	ResetEvent string
+	// OnResumeGap is called when a client resumes from a Last-Event-ID that is no longer in
+	// history, e.g. to count or log it. lastID is sent by the client.
+	OnResumeGap func(r *http.Request, topic, lastID string)
+}
+
+type client struct {
+	events chan Event
+	evict  chan struct{}
+	once   sync.Once
+}
+
+func (c *client) close() {
+	c.once.Do(func() { close(c.evict) })
+}
+
+type topic struct {
+	clients map[*client]struct{}
+	history []Event // oldest first, at most Options.History
+}
+
+// Broker fans published events out to subscribed clients, with heartbeats,
+// per-client buffering, slow-client eviction and Last-Event-ID resume
+type Broker struct {
+	opts Options
+
+	mu      sync.Mutex
+	seq     uint64
+	topics  map[string]*topic
+	closing bool
+	wg      sync.WaitGroup
+}
+
+// NewBroker creates a broker; mount it as an http.Handler
+func NewBroker(opts Options) *Broker {
+	if opts.Topic == nil {
+		opts.Topic = func(*http.Request) string { return "" }
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = 15 * time.Second
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 32
+	}
+	if opts.History <= 0 {
+		opts.History = 100
+	}
+	if opts.ResetEvent == "" {
+		opts.ResetEvent = "reset"
+	}
+	return &Broker{opts: opts, topics: map[string]*topic{}}
+}
+
+// Publish sends e to every client of topic and records it for resume. Events without
+// an ID get the next sequence number. It never blocks on clients.
+func (b *Broker) Publish(topicName string, e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e.ID == "" {
+		b.seq++
+		e.ID = strconv.FormatUint(b.seq, 10)
+	}
+
+	t := b.topic(topicName)
+	t.history = append(t.history, e)
+	if len(t.history) > b.opts.History {
+		t.history = t.history[len(t.history)-b.opts.History:]
+	}
+
+	for c := range t.clients {
+		select {
+		case c.events <- e:
+		default:
+			delete(t.clients, c)
+			c.close()
+		}
+	}
+}
+
+// Clients returns the number of clients subscribed to topic
+func (b *Broker) Clients(topicName string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.topics[topicName]; ok {
+		return len(t.clients)
+	}
+	return 0
+}
+
+// Drop disconnects the clients of topic and forgets its history, e.g. once a job has finished
+func (b *Broker) Drop(topicName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.topics[topicName]; ok {
+		for c := range t.clients {
+			c.close()
+		}
+		delete(b.topics, topicName)
+	}
+}
+
+// ServeHTTP streams the request's topic until the client disconnects, it is evicted or the broker shuts down
+func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	topicName := b.opts.Topic(r)
+	lastID := LastEventID(r)
+	c, backlog, reset, ok := b.subscribe(topicName, lastID)
+	if !ok {
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer b.wg.Done()
+	defer b.unsubscribe(topicName, c)
+
+	stream, err := NewStream(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if b.opts.Retry > 0 {
+		if err := stream.SetRetry(b.opts.Retry); err != nil {
+			return
+		}
+	}
+	if reset != nil {
+		if b.opts.OnResumeGap != nil {
+			b.opts.OnResumeGap(r, topicName, lastID)
+		}
+		if err := stream.Send(*reset); err != nil {
+			return
+		}
+	}
+	for _, e := range backlog {
+		if err := stream.Send(e); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(b.opts.Heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c.evict:
+			return
+		case e := <-c.events:
+			if err := stream.Send(e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := stream.Heartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Shutdown disconnects every client and waits for their handlers to return.
+// Clients reconnect elsewhere and resume from their Last-Event-ID.
+func (b *Broker) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	b.closing = true
+	for _, t := range b.topics {
+		for c := range t.clients {
+			c.close()
+		}
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to close event streams gracefully: %w", ctx.Err())
+	}
+}
+
+// subscribe registers a client and returns the events it missed since lastID, or the reset
+// event to send when lastID aged out of history. It returns ok=false once Shutdown has
+// started, checked under the lock that registers the client so Shutdown cannot miss it.
+// When ok, the caller must call b.wg.Done.
+func (b *Broker) subscribe(topicName, lastID string) (c *client, backlog []Event, reset *Event, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closing {
+		return nil, nil, nil, false
+	}
+	b.wg.Add(1)
+	t := b.topic(topicName)
+	c = &client{events: make(chan Event, b.opts.Buffer), evict: make(chan struct{})}
+	t.clients[c] = struct{}{}
+
+	if lastID == "" {
+		return c, nil, nil, true
+	}
+	for i, e := range t.history {
+		if e.ID == lastID {
+			return c, append([]Event(nil), t.history[i+1:]...), nil, true
+		}
+	}
+	// The ID aged out of history; the client has to refetch state itself
+	reset = &Event{Event: b.opts.ResetEvent}
+	if n := len(t.history); n > 0 {
+		reset.ID = t.history[n-1].ID
+	}
+	return c, nil, reset, true
+}
+
+func (b *Broker) unsubscribe(topicName string, c *client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.topics[topicName]; ok {
+		delete(t.clients, c)
+	}
+}
+
+// topic returns the named topic, creating it; b.mu must be held
+func (b *Broker) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{clients: map[*client]struct{}{}}
+		b.topics[name] = t
+	}
+	return t
+}
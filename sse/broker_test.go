@@ -0,0 +1,62 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	b := NewBroker(Options{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+	}()
+	for b.Clients("") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	<-done
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status after Shutdown = %d, want 503", rec.Code)
+	}
+	if n := b.Clients(""); n != 0 {
+		t.Errorf("%d clients subscribed after Shutdown", n)
+	}
+}
+
+// Requests racing Shutdown are either disconnected by it or refused, never left streaming
+func TestShutdownRacingSubscribe(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		b := NewBroker(Options{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			b.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := b.Shutdown(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("Shutdown() error = %v; a client subscribed after it started", err)
+		}
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("handler still streaming after Shutdown returned")
+		}
+	}
+}
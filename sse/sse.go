@@ -0,0 +1,122 @@
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrStreamingUnsupported is returned when the ResponseWriter cannot flush
+var ErrStreamingUnsupported = errors.New("streaming not supported by response writer")
+
+// Event is one server-sent event
+type Event struct {
+	// ID is sent to the client, which reports the last one it saw in Last-Event-ID when it reconnects.
+	// The Broker assigns IDs to published events that have none.
+	ID string
+	// Event is the event type; clients listen for it with addEventListener. Empty means "message".
+	Event string
+	// Data is the payload; newlines are sent as multiple data lines.
+	Data string
+	// Retry tells the client how long to wait before reconnecting.
+	Retry time.Duration
+}
+
+// WriteTo writes the event in the text/event-stream format
+func (e Event) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + stripNewlines(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + stripNewlines(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	data := strings.ReplaceAll(e.Data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// Stream writes events to one client. Use it directly in a handler for a single
+// long-running response such as job progress; use a Broker to fan out to many clients.
+type Stream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewStream sets the event-stream headers and flushes them to the client
+func NewStream(w http.ResponseWriter) (*Stream, error) {
+	rc := http.NewResponseController(w)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // disable proxy buffering in nginx
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStreamingUnsupported, err)
+	}
+	// Streams outlive the server's WriteTimeout; clear it where supported
+	rc.SetWriteDeadline(time.Time{})
+
+	return &Stream{w: w, rc: rc}, nil
+}
+
+// Send writes the event and flushes it
+func (s *Stream) Send(e Event) error {
+	if _, err := e.WriteTo(s.w); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("failed to flush event: %w", err)
+	}
+	return nil
+}
+
+// Heartbeat writes a comment line, which keeps proxies from closing an idle connection
+func (s *Stream) Heartbeat() error {
+	if _, err := io.WriteString(s.w, ": ping\n\n"); err != nil {
+		return fmt.Errorf("failed to write heartbeat: %w", err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("failed to flush heartbeat: %w", err)
+	}
+	return nil
+}
+
+// SetRetry tells the client how long to wait before reconnecting, without dispatching an event
+func (s *Stream) SetRetry(d time.Duration) error {
+	if _, err := io.WriteString(s.w, "retry: "+strconv.FormatInt(d.Milliseconds(), 10)+"\n\n"); err != nil {
+		return fmt.Errorf("failed to write retry: %w", err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("failed to flush retry: %w", err)
+	}
+	return nil
+}
+
+// LastEventID returns the ID the client last received, from the Last-Event-ID header
+// or, for clients that cannot set headers, the lastEventId query parameter
+func LastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventId")
+}
@@ -0,0 +1,270 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RestartPolicy decides whether a child is restarted when its Run returns
+type RestartPolicy int
+
+const (
+	// RestartAlways restarts the child whenever it returns, with or without an error (default)
+	RestartAlways RestartPolicy = iota
+	// RestartOnFailure restarts the child only when it returns an error or panics
+	RestartOnFailure
+	// RestartNever runs the child once
+	RestartNever
+)
+
+// State is the current state of a supervised child
+type State string
+
+const (
+	StateRunning State = "running"
+	StateBackoff State = "backoff"
+	StateStopped State = "stopped"
+	StateFailed  State = "failed"
+)
+
+// Child describes a long-running loop to supervise, such as a queue consumer or a change stream watcher
+type Child struct {
+	Name string
+	// Run is the loop itself. It should block until ctx is cancelled.
+	Run    func(ctx context.Context) error
+	Policy RestartPolicy
+	// MaxRestarts within RestartWindow before the supervisor gives up on the child. Zero means unlimited.
+	MaxRestarts   int
+	RestartWindow time.Duration
+	// InitialBackoff is doubled after each consecutive failure up to MaxBackoff.
+	// Defaults to 1s and 1m.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// StableAfter resets the backoff once a run has lasted this long. Defaults to 1m.
+	StableAfter time.Duration
+}
+
+// ChildStatus is a snapshot of a child's health
+type ChildStatus struct {
+	Name      string
+	State     State
+	Restarts  int
+	LastError string
+	StartedAt time.Time
+}
+
+// Options configures a Supervisor
+type Options struct {
+	// FailFast stops every child and makes Run return as soon as one child is given up on.
+	FailFast bool
+	// OnRestart is called before a child is restarted, with the error that ended its last run (nil if it returned cleanly).
+	OnRestart func(child string, restarts int, err error)
+}
+
+// ErrPanic wraps panics recovered from a child
+var ErrPanic = errors.New("child panicked")
+
+// Supervisor runs children and restarts them according to their policy,
+// so a single crashed consumer does not silently stop processing.
+type Supervisor struct {
+	opts Options
+
+	mu       sync.RWMutex
+	children []Child
+	status   map[string]*ChildStatus
+}
+
+// New creates an empty Supervisor
+func New(opts Options) *Supervisor {
+	return &Supervisor{opts: opts, status: map[string]*ChildStatus{}}
+}
+
+// Add registers a child. Children must be added before Run.
+func (s *Supervisor) Add(c Child) error {
+	if c.Name == "" || c.Run == nil {
+		return fmt.Errorf("supervised child requires a name and a run func")
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = time.Minute
+	}
+	if c.StableAfter <= 0 {
+		c.StableAfter = time.Minute
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.status[c.Name]; ok {
+		return fmt.Errorf("child %s already registered", c.Name)
+	}
+	s.children = append(s.children, c)
+	s.status[c.Name] = &ChildStatus{Name: c.Name, State: StateStopped}
+	return nil
+}
+
+// Run supervises every child until ctx is cancelled and all children have returned.
+// With FailFast it returns the error of the first child given up on.
+func (s *Supervisor) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.mu.RLock()
+	children := make([]Child, len(s.children))
+	copy(children, s.children)
+	s.mu.RUnlock()
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		failErr  error
+	)
+	for _, c := range children {
+		wg.Add(1)
+		go func(c Child) {
+			defer wg.Done()
+			if err := s.supervise(ctx, c); err != nil && s.opts.FailFast {
+				failOnce.Do(func() {
+					failErr = err
+					cancel()
+				})
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return failErr
+}
+
+// supervise runs one child until ctx ends, its policy says stop or it exceeds its restart budget
+func (s *Supervisor) supervise(ctx context.Context, c Child) error {
+	var (
+		restarts []time.Time
+		backoff  = c.InitialBackoff
+	)
+
+	for {
+		s.update(c.Name, func(st *ChildStatus) {
+			st.State = StateRunning
+			st.StartedAt = time.Now()
+		})
+
+		start := time.Now()
+		err := runChild(ctx, c)
+
+		if ctx.Err() != nil {
+			s.update(c.Name, func(st *ChildStatus) { st.State = StateStopped })
+			return nil
+		}
+		if err != nil {
+			s.update(c.Name, func(st *ChildStatus) { st.LastError = err.Error() })
+		}
+
+		if c.Policy == RestartNever || (c.Policy == RestartOnFailure && err == nil) {
+			s.update(c.Name, func(st *ChildStatus) { st.State = StateStopped })
+			return nil
+		}
+
+		// Enforce the restart budget within the sliding window
+		now := time.Now()
+		restarts = append(restarts, now)
+		if c.RestartWindow > 0 {
+			cutoff := now.Add(-c.RestartWindow)
+			for len(restarts) > 0 && restarts[0].Before(cutoff) {
+				restarts = restarts[1:]
+			}
+		}
+		if c.MaxRestarts > 0 && len(restarts) > c.MaxRestarts {
+			giveUp := fmt.Errorf("child %s exceeded %d restarts: %w", c.Name, c.MaxRestarts, err)
+			if err == nil {
+				giveUp = fmt.Errorf("child %s exceeded %d restarts", c.Name, c.MaxRestarts)
+			}
+			s.update(c.Name, func(st *ChildStatus) { st.State = StateFailed })
+			return giveUp
+		}
+
+		if time.Since(start) >= c.StableAfter {
+			backoff = c.InitialBackoff
+		}
+
+		s.update(c.Name, func(st *ChildStatus) {
+			st.State = StateBackoff
+			st.Restarts++
+		})
+		if s.opts.OnRestart != nil {
+			s.opts.OnRestart(c.Name, s.restarts(c.Name), err)
+		}
+
+		// Equal jitter, half the backoff plus a random half, keeps a fleet of replicas from
+		// restarting in lockstep while still waiting at least half the backoff
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			s.update(c.Name, func(st *ChildStatus) { st.State = StateStopped })
+			return nil
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+		if backoff > c.MaxBackoff {
+			backoff = c.MaxBackoff
+		}
+	}
+}
+
+func runChild(ctx context.Context, c Child) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+	return c.Run(ctx)
+}
+
+// Status returns a snapshot of every child, sorted by name
+func (s *Supervisor) Status() []ChildStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]ChildStatus, 0, len(s.status))
+	for _, st := range s.status {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// HealthCheck returns an error naming the children that were given up on.
+// Its signature matches health.CheckFunc so it can be registered directly.
+func (s *Supervisor) HealthCheck(ctx context.Context) error {
+	var failed []string
+	for _, st := range s.Status() {
+		if st.State == StateFailed {
+			failed = append(failed, st.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("supervised children failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (s *Supervisor) update(name string, fn func(st *ChildStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.status[name])
+}
+
+func (s *Supervisor) restarts(name string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status[name].Restarts
+}
@@ -0,0 +1,183 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+func TestRestartPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RestartPolicy
+		result func() error
+		// runs is 1 for a child that is not restarted; a restarted child runs a
+		// fourth time, which cancels the supervisor
+		runs int32
+	}{
+		{"never restarts a failure", RestartNever, func() error { return errBoom }, 1},
+		{"on failure skips a clean exit", RestartOnFailure, func() error { return nil }, 1},
+		{"on failure restarts an error", RestartOnFailure, func() error { return errBoom }, 4},
+		{"on failure restarts a panic", RestartOnFailure, func() error { panic("boom") }, 4},
+		{"always restarts a clean exit", RestartAlways, func() error { return nil }, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var runs atomic.Int32
+			s := New(Options{})
+			err := s.Add(Child{
+				Name:   "worker",
+				Policy: tt.policy,
+				Run: func(ctx context.Context) error {
+					if runs.Add(1) > 3 {
+						cancel()
+						<-ctx.Done()
+						return nil
+					}
+					return tt.result()
+				},
+				InitialBackoff: time.Millisecond,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			done := make(chan error, 1)
+			go func() { done <- s.Run(ctx) }()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("Run() = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Run did not return")
+			}
+
+			if got := runs.Load(); got != tt.runs {
+				t.Fatalf("runs = %d, want %d", got, tt.runs)
+			}
+		})
+	}
+}
+
+func TestOnRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var restarts []int
+	var errs []error
+	s := New(Options{OnRestart: func(child string, n int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		restarts = append(restarts, n)
+		errs = append(errs, err)
+		if n == 2 {
+			cancel()
+		}
+	}})
+	runs := 0
+	s.Add(Child{
+		Name: "worker",
+		Run: func(ctx context.Context) error {
+			runs++
+			if runs == 1 {
+				panic("boom")
+			}
+			return errBoom
+		},
+		InitialBackoff: time.Millisecond,
+	})
+
+	if err := s.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(restarts) != 2 || restarts[0] != 1 || restarts[1] != 2 {
+		t.Fatalf("restarts = %v, want [1 2]", restarts)
+	}
+	if !errors.Is(errs[0], ErrPanic) || !errors.Is(errs[1], errBoom) {
+		t.Fatalf("errors = %v, want ErrPanic then errBoom", errs)
+	}
+	if st := s.Status()[0]; st.Restarts != 2 || st.LastError != errBoom.Error() {
+		t.Fatalf("status = %+v", st)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var starts []time.Time
+	s := New(Options{})
+	s.Add(Child{
+		Name: "worker",
+		Run: func(ctx context.Context) error {
+			starts = append(starts, time.Now())
+			if len(starts) == 4 {
+				cancel()
+			}
+			return errBoom
+		},
+		InitialBackoff: 20 * time.Millisecond,
+		MaxBackoff:     40 * time.Millisecond,
+	})
+	if err := s.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The backoff doubles from 20ms and is capped at 40ms; jitter waits at least half of it
+	minWaits := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond}
+	for i, min := range minWaits {
+		if wait := starts[i+1].Sub(starts[i]); wait < min {
+			t.Errorf("wait before restart %d = %v, want at least %v", i+1, wait, min)
+		}
+	}
+}
+
+func TestMaxRestarts(t *testing.T) {
+	var runs atomic.Int32
+	s := New(Options{FailFast: true})
+	s.Add(Child{
+		Name: "worker",
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return errBoom
+		},
+		MaxRestarts:    2,
+		RestartWindow:  time.Minute,
+		InitialBackoff: time.Millisecond,
+	})
+	s.Add(Child{
+		Name: "steady",
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		},
+	})
+
+	err := s.Run(context.Background())
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Run() = %v, want the child error", err)
+	}
+	if got := runs.Load(); got != 3 {
+		t.Fatalf("runs = %d, want 3: the first run and 2 restarts", got)
+	}
+
+	status := s.Status()
+	if status[0].State != StateStopped || status[1].State != StateFailed {
+		t.Fatalf("status = %+v, want steady stopped and worker failed", status)
+	}
+	if err := s.HealthCheck(context.Background()); err == nil {
+		t.Fatal("HealthCheck() = nil, want the failed child")
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/cdcloud-io/go-libs/tenant
+
+go 1.22.4
+
+require (
+	github.com/cdcloud-io/go-libs/errs v0.1.0
+	github.com/cdcloud-io/go-libs/jwtauth v0.1.0
+)
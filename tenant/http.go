@@ -0,0 +1,153 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cdcloud-io/go-libs/jwtauth"
+)
+
+// Extractor reads the tenant ID of an incoming request. It returns "" when the request
+// carries none.
+type Extractor func(r *http.Request) (string, error)
+
+// FromHeader reads the tenant ID from the named request header
+func FromHeader(name string) Extractor {
+	return func(r *http.Request) (string, error) {
+		return r.Header.Get(name), nil
+	}
+}
+
+// FromClaim reads the tenant ID from a string claim of the token stored by
+// jwtauth.Middleware, e.g. "tid" for Entra ID tokens
+func FromClaim(claim string) Extractor {
+	return func(r *http.Request) (string, error) {
+		tok, ok := jwtauth.FromContext(r.Context())
+		if !ok {
+			return "", nil
+		}
+		var claims map[string]interface{}
+		if err := tok.Decode(&claims); err != nil {
+			return "", err
+		}
+		id, _ := claims[claim].(string)
+		return id, nil
+	}
+}
+
+// First returns the ID of the first extractor that finds one
+func First(extractors ...Extractor) Extractor {
+	return func(r *http.Request) (string, error) {
+		for _, e := range extractors {
+			id, err := e(r)
+			if err != nil || id != "" {
+				return id, err
+			}
+		}
+		return "", nil
+	}
+}
+
+// Options configures Middleware
+type Options struct {
+	// Extract defaults to FromHeader(Header). Behind jwtauth.Middleware, prefer FromClaim so
+	// clients cannot pick their tenant.
+	Extract Extractor
+	// Required rejects requests without a tenant with 400.
+	Required bool
+	// Authorize, when set, rejects requests for tenants the caller may not access with 403,
+	// e.g. a header-supplied tenant that is not among the token's tenants.
+	Authorize func(r *http.Request, id string) bool
+}
+
+// Middleware stores the tenant ID of each request in its context for FromContext.
+// IDs that are not Valid are rejected with 400.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	if opts.Extract == nil {
+		opts.Extract = FromHeader(Header)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := opts.Extract(r)
+			if err != nil {
+				http.Error(w, "invalid tenant", http.StatusBadRequest)
+				return
+			}
+			if id == "" {
+				if opts.Required {
+					http.Error(w, "missing tenant", http.StatusBadRequest)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !Valid(id) {
+				http.Error(w, "invalid tenant", http.StatusBadRequest)
+				return
+			}
+			if opts.Authorize != nil && !opts.Authorize(r, id) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+		})
+	}
+}
+
+// Transport is an http.RoundTripper that sends the tenant ID of the request context in
+// the Header header, so outbound calls stay in the caller's tenant
+type Transport struct {
+	// Base defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// RoundTrip adds the tenant header unless the request already has one
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if id, ok := FromContext(r.Context()); ok && r.Header.Get(Header) == "" {
+		r = r.Clone(r.Context())
+		r.Header.Set(Header, id)
+	}
+	return base.RoundTrip(r)
+}
+
+// Carrier is a set of message headers, e.g. http.Header or MapCarrier
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// MapCarrier adapts a map of message headers or attributes to Carrier
+type MapCarrier map[string]string
+
+// Get returns the value of key
+func (m MapCarrier) Get(key string) string {
+	return m[key]
+}
+
+// Set sets key to value
+func (m MapCarrier) Set(key, value string) {
+	m[key] = value
+}
+
+// Inject writes the tenant ID of ctx to the headers of an outgoing message
+func Inject(ctx context.Context, c Carrier) {
+	if id, ok := FromContext(ctx); ok {
+		c.Set(Header, id)
+	}
+}
+
+// Extract returns ctx with the tenant ID from the headers of an incoming message
+func Extract(ctx context.Context, c Carrier) (context.Context, error) {
+	id := c.Get(Header)
+	if id == "" {
+		return ctx, nil
+	}
+	if !Valid(id) {
+		return ctx, ErrInvalid
+	}
+	return WithID(ctx, id), nil
+}
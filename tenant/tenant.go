@@ -0,0 +1,98 @@
+package tenant
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/cdcloud-io/go-libs/errs"
+)
+
+// Header is the HTTP and message header carrying the tenant ID between services
+const Header = "X-Tenant-ID"
+
+// LogKey is the attribute key of the tenant ID in logs
+const LogKey = "tenant_id"
+
+var (
+	// ErrMissing is returned by Require when the context has no tenant
+	ErrMissing = errs.New(errs.Invalid, "tenant ID missing")
+	// ErrInvalid is returned by Extract for tenant IDs that are not Valid
+	ErrInvalid = errs.New(errs.Invalid, "invalid tenant ID")
+)
+
+type tenantKey struct{}
+
+// WithID stores the tenant ID in ctx
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant ID stored in ctx
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// ID returns the tenant ID stored in ctx, or "" if there is none
+func ID(ctx context.Context) string {
+	id, _ := FromContext(ctx)
+	return id
+}
+
+// Require returns the tenant ID stored in ctx, or ErrMissing. Use it wherever data is
+// scoped by tenant, so a missing tenant fails instead of reading across tenants.
+func Require(ctx context.Context) (string, error) {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return "", ErrMissing
+	}
+	return id, nil
+}
+
+// Valid reports whether id is 1 to 64 letters, digits, '-' or '_', so it is safe to use
+// in headers, metric labels and database names
+func Valid(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Label returns the tenant ID for use as a metric label, or "none" without a tenant.
+// Only use it as a label when the number of tenants is bounded.
+func Label(ctx context.Context) string {
+	if id, ok := FromContext(ctx); ok {
+		return id
+	}
+	return "none"
+}
+
+// LogHandler wraps h so every record logged with a tenant context carries the tenant ID
+func LogHandler(h slog.Handler) slog.Handler {
+	return &logHandler{Handler: h}
+}
+
+type logHandler struct {
+	slog.Handler
+}
+
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := FromContext(ctx); ok {
+		r.AddAttrs(slog.String(LogKey, id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,99 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/cdcloud-io/go-libs/filestore"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Azurite's well-known development account
+const (
+	AzuriteAccountName = "devstoreaccount1"
+	AzuriteAccountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+)
+
+// AzuriteFixture is a running Azurite blob service with a file store on a fresh container
+type AzuriteFixture struct {
+	Store      *filestore.AzureStore
+	Config     filestore.AzureConfig
+	BlobClient *azblob.Client
+}
+
+// Azurite starts an Azurite blob service, creates a unique container and returns a
+// filestore.AzureStore for it. image defaults to "mcr.microsoft.com/azure-storage/azurite".
+// The container is removed when the test ends.
+func Azurite(t *testing.T, image string) *AzuriteFixture {
+	t.Helper()
+	RequireDocker(t)
+
+	if image == "" {
+		image = "mcr.microsoft.com/azure-storage/azurite"
+	}
+
+	ctx, cancel := startContext()
+	defer cancel()
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        image,
+			Cmd:          []string{"azurite-blob", "--blobHost", "0.0.0.0", "--skipApiVersionCheck", "--loose"},
+			ExposedPorts: []string{"10000/tcp"},
+			WaitingFor:   wait.ForListeningPort("10000/tcp"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("testkit: failed to start azurite container: %v", err)
+	}
+	terminate(t, container)
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("testkit: failed to get azurite host: %v", err)
+	}
+	port, err := container.MappedPort(ctx, "10000/tcp")
+	if err != nil {
+		t.Fatalf("testkit: failed to get azurite port: %v", err)
+	}
+
+	cfg := filestore.AzureConfig{
+		AccountURL:  fmt.Sprintf("http://%s:%s/%s", host, port.Port(), AzuriteAccountName),
+		Container:   UniqueName("test"),
+		AccountName: AzuriteAccountName,
+		AccountKey:  AzuriteAccountKey,
+	}
+
+	cred, err := azblob.NewSharedKeyCredential(cfg.AccountName, cfg.AccountKey)
+	if err != nil {
+		t.Fatalf("testkit: failed to create azurite credential: %v", err)
+	}
+	blobClient, err := azblob.NewClientWithSharedKeyCredential(cfg.AccountURL, cred, nil)
+	if err != nil {
+		t.Fatalf("testkit: failed to create azurite client: %v", err)
+	}
+	if _, err := blobClient.CreateContainer(ctx, cfg.Container, nil); err != nil {
+		t.Fatalf("testkit: failed to create azurite container %s: %v", cfg.Container, err)
+	}
+
+	store, err := filestore.NewAzure(ctx, cfg)
+	if err != nil {
+		t.Fatalf("testkit: failed to create azure file store: %v", err)
+	}
+	return &AzuriteFixture{Store: store, Config: cfg, BlobClient: blobClient}
+}
+
+// Seed uploads files, keyed by object key, to the fixture's container
+func (f *AzuriteFixture) Seed(t *testing.T, files map[string][]byte) {
+	t.Helper()
+	ctx := context.Background()
+	for key, content := range files {
+		if _, err := f.BlobClient.UploadBuffer(ctx, f.Config.Container, key, content, nil); err != nil {
+			t.Fatalf("testkit: failed to seed %s: %v", key, err)
+		}
+	}
+}
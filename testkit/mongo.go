@@ -0,0 +1,65 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"github.com/cdcloud-io/go-libs/mongoclient/mongotest"
+)
+
+// MongoURIEnv names the environment variable that points Mongo at an existing server, e.g.
+// a CI service container, instead of starting a container
+const MongoURIEnv = mongotest.URIEnv
+
+// MongoOptions configures Mongo
+type MongoOptions struct {
+	// Image defaults to "mongo:7".
+	Image string
+	// ReplicaSet starts a single-node replica set, needed for transactions and change streams.
+	// It is ignored with MONGO_TEST_URI, whose server must be a replica set itself.
+	ReplicaSet bool
+}
+
+// MongoFixture is a running MongoDB with a connected client
+type MongoFixture struct {
+	Client *mongoclient.Client
+	URI    string
+	// Database is a unique database name for this test
+	Database string
+
+	server *mongotest.Server
+	db     *mongotest.DB
+}
+
+// Mongo starts a MongoDB container and returns a connected mongoclient, or connects to the
+// server in MONGO_TEST_URI when it is set, using mongoclient/mongotest. The fixture's
+// database is dropped and the container and client are cleaned up when the test ends.
+func Mongo(t *testing.T, opts MongoOptions) *MongoFixture {
+	t.Helper()
+	server := mongotest.Start(t, mongotest.Options{Image: opts.Image, ReplicaSet: opts.ReplicaSet})
+	return newMongoFixture(server, server.New(t))
+}
+
+func newMongoFixture(server *mongotest.Server, db *mongotest.DB) *MongoFixture {
+	return &MongoFixture{Client: db.Client, URI: db.URI, Database: db.Name, server: server, db: db}
+}
+
+// Isolated returns a fixture on the same server with its own database, dropped when t ends.
+// Start one server per package and call Isolated in each subtest, so subtests can run in
+// parallel without seeing each other's data.
+func (f *MongoFixture) Isolated(t *testing.T) *MongoFixture {
+	t.Helper()
+	return newMongoFixture(f.server, f.server.New(t))
+}
+
+// Seed inserts docs into collection of the fixture's database
+func (f *MongoFixture) Seed(t *testing.T, collection string, docs ...interface{}) {
+	t.Helper()
+	f.db.Seed(t, collection, docs...)
+}
+
+// Reset drops the fixture's database, for tests sharing one container
+func (f *MongoFixture) Reset(t *testing.T) {
+	t.Helper()
+	f.db.Reset(t)
+}
@@ -0,0 +1,68 @@
+package testkit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cdcloud-io/go-libs/cache/rediscache"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+// RedisFixture is a running Redis with a connected client
+type RedisFixture struct {
+	Client *goredis.Client
+	// Store is a cache store on Client, namespaced per test
+	Store *rediscache.Store
+	URL   string
+}
+
+// Redis starts a Redis container and returns a connected client. image defaults to "redis:7".
+// The container and client are cleaned up when the test ends.
+func Redis(t *testing.T, image string) *RedisFixture {
+	t.Helper()
+	RequireDocker(t)
+
+	if image == "" {
+		image = "redis:7"
+	}
+
+	ctx, cancel := startContext()
+	defer cancel()
+
+	container, err := redis.Run(ctx, image)
+	if err != nil {
+		t.Fatalf("testkit: failed to start redis container: %v", err)
+	}
+	terminate(t, container)
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("testkit: failed to get redis connection string: %v", err)
+	}
+	opts, err := goredis.ParseURL(url)
+	if err != nil {
+		t.Fatalf("testkit: failed to parse redis url: %v", err)
+	}
+	client := goredis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Fatalf("testkit: failed to connect to redis: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	return &RedisFixture{
+		Client: client,
+		Store:  rediscache.NewStore(client, UniqueName("test")+":"),
+		URL:    url,
+	}
+}
+
+// Flush removes every key, for tests sharing one container
+func (f *RedisFixture) Flush(t *testing.T) {
+	t.Helper()
+	if err := f.Client.FlushAll(context.Background()).Err(); err != nil {
+		t.Fatalf("testkit: failed to flush redis: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package testkit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// StartTimeout bounds how long a container may take to become ready
+var StartTimeout = 2 * time.Minute
+
+// RequireDocker skips the test when no container runtime is reachable,
+// so integration tests pass quietly on machines without Docker
+func RequireDocker(t *testing.T) {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
+
+// UniqueName returns prefix with a random suffix, for per-test databases, buckets and containers
+func UniqueName(prefix string) string {
+	var b [4]byte
+	rand.Read(b[:])
+	return prefix + "-" + hex.EncodeToString(b[:])
+}
+
+func startContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), StartTimeout)
+}
+
+// terminate stops the container when the test ends
+func terminate(t *testing.T, c testcontainers.Container) {
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := c.Terminate(ctx); err != nil {
+			t.Logf("testkit: failed to terminate container: %v", err)
+		}
+	})
+}
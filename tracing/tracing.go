@@ -0,0 +1,187 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/appconfig"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Sampler names accepted in the telemetry.sampler config value
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+)
+
+// Provider owns the TracerProvider installed by Setup.
+// Keep it around for the lifetime of the application and call Shutdown on exit
+// so buffered spans are flushed to the collector.
+type Provider struct {
+	tp *sdktrace.TracerProvider
+}
+
+// Setup configures the global OpenTelemetry TracerProvider and propagator from the
+// telemetry section of the application config.
+// Instrumented packages (mongoclient, HTTP middleware, ...) pick up the global provider
+// through otel.GetTracerProvider, so nothing else needs to be wired.
+// When telemetry is disabled, a no-op provider is installed and Shutdown does nothing.
+func Setup(ctx context.Context, cfg appconfig.Config) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.Telemetry.Enabled {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return &Provider{}, nil
+	}
+
+	res, err := newResource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sampler, err := newSampler(cfg.Telemetry.Sampler, cfg.Telemetry.SamplerRatio)
+	if err != nil {
+		return nil, err
+	}
+
+	// Created last, so a failure above does not leave its connection open
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
+	)
+	otel.SetTracerProvider(tp)
+
+	return &Provider{tp: tp}, nil
+}
+
+// Tracer returns a named tracer from the global provider
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Shutdown flushes any pending spans and stops the exporter.
+// The context bounds how long the flush may take.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil || p.tp == nil {
+		return nil
+	}
+	if err := p.tp.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown tracer provider: %w", err)
+	}
+	return nil
+}
+
+// ForceFlush exports all spans that have not been exported yet
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	if p == nil || p.tp == nil {
+		return nil
+	}
+	return p.tp.ForceFlush(ctx)
+}
+
+func newExporter(ctx context.Context, cfg appconfig.Config) (*otlptrace.Exporter, error) {
+	t := cfg.Telemetry
+
+	var client otlptrace.Client
+	switch strings.ToLower(t.OTLPProtocol) {
+	case "", "grpc":
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithTimeout(10 * time.Second)}
+		if t.OTLPEndpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(t.OTLPEndpoint))
+		}
+		if t.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		client = otlptracegrpc.NewClient(opts...)
+	case "http", "http/protobuf":
+		opts := []otlptracehttp.Option{otlptracehttp.WithTimeout(10 * time.Second)}
+		if t.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(t.OTLPEndpoint))
+		}
+		if t.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		client = otlptracehttp.NewClient(opts...)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", t.OTLPProtocol)
+	}
+
+	exporter, err := otlptrace.New(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// newResource describes this service using the app section of the config,
+// plus any extra attributes listed under telemetry.resource_attributes.
+func newResource(ctx context.Context, cfg appconfig.Config) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(cfg.App.Name),
+		semconv.ServiceVersion(cfg.App.Version),
+	}
+	if cfg.App.Env != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(cfg.App.Env))
+	}
+	if cfg.App.CommitSha != "" {
+		attrs = append(attrs, attribute.String("app.commit_sha", cfg.App.CommitSha))
+	}
+	if cfg.App.BuildID != "" {
+		attrs = append(attrs, attribute.String("app.build_id", cfg.App.BuildID))
+	}
+	for k, v := range cfg.Telemetry.ResourceAttributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(attrs...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+	return res, nil
+}
+
+func newSampler(name string, ratio float64) (sdktrace.Sampler, error) {
+	switch strings.ToLower(name) {
+	case "", SamplerParentBasedTraceIDRatio:
+		if name == "" {
+			ratio = 1
+		}
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	case SamplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil
+	case SamplerAlwaysOff:
+		return sdktrace.NeverSample(), nil
+	case SamplerTraceIDRatio:
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	default:
+		return nil, fmt.Errorf("unsupported sampler %q", name)
+	}
+}
@@ -0,0 +1,40 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxBodyBytes bounds request bodies read by DecodeJSON
+const DefaultMaxBodyBytes = 1 << 20
+
+// DecodeJSON decodes the request body into dst, rejecting unknown fields and trailing data,
+// then validates dst. Validation failures are returned as Errors; anything else is a decoding error.
+func DecodeJSON(r *http.Request, dst interface{}) error {
+	body := http.MaxBytesReader(nil, r.Body, DefaultMaxBodyBytes)
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("failed to decode request body: %w", err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to decode request body: unexpected data after JSON object")
+	}
+	return Struct(dst)
+}
+
+// WriteErrors writes a 422 response listing the failed fields:
+//
+//	{"error": "validation failed", "fields": {"email": "email must be a valid email address"}}
+func WriteErrors(w http.ResponseWriter, errs Errors) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "validation failed",
+		"fields": errs.Fields(),
+	})
+}
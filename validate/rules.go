@@ -0,0 +1,123 @@
+package validate
+
+import (
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMessages are the English message templates for the built-in rules
+var DefaultMessages = map[string]string{
+	"required": "{field} is required",
+	"min":      "{field} must be at least {param}",
+	"max":      "{field} must be at most {param}",
+	"len":      "{field} must have length {param}",
+	"gt":       "{field} must be greater than {param}",
+	"gte":      "{field} must be greater than or equal to {param}",
+	"lt":       "{field} must be less than {param}",
+	"lte":      "{field} must be less than or equal to {param}",
+	"oneof":    "{field} must be one of [{param}]",
+	"email":    "{field} must be a valid email address",
+	"url":      "{field} must be a valid URL",
+	"hostport": "{field} must be a host:port address",
+	"alpha":    "{field} must contain only letters",
+	"alphanum": "{field} must contain only letters and digits",
+	"numeric":  "{field} must be numeric",
+	"uuid":     "{field} must be a valid UUID",
+}
+
+var builtinRules = map[string]RuleFunc{
+	"required": func(v reflect.Value, _ string) bool { return !isEmpty(v) },
+	"min":      sizeRule(func(a, b float64) bool { return a >= b }),
+	"max":      sizeRule(func(a, b float64) bool { return a <= b }),
+	"len":      sizeRule(func(a, b float64) bool { return a == b }),
+	"gt":       sizeRule(func(a, b float64) bool { return a > b }),
+	"gte":      sizeRule(func(a, b float64) bool { return a >= b }),
+	"lt":       sizeRule(func(a, b float64) bool { return a < b }),
+	"lte":      sizeRule(func(a, b float64) bool { return a <= b }),
+	"oneof": func(v reflect.Value, p string) bool {
+		s := scalarString(v)
+		for _, opt := range strings.Fields(p) {
+			if s == opt {
+				return true
+			}
+		}
+		return false
+	},
+	"email": stringRule(func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	}),
+	"url": stringRule(func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	}),
+	"hostport": stringRule(func(s string) bool {
+		i := strings.LastIndex(s, ":")
+		if i < 0 {
+			return false
+		}
+		port, err := strconv.Atoi(s[i+1:])
+		return err == nil && port > 0 && port <= 65535
+	}),
+	"alpha":    stringRule(regexp.MustCompile(`^[\pL]+$`).MatchString),
+	"alphanum": stringRule(regexp.MustCompile(`^[\pL\pN]+$`).MatchString),
+	"numeric":  stringRule(func(s string) bool { _, err := strconv.ParseFloat(s, 64); return err == nil }),
+	"uuid":     stringRule(regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString),
+}
+
+func sizeRule(cmp func(a, b float64) bool) RuleFunc {
+	return func(v reflect.Value, param string) bool {
+		return compareSize(v, param, cmp)
+	}
+}
+
+func stringRule(fn func(string) bool) RuleFunc {
+	return func(v reflect.Value, _ string) bool {
+		if v.Kind() != reflect.String {
+			return false
+		}
+		return fn(v.String())
+	}
+}
+
+// compareSize compares numbers by value and strings, slices and maps by length
+func compareSize(v reflect.Value, param string, cmp func(a, b float64) bool) bool {
+	want, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return cmp(float64(utf8.RuneCountInString(v.String())), want)
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return cmp(float64(v.Len()), want)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp(float64(v.Int()), want)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp(float64(v.Uint()), want)
+	case reflect.Float32, reflect.Float64:
+		return cmp(v.Float(), want)
+	default:
+		return false
+	}
+}
+
+func scalarString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	default:
+		return ""
+	}
+}
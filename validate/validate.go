@@ -0,0 +1,321 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldError describes one failed rule
+type FieldError struct {
+	// Field is the dotted path of the field using its json/yaml name, e.g. "server.port" or "items[2].sku".
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+	// Message is the rendered, possibly translated, message.
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// Errors is the list of failures returned by Struct
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Fields returns the messages keyed by field path, handy for API error bodies
+func (e Errors) Fields() map[string]string {
+	out := make(map[string]string, len(e))
+	for _, fe := range e {
+		if _, ok := out[fe.Field]; !ok {
+			out[fe.Field] = fe.Message
+		}
+	}
+	return out
+}
+
+// RuleFunc reports whether v satisfies the rule with the given parameter
+type RuleFunc func(v reflect.Value, param string) bool
+
+// Validatable is implemented by types with checks that tags cannot express.
+// Validate runs after the tag rules; return an Errors (built with Errorf) to report field-level failures.
+type Validatable interface {
+	Validate() error
+}
+
+// Validator validates structs using `validate` struct tags:
+//
+//	Name  string   `json:"name" validate:"required,max=50"`
+//	Port  int      `yaml:"port" validate:"gte=1,lte=65535"`
+//	Email string   `json:"email" validate:"omitempty,email"`
+//	Tags  []string `json:"tags" validate:"max=10,dive,min=1"`
+//
+// Rules are separated by commas; "dive" applies the rules after it to every slice element or map value.
+type Validator struct {
+	mu       sync.RWMutex
+	rules    map[string]RuleFunc
+	messages map[string]string
+}
+
+// New creates a Validator with the built-in rules and English messages
+func New() *Validator {
+	v := &Validator{rules: map[string]RuleFunc{}, messages: map[string]string{}}
+	for name, fn := range builtinRules {
+		v.rules[name] = fn
+	}
+	for rule, msg := range DefaultMessages {
+		v.messages[rule] = msg
+	}
+	return v
+}
+
+var std = New()
+
+// Struct validates s with the default Validator
+func Struct(s interface{}) error {
+	return std.Struct(s)
+}
+
+// RegisterRule adds a custom rule to the default Validator
+func RegisterRule(name, message string, fn RuleFunc) {
+	std.RegisterRule(name, message, fn)
+}
+
+// RegisterRule adds or replaces a rule. message is the template used when it fails.
+func (v *Validator) RegisterRule(name, message string, fn RuleFunc) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[name] = fn
+	v.messages[name] = message
+}
+
+// SetMessages overrides message templates by rule name, e.g. to translate them.
+// Templates may use {field} and {param}.
+func (v *Validator) SetMessages(messages map[string]string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for rule, msg := range messages {
+		v.messages[rule] = msg
+	}
+}
+
+// Struct validates s, which must be a struct or a pointer to one.
+// It returns nil or Errors listing every failure.
+func (v *Validator) Struct(s interface{}) error {
+	rv := reflect.ValueOf(s)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return fmt.Errorf("validate: nil %T", s)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: expected a struct, got %T", s)
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var errs Errors
+	v.walkStruct(rv, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Var validates a single value against a tag expression such as "required,email"
+func (v *Validator) Var(field string, value interface{}, tag string) error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var errs Errors
+	v.apply(reflect.ValueOf(value), field, tag, &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (v *Validator) walkStruct(rv reflect.Value, prefix string, errs *Errors) {
+	t := rv.Type()
+	for i := 0; i < rv.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		path := join(prefix, fieldName(f))
+		fv := rv.Field(i)
+
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			v.apply(fv, path, tag, errs)
+		}
+		if f.Tag.Get("validate") != "-" {
+			v.descend(fv, path, errs)
+		}
+	}
+
+	if rv.CanAddr() {
+		if val, ok := rv.Addr().Interface().(Validatable); ok {
+			v.custom(val, prefix, errs)
+			return
+		}
+	}
+	if val, ok := rv.Interface().(Validatable); ok {
+		v.custom(val, prefix, errs)
+	}
+}
+
+// descend validates nested structs, including those in slices and maps
+func (v *Validator) descend(fv reflect.Value, path string, errs *Errors) {
+	for fv.Kind() == reflect.Pointer || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return
+		}
+		fv = fv.Elem()
+	}
+	switch fv.Kind() {
+	case reflect.Struct:
+		v.walkStruct(fv, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			v.descend(fv.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		iter := fv.MapRange()
+		for iter.Next() {
+			v.descend(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key().Interface()), errs)
+		}
+	}
+}
+
+func (v *Validator) custom(val Validatable, prefix string, errs *Errors) {
+	err := val.Validate()
+	if err == nil {
+		return
+	}
+	var fe Errors
+	if errors.As(err, &fe) {
+		for _, e := range fe {
+			e.Field = join(prefix, e.Field)
+			*errs = append(*errs, e)
+		}
+		return
+	}
+	*errs = append(*errs, FieldError{Field: prefix, Rule: "custom", Message: err.Error()})
+}
+
+func (v *Validator) apply(fv reflect.Value, path, tag string, errs *Errors) {
+	rules := strings.Split(tag, ",")
+	for i, r := range rules {
+		name, param, _ := strings.Cut(strings.TrimSpace(r), "=")
+		switch name {
+		case "":
+			continue
+		case "omitempty":
+			if isEmpty(fv) {
+				return
+			}
+			continue
+		case "dive":
+			v.dive(fv, path, strings.Join(rules[i+1:], ","), errs)
+			return
+		}
+
+		fn, ok := v.rules[name]
+		if !ok {
+			*errs = append(*errs, FieldError{Field: path, Rule: name, Message: fmt.Sprintf("%s: unknown validation rule %q", path, name)})
+			continue
+		}
+		if !fn(indirect(fv), param) {
+			*errs = append(*errs, v.fieldError(path, name, param))
+			if name == "required" {
+				return // the remaining rules would only repeat the same failure
+			}
+		}
+	}
+}
+
+func (v *Validator) dive(fv reflect.Value, path, tag string, errs *Errors) {
+	fv = indirect(fv)
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			v.apply(fv.Index(i), fmt.Sprintf("%s[%d]", path, i), tag, errs)
+		}
+	case reflect.Map:
+		keys := fv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			v.apply(fv.MapIndex(k), fmt.Sprintf("%s[%v]", path, k.Interface()), tag, errs)
+		}
+	}
+}
+
+func (v *Validator) fieldError(path, rule, param string) FieldError {
+	tmpl, ok := v.messages[rule]
+	if !ok {
+		tmpl = "{field} failed {rule} validation"
+	}
+	msg := strings.NewReplacer("{field}", path, "{param}", param, "{rule}", rule).Replace(tmpl)
+	return FieldError{Field: path, Rule: rule, Param: param, Message: msg}
+}
+
+// Errorf builds a single-field error for use in Validatable implementations
+func Errorf(field, rule, format string, args ...interface{}) Errors {
+	return Errors{{Field: field, Rule: rule, Message: fmt.Sprintf(format, args...)}}
+}
+
+func fieldName(f reflect.StructField) string {
+	for _, key := range []string{"json", "yaml"} {
+		if tag, ok := f.Tag.Lookup(key); ok {
+			name, _, _ := strings.Cut(tag, ",")
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+	}
+	return f.Name
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	if name == "" {
+		return prefix
+	}
+	return prefix + "." + name
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+func isEmpty(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,514 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cdcloud-io/go-libs/mongoclient"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Headers set on every outbound delivery besides SignatureHeader
+const (
+	DeliveryHeader = "X-Webhook-Id"
+	EventHeader    = "X-Webhook-Event"
+)
+
+// DeliveryStatus is the state of a delivery
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryRunning   DeliveryStatus = "running"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// ErrNotFound is returned when a subscription or delivery does not exist
+var ErrNotFound = errors.New("webhook not found")
+
+// Subscription is a subscriber endpoint
+type Subscription struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	// Owner scopes subscriptions, e.g. a tenant or customer ID.
+	Owner string `bson:"owner" json:"owner"`
+	URL   string `bson:"url" json:"url"`
+	// Events the subscriber receives; "*" matches every event.
+	Events []string `bson:"events" json:"events"`
+	// Secrets sign deliveries. The first is current; the rest are kept while a rotation is rolled out.
+	Secrets   []string  `bson:"secrets" json:"-"`
+	Active    bool      `bson:"active" json:"active"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Delivery is one event sent to one subscription, including its retry state
+type Delivery struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SubscriptionID primitive.ObjectID `bson:"subscription_id" json:"subscription_id"`
+	Owner          string             `bson:"owner" json:"owner"`
+	Event          string             `bson:"event" json:"event"`
+	// Body is the exact JSON that is signed and sent.
+	Body          string         `bson:"body" json:"-"`
+	Status        DeliveryStatus `bson:"status" json:"status"`
+	Attempts      int            `bson:"attempts" json:"attempts"`
+	MaxAttempts   int            `bson:"max_attempts" json:"max_attempts"`
+	NextAttemptAt time.Time      `bson:"next_attempt_at" json:"next_attempt_at"`
+	LockedUntil   time.Time      `bson:"locked_until,omitempty" json:"-"`
+	LockedBy      string         `bson:"locked_by,omitempty" json:"-"`
+	LastStatus    int            `bson:"last_status,omitempty" json:"last_status,omitempty"`
+	LastError     string         `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt     time.Time      `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time      `bson:"updated_at" json:"updated_at"`
+	DeliveredAt   *time.Time     `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+}
+
+// DispatcherOptions configures a Dispatcher
+type DispatcherOptions struct {
+	Database string
+	// SubscriptionsCollection defaults to "webhook_subscriptions".
+	SubscriptionsCollection string
+	// DeliveriesCollection defaults to "webhook_deliveries".
+	DeliveriesCollection string
+	// MaxAttempts before a delivery is dead-lettered. Defaults to 8.
+	MaxAttempts int
+	// Backoff returns the delay before the next attempt. Defaults to 30s doubling up to 6h.
+	Backoff func(attempt int) time.Duration
+	// Concurrency is the number of deliveries sent in parallel by Run. Defaults to 4.
+	Concurrency int
+	// PollInterval is how often idle workers look for due deliveries. Defaults to 1s.
+	PollInterval time.Duration
+	// HTTPClient defaults to a client with a 10s timeout that refuses to connect to
+	// loopback, private and link-local addresses. A custom client is used as is, so it must
+	// guard its own dialer.
+	HTTPClient *http.Client
+	// AllowedHosts are subscriber hosts exempt from the https requirement and the block on
+	// internal addresses, e.g. a relay inside our network, or "127.0.0.1" in tests.
+	AllowedHosts []string
+	// UserAgent defaults to "go-libs-webhooks".
+	UserAgent string
+}
+
+// Dispatcher stores subscriptions and delivers events to them with signing,
+// exponential retry and dead-lettering. Any number of replicas can run it. Every
+// operation goes through the mongoclient.Client, so a client with a TenantResolver keeps
+// the subscriptions and deliveries of each tenant apart and needs the tenant in the
+// context of every call, including Run.
+type Dispatcher struct {
+	client     *mongoclient.Client
+	subs       *mongoclient.CollectionRepository
+	deliveries *mongoclient.CollectionRepository
+	opts       DispatcherOptions
+}
+
+// NewDispatcher creates a dispatcher backed by the given client
+func NewDispatcher(client *mongoclient.Client, opts DispatcherOptions) *Dispatcher {
+	if opts.SubscriptionsCollection == "" {
+		opts.SubscriptionsCollection = "webhook_subscriptions"
+	}
+	if opts.DeliveriesCollection == "" {
+		opts.DeliveriesCollection = "webhook_deliveries"
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = func(attempt int) time.Duration {
+			d := time.Duration(float64(30*time.Second) * math.Pow(2, float64(attempt-1)))
+			if d <= 0 || d > 6*time.Hour {
+				return 6 * time.Hour
+			}
+			return d
+		}
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "go-libs-webhooks"
+	}
+	d := &Dispatcher{
+		client:     client,
+		subs:       client.Repository(opts.Database, opts.SubscriptionsCollection),
+		deliveries: client.Repository(opts.Database, opts.DeliveriesCollection),
+		opts:       opts,
+	}
+	if d.opts.HTTPClient == nil {
+		d.opts.HTTPClient = d.newHTTPClient()
+	}
+	return d
+}
+
+// EnsureIndexes creates the indexes used to match subscriptions and claim deliveries
+func (d *Dispatcher) EnsureIndexes(ctx context.Context) error {
+	if err := d.ensureIndexes(ctx, d.opts.SubscriptionsCollection, []mongoclient.IndexSpec{
+		{Keys: bson.D{{Key: "owner", Value: 1}, {Key: "active", Value: 1}, {Key: "events", Value: 1}}},
+	}); err != nil {
+		return fmt.Errorf("failed to create webhook subscription index: %w", err)
+	}
+	if err := d.ensureIndexes(ctx, d.opts.DeliveriesCollection, []mongoclient.IndexSpec{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "subscription_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	}); err != nil {
+		return fmt.Errorf("failed to create webhook delivery indexes: %w", err)
+	}
+	return nil
+}
+
+// ensureIndexes creates specs on collection in the namespace of the tenant in ctx
+func (d *Dispatcher) ensureIndexes(ctx context.Context, collection string, specs []mongoclient.IndexSpec) error {
+	ns, err := d.client.Resolve(ctx, mongoclient.Namespace{Database: d.opts.Database, Collection: collection})
+	if err != nil {
+		return err
+	}
+	return d.client.EnsureIndexes(ctx, ns.Database, ns.Collection, specs)
+}
+
+// Subscribe stores a new active subscription and returns it with its ID set. The URL must
+// use https and must not resolve to an internal address, unless its host is in
+// DispatcherOptions.AllowedHosts; otherwise the error matches ErrUnsafeURL.
+func (d *Dispatcher) Subscribe(ctx context.Context, sub Subscription) (*Subscription, error) {
+	if sub.URL == "" || len(sub.Events) == 0 || len(sub.Secrets) == 0 {
+		return nil, fmt.Errorf("failed to subscribe: url, events and a secret are required")
+	}
+	if err := d.checkURL(ctx, sub.URL, true); err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	now := time.Now().UTC()
+	sub.ID = primitive.NewObjectID()
+	sub.Active = true
+	sub.CreatedAt, sub.UpdatedAt = now, now
+	if _, err := d.subs.Insert(ctx, sub); err != nil {
+		return nil, fmt.Errorf("failed to store webhook subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// Unsubscribe deletes a subscription; pending deliveries to it are dead-lettered
+func (d *Dispatcher) Unsubscribe(ctx context.Context, id primitive.ObjectID) error {
+	deleted, err := d.subs.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription %s: %w", id.Hex(), err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: subscription %s", ErrNotFound, id.Hex())
+	}
+	_, err = d.deliveries.UpdateMany(ctx,
+		bson.M{"subscription_id": id, "status": DeliveryPending},
+		bson.M{"$set": bson.M{"status": DeliveryDead, "last_error": "subscription deleted", "updated_at": time.Now().UTC()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel deliveries for subscription %s: %w", id.Hex(), err)
+	}
+	return nil
+}
+
+// SetActive pauses or resumes a subscription. Paused subscriptions receive no new deliveries.
+func (d *Dispatcher) SetActive(ctx context.Context, id primitive.ObjectID, active bool) error {
+	return d.updateSubscription(ctx, id, bson.M{"$set": bson.M{"active": active, "updated_at": time.Now().UTC()}})
+}
+
+// RotateSecret makes secret the current signing secret, keeping at most keep previous
+// secrets so receivers can switch over without rejecting deliveries
+func (d *Dispatcher) RotateSecret(ctx context.Context, id primitive.ObjectID, secret string, keep int) error {
+	return d.updateSubscription(ctx, id, bson.M{
+		"$push": bson.M{"secrets": bson.M{"$each": bson.A{secret}, "$position": 0, "$slice": keep + 1}},
+		"$set":  bson.M{"updated_at": time.Now().UTC()},
+	})
+}
+
+func (d *Dispatcher) updateSubscription(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	result, err := d.subs.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook subscription %s: %w", id.Hex(), err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: subscription %s", ErrNotFound, id.Hex())
+	}
+	return nil
+}
+
+// Subscriptions lists the subscriptions of owner
+func (d *Dispatcher) Subscriptions(ctx context.Context, owner string) ([]Subscription, error) {
+	var subs []Subscription
+	opts := mongoclient.QueryOptions{Sort: bson.D{{Key: "created_at", Value: 1}}}
+	if err := d.subs.Find(ctx, bson.M{"owner": owner}, opts, &subs); err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// Publish queues event for every active subscription of owner listening to it and
+// returns the number of deliveries created. data is sent as the "data" field of the body:
+//
+//	{"id": "<delivery id>", "event": "order.created", "created_at": "...", "data": {...}}
+func (d *Dispatcher) Publish(ctx context.Context, owner, event string, data interface{}) (int, error) {
+	var subs []Subscription
+	err := d.subs.Find(ctx, bson.M{
+		"owner":  owner,
+		"active": true,
+		"events": bson.M{"$in": bson.A{event, "*"}},
+	}, mongoclient.QueryOptions{}, &subs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find subscribers for %s: %w", event, err)
+	}
+	if len(subs) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now().UTC()
+	docs := make([]interface{}, len(subs))
+	for i, sub := range subs {
+		id := primitive.NewObjectID()
+		body, err := json.Marshal(map[string]interface{}{
+			"id":         id.Hex(),
+			"event":      event,
+			"created_at": now,
+			"data":       data,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode %s payload: %w", event, err)
+		}
+		docs[i] = Delivery{
+			ID:             id,
+			SubscriptionID: sub.ID,
+			Owner:          owner,
+			Event:          event,
+			Body:           string(body),
+			Status:         DeliveryPending,
+			MaxAttempts:    d.opts.MaxAttempts,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+	}
+	if _, err := d.deliveries.InsertMany(ctx, docs); err != nil {
+		return 0, fmt.Errorf("failed to queue %s deliveries: %w", event, err)
+	}
+	return len(docs), nil
+}
+
+// Run delivers due webhooks until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) error {
+	host, _ := os.Hostname()
+	var wg sync.WaitGroup
+	for i := 0; i < d.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func(workerID string) {
+			defer wg.Done()
+			d.work(ctx, workerID)
+		}(host + "-" + strconv.Itoa(os.Getpid()) + "-" + strconv.Itoa(i))
+	}
+	wg.Wait()
+	return nil
+}
+
+func (d *Dispatcher) work(ctx context.Context, workerID string) {
+	for ctx.Err() == nil {
+		delivery, err := d.claim(ctx, workerID)
+		if err != nil {
+			if !errors.Is(err, mongoclient.ErrNotFound) && ctx.Err() == nil {
+				log.Printf("webhooks: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(d.opts.PollInterval):
+			}
+			continue
+		}
+		if err := d.deliver(ctx, delivery); err != nil && ctx.Err() == nil {
+			log.Printf("webhooks: %v", err)
+		}
+	}
+}
+
+// claim takes the next due delivery, including ones whose worker died mid-send
+func (d *Dispatcher) claim(ctx context.Context, workerID string) (*Delivery, error) {
+	now := time.Now().UTC()
+	filter := bson.M{"$or": bson.A{
+		bson.M{"status": DeliveryPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"status": DeliveryRunning, "locked_until": bson.M{"$lte": now}},
+	}}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       DeliveryRunning,
+			"locked_by":    workerID,
+			"locked_until": now.Add(2 * d.opts.HTTPClient.Timeout).Add(time.Minute),
+			"updated_at":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := mongoclient.FindAndModifyOptions{ReturnAfter: true, Sort: bson.D{{Key: "next_attempt_at", Value: 1}}}
+
+	var delivery Delivery
+	if err := d.client.FindOneAndUpdate(ctx, d.deliveries.Params(filter), update, opts, &delivery); err != nil {
+		if errors.Is(err, mongoclient.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *Delivery) error {
+	var sub Subscription
+	err := d.subs.FindOne(ctx, bson.M{"_id": delivery.SubscriptionID}, mongoclient.QueryOptions{}, &sub)
+	if errors.Is(err, mongoclient.ErrNotFound) || (err == nil && !sub.Active) {
+		return d.finish(ctx, delivery, bson.M{"status": DeliveryDead, "last_error": "subscription deleted or paused"})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load subscription for delivery %s: %w", delivery.ID.Hex(), err)
+	}
+
+	status, sendErr := d.send(ctx, sub, delivery)
+
+	// Record the outcome with a context that survives shutdown, keeping the values of ctx
+	// such as the tenant, so a delivery that was sent is not left running and sent again
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	set := bson.M{"last_status": status}
+	switch {
+	case sendErr == nil:
+		now := time.Now().UTC()
+		set["status"] = DeliverySucceeded
+		set["delivered_at"] = now
+		set["last_error"] = ""
+	case status == http.StatusGone:
+		// The receiver asked us to stop: pause the subscription rather than retrying
+		set["status"] = DeliveryDead
+		set["last_error"] = sendErr.Error()
+		if err := d.SetActive(ctx, sub.ID, false); err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+	case errors.Is(sendErr, ErrUnsafeURL), delivery.Attempts >= delivery.MaxAttempts:
+		set["status"] = DeliveryDead
+		set["last_error"] = sendErr.Error()
+	default:
+		set["status"] = DeliveryPending
+		set["last_error"] = sendErr.Error()
+		set["next_attempt_at"] = time.Now().UTC().Add(d.opts.Backoff(delivery.Attempts))
+	}
+	return d.finish(ctx, delivery, set)
+}
+
+func (d *Dispatcher) send(ctx context.Context, sub Subscription, delivery *Delivery) (int, error) {
+	body := []byte(delivery.Body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	secrets := make([][]byte, len(sub.Secrets))
+	for i, s := range sub.Secrets {
+		secrets[i] = []byte(s)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", d.opts.UserAgent)
+	req.Header.Set(DeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(EventHeader, delivery.Event)
+	SignRequest(req, body, secrets...)
+
+	resp, err := d.opts.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) finish(ctx context.Context, delivery *Delivery, set bson.M) error {
+	set["updated_at"] = time.Now().UTC()
+	_, err := d.deliveries.UpdateOne(ctx,
+		bson.M{"_id": delivery.ID, "status": DeliveryRunning, "locked_by": delivery.LockedBy},
+		bson.M{"$set": set, "$unset": bson.M{"locked_until": "", "locked_by": ""}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery %s: %w", delivery.ID.Hex(), err)
+	}
+	return nil
+}
+
+// DeliveryFilter selects deliveries for Deliveries. Empty fields match everything.
+type DeliveryFilter struct {
+	Owner          string
+	SubscriptionID primitive.ObjectID
+	Event          string
+	Status         DeliveryStatus
+	// Limit defaults to 50.
+	Limit int64
+}
+
+// Deliveries lists deliveries, newest first
+func (d *Dispatcher) Deliveries(ctx context.Context, f DeliveryFilter) ([]Delivery, error) {
+	filter := bson.M{}
+	if f.Owner != "" {
+		filter["owner"] = f.Owner
+	}
+	if !f.SubscriptionID.IsZero() {
+		filter["subscription_id"] = f.SubscriptionID
+	}
+	if f.Event != "" {
+		filter["event"] = f.Event
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+
+	var deliveries []Delivery
+	opts := mongoclient.QueryOptions{Sort: bson.D{{Key: "created_at", Value: -1}}, Limit: f.Limit}
+	if err := d.deliveries.Find(ctx, filter, opts, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Delivery returns one delivery by ID
+func (d *Dispatcher) Delivery(ctx context.Context, id primitive.ObjectID) (*Delivery, error) {
+	var delivery Delivery
+	err := d.deliveries.FindOne(ctx, bson.M{"_id": id}, mongoclient.QueryOptions{}, &delivery)
+	if errors.Is(err, mongoclient.ErrNotFound) {
+		return nil, fmt.Errorf("%w: delivery %s", ErrNotFound, id.Hex())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery %s: %w", id.Hex(), err)
+	}
+	return &delivery, nil
+}
+
+// Redeliver requeues a dead or succeeded delivery with a fresh set of attempts
+func (d *Dispatcher) Redeliver(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now().UTC()
+	result, err := d.deliveries.UpdateOne(ctx,
+		bson.M{"_id": id, "status": bson.M{"$in": bson.A{DeliveryDead, DeliverySucceeded}}},
+		bson.M{"$set": bson.M{"status": DeliveryPending, "attempts": 0, "next_attempt_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to redeliver webhook %s: %w", id.Hex(), err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: no dead or succeeded delivery %s", ErrNotFound, id.Hex())
+	}
+	return nil
+}
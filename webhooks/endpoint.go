@@ -0,0 +1,123 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrUnsafeURL is returned by Subscribe for a subscriber URL that does not use https or
+// that resolves to a loopback, private or link-local address, and fails deliveries whose
+// host resolves to one by the time they are sent
+var ErrUnsafeURL = errors.New("webhook URL not allowed")
+
+// blockedPrefixes are ranges besides the ones netip classifies that no customer endpoint
+// lives in
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+}
+
+// blockedAddr reports whether a is internal to our network or the host, e.g. the cloud
+// metadata endpoint 169.254.169.254
+func blockedAddr(a netip.Addr) bool {
+	a = a.Unmap()
+	if a.IsLoopback() || a.IsPrivate() || a.IsUnspecified() || a.IsLinkLocalUnicast() ||
+		a.IsLinkLocalMulticast() || a.IsInterfaceLocalMulticast() || a.IsMulticast() {
+		return true
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedHost reports whether host is exempt from the checks, see DispatcherOptions.AllowedHosts
+func (d *Dispatcher) allowedHost(host string) bool {
+	for _, h := range d.opts.AllowedHosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkURL rejects subscriber URLs that are not https or, with resolve, whose host
+// resolves to a blocked address
+func (d *Dispatcher) checkURL(ctx context.Context, raw string, resolve bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: %q is not an absolute URL", ErrUnsafeURL, raw)
+	}
+	host := u.Hostname()
+	if d.allowedHost(host) {
+		return nil
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: %s must use https", ErrUnsafeURL, raw)
+	}
+	if !resolve {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%w: failed to resolve %s: %v", ErrUnsafeURL, host, err)
+	}
+	for _, a := range addrs {
+		if blockedAddr(a) {
+			return fmt.Errorf("%w: %s resolves to internal address %s", ErrUnsafeURL, host, a)
+		}
+	}
+	return nil
+}
+
+// newHTTPClient returns the default delivery client. Its dialer checks the address it
+// actually connects to, since the name of a subscriber that passed Subscribe can later
+// resolve to an internal address (DNS rebinding). It ignores proxy settings, which would
+// hide the address from the check.
+func (d *Dispatcher) newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = d.dial
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return d.checkURL(req.Context(), req.URL.String(), false)
+		},
+	}
+}
+
+func (d *Dispatcher) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	if host, _, err := net.SplitHostPort(addr); err != nil || !d.allowedHost(host) {
+		dialer.Control = controlAddr
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// controlAddr refuses connections to blocked addresses, after name resolution
+func controlAddr(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unexpected dial address %s", ErrUnsafeURL, address)
+	}
+	if blockedAddr(ap.Addr()) {
+		return fmt.Errorf("%w: refusing to connect to internal address %s", ErrUnsafeURL, ap.Addr())
+	}
+	return nil
+}
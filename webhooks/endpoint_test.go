@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCheckURL(t *testing.T) {
+	d := &Dispatcher{opts: DispatcherOptions{AllowedHosts: []string{"relay.internal", "127.0.0.1"}}}
+
+	tests := []struct {
+		name string
+		url  string
+		ok   bool
+	}{
+		{"public https", "https://93.184.216.34/hooks", true},
+		{"plain http", "http://93.184.216.34/hooks", false},
+		{"other scheme", "ftp://93.184.216.34/hooks", false},
+		{"relative", "/hooks", false},
+		{"metadata endpoint", "https://169.254.169.254/latest/meta-data", false},
+		{"loopback", "https://127.0.0.2/", false},
+		{"private", "https://10.1.2.3/", false},
+		{"carrier-grade NAT", "https://100.64.0.1/", false},
+		{"unspecified", "https://0.0.0.0/", false},
+		{"IPv6 loopback", "https://[::1]/", false},
+		{"IPv6 unique local", "https://[fd00::1]/", false},
+		{"IPv4-mapped loopback", "https://[::ffff:127.0.0.1]/", false},
+		{"allowed host over http", "http://relay.internal:8080/hooks", true},
+		{"allowed host is case-insensitive", "http://RELAY.internal/hooks", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := d.checkURL(context.Background(), tt.url, true)
+			if tt.ok && err != nil {
+				t.Fatalf("checkURL(%s) = %v", tt.url, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrUnsafeURL) {
+				t.Fatalf("checkURL(%s) = %v, want ErrUnsafeURL", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestDeliveryClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The URL passed Subscribe as a public name and now resolves to loopback
+	blocked := &Dispatcher{}
+	_, err := blocked.newHTTPClient().Get(server.URL)
+	if !errors.Is(err, ErrUnsafeURL) {
+		t.Fatalf("Get(%s) = %v, want ErrUnsafeURL", server.URL, err)
+	}
+
+	u, _ := url.Parse(server.URL)
+	allowed := &Dispatcher{opts: DispatcherOptions{AllowedHosts: []string{u.Hostname()}}}
+	resp, err := allowed.newHTTPClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get(%s) on an allowed host = %v", server.URL, err)
+	}
+	resp.Body.Close()
+}
+
+func TestDeliveryClientChecksRedirects(t *testing.T) {
+	target := "http://93.184.216.34/"
+	server := httptest.NewServer(http.RedirectHandler(target, http.StatusFound))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	d := &Dispatcher{opts: DispatcherOptions{AllowedHosts: []string{u.Hostname()}}}
+	_, err := d.newHTTPClient().Get(server.URL)
+	if !errors.Is(err, ErrUnsafeURL) {
+		t.Fatalf("redirect to %s = %v, want ErrUnsafeURL", target, err)
+	}
+}
@@ -0,0 +1,137 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1718000000, 0)
+	oldSecret, newSecret, otherSecret := []byte("old-secret"), []byte("new-secret"), []byte("other-secret")
+	body := []byte(`{"event":"order.created","id":7}`)
+	v := &Verifier{Secrets: [][]byte{oldSecret}, Now: func() time.Time { return now }}
+
+	signed := Sign(body, now, oldSecret)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := strings.TrimPrefix(signed, "t="+ts+",v1=")
+	bodyOnly := hmac.New(sha256.New, oldSecret)
+	bodyOnly.Write(body)
+
+	tests := []struct {
+		name   string
+		body   []byte
+		header string
+		err    error
+	}{
+		{"valid", body, signed, nil},
+		{"uppercase hex", body, "t=" + ts + ",v1=" + strings.ToUpper(sig), nil},
+		{"spaces after commas", body, "t=" + ts + ", v1=" + sig, nil},
+		{"within tolerance in the past", body, Sign(body, now.Add(-DefaultTolerance), oldSecret), nil},
+		{"within tolerance in the future", body, Sign(body, now.Add(DefaultTolerance), oldSecret), nil},
+		{"too old", body, Sign(body, now.Add(-DefaultTolerance-time.Second), oldSecret), ErrTimestampExpired},
+		{"too far in the future", body, Sign(body, now.Add(DefaultTolerance+time.Second), oldSecret), ErrTimestampExpired},
+		{"rotation, old secret second", body, Sign(body, now, newSecret, oldSecret), nil},
+		{"rotation, old secret first", body, Sign(body, now, oldSecret, newSecret), nil},
+		{"only an unknown secret", body, Sign(body, now, otherSecret), ErrInvalidSignature},
+		{"tampered body", []byte(`{"event":"order.created","id":8}`), signed, ErrInvalidSignature},
+		{"empty body", nil, signed, ErrInvalidSignature},
+		// The signature binds the timestamp: replaying it with a fresh t must fail
+		{"timestamp swapped", body, "t=" + strconv.FormatInt(now.Unix()+1, 10) + ",v1=" + sig, ErrInvalidSignature},
+		{"MAC of the body alone", body, "t=" + ts + ",v1=" + hex.EncodeToString(bodyOnly.Sum(nil)), ErrInvalidSignature},
+		{"truncated signature", body, "t=" + ts + ",v1=" + sig[:32], ErrInvalidSignature},
+		{"signature with extra bytes", body, "t=" + ts + ",v1=" + sig + "00", ErrInvalidSignature},
+		{"hex of the signature compared as text", body, "t=" + ts + ",v1=" + hex.EncodeToString([]byte(sig)), ErrInvalidSignature},
+		{"unknown scheme only", body, "t=" + ts + ",v0=" + sig, ErrInvalidSignature},
+		{"no timestamp", body, "v1=" + sig, ErrInvalidSignature},
+		{"malformed timestamp", body, "t=yesterday,v1=" + sig, ErrInvalidSignature},
+		{"missing", body, "", ErrMissingSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(tt.body, tt.header)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Verify(%s) error = %v, want %v", tt.header, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerifySecretRotation(t *testing.T) {
+	now := time.Unix(1718000000, 0)
+	oldSecret, newSecret := []byte("old-secret"), []byte("new-secret")
+	body := []byte(`{}`)
+
+	// Senders sign with both secrets while receivers move over, and receivers accept both
+	// while senders move over
+	tests := []struct {
+		name    string
+		signed  [][]byte
+		secrets [][]byte
+		ok      bool
+	}{
+		{"receiver not rotated yet", [][]byte{newSecret, oldSecret}, [][]byte{oldSecret}, true},
+		{"receiver rotated", [][]byte{newSecret, oldSecret}, [][]byte{newSecret}, true},
+		{"receiver accepting both", [][]byte{oldSecret}, [][]byte{newSecret, oldSecret}, true},
+		{"old secret retired", [][]byte{oldSecret}, [][]byte{newSecret}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &Verifier{Secrets: tt.secrets, Now: func() time.Time { return now }}
+			err := v.Verify(body, Sign(body, now, tt.signed...))
+			if tt.ok != (err == nil) {
+				t.Fatalf("Verify() error = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestVerifierMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	v := &Verifier{Secrets: [][]byte{secret}}
+	var got string
+	handler := v.Middleware(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		got = string(b)
+	}))
+
+	body := `{"event":"order.created"}`
+	tests := []struct {
+		name   string
+		body   string
+		sign   bool
+		status int
+	}{
+		{"signed", body, true, http.StatusOK},
+		{"unsigned", body, false, http.StatusUnauthorized},
+		{"over the size limit", strings.Repeat("x", 1025), true, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+			req := httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(tt.body))
+			if tt.sign {
+				SignRequest(req, []byte(tt.body), secret)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && got != tt.body {
+				t.Errorf("handler read %q; the body must be restored after verification", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/cdcloud-io/go-libs/websocket
+
+go 1.22.4
+
+require github.com/gorilla/websocket v1.5.3
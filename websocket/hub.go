@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Hub tracks live connections and the rooms they joined, for broadcasting
+type Hub struct {
+	mu    sync.RWMutex
+	conns map[*Conn]map[string]struct{} // connection -> rooms
+	rooms map[string]map[*Conn]struct{}
+}
+
+func newHub() *Hub {
+	return &Hub{
+		conns: map[*Conn]map[string]struct{}{},
+		rooms: map[string]map[*Conn]struct{}{},
+	}
+}
+
+func (h *Hub) add(c *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[c] = map[string]struct{}{}
+}
+
+func (h *Hub) remove(c *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for room := range h.conns[c] {
+		delete(h.rooms[room], c)
+		if len(h.rooms[room]) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+	delete(h.conns, c)
+}
+
+func (h *Hub) all() []*Conn {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		out = append(out, c)
+	}
+	return out
+}
+
+// Join adds c to room, e.g. a dashboard or tenant ID
+func (h *Hub) Join(c *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rooms, ok := h.conns[c]
+	if !ok {
+		return // already closed
+	}
+	rooms[room] = struct{}{}
+	if h.rooms[room] == nil {
+		h.rooms[room] = map[*Conn]struct{}{}
+	}
+	h.rooms[room][c] = struct{}{}
+}
+
+// Leave removes c from room
+func (h *Hub) Leave(c *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns[c], room)
+	delete(h.rooms[room], c)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// Count returns the number of live connections
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// Broadcast sends msg to every connection. Slow clients are dropped rather than blocking the others.
+func (h *Hub) Broadcast(msg []byte) {
+	for _, c := range h.all() {
+		c.Send(msg)
+	}
+}
+
+// BroadcastTo sends msg to every connection in room
+func (h *Hub) BroadcastTo(room string, msg []byte) {
+	h.mu.RLock()
+	targets := make([]*Conn, 0, len(h.rooms[room]))
+	for c := range h.rooms[room] {
+		targets = append(targets, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range targets {
+		c.Send(msg)
+	}
+}
+
+// BroadcastJSON encodes v once and sends it to room, or to everyone if room is empty
+func (h *Hub) BroadcastJSON(room string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode websocket broadcast: %w", err)
+	}
+	if room == "" {
+		h.Broadcast(b)
+	} else {
+		h.BroadcastTo(room, b)
+	}
+	return nil
+}
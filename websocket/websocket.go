@@ -0,0 +1,349 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+)
+
+// ErrClosed is returned when sending to a closed connection
+var ErrClosed = errors.New("websocket connection closed")
+
+// ErrSlowClient is returned when a connection's send buffer is full; the connection is closed
+var ErrSlowClient = errors.New("websocket client too slow")
+
+// Options configures a Server
+type Options struct {
+	// AllowedOrigins lists accepted Origin hosts, e.g. "app.example.com". Empty allows same-origin only.
+	AllowedOrigins []string
+	// ReadLimit is the maximum inbound message size. Defaults to 64KiB.
+	ReadLimit int64
+	// PingInterval is how often the server pings idle clients. Defaults to 30s.
+	PingInterval time.Duration
+	// PongWait is how long to wait for any message or pong before dropping a client. Defaults to 2x PingInterval.
+	PongWait time.Duration
+	// WriteWait bounds each write. Defaults to 10s.
+	WriteWait time.Duration
+	// SendBuffer is the number of outbound messages queued per connection before it is
+	// considered too slow and closed. Defaults to 64.
+	SendBuffer int
+
+	// OnConnect runs after the upgrade; returning an error closes the connection.
+	OnConnect func(c *Conn) error
+	// OnMessage runs for every inbound message, sequentially per connection.
+	OnMessage func(c *Conn, msg []byte)
+	// OnClose runs once when the connection ends.
+	OnClose func(c *Conn)
+}
+
+// Server upgrades HTTP requests to WebSocket connections and tracks them in a Hub
+type Server struct {
+	opts     Options
+	upgrader gorilla.Upgrader
+	hub      *Hub
+
+	mu       sync.Mutex
+	closing  bool
+	sessions sync.WaitGroup
+}
+
+// NewServer creates a server; mount it as an http.Handler
+func NewServer(opts Options) *Server {
+	if opts.ReadLimit <= 0 {
+		opts.ReadLimit = 64 << 10
+	}
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	if opts.PongWait <= opts.PingInterval {
+		opts.PongWait = 2 * opts.PingInterval
+	}
+	if opts.WriteWait <= 0 {
+		opts.WriteWait = 10 * time.Second
+	}
+	if opts.SendBuffer <= 0 {
+		opts.SendBuffer = 64
+	}
+
+	s := &Server{opts: opts, hub: newHub()}
+	s.upgrader = gorilla.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     s.checkOrigin,
+	}
+	return s
+}
+
+// Hub returns the registry of live connections
+func (s *Server) Hub() *Hub {
+	return s.hub
+}
+
+// ServeHTTP upgrades the request and runs the connection until it closes
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	s.sessions.Add(1)
+	s.mu.Unlock()
+	defer s.sessions.Done()
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // the upgrader already wrote an error response
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	c := &Conn{
+		ID:      newID(),
+		Request: r,
+		ws:      ws,
+		send:    make(chan outbound, s.opts.SendBuffer),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+		server:  s,
+	}
+
+	if s.opts.OnConnect != nil {
+		if err := s.opts.OnConnect(c); err != nil {
+			ws.WriteControl(gorilla.CloseMessage,
+				gorilla.FormatCloseMessage(gorilla.ClosePolicyViolation, err.Error()),
+				time.Now().Add(s.opts.WriteWait))
+			ws.Close()
+			cancel()
+			return
+		}
+	}
+
+	s.hub.add(c)
+	go c.writePump()
+	c.readPump()
+}
+
+// Shutdown sends a going-away close frame to every connection and waits for them to end
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+
+	for _, c := range s.hub.all() {
+		c.CloseWith(gorilla.CloseGoingAway, "server shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.sessions.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, c := range s.hub.all() {
+			c.ws.Close()
+		}
+		return fmt.Errorf("failed to close websocket connections gracefully: %w", ctx.Err())
+	}
+}
+
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if u.Host == r.Host {
+		return true
+	}
+	for _, allowed := range s.opts.AllowedOrigins {
+		if allowed == "*" || allowed == u.Host {
+			return true
+		}
+	}
+	return false
+}
+
+type outbound struct {
+	kind int
+	data []byte
+}
+
+// Conn is one client connection. Sends are queued and written by a dedicated goroutine,
+// so they are safe to call concurrently and never block on the network.
+type Conn struct {
+	// ID uniquely identifies the connection within the process.
+	ID string
+	// Request is the original upgrade request, e.g. for auth claims in its context.
+	Request *http.Request
+
+	ws     *gorilla.Conn
+	send   chan outbound
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	server *Server
+
+	closeOnce sync.Once
+	mu        sync.Mutex
+	values    map[string]interface{}
+}
+
+// Context is cancelled when the connection ends
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Set stores a per-connection value, e.g. the authenticated user
+func (c *Conn) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = map[string]interface{}{}
+	}
+	c.values[key] = value
+}
+
+// Get returns a value stored with Set
+func (c *Conn) Get(key string) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+// Send queues a text message. A full buffer closes the connection with ErrSlowClient.
+func (c *Conn) Send(msg []byte) error {
+	return c.enqueue(outbound{kind: gorilla.TextMessage, data: msg})
+}
+
+// SendBinary queues a binary message
+func (c *Conn) SendBinary(msg []byte) error {
+	return c.enqueue(outbound{kind: gorilla.BinaryMessage, data: msg})
+}
+
+// SendJSON encodes v and queues it as a text message
+func (c *Conn) SendJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode websocket message: %w", err)
+	}
+	return c.Send(b)
+}
+
+func (c *Conn) enqueue(m outbound) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case c.send <- m:
+		return nil
+	case <-c.done:
+		return ErrClosed
+	default:
+		c.CloseWith(gorilla.ClosePolicyViolation, "client too slow")
+		return ErrSlowClient
+	}
+}
+
+// Close closes the connection normally
+func (c *Conn) Close() {
+	c.CloseWith(gorilla.CloseNormalClosure, "")
+}
+
+// CloseWith sends a close frame with code and reason, then ends the connection
+func (c *Conn) CloseWith(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.ws.WriteControl(gorilla.CloseMessage, gorilla.FormatCloseMessage(code, reason), time.Now().Add(c.server.opts.WriteWait))
+		close(c.done)
+		c.cancel()
+		// unblock readPump; it finishes cleanup
+		c.ws.SetReadDeadline(time.Now())
+	})
+}
+
+func (c *Conn) readPump() {
+	opts := c.server.opts
+	defer func() {
+		c.closeOnce.Do(func() {
+			close(c.done)
+			c.cancel()
+		})
+		c.server.hub.remove(c)
+		c.ws.Close()
+		if opts.OnClose != nil {
+			opts.OnClose(c)
+		}
+	}()
+
+	c.ws.SetReadLimit(opts.ReadLimit)
+	c.ws.SetReadDeadline(time.Now().Add(opts.PongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(opts.PongWait))
+	})
+
+	for {
+		_, msg, err := c.ws.ReadMessage()
+		if err != nil {
+			if gorilla.IsUnexpectedCloseError(err, gorilla.CloseNormalClosure, gorilla.CloseGoingAway, gorilla.CloseNoStatusReceived) {
+				select {
+				case <-c.done:
+				default:
+					log.Printf("websocket: connection %s: %v", c.ID, err)
+				}
+			}
+			return
+		}
+		c.ws.SetReadDeadline(time.Now().Add(opts.PongWait))
+		if opts.OnMessage != nil {
+			opts.OnMessage(c, msg)
+		}
+	}
+}
+
+func (c *Conn) writePump() {
+	opts := c.server.opts
+	ticker := time.NewTicker(opts.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case m := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(opts.WriteWait))
+			if err := c.ws.WriteMessage(m.kind, m.data); err != nil {
+				c.ws.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(gorilla.PingMessage, nil, time.Now().Add(opts.WriteWait)); err != nil {
+				c.ws.Close()
+				return
+			}
+		}
+	}
+}
+
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,3 @@
+module github.com/cdcloud-io/go-libs/workerpool
+
+go 1.22.4
@@ -0,0 +1,212 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// Task is a unit of work executed by the pool
+type Task[T any] func(ctx context.Context) (T, error)
+
+// Result holds the outcome of a task.
+// Index is the order in which the task was submitted, starting at 0.
+type Result[T any] struct {
+	Index int
+	Value T
+	Err   error
+}
+
+// Options configures a Pool
+type Options struct {
+	// Workers is the maximum number of tasks running concurrently. Defaults to 1.
+	Workers int
+	// QueueSize is the number of submitted tasks waiting for a worker before Submit blocks.
+	// Defaults to Workers.
+	QueueSize int
+}
+
+// Stats is a snapshot of pool counters
+type Stats struct {
+	Queued    int
+	Running   int64
+	Completed uint64
+	Failed    uint64
+	Panicked  uint64
+}
+
+var (
+	// ErrClosed is returned by Submit after Wait has been called
+	ErrClosed = errors.New("worker pool closed")
+	// ErrPanic wraps panics recovered from tasks
+	ErrPanic = errors.New("task panicked")
+)
+
+type job[T any] struct {
+	index int
+	task  Task[T]
+}
+
+// Pool runs tasks with bounded concurrency and collects their results.
+// A panicking task is recovered and reported as an ErrPanic result instead of crashing the process.
+type Pool[T any] struct {
+	ctx   context.Context
+	queue chan job[T]
+	wg    sync.WaitGroup
+
+	closeMu sync.RWMutex
+	closed  bool
+
+	mu      sync.Mutex
+	results []Result[T]
+
+	running   atomic.Int64
+	completed atomic.Uint64
+	failed    atomic.Uint64
+	panicked  atomic.Uint64
+}
+
+// New starts a pool whose workers stop picking up tasks once ctx is cancelled.
+// Tasks still queued at that point complete with ctx.Err() without running.
+func New[T any](ctx context.Context, opts Options) *Pool[T] {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = opts.Workers
+	}
+
+	p := &Pool[T]{
+		ctx:   ctx,
+		queue: make(chan job[T], opts.QueueSize),
+	}
+	for i := 0; i < opts.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p
+}
+
+// Submit queues a task, blocking while the queue is full.
+// It returns ctx.Err() if ctx (or the pool's context) is done before the task is queued.
+func (p *Pool[T]) Submit(ctx context.Context, task Task[T]) error {
+	// closeMu is held for reading while sending so Wait cannot close the queue under us
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	p.mu.Lock()
+	j := job[T]{index: len(p.results), task: task}
+	p.results = append(p.results, Result[T]{Index: j.index})
+	p.mu.Unlock()
+
+	var err error
+	select {
+	case p.queue <- j:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-p.ctx.Done():
+		err = p.ctx.Err()
+	}
+
+	p.mu.Lock()
+	p.results[j.index].Err = err
+	p.mu.Unlock()
+	return err
+}
+
+// Wait stops accepting tasks, waits for all queued tasks to finish and
+// returns their results in submission order.
+func (p *Pool[T]) Wait() []Result[T] {
+	p.closeMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.closeMu.Unlock()
+
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Result[T], len(p.results))
+	copy(out, p.results)
+	return out
+}
+
+// Stats returns the current queue depth and task counters
+func (p *Pool[T]) Stats() Stats {
+	return Stats{
+		Queued:    len(p.queue),
+		Running:   p.running.Load(),
+		Completed: p.completed.Load(),
+		Failed:    p.failed.Load(),
+		Panicked:  p.panicked.Load(),
+	}
+}
+
+func (p *Pool[T]) worker() {
+	defer p.wg.Done()
+
+	for j := range p.queue {
+		var res Result[T]
+		if err := p.ctx.Err(); err != nil {
+			res = Result[T]{Index: j.index, Err: err}
+		} else {
+			res = p.execute(j)
+		}
+
+		p.completed.Add(1)
+		if res.Err != nil {
+			p.failed.Add(1)
+		}
+
+		p.mu.Lock()
+		p.results[j.index] = res
+		p.mu.Unlock()
+	}
+}
+
+func (p *Pool[T]) execute(j job[T]) (res Result[T]) {
+	res.Index = j.index
+
+	p.running.Add(1)
+	defer p.running.Add(-1)
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.panicked.Add(1)
+			res.Err = fmt.Errorf("%w: %v\n%s", ErrPanic, r, debug.Stack())
+		}
+	}()
+
+	res.Value, res.Err = j.task(p.ctx)
+	return res
+}
+
+// Run executes tasks with at most workers running concurrently and returns
+// their results in order, along with the errors of all failed tasks joined together.
+func Run[T any](ctx context.Context, workers int, tasks []Task[T]) ([]Result[T], error) {
+	p := New[T](ctx, Options{Workers: workers})
+	for _, t := range tasks {
+		if err := p.Submit(ctx, t); err != nil {
+			break
+		}
+	}
+	results := p.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("task %d: %w", r.Index, r.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}